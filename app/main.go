@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -26,12 +27,61 @@ var revision string
 var opts struct {
 	Config string `short:"f" long:"config" env:"CONFIG" description:"config file"`
 
-	Listen  string   `short:"l" long:"listen" env:"LISTEN" default:"localhost:8080" description:"listen on host:port"`
-	Volumes []string `short:"v" long:"volume" env:"VOLUMES" default:"root:/" env-delim:"," description:"volumes to report"`
+	Listen   string   `short:"l" long:"listen" env:"LISTEN" default:"localhost:8080" description:"listen on host:port"`
+	Volumes  []string `short:"v" long:"volume" env:"VOLUMES" default:"root:/" env-delim:"," description:"volumes to report"`
+	HostRoot string   `long:"host-root" env:"HOST_ROOT" description:"prefix for volume paths, i.e. /hostroot"`
+
+	Metrics          string        `long:"metrics" env:"METRICS" default:"/metrics" description:"prometheus metrics path, empty to disable"`
+	MetricsSkipMuted bool          `long:"metrics-skip-muted" env:"METRICS_SKIP_MUTED" description:"don't report up metric of services skipped by gate"`
+	MetricsAge       time.Duration `long:"metrics-age" env:"METRICS_AGE" default:"1m" description:"max age of the last status reused by metrics, 0 to check on each scrape"`
+	Textfile         string        `long:"textfile" env:"TEXTFILE" description:"write metrics to prometheus textfile, i.e. /var/lib/node_exporter/sys-agent.prom"`
+	TextfileInterval time.Duration `long:"textfile-interval" env:"TEXTFILE_INTERVAL" default:"1m" description:"interval of textfile updates"`
+
+	CPUSample   time.Duration `long:"cpu-sample" env:"CPU_SAMPLE" default:"1s" description:"sampling interval for background cpu usage, 0 to disable"`
+	CoresSample time.Duration `long:"cores-sample" env:"CORES_SAMPLE" description:"sampling interval for per-core cpu usage, i.e. 200ms"`
+	ZFS         bool          `long:"zfs" env:"ZFS" description:"report zfs pools health, requires zpool"`
+	Connections []string      `long:"conn" env:"CONNECTIONS" env-delim:"," description:"ports to count established connections, name:port[:min[:max]]"`
+	AllowPorts  []string      `long:"allow-port" env:"ALLOW_PORTS" env-delim:"," description:"allowed listening ports, [proto:]port"`
+	Bindings    []string      `long:"bind" env:"BINDINGS" env-delim:"," description:"ports expected to be held by processes, name:[proto:]port:process, linux only"`
+	Sockets     []string      `long:"socket" env:"SOCKETS" env-delim:"," description:"listening tcp ports to check accept queue, name:port[:warn[:fail]], linux only"`
+	Mounts      []string      `long:"mount" env:"MOUNTS" env-delim:"," description:"mount points to check options, name:path:opt[+opt...], !opt for forbidden, linux only"`
+
+	Systemd       bool     `long:"systemd" env:"SYSTEMD" description:"report failed systemd units, requires systemctl"`
+	SystemdIgnore []string `long:"systemd-ignore" env:"SYSTEMD_IGNORE" env-delim:"," description:"failed systemd units to ignore, name or glob"`
+
+	UPS             bool          `long:"ups" env:"UPS" description:"report ups status from apcupsd, requires apcaccess"`
+	UPSMinCharge    int           `long:"ups-min-charge" env:"UPS_MIN_CHARGE" default:"50" description:"min ups battery charge percent"`
+	UPSMaxOnBattery time.Duration `long:"ups-max-on-battery" env:"UPS_MAX_ON_BATTERY" default:"5m" description:"max time on battery"`
+
+	GPU         bool `long:"gpu" env:"GPU" description:"report gpus health, requires nvidia-smi"`
+	GPUCount    int  `long:"gpu-count" env:"GPU_COUNT" description:"expected number of gpus"`
+	GPUTempWarn int  `long:"gpu-temp-warn" env:"GPU_TEMP_WARN" default:"80" description:"gpu temperature in celsius to warn"`
+	GPUTempFail int  `long:"gpu-temp-fail" env:"GPU_TEMP_FAIL" default:"90" description:"gpu temperature in celsius to fail"`
+	GPUMemWarn  int  `long:"gpu-mem-warn" env:"GPU_MEM_WARN" description:"gpu memory used percent to warn"`
+	GPUMemFail  int  `long:"gpu-mem-fail" env:"GPU_MEM_FAIL" description:"gpu memory used percent to fail"`
+
+	Kmsg       bool          `long:"kmsg" env:"KMSG" description:"report critical kernel log events, linux only"`
+	KmsgWindow time.Duration `long:"kmsg-window" env:"KMSG_WINDOW" default:"1h" description:"report kernel log events logged within this window"`
+	KmsgFail   []string      `long:"kmsg-fail" env:"KMSG_FAIL" env-delim:"," description:"kernel log patterns reported as failed, name:regex"`
+	KmsgWarn   []string      `long:"kmsg-warn" env:"KMSG_WARN" env-delim:"," description:"kernel log patterns reported as warn, name:regex"`
+
+	FSErrors       bool          `long:"fs-errors" env:"FS_ERRORS" description:"report ext4 filesystem errors, linux only"`
+	FSErrorsWindow time.Duration `long:"fs-errors-window" env:"FS_ERRORS_WINDOW" default:"24h" description:"errors within this window reported as failed, older as warn"`
+
+	ConfigDrift bool `long:"config-drift" env:"CONFIG_DRIFT" description:"report changes of the config file since startup"`
+
+	Labels []string `long:"label" env:"LABELS" env-delim:"," description:"static labels to report, name:value"`
 
 	Services []string      `short:"s" long:"service" env:"SERVICES" env-delim:"," description:"services to report"`
 	TimeOut  time.Duration `long:"timeout" env:"TIMEOUT" default:"5s" description:"timeout for each request to services"`
 
+	SoftStart        time.Duration `long:"soft-start" env:"SOFT_START" description:"spread the first round of service requests over this window"`
+	LatencyDeviation float64       `long:"latency-deviation" env:"LATENCY_DEVIATION" description:"warn if response time deviates from baseline by this multiple of stddev"`
+	SlowThreshold    time.Duration `long:"slow-threshold" env:"SLOW_THRESHOLD" description:"log service checks taking longer than this"`
+	Availability     bool          `long:"availability" env:"AVAILABILITY" description:"report availability of services over the last hour"`
+	When             []string      `long:"when" env:"WHEN" env-delim:"," description:"condition to run service check, name:condition"`
+	Preflight        string        `long:"preflight" env:"PREFLIGHT" description:"connectivity check url, remote services skipped if it fails"`
+
 	Concurrency int  `long:"concurrency" env:"CONCURRENCY" default:"4" description:"number of concurrent requests to services"`
 	Dbg         bool `long:"dbg" env:"DEBUG" description:"show debug info"`
 }
@@ -80,25 +130,168 @@ func main() {
 		log.Fatalf("[ERROR] %s", err)
 	}
 
+	conns, err := parseConnections(opts.Connections, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+
+	labels, err := parseLabels(opts.Labels, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+
+	allowed, err := parseAllowPorts(opts.AllowPorts, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+
+	bindings, err := parseBindings(opts.Bindings, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+
+	sockets, err := parseSockets(opts.Sockets, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+
+	mounts, err := parseMounts(opts.Mounts, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+
+	var drift *status.ConfigWatch
+	if opts.ConfigDrift {
+		if opts.Config == "" {
+			log.Fatalf("[ERROR] config drift check requires config file")
+		}
+		if drift, err = status.NewConfigWatch(opts.Config); err != nil {
+			log.Fatalf("[ERROR] %s", err)
+		}
+	}
+
+	var ups *status.UPSLimits
+	if opts.UPS {
+		ups = &status.UPSLimits{MinCharge: opts.UPSMinCharge, MaxOnBattery: opts.UPSMaxOnBattery}
+	}
+
+	var gpu *status.GPULimits
+	if opts.GPU {
+		gpu = &status.GPULimits{Count: opts.GPUCount, TempWarn: opts.GPUTempWarn, TempFail: opts.GPUTempFail,
+			MemWarn: opts.GPUMemWarn, MemFail: opts.GPUMemFail}
+	}
+
+	var kmsg *status.KmsgCheck
+	if opts.Kmsg {
+		if kmsg, err = status.NewKmsgCheck(opts.KmsgWindow, opts.KmsgFail, opts.KmsgWarn); err != nil {
+			log.Fatalf("[ERROR] %s", err)
+		}
+	}
+
+	var fsErrors time.Duration
+	if opts.FSErrors {
+		if opts.FSErrorsWindow <= 0 {
+			log.Fatalf("[ERROR] filesystem errors window should be positive, got %v", opts.FSErrorsWindow)
+		}
+		fsErrors = opts.FSErrorsWindow
+	}
+
 	providers := external.Providers{
 		HTTP:        &external.HTTPProvider{Client: http.Client{Timeout: opts.TimeOut}},
 		Mongo:       &external.MongoProvider{TimeOut: opts.TimeOut},
 		Docker:      &external.DockerProvider{TimeOut: opts.TimeOut},
-		Program:     &external.ProgramProvider{TimeOut: opts.TimeOut, WithShell: true},
+		Program:     &external.ProgramProvider{TimeOut: opts.TimeOut},
 		Nginx:       &external.NginxProvider{TimeOut: opts.TimeOut},
 		Certificate: &external.CertificateProvider{TimeOut: opts.TimeOut},
 		File:        &external.FileProvider{TimeOut: opts.TimeOut},
 		RMQ:         &external.RMQProvider{TimeOut: opts.TimeOut},
+		NATS:        &external.NATSProvider{TimeOut: opts.TimeOut},
 		Mysql:       &external.MysqlProvider{TimeOut: opts.TimeOut},
+		DNSZone:     &external.DNSZoneProvider{TimeOut: opts.TimeOut},
+		SNMP:        &external.SNMPProvider{TimeOut: opts.TimeOut},
+		Agent:       &external.AgentProvider{TimeOut: opts.TimeOut},
+		Graphite:    &external.GraphiteProvider{TimeOut: opts.TimeOut},
+		Resolver:    &external.ResolverProvider{TimeOut: opts.TimeOut},
+		WinService:  &external.WinServiceProvider{TimeOut: opts.TimeOut},
+		Beanstalk:   &external.BeanstalkProvider{TimeOut: opts.TimeOut},
+		Postgres:    &external.PostgresProvider{TimeOut: opts.TimeOut},
+		Redis:       &external.RedisProvider{TimeOut: opts.TimeOut},
+		Nomad:       &external.NomadProvider{TimeOut: opts.TimeOut},
+		TCP:         &external.TCPProvider{TimeOut: opts.TimeOut},
+		DNS:         &external.DNSProvider{TimeOut: opts.TimeOut},
+		Ping:        &external.PingProvider{TimeOut: opts.TimeOut},
+		Webhook:     &external.WebhookProvider{TimeOut: opts.TimeOut},
+		Apache:      &external.ApacheProvider{TimeOut: opts.TimeOut},
+		PHPFPM:      &external.PHPFPMProvider{TimeOut: opts.TimeOut},
+		Varnish:     &external.VarnishProvider{TimeOut: opts.TimeOut},
+		GRPC:        &external.GRPCProvider{TimeOut: opts.TimeOut},
+		SMTP:        &external.SMTPProvider{TimeOut: opts.TimeOut},
+		Kafka:       &external.KafkaProvider{TimeOut: opts.TimeOut},
+	}
+
+	if opts.SoftStart > 0 && opts.SoftStart+opts.TimeOut >= server.WriteTimeout {
+		// the first status request waits for the whole window and would fail with the write timeout
+		log.Fatalf("[ERROR] soft start window %v with timeout %v should be below %v", opts.SoftStart, opts.TimeOut, server.WriteTimeout)
+	}
+
+	extServices := external.NewService(providers, opts.Concurrency, services(opts.Services, conf)...)
+	extServices.LatencyDeviation = opts.LatencyDeviation
+	extServices.SoftStart = opts.SoftStart
+	extServices.SlowThreshold = opts.SlowThreshold
+	extServices.Availability = opts.Availability
+	gates, err := parseGates(opts.When, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+	if err = extServices.SetGates(gates); err != nil {
+		log.Fatalf("[ERROR] invalid service gates: %s", err)
+	}
+	preflight := opts.Preflight
+	if preflight == "" && conf != nil {
+		preflight = conf.Preflight
+	}
+	if err = extServices.SetPreflight(preflight); err != nil {
+		log.Fatalf("[ERROR] invalid pre-flight check: %s", err)
+	}
+
+	var cpuSampler *status.CPUSampler
+	if opts.CPUSample > 0 {
+		cpuSampler = &status.CPUSampler{Interval: opts.CPUSample}
+		go cpuSampler.Run(ctx)
 	}
 
 	srv := server.Rest{
-		Listen:  opts.Listen,
-		Version: revision,
+		Listen:      opts.Listen,
+		Version:     revision,
+		MetricsPath: opts.Metrics,
+		MetricsAge:  opts.MetricsAge,
+		SkipMuted:   opts.MetricsSkipMuted,
+		Labels:      labels,
+
+		Textfile:         opts.Textfile,
+		TextfileInterval: opts.TextfileInterval,
 		Status: &status.Service{
 			Volumes:     vols,
-			ExtServices: external.NewService(providers, opts.Concurrency, services(opts.Services, conf)...),
+			HostRoot:    opts.HostRoot,
+			CoresSample: opts.CoresSample,
+			CPU:         cpuSampler,
+			Connections: conns,
+			ZFS:         opts.ZFS,
+			Systemd:     opts.Systemd || len(opts.SystemdIgnore) > 0,
+			IgnoreUnits: opts.SystemdIgnore,
+			UPS:         ups,
+			GPU:         gpu,
+			ConfigDrift: drift,
+			Kmsg:        kmsg,
+			FSErrors:    fsErrors,
+			Labels:      labels,
+			AllowPorts:  allowed,
+			Bindings:    bindings,
+			Sockets:     sockets,
+			Mounts:      mounts,
+			ExtServices: extServices,
 		},
+		Providers: extServices,
 	}
 
 	if err := srv.Run(ctx); err != nil && err.Error() != "http: Server closed" {
@@ -114,19 +307,23 @@ func services(optsSvcs []string, conf *config.Parameters) (res []string) {
 	if conf != nil {
 		res = append(res, conf.MarshalServices()...)
 	}
-	log.Printf("[DEBUG] services: %+v", res)
+	log.Printf("[DEBUG] services: %d", len(res)) // each service logged by external.NewService with env values masked
 	return res
 }
 
-// parseVolumes parses volumes from string list, each element in format "name:path"
-// picks volumes from config if present and overrides with command line
+// parseVolumes parses volumes from string list, each element in format "name:path" or "name:path:max",
+// with max usage percent to report the volume as failed. Picks volumes from config if present and overrides
+// with command line
 func parseVolumes(volumes []string, conf *config.Parameters) ([]status.Volume, error) {
 	res := []status.Volume{}
 
 	// load from config if present and volumes provided
 	if conf != nil && len(conf.Volumes) > 0 {
 		for _, v := range conf.Volumes {
-			res = append(res, status.Volume{Name: v.Name, Path: v.Path})
+			if v.MaxUsedPct < 0 || v.MaxUsedPct > 100 {
+				return nil, fmt.Errorf("invalid max used percent %d for volume %s, should be 1-100", v.MaxUsedPct, v.Name)
+			}
+			res = append(res, status.Volume{Name: v.Name, Path: v.Path, ProbeWrite: v.ProbeWrite, MaxUsedPct: v.MaxUsedPct})
 		}
 	}
 
@@ -138,7 +335,17 @@ func parseVolumes(volumes []string, conf *config.Parameters) ([]status.Volume, e
 			if len(parts) != 2 {
 				return nil, errors.New("invalid volume format, should be <name>:<path>")
 			}
-			res = append(res, status.Volume{Name: parts[0], Path: parts[1]})
+			vol := status.Volume{Name: parts[0], Path: parts[1]}
+			// the last part is max usage if numeric, windows paths like "C:\" have ":" too
+			if i := strings.LastIndex(parts[1], ":"); i >= 0 {
+				if maxUsed, err := strconv.Atoi(parts[1][i+1:]); err == nil {
+					if maxUsed < 1 || maxUsed > 100 {
+						return nil, fmt.Errorf("invalid max used percent in %q, should be 1-100", v)
+					}
+					vol.Path, vol.MaxUsedPct = parts[1][:i], maxUsed
+				}
+			}
+			res = append(res, vol)
 		}
 	}
 
@@ -146,6 +353,235 @@ func parseVolumes(volumes []string, conf *config.Parameters) ([]status.Volume, e
 	return res, nil
 }
 
+// parseLabels parses labels from string list, each element in format "name:value"
+// merges labels from config with command line, command line wins. Sets hostname label from os.Hostname if not set.
+func parseLabels(labels []string, conf *config.Parameters) (map[string]string, error) {
+	res := map[string]string{}
+	if conf != nil {
+		for k, v := range conf.Labels {
+			res[k] = v
+		}
+	}
+
+	for _, l := range labels {
+		parts := strings.SplitN(l, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New("invalid label format, should be <name>:<value>")
+		}
+		res[parts[0]] = parts[1]
+	}
+
+	if _, ok := res["hostname"]; !ok {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname: %w", err)
+		}
+		res["hostname"] = hostname
+	}
+
+	log.Printf("[DEBUG] labels: %+v", res)
+	return res, nil
+}
+
+// parseAllowPorts checks allowlist of listening ports, each element in format "[proto:]port"
+// picks ports from config if not set on command line
+func parseAllowPorts(ports []string, conf *config.Parameters) ([]string, error) {
+	if len(ports) == 0 && conf != nil {
+		ports = conf.AllowPorts
+	}
+	for _, p := range ports {
+		proto, port, found := strings.Cut(p, ":")
+		if !found {
+			port = proto
+		}
+		if found && proto != "tcp" && proto != "udp" {
+			return nil, fmt.Errorf("invalid allowed port %q, protocol should be tcp or udp", p)
+		}
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("invalid allowed port %q: %w", p, err)
+		}
+	}
+	log.Printf("[DEBUG] allowed ports: %v", ports)
+	return ports, nil
+}
+
+// parseBindings parses port bindings from string list, each element in format "name:[proto:]port:process",
+// proto is tcp by default and process is the name or pid. Picks bindings from config if present and overrides
+// with command line.
+func parseBindings(bindings []string, conf *config.Parameters) ([]status.Binding, error) {
+	res := []status.Binding{}
+
+	if conf != nil && len(conf.Bindings) > 0 {
+		for _, b := range conf.Bindings {
+			proto := b.Proto
+			if proto == "" {
+				proto = "tcp"
+			}
+			res = append(res, status.Binding{Name: b.Name, Proto: proto, Port: b.Port, Process: b.Process})
+		}
+	}
+
+	if len(bindings) > 0 {
+		res = []status.Binding{} // reset bindings from config (if filled), don't merge
+		for _, b := range bindings {
+			parts := strings.Split(b, ":")
+			if len(parts) == 3 {
+				parts = []string{parts[0], "tcp", parts[1], parts[2]}
+			}
+			if len(parts) != 4 || parts[0] == "" || parts[3] == "" {
+				return nil, errors.New("invalid binding format, should be <name>:[<proto>:]<port>:<process>")
+			}
+			port, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid binding %q: %w", b, err)
+			}
+			res = append(res, status.Binding{Name: parts[0], Proto: parts[1], Port: port, Process: parts[3]})
+		}
+	}
+
+	for _, b := range res {
+		if b.Proto != "tcp" && b.Proto != "udp" {
+			return nil, fmt.Errorf("invalid binding %q, protocol should be tcp or udp", b.Name)
+		}
+	}
+	log.Printf("[DEBUG] bindings: %+v", res)
+	return res, nil
+}
+
+// parseGates parses conditions to run service checks from string list, each element in format "name:condition",
+// i.e. "pg_lag:pg_role" or "pg_lag:!file:/var/lib/postgresql/primary". Merges gates from config with command line,
+// command line wins.
+func parseGates(gates []string, conf *config.Parameters) (map[string]string, error) {
+	res := map[string]string{}
+	if conf != nil {
+		for k, v := range conf.When {
+			res[k] = v
+		}
+	}
+
+	for _, g := range gates {
+		name, cond, found := strings.Cut(g, ":")
+		if !found || name == "" || cond == "" {
+			return nil, errors.New("invalid gate format, should be <name>:<condition>")
+		}
+		res[name] = cond
+	}
+	log.Printf("[DEBUG] gates: %+v", res)
+	return res, nil
+}
+
+// parseSockets parses accept queue checks from string list, each element in format "name:port[:warn[:fail]]"
+// picks sockets from config if present and overrides with command line
+func parseSockets(sockets []string, conf *config.Parameters) ([]status.SocketQueue, error) {
+	res := []status.SocketQueue{}
+
+	if conf != nil && len(conf.Sockets) > 0 {
+		for _, s := range conf.Sockets {
+			res = append(res, status.SocketQueue{Name: s.Name, Port: s.Port, Warn: s.Warn, Fail: s.Fail})
+		}
+	}
+
+	if len(sockets) > 0 {
+		res = []status.SocketQueue{} // reset sockets from config (if filled), don't merge
+		for _, s := range sockets {
+			parts := strings.Split(s, ":")
+			if len(parts) < 2 || len(parts) > 4 || parts[0] == "" {
+				return nil, errors.New("invalid socket format, should be <name>:<port>[:<warn>[:<fail>]]")
+			}
+			vals := make([]int, 3) // port, warn, fail
+			for i, p := range parts[1:] {
+				if p == "" && i > 0 {
+					continue // empty warn or fail
+				}
+				v, err := strconv.Atoi(p)
+				if err != nil {
+					return nil, fmt.Errorf("invalid socket %q: %w", s, err)
+				}
+				vals[i] = v
+			}
+			res = append(res, status.SocketQueue{Name: parts[0], Port: vals[0], Warn: vals[1], Fail: vals[2]})
+		}
+	}
+
+	log.Printf("[DEBUG] sockets: %+v", res)
+	return res, nil
+}
+
+// parseMounts parses mount options checks from string list, each element in format "name:path:opt[+opt...]",
+// options with "!" prefix are forbidden, i.e. "tmp:/tmp:noexec+nosuid+!exec".
+// picks mounts from config if present and overrides with command line
+func parseMounts(mounts []string, conf *config.Parameters) ([]status.Mount, error) {
+	res := []status.Mount{}
+
+	if conf != nil && len(conf.Mounts) > 0 {
+		for _, m := range conf.Mounts {
+			res = append(res, status.Mount{Name: m.Name, Path: m.Path, Require: m.Require, Forbid: m.Forbid})
+		}
+	}
+
+	if len(mounts) > 0 {
+		res = []status.Mount{} // reset mounts from config (if filled), don't merge
+		for _, m := range mounts {
+			parts := strings.SplitN(m, ":", 3)
+			if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+				return nil, errors.New("invalid mount format, should be <name>:<path>:<option>[+<option>...]")
+			}
+			mount := status.Mount{Name: parts[0], Path: parts[1]}
+			for _, opt := range strings.Split(parts[2], "+") {
+				switch {
+				case opt == "" || opt == "!":
+					return nil, fmt.Errorf("empty option in mount %q", m)
+				case strings.HasPrefix(opt, "!"):
+					mount.Forbid = append(mount.Forbid, opt[1:])
+				default:
+					mount.Require = append(mount.Require, opt)
+				}
+			}
+			res = append(res, mount)
+		}
+	}
+
+	log.Printf("[DEBUG] mounts: %+v", res)
+	return res, nil
+}
+
+// parseConnections parses connection checks from string list, each element in format "name:port[:min[:max]]"
+// picks connections from config if present and overrides with command line
+func parseConnections(conns []string, conf *config.Parameters) ([]status.Connection, error) {
+	res := []status.Connection{}
+
+	if conf != nil && len(conf.Connections) > 0 {
+		for _, c := range conf.Connections {
+			res = append(res, status.Connection{Name: c.Name, Port: c.Port, Min: c.Min, Max: c.Max})
+		}
+	}
+
+	if len(conns) > 0 {
+		res = []status.Connection{} // reset connections from config (if filled), don't merge
+		for _, c := range conns {
+			parts := strings.Split(c, ":")
+			if len(parts) < 2 || len(parts) > 4 || parts[0] == "" {
+				return nil, errors.New("invalid connection format, should be <name>:<port>[:<min>[:<max>]]")
+			}
+			vals := make([]int, 3) // port, min, max
+			for i, p := range parts[1:] {
+				if p == "" && i > 0 {
+					continue // empty min or max
+				}
+				v, err := strconv.Atoi(p)
+				if err != nil {
+					return nil, fmt.Errorf("invalid connection %q: %w", c, err)
+				}
+				vals[i] = v
+			}
+			res = append(res, status.Connection{Name: parts[0], Port: vals[0], Min: vals[1], Max: vals[2]})
+		}
+	}
+
+	log.Printf("[DEBUG] connections: %+v", res)
+	return res, nil
+}
+
 func setupLog(dbg bool) {
 	logOpts := []lgr.Option{lgr.Msec, lgr.LevelBraces, lgr.StackTraceOnError}
 	if dbg {
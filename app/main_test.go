@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -29,7 +30,11 @@ func Test_parseVolumes_ArgsOnly(t *testing.T) {
 		{[]string{"data volume:/data"}, []status.Volume{{Name: "data volume", Path: "/data"}}, nil},
 		{[]string{"data volume:/data", "blah:/"},
 			[]status.Volume{{Name: "data volume", Path: "/data"}, {Name: "blah", Path: "/"}}, nil},
+		{[]string{"data:/data:90", `win:C:\`, `win_max:C:\:95`},
+			[]status.Volume{{Name: "data", Path: "/data", MaxUsedPct: 90}, {Name: "win", Path: `C:\`},
+				{Name: "win_max", Path: `C:\`, MaxUsedPct: 95}}, nil},
 		{[]string{"/data"}, []status.Volume{}, errors.New("invalid volume format, should be <name>:<path>")},
+		{[]string{"data:/data:120"}, []status.Volume{}, errors.New(`invalid max used percent in "data:/data:120", should be 1-100`)},
 	}
 
 	for i, tt := range tbl {
@@ -49,7 +54,7 @@ func Test_parseVolumes_ConfigOnly(t *testing.T) {
 	require.NoError(t, err)
 	vols, err := parseVolumes(nil, conf)
 	require.NoError(t, err)
-	assert.Equal(t, []status.Volume{{Name: "root", Path: "/hostroot"}, {Name: "data", Path: "/data"}}, vols)
+	assert.Equal(t, []status.Volume{{Name: "root", Path: "/hostroot"}, {Name: "data", Path: "/data", ProbeWrite: true, MaxUsedPct: 90}}, vols)
 }
 
 func Test_parseVolumes_ArgsAndConfig(t *testing.T) {
@@ -60,9 +65,236 @@ func Test_parseVolumes_ArgsAndConfig(t *testing.T) {
 	assert.Equal(t, []status.Volume{{Name: "data volume", Path: "/data"}, {Name: "blah", Path: "/"}}, vols)
 }
 
+func Test_parseAllowPorts(t *testing.T) {
+	tbl := []struct {
+		inp   []string
+		ports []string
+		err   string
+	}{
+		{[]string{"22", "tcp:8080", "udp:53"}, []string{"22", "tcp:8080", "udp:53"}, ""},
+		{[]string{"sctp:22"}, nil, `invalid allowed port "sctp:22", protocol should be tcp or udp`},
+		{[]string{"tcp:ssh"}, nil, `invalid allowed port "tcp:ssh": strconv.Atoi: parsing "ssh": invalid syntax`},
+	}
+
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ports, err := parseAllowPorts(tt.inp, nil)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.ports, ports)
+		})
+	}
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	ports, err := parseAllowPorts(nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"22", "tcp:8080", "udp:53"}, ports)
+
+	ports, err = parseAllowPorts([]string{"443"}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"443"}, ports, "command line overrides config")
+}
+
+func Test_parseBindings(t *testing.T) {
+	tbl := []struct {
+		inp      []string
+		bindings []status.Binding
+		err      string
+	}{
+		{[]string{"web:8080:nginx"}, []status.Binding{{Name: "web", Proto: "tcp", Port: 8080, Process: "nginx"}}, ""},
+		{[]string{"dns:udp:53:dnsmasq", "pg:tcp:5432:1234"}, []status.Binding{
+			{Name: "dns", Proto: "udp", Port: 53, Process: "dnsmasq"}, {Name: "pg", Proto: "tcp", Port: 5432, Process: "1234"}}, ""},
+		{[]string{"web:8080"}, nil, "invalid binding format, should be <name>:[<proto>:]<port>:<process>"},
+		{[]string{"web:8080:"}, nil, "invalid binding format, should be <name>:[<proto>:]<port>:<process>"},
+		{[]string{"web:http:nginx"}, nil, `invalid binding "web:http:nginx": strconv.Atoi: parsing "http": invalid syntax`},
+		{[]string{"web:sctp:80:nginx"}, nil, `invalid binding "web", protocol should be tcp or udp`},
+	}
+
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			bindings, err := parseBindings(tt.inp, nil)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.bindings, bindings)
+		})
+	}
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	bindings, err := parseBindings(nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.Binding{{Name: "web", Proto: "tcp", Port: 8080, Process: "nginx"},
+		{Name: "dns", Proto: "udp", Port: 53, Process: "dnsmasq"}}, bindings)
+
+	bindings, err = parseBindings([]string{"ssh:22:sshd"}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.Binding{{Name: "ssh", Proto: "tcp", Port: 22, Process: "sshd"}}, bindings,
+		"command line overrides config")
+}
+
+func Test_parseConnections(t *testing.T) {
+	tbl := []struct {
+		inp   []string
+		conns []status.Connection
+		err   string
+	}{
+		{[]string{"web:8080"}, []status.Connection{{Name: "web", Port: 8080}}, ""},
+		{[]string{"pg:5432:1:100", "web:8080:2", "redis:6379::50"}, []status.Connection{
+			{Name: "pg", Port: 5432, Min: 1, Max: 100}, {Name: "web", Port: 8080, Min: 2}, {Name: "redis", Port: 6379, Max: 50}}, ""},
+		{[]string{"web"}, nil, "invalid connection format, should be <name>:<port>[:<min>[:<max>]]"},
+		{[]string{"web:8080:1:2:3"}, nil, "invalid connection format, should be <name>:<port>[:<min>[:<max>]]"},
+		{[]string{"web:http"}, nil, `invalid connection "web:http": strconv.Atoi: parsing "http": invalid syntax`},
+		{[]string{"web::1"}, nil, `invalid connection "web::1": strconv.Atoi: parsing "": invalid syntax`},
+	}
+
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			conns, err := parseConnections(tt.inp, nil)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.conns, conns)
+		})
+	}
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	conns, err := parseConnections(nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.Connection{{Name: "postgres", Port: 5432, Min: 1, Max: 100}, {Name: "web", Port: 8080}}, conns)
+
+	conns, err = parseConnections([]string{"web:80"}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.Connection{{Name: "web", Port: 80}}, conns, "command line overrides config")
+}
+
+func Test_parseLabels(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	res, err := parseLabels([]string{"datacenter:dc1", "role:web:front"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"hostname": hostname, "datacenter": "dc1", "role": "web:front"}, res)
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	res, err = parseLabels([]string{"role:web", "hostname:node1"}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"hostname": "node1", "datacenter": "us-east-1", "role": "web"}, res)
+
+	_, err = parseLabels([]string{"blah"}, nil)
+	require.EqualError(t, err, "invalid label format, should be <name>:<value>")
+}
+
+func Test_parseSockets(t *testing.T) {
+	tbl := []struct {
+		inp     []string
+		sockets []status.SocketQueue
+		err     string
+	}{
+		{[]string{"app:3000"}, []status.SocketQueue{{Name: "app", Port: 3000}}, ""},
+		{[]string{"app:3000:10:100", "web:8080::50"}, []status.SocketQueue{
+			{Name: "app", Port: 3000, Warn: 10, Fail: 100}, {Name: "web", Port: 8080, Fail: 50}}, ""},
+		{[]string{"app"}, nil, "invalid socket format, should be <name>:<port>[:<warn>[:<fail>]]"},
+		{[]string{"app:3000:1:2:3"}, nil, "invalid socket format, should be <name>:<port>[:<warn>[:<fail>]]"},
+		{[]string{"app:http"}, nil, `invalid socket "app:http": strconv.Atoi: parsing "http": invalid syntax`},
+	}
+
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			sockets, err := parseSockets(tt.inp, nil)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.sockets, sockets)
+		})
+	}
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	sockets, err := parseSockets(nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.SocketQueue{{Name: "app", Port: 3000, Warn: 10, Fail: 100}}, sockets)
+
+	sockets, err = parseSockets([]string{"web:8080"}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.SocketQueue{{Name: "web", Port: 8080}}, sockets, "command line overrides config")
+}
+
+func Test_parseMounts(t *testing.T) {
+	tbl := []struct {
+		inp    []string
+		mounts []status.Mount
+		err    string
+	}{
+		{[]string{"tmp:/tmp:noexec"}, []status.Mount{{Name: "tmp", Path: "/tmp", Require: []string{"noexec"}}}, ""},
+		{[]string{"tmp:/tmp:noexec+nosuid+!exec", "data:/data:!rw"}, []status.Mount{
+			{Name: "tmp", Path: "/tmp", Require: []string{"noexec", "nosuid"}, Forbid: []string{"exec"}},
+			{Name: "data", Path: "/data", Forbid: []string{"rw"}}}, ""},
+		{[]string{"tmp:/tmp:mode=1777"}, []status.Mount{{Name: "tmp", Path: "/tmp", Require: []string{"mode=1777"}}}, ""},
+		{[]string{"tmp:/tmp"}, nil, "invalid mount format, should be <name>:<path>:<option>[+<option>...]"},
+		{[]string{":/tmp:noexec"}, nil, "invalid mount format, should be <name>:<path>:<option>[+<option>...]"},
+		{[]string{"tmp:/tmp:noexec++nosuid"}, nil, `empty option in mount "tmp:/tmp:noexec++nosuid"`},
+		{[]string{"tmp:/tmp:!"}, nil, `empty option in mount "tmp:/tmp:!"`},
+	}
+
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			mounts, err := parseMounts(tt.inp, nil)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.mounts, mounts)
+		})
+	}
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	mounts, err := parseMounts(nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.Mount{{Name: "tmp", Path: "/tmp", Require: []string{"noexec", "nosuid", "nodev"}},
+		{Name: "data", Path: "/data", Require: []string{"noatime"}, Forbid: []string{"ro"}}}, mounts)
+
+	mounts, err = parseMounts([]string{"var:/var:nodev"}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []status.Mount{{Name: "var", Path: "/var", Require: []string{"nodev"}}}, mounts,
+		"command line overrides config")
+}
+
+func Test_parseGates(t *testing.T) {
+	res, err := parseGates([]string{"pg_lag:pg_role", "web:!file:/etc/maintenance"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pg_lag": "pg_role", "web": "!file:/etc/maintenance"}, res)
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	res, err = parseGates([]string{"mail_mx:hostname:mx-*"}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pg_replica": "!file:/var/lib/postgresql/primary", "mail_mx": "hostname:mx-*"}, res)
+
+	_, err = parseGates([]string{"pg_lag"}, nil)
+	require.EqualError(t, err, "invalid gate format, should be <name>:<condition>")
+	_, err = parseGates([]string{"pg_lag:"}, nil)
+	require.EqualError(t, err, "invalid gate format, should be <name>:<condition>")
+}
+
 func Test_main(t *testing.T) {
 	port := 40000 + int(rand.Int31n(1000))
-	os.Args = []string{"app", "--listen=127.0.0.1:" + strconv.Itoa(port), "-v root:/", "-s echo:https://echo.umputun.com", "--dbg"}
+	os.Args = []string{"app", "--listen=127.0.0.1:" + strconv.Itoa(port), "-v root:/", "-s echo:https://echo.umputun.com",
+		"--label=datacenter:dc1", "--dbg"}
 
 	done := make(chan struct{})
 	go func() {
@@ -101,6 +333,11 @@ func Test_main(t *testing.T) {
 		require.NoError(t, err)
 		defer resp.Body.Close()
 		assert.Equal(t, 200, resp.StatusCode)
+		st := status.Info{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+		hostname, err := os.Hostname()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"hostname": hostname, "datacenter": "dc1"}, st.Labels)
 	}
 }
 
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/umputun/sys-agent/app/status"
+)
+
+// lastStatus wraps Status and keeps the last status got by /status request or metrics scrape. Metrics reuse it
+// if it is not older than maxAge, so /status and /metrics polled together run the checks once, and the state kept
+// between checks, i.e. docker restart counters, etag, jwks rotation, latency baseline and availability, is updated once.
+type lastStatus struct {
+	Status
+	maxAge time.Duration // reuse disabled if 0, each call gets the status
+
+	lock sync.Mutex
+	info *status.Info
+	at   time.Time
+}
+
+// Get gets the status and keeps it as the last one
+func (l *lastStatus) Get() (*status.Info, error) {
+	info, err := l.Status.Get()
+	if err != nil {
+		return nil, err
+	}
+	l.lock.Lock()
+	l.info, l.at = info, time.Now()
+	l.lock.Unlock()
+	return info, nil
+}
+
+// Recent returns the last status if it is not older than maxAge, otherwise gets a new one.
+// Concurrent calls wait for the one getting the status and reuse it.
+func (l *lastStatus) Recent() (*status.Info, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.info != nil && time.Since(l.at) < l.maxAge {
+		return l.info, nil
+	}
+	info, err := l.Status.Get()
+	if err != nil {
+		return nil, err
+	}
+	l.info, l.at = info, time.Now()
+	return info, nil
+}
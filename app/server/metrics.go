@@ -0,0 +1,120 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statusCollector is prometheus collector reporting the last status, got by /status request or previous scrape,
+// if it is recent enough, otherwise the scrape gets the status the same way as /status request.
+// Static labels, i.e. hostname, are attached to all metrics.
+// Services skipped by the gate, i.e. "web:!file:/etc/maintenance", or as offline by the pre-flight check are muted.
+// With skipMuted they are not reported in sys_agent_service_up, so aggregates and alerts ignore them,
+// sys_agent_service_muted is reported for all services. It is named after the other metrics, all in sys_agent_
+// namespace, not sysagent_.
+type statusCollector struct {
+	status    *lastStatus
+	skipMuted bool
+
+	serviceUp     *prometheus.Desc
+	serviceMuted  *prometheus.Desc
+	responseTime  *prometheus.Desc
+	availability  *prometheus.Desc
+	volumeUsage   *prometheus.Desc
+	volumeInodes  *prometheus.Desc
+	cpuPercent    *prometheus.Desc
+	memPercent    *prometheus.Desc
+	offline       *prometheus.Desc
+	statusSuccess *prometheus.Desc
+}
+
+func newStatusCollector(st *lastStatus, skipMuted bool, labels map[string]string) *statusCollector {
+	constLabels := metricLabels(labels)
+	return &statusCollector{
+		status:    st,
+		skipMuted: skipMuted,
+		serviceUp: prometheus.NewDesc("sys_agent_service_up",
+			"1 if the last check of the service is not failed, 0 otherwise", []string{"name"}, constLabels),
+		serviceMuted: prometheus.NewDesc("sys_agent_service_muted",
+			"1 if the service check is skipped by the gate, i.e. in maintenance, 0 otherwise", []string{"name"}, constLabels),
+		responseTime: prometheus.NewDesc("sys_agent_service_response_time_seconds",
+			"response time of the last check of the service", []string{"name"}, constLabels),
+		availability: prometheus.NewDesc("sys_agent_service_availability_1h_percent",
+			"percent of time the service was up over the last hour", []string{"name"}, constLabels),
+		volumeUsage: prometheus.NewDesc("sys_agent_volume_usage_percent",
+			"disk usage of the volume", []string{"name", "path"}, constLabels),
+		volumeInodes: prometheus.NewDesc("sys_agent_volume_inodes_used_percent",
+			"inodes usage of the volume", []string{"name", "path"}, constLabels),
+		cpuPercent:    prometheus.NewDesc("sys_agent_cpu_percent", "cpu utilization", nil, constLabels),
+		memPercent:    prometheus.NewDesc("sys_agent_mem_percent", "memory utilization", nil, constLabels),
+		offline:       prometheus.NewDesc("sys_agent_offline", "1 if the pre-flight connectivity check failed, 0 otherwise", nil, constLabels),
+		statusSuccess: prometheus.NewDesc("sys_agent_status_success", "1 if the status is collected, 0 otherwise", nil, constLabels),
+	}
+}
+
+// Describe sends descriptors of all metrics reported by the collector
+func (c *statusCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.serviceUp, c.serviceMuted, c.responseTime, c.availability, c.volumeUsage,
+		c.volumeInodes, c.cpuPercent, c.memPercent, c.offline, c.statusSuccess} {
+		ch <- d
+	}
+}
+
+// Collect gets the recent status and sends its metrics. Failed status is reported with sys_agent_status_success 0,
+// the scrape itself doesn't fail, so the target stays up.
+func (c *statusCollector) Collect(ch chan<- prometheus.Metric) {
+	info, err := c.status.Recent()
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.statusSuccess, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.statusSuccess, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(c.cpuPercent, prometheus.GaugeValue, float64(info.CPUPercent))
+	ch <- prometheus.MustNewConstMetric(c.memPercent, prometheus.GaugeValue, float64(info.MemPercent))
+	offline := 0.0
+	if info.Offline != nil {
+		offline = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.offline, prometheus.GaugeValue, offline)
+	for _, v := range info.Volumes {
+		ch <- prometheus.MustNewConstMetric(c.volumeUsage, prometheus.GaugeValue, float64(v.UsagePercent), v.Name, v.Path)
+		ch <- prometheus.MustNewConstMetric(c.volumeInodes, prometheus.GaugeValue, float64(v.InodesUsedPct), v.Name, v.Path)
+	}
+	for name, r := range info.ExtServices {
+		muted := 0.0
+		if r.Skipped != "" {
+			muted = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.serviceMuted, prometheus.GaugeValue, muted, name)
+		if muted == 1 && c.skipMuted {
+			continue
+		}
+		up := 0.0
+		if r.StatusCode < 400 && !strings.HasSuffix(r.Summary, "(failed)") {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.serviceUp, prometheus.GaugeValue, up, name)
+		ch <- prometheus.MustNewConstMetric(c.responseTime, prometheus.GaugeValue, float64(r.ResponseTime)/1000, name)
+		if r.Availability != nil {
+			ch <- prometheus.MustNewConstMetric(c.availability, prometheus.GaugeValue, *r.Availability, name)
+		}
+	}
+}
+
+var labelNameRe = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// metricLabels makes prometheus const labels from static labels. Invalid characters of the name are replaced with "_",
+// and names used by metrics themselves, name and path, are prefixed with "label_".
+func metricLabels(labels map[string]string) prometheus.Labels {
+	res := prometheus.Labels{}
+	for k, v := range labels {
+		name := labelNameRe.ReplaceAllString(k, "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') || strings.HasPrefix(name, "__") || name == "name" || name == "path" {
+			name = "label_" + name
+		}
+		res[name] = v
+	}
+	return res
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/didip/tollbooth/v7"
@@ -10,17 +13,35 @@ import (
 	"github.com/go-chi/chi/v5"
 	log "github.com/go-pkgz/lgr"
 	"github.com/go-pkgz/rest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/vmihailenco/msgpack/v5"
 
 	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
 )
 
 //go:generate moq -out status_mock.go -skip-ensure -fmt goimports . Status
+//go:generate moq -out providers_mock.go -skip-ensure -fmt goimports . Providers
+
+// WriteTimeout limits time to respond, including all checks of the request
+const WriteTimeout = 30 * time.Second
 
 // Rest implement http api invoking remote execution for requested tasks
 type Rest struct {
-	Listen  string
-	Version string
-	Status  Status
+	Listen      string
+	Version     string
+	Status      Status
+	Providers   Providers
+	MetricsPath string            // path of prometheus metrics endpoint, i.e. /metrics, disabled if empty
+	MetricsAge  time.Duration     // max age of the last status reused by metrics, each scrape gets the status if 0
+	SkipMuted   bool              // don't report services skipped by the gate in up metric
+	Labels      map[string]string // static labels attached to all metrics, i.e. hostname
+
+	Textfile         string        // path of prometheus textfile for node-exporter textfile collector, disabled if empty
+	TextfileInterval time.Duration // interval of textfile updates, 1m if not set
+
+	last *lastStatus // shared by /status, metrics and textfile, made by router
 }
 
 // Status is used to get status info of the server
@@ -28,6 +49,11 @@ type Status interface {
 	Get() (*status.Info, error)
 }
 
+// Providers is used to get list of supported url schemes of external services
+type Providers interface {
+	Schemes() []external.Scheme
+}
+
 // Run starts http server and closes on context cancellation
 func (s *Rest) Run(ctx context.Context) error {
 	log.Printf("[INFO] start http server on %s", s.Listen)
@@ -36,11 +62,15 @@ func (s *Rest) Run(ctx context.Context) error {
 		Addr:              s.Listen,
 		Handler:           s.router(),
 		ReadHeaderTimeout: time.Second,
-		WriteTimeout:      30 * time.Second,
+		WriteTimeout:      WriteTimeout,
 		IdleTimeout:       time.Second,
 		ErrorLog:          log.ToStdLogger(log.Default(), "WARN"),
 	}
 
+	if s.Textfile != "" && s.Status != nil {
+		go s.runTextfile(ctx, s.last)
+	}
+
 	go func() {
 		<-ctx.Done()
 		if httpServer != nil {
@@ -62,15 +92,63 @@ func (s *Rest) router() http.Handler {
 	router.Use(rest.Ping)
 	router.Use(tollbooth_chi.LimitHandler(tollbooth.NewLimiter(10, nil)))
 
+	s.last = &lastStatus{Status: s.Status, maxAge: s.MetricsAge}
+	last := s.last
 	router.Get("/status", func(w http.ResponseWriter, r *http.Request) {
 
-		resp, err := s.Status.Get()
+		resp, err := last.Get()
 		if err != nil {
 			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
 			return
 		}
+		w.Header().Add("Vary", "Accept")
+		if acceptsMsgpack(r) {
+			renderMsgpack(w, r, resp)
+			return
+		}
 		rest.RenderJSON(w, resp)
 	})
 
+	router.Get("/providers", func(w http.ResponseWriter, r *http.Request) {
+		res := []external.Scheme{}
+		if s.Providers != nil {
+			res = s.Providers.Schemes()
+		}
+		rest.RenderJSON(w, res)
+	})
+
+	if s.MetricsPath != "" && s.Status != nil {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(newStatusCollector(last, s.SkipMuted, s.Labels))
+		router.Handle(s.MetricsPath, promhttp.HandlerFor(reg, promhttp.HandlerOpts{ErrorLog: log.ToStdLogger(log.Default(), "WARN")}))
+	}
+
 	return router
 }
+
+// acceptsMsgpack checks the client asked for msgpack in Accept header, i.e. "application/msgpack, application/json;q=0.9".
+// Json stays the default for any other Accept, including */*.
+func acceptsMsgpack(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != external.MsgpackContentType {
+			continue
+		}
+		return params["q"] != "0"
+	}
+	return false
+}
+
+// renderMsgpack sends the data encoded with msgpack, fields are named by json tags to match json response
+func renderMsgpack(w http.ResponseWriter, r *http.Request, data interface{}) {
+	buf := bytes.Buffer{}
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(data); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to encode status")
+		return
+	}
+	w.Header().Set("Content-Type", external.MsgpackContentType)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -10,8 +13,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
 
 	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
 )
 
 func TestRest_Run(t *testing.T) {
@@ -48,3 +53,281 @@ func TestStatusCtrl(t *testing.T) {
 	assert.Contains(t, string(body), `"load_average":`, string(body))
 	assert.Equal(t, 1, len(sts.GetCalls()))
 }
+
+func TestStatusCtrl_Msgpack(t *testing.T) {
+	writable := true
+	info := &status.Info{
+		Labels: map[string]string{"role": "edge"}, HostName: "edge1", Procs: 120, CPUPercent: 12, MemPercent: 45, Uptime: 86400,
+		Volumes: map[string]status.Volume{"root": {Name: "root", Path: "/", UsagePercent: 78, ProbeWrite: true,
+			Writable: &writable, Status: "ok", Summary: "disk / at 78% (ok)"}},
+		ExtServices: map[string]external.Response{
+			"web": {Name: "web", StatusCode: 200, ResponseTime: 15, Summary: "http 200, 4 bytes (ok)",
+				Body: map[string]interface{}{"text": "pong", "body_length": 4, "ratio": 0.25, "big": int64(1) << 40,
+					"ok": true, "missing": nil, "kids": []string{"a", "b"}, "nested": map[string]interface{}{"neg": -3}}},
+			"db": {Name: "db", StatusCode: 500},
+		},
+		CPUCores:    &status.Cores{Usage: []int{10, 99}, Status: "warn"},
+		Connections: map[string]status.Connection{"pg": {Name: "pg", Port: 5432, Min: 1, Count: 7, Status: "ok"}},
+		Listen:      &status.Listen{Ports: []string{"tcp:22"}, Unexpected: []status.ListenPort{}, Status: "ok"},
+		ConfigDrift: &status.ConfigDrift{Path: "/etc/sys-agent.yml", Hash: "abc", CurrentHash: "abc",
+			ModTime: time.Date(2026, 10, 14, 10, 0, 0, 123, time.Local), CurrentModTime: time.Date(2026, 10, 14, 10, 0, 0, 123, time.Local),
+			Status: "ok"},
+	}
+	info.Loads.One, info.Loads.Five = 1.5, 0.75
+
+	srv := Rest{Listen: "localhost:54009", Status: &StatusMock{GetFunc: func() (*status.Info, error) { return info, nil }}}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(accept string) (contentType string, body []byte) {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/status", http.NoBody)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Accept", resp.Header.Get("Vary"))
+		body, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.Header.Get("Content-Type"), body
+	}
+
+	ct, jsonBody := get("")
+	assert.Contains(t, ct, "application/json")
+	for _, accept := range []string{"*/*", "application/json", "application/msgpack;q=0, application/json"} {
+		ct, _ = get(accept)
+		assert.Contains(t, ct, "application/json", accept)
+	}
+
+	ct, msgpackBody := get("application/msgpack, application/json;q=0.9")
+	assert.Equal(t, "application/msgpack", ct)
+	assert.Less(t, len(msgpackBody), len(jsonBody))
+
+	// generic decoding of both encodings gives the same data
+	var fromJSON, fromMsgpack interface{}
+	require.NoError(t, json.Unmarshal(jsonBody, &fromJSON))
+	require.NoError(t, msgpack.Unmarshal(msgpackBody, &fromMsgpack))
+	normalized, err := json.Marshal(fromMsgpack)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(jsonBody), string(normalized))
+
+	// decoding to the struct gives the original info, except for body numbers typed differently by each encoding
+	dec := msgpack.NewDecoder(bytes.NewReader(msgpackBody))
+	dec.SetCustomStructTag("json")
+	res := status.Info{}
+	require.NoError(t, dec.Decode(&res))
+	assert.Equal(t, info.ConfigDrift, res.ConfigDrift)
+	assert.Equal(t, info.Listen, res.Listen)
+	assert.Equal(t, info.Loads, res.Loads)
+	assert.Equal(t, "edge1", res.HostName)
+	assert.False(t, res.Volumes["root"].ProbeWrite, "not exported")
+	normalized, err = json.Marshal(res)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(jsonBody), string(normalized))
+}
+
+func TestMetricsCtrl(t *testing.T) {
+	availability := 99.5
+	info := &status.Info{CPUPercent: 12, MemPercent: 45,
+		Volumes: map[string]status.Volume{"root": {Name: "root", Path: "/", UsagePercent: 78, InodesUsedPct: 12}},
+		ExtServices: map[string]external.Response{
+			"web":  {Name: "web", StatusCode: 200, ResponseTime: 15, Summary: "http 200, 4 bytes (ok)", Availability: &availability},
+			"pg":   {Name: "pg", StatusCode: 200, ResponseTime: 1500, Summary: "postgres replica, lag 12MB (failed)"},
+			"mail": {Name: "mail", StatusCode: 500},
+		},
+	}
+	getErr := error(nil)
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return info, getErr }}
+	srv := Rest{Listen: "localhost:54009", Status: sts, MetricsPath: "/prom/metrics"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/prom/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	t.Log(string(body))
+	for _, m := range []string{
+		`sys_agent_service_up{name="web"} 1`, `sys_agent_service_up{name="pg"} 0`, `sys_agent_service_up{name="mail"} 0`,
+		`sys_agent_service_response_time_seconds{name="web"} 0.015`, `sys_agent_service_response_time_seconds{name="pg"} 1.5`,
+		`sys_agent_volume_usage_percent{name="root",path="/"} 78`, `sys_agent_volume_inodes_used_percent{name="root",path="/"} 12`,
+		"sys_agent_cpu_percent 12", "sys_agent_mem_percent 45",
+		"sys_agent_status_success 1", `sys_agent_service_availability_1h_percent{name="web"} 99.5`,
+	} {
+		assert.Contains(t, string(body), m+"\n")
+	}
+	assert.NotContains(t, string(body), `sys_agent_service_availability_1h_percent{name="pg"}`, "no availability")
+	assert.Contains(t, string(body), "sys_agent_offline 0\n")
+
+	getErr = errors.New("failed")
+	resp, err = http.Get(ts.URL + "/prom/metrics")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "sys_agent_status_success 0\n")
+	assert.NotContains(t, string(body), "sys_agent_service_up")
+	assert.Equal(t, 2, len(sts.GetCalls()))
+
+	srv = Rest{Listen: "localhost:54009", Status: sts}
+	ts2 := httptest.NewServer(srv.router())
+	defer ts2.Close()
+	resp, err = http.Get(ts2.URL + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "disabled without path")
+}
+
+func TestMetricsCtrl_LastStatus(t *testing.T) {
+	info := &status.Info{CPUPercent: 12}
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return info, nil }}
+	srv := Rest{Status: sts, MetricsPath: "/metrics", MetricsAge: time.Minute}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(path string) {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	get("/status")
+	assert.Equal(t, 1, len(sts.GetCalls()))
+	get("/metrics")
+	get("/metrics")
+	assert.Equal(t, 1, len(sts.GetCalls()), "metrics reuse the status got by /status")
+	get("/status")
+	assert.Equal(t, 2, len(sts.GetCalls()), "/status always gets the status")
+
+	srv = Rest{Status: sts, MetricsPath: "/metrics", MetricsAge: time.Millisecond}
+	ts2 := httptest.NewServer(srv.router())
+	defer ts2.Close()
+	for i := 0; i < 2; i++ {
+		time.Sleep(2 * time.Millisecond)
+		resp, err := http.Get(ts2.URL + "/metrics")
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, 4, len(sts.GetCalls()), "expired status is not reused")
+}
+
+func TestMetricsCtrl_Muted(t *testing.T) {
+	info := &status.Info{ExtServices: map[string]external.Response{
+		"web": {Name: "web", StatusCode: 200, ResponseTime: 15, Summary: "http 200, 4 bytes (ok)"},
+		"pg": {Name: "pg", StatusCode: 200, Summary: "skipped, !file:/etc/maintenance is false (ok)",
+			Skipped: external.SkippedStatus, Body: map[string]interface{}{"status": external.SkippedStatus, "when": "!file:/etc/maintenance"}},
+		"remote": {Name: "remote", StatusCode: 200, Summary: "http 200 (ok)", Body: map[string]interface{}{"status": external.SkippedStatus}},
+	}}
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return info, nil }}
+
+	get := func(srv Rest) string {
+		ts := httptest.NewServer(srv.router())
+		defer ts.Close()
+		resp, err := http.Get(ts.URL + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	body := get(Rest{Status: sts, MetricsPath: "/metrics"})
+	for _, m := range []string{`sys_agent_service_muted{name="web"} 0`, `sys_agent_service_muted{name="pg"} 1`,
+		`sys_agent_service_up{name="web"} 1`, `sys_agent_service_up{name="pg"} 1`, `sys_agent_service_muted{name="remote"} 0`} {
+		assert.Contains(t, body, m+"\n")
+	}
+
+	body = get(Rest{Status: sts, MetricsPath: "/metrics", SkipMuted: true})
+	for _, m := range []string{`sys_agent_service_muted{name="web"} 0`, `sys_agent_service_muted{name="pg"} 1`,
+		`sys_agent_service_up{name="web"} 1`} {
+		assert.Contains(t, body, m+"\n")
+	}
+	assert.NotContains(t, body, `sys_agent_service_up{name="pg"}`)
+	assert.NotContains(t, body, `sys_agent_service_response_time_seconds{name="pg"}`)
+
+	// offline services are muted as well
+	info = &status.Info{Offline: &status.Offline{Preflight: "ping://10.0.0.1", Skipped: []string{"db"}},
+		ExtServices: map[string]external.Response{"db": {Name: "db", StatusCode: 200, Summary: "skipped, host is offline (ok)",
+			Skipped: external.OfflineStatus, Body: map[string]interface{}{"status": external.OfflineStatus}}}}
+	body = get(Rest{Status: sts, MetricsPath: "/metrics", SkipMuted: true})
+	assert.Contains(t, body, `sys_agent_service_muted{name="db"} 1`+"\n")
+	assert.Contains(t, body, "sys_agent_offline 1\n")
+	assert.NotContains(t, body, `sys_agent_service_up{name="db"}`)
+}
+
+func TestMetricsCtrl_Labels(t *testing.T) {
+	info := &status.Info{CPUPercent: 12, Labels: map[string]string{"hostname": "h1"},
+		ExtServices: map[string]external.Response{"web": {Name: "web", StatusCode: 200, Summary: "http 200, 4 bytes (ok)"}}}
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return info, nil }}
+
+	srv := Rest{Status: sts, MetricsPath: "/metrics", Labels: map[string]string{"hostname": "h1", "data-center": "us-east-1",
+		"name": "x"}}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	for _, m := range []string{`sys_agent_cpu_percent{data_center="us-east-1",hostname="h1",label_name="x"} 12`,
+		`sys_agent_service_up{data_center="us-east-1",hostname="h1",label_name="x",name="web"} 1`,
+		`sys_agent_status_success{data_center="us-east-1",hostname="h1",label_name="x"} 1`} {
+		assert.Contains(t, string(body), m+"\n")
+	}
+}
+
+func TestProvidersCtrl(t *testing.T) {
+	prov := &ProvidersMock{
+		SchemesFunc: func() []external.Scheme {
+			return []external.Scheme{
+				{Scheme: "http", Provider: "HTTP", Description: "http check"},
+				{Scheme: "docker", Provider: "Docker", Description: "docker check"},
+			}
+		},
+	}
+	srv := Rest{Listen: "localhost:54009", Providers: prov, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/providers")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	res := []external.Scheme{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	assert.Equal(t, prov.SchemesFunc(), res)
+	assert.Equal(t, 1, len(prov.SchemesCalls()))
+}
+
+func TestProvidersCtrl_AllWired(t *testing.T) {
+	mock := &external.StatusProviderMock{}
+	svc := external.NewService(external.Providers{HTTP: mock, Mongo: mock, Mysql: mock, Docker: mock, Program: mock,
+		Nginx: mock, Certificate: mock, File: mock, RMQ: mock, DNSZone: mock}, 1)
+	srv := Rest{Listen: "localhost:54009", Providers: svc, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/providers")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	res := []external.Scheme{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	schemes := []string{}
+	for _, r := range res {
+		schemes = append(schemes, r.Scheme)
+	}
+	assert.Equal(t, []string{"http", "https", "mongodb", "mysql", "docker", "program", "nginx", "cert", "cert+file", "file",
+		"rmq", "dnszone"}, schemes)
+}
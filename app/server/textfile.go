@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultTextfileInterval is used if TextfileInterval is not set
+const defaultTextfileInterval = time.Minute
+
+// runTextfile writes the same metrics as metrics endpoint to the textfile every TextfileInterval, until the context
+// is canceled. The status is reused the same way as by metrics scrape, see lastStatus.
+func (s *Rest) runTextfile(ctx context.Context, last *lastStatus) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newStatusCollector(last, s.SkipMuted, s.Labels))
+
+	interval := s.TextfileInterval
+	if interval <= 0 {
+		interval = defaultTextfileInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.writeTextfile(reg)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeTextfile writes metrics to a temp file in the directory of the textfile and renames it, so the textfile
+// collector never reads a partially written file. The temp file has no .prom extension and is ignored by the collector.
+func (s *Rest) writeTextfile(g prometheus.Gatherer) {
+	if err := prometheus.WriteToTextfile(s.Textfile, g); err != nil {
+		log.Printf("[WARN] failed to write metrics to %s: %v", s.Textfile, err)
+	}
+}
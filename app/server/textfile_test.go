@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestRest_Textfile(t *testing.T) {
+	info := &status.Info{CPUPercent: 12, MemPercent: 34, ExtServices: map[string]external.Response{
+		"web": {Name: "web", StatusCode: 200, ResponseTime: 15, Summary: "http 200, 4 bytes (ok)"},
+		"db":  {Name: "db", StatusCode: 500, Summary: "http 500 (failed)"},
+	}}
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return info, nil }}
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "sys-agent.prom")
+	require.NoError(t, os.WriteFile(fname, []byte("previous content"), 0o600))
+	prevFh, err := os.Open(fname) //nolint:gosec // test file, kept open to keep its inode from reuse
+	require.NoError(t, err)
+	defer prevFh.Close()
+	prev, err := prevFh.Stat()
+	require.NoError(t, err)
+
+	srv := Rest{Status: sts, MetricsAge: time.Minute, Labels: map[string]string{"host": "h1"},
+		Textfile: fname, TextfileInterval: 10 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.runTextfile(ctx, &lastStatus{Status: sts, maxAge: srv.MetricsAge})
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(sts.GetCalls()) > 0 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond) // a few more writes
+	cancel()
+	<-done
+
+	assert.Equal(t, 1, len(sts.GetCalls()), "status reused between writes within metrics age")
+
+	curr, err := os.Stat(fname)
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(prev, curr), "file replaced by rename, not rewritten in place")
+	prevData, err := io.ReadAll(prevFh)
+	require.NoError(t, err)
+	assert.Equal(t, "previous content", string(prevData), "reader of the previous file sees it whole")
+	assert.Equal(t, os.FileMode(0o644), curr.Mode().Perm())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries), "no temp files left")
+
+	fh, err := os.Open(fname) //nolint:gosec // test file
+	require.NoError(t, err)
+	defer fh.Close()
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(fh)
+	require.NoError(t, err, "valid prometheus text format")
+
+	require.Contains(t, families, "sys_agent_cpu_percent")
+	assert.Equal(t, 12.0, families["sys_agent_cpu_percent"].GetMetric()[0].GetGauge().GetValue())
+	require.Contains(t, families, "sys_agent_service_up")
+	up := map[string]float64{}
+	for _, m := range families["sys_agent_service_up"].GetMetric() {
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		assert.Equal(t, "h1", labels["host"])
+		up[labels["name"]] = m.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{"web": 1, "db": 0}, up)
+}
+
+func TestRest_TextfileFailed(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return &status.Info{}, nil }}
+	srv := Rest{Status: sts, Textfile: filepath.Join(t.TempDir(), "no-such-dir", "sys-agent.prom")}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	srv.runTextfile(ctx, &lastStatus{Status: sts, maxAge: srv.MetricsAge}) // write failure logged, not fatal
+	_, err := os.Stat(srv.Textfile)
+	assert.True(t, os.IsNotExist(err))
+}
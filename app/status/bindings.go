@@ -0,0 +1,69 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxCommLen = 15 // process name in /proc/[pid]/comm is truncated to this length
+
+// Binding contains the port expected to be held by the process and the processes actually holding it
+type Binding struct {
+	Name    string   `json:"name"`
+	Proto   string   `json:"proto"`   // tcp or udp
+	Port    int      `json:"port"`    // listening port
+	Process string   `json:"process"` // expected process name or pid
+	Owners  []string `json:"owners"`  // processes holding the port as name(pid)
+	Status  string   `json:"status"`  // ok, failed if not bound or held by unexpected process
+}
+
+// procOwner is a process holding a socket
+type procOwner struct {
+	PID  int
+	Name string
+}
+
+func (p procOwner) String() string {
+	return p.Name + "(" + strconv.Itoa(p.PID) + ")"
+}
+
+// matches checks the owner against expected process name or pid. Names are compared up to the length of comm.
+func (p procOwner) matches(expected string) bool {
+	if pid, err := strconv.Atoi(expected); err == nil {
+		return p.PID == pid
+	}
+	if len(expected) > maxCommLen {
+		expected = expected[:maxCommLen]
+	}
+	return p.Name == expected
+}
+
+// checkBinding sets owners of the binding's port and the status. It is failed if nothing listens on the port,
+// or any of the owners is not the expected process, i.e. another process bound the same port with SO_REUSEPORT.
+func checkBinding(b Binding, owners []procOwner) Binding {
+	sort.Slice(owners, func(i, j int) bool { return owners[i].PID < owners[j].PID })
+	b.Owners, b.Status = []string{}, "ok"
+	var unexpected []string
+	seen := map[int]bool{}
+	for _, o := range owners {
+		if seen[o.PID] {
+			continue // the same process with ipv4 and ipv6 sockets or a few fds
+		}
+		seen[o.PID] = true
+		b.Owners = append(b.Owners, o.String())
+		if !o.matches(b.Process) {
+			unexpected = append(unexpected, o.String())
+		}
+	}
+
+	port := b.Proto + ":" + strconv.Itoa(b.Port)
+	switch {
+	case len(owners) == 0:
+		b.Status = fmt.Sprintf("failed: %s not bound, expected %s", port, b.Process)
+	case len(unexpected) > 0:
+		b.Status = fmt.Sprintf("failed: %s held by %s, expected %s", port, strings.Join(unexpected, ","), b.Process)
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package status
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// portOwners returns processes holding listening tcp and udp sockets by proto:port, i.e. tcp:8080.
+// Ports listening without known owner, i.e. processes of other users, are reported with empty list.
+func portOwners() (map[string][]procOwner, error) {
+	res := map[string][]procOwner{}
+	ports := map[string]string{} // socket inode to proto:port
+	ephemeral := ephemeralPorts()
+	for _, p := range procNetListen {
+		skip := portRange{}
+		if p.client {
+			skip = ephemeral
+		}
+		listening, err := parseListening(filepath.Join(procRoot, p.fname), p.proto, p.state, skip)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for inode, port := range listening {
+			ports[inode] = port.String()
+			if _, ok := res[port.String()]; !ok {
+				res[port.String()] = []procOwner{}
+			}
+		}
+	}
+
+	for inode, owners := range socketOwners(procRoot) {
+		if port, ok := ports[inode]; ok {
+			res[port] = append(res[port], owners...)
+		}
+	}
+	return res, nil
+}
+
+// socketOwners scans /proc/[pid]/fd links and returns processes by socket inode, ordered as the fd links.
+// Processes not accessible by the agent's user are skipped.
+func socketOwners(root string) map[string][]procOwner {
+	res := map[string][]procOwner{}
+	fds, err := filepath.Glob(filepath.Join(root, "[0-9]*", "fd", "[0-9]*"))
+	if err != nil {
+		return res
+	}
+	comms := map[string]string{} // process dir to comm
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		procDir := filepath.Dir(filepath.Dir(fd))
+		pid, err := strconv.Atoi(filepath.Base(procDir))
+		if err != nil {
+			continue
+		}
+		comm, ok := comms[procDir]
+		if !ok {
+			data, err := os.ReadFile(filepath.Join(procDir, "comm")) //nolint:gosec // procfs file
+			if err != nil {
+				continue
+			}
+			comm = strings.TrimSpace(string(data))
+			comms[procDir] = comm
+		}
+		inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+		res[inode] = append(res[inode], procOwner{PID: pid, Name: comm})
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_portOwners(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "net"), 0o700))
+	for src, dst := range map[string]string{"proc_net_tcp.txt": "tcp", "proc_net_udp.txt": "udp"} {
+		data, err := os.ReadFile(filepath.Join("testdata", src))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(root, "net", dst), data, 0o600))
+	}
+	mkProc := func(pid, comm string, links map[string]string) {
+		fdDir := filepath.Join(root, pid, "fd")
+		require.NoError(t, os.MkdirAll(fdDir, 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(root, pid, "comm"), []byte(comm+"\n"), 0o600))
+		for fd, target := range links {
+			require.NoError(t, os.Symlink(target, filepath.Join(fdDir, fd)))
+		}
+	}
+	mkProc("100", "postgres", map[string]string{"3": "socket:[20133]", "4": "socket:[99999]"})
+	mkProc("200", "postgres", map[string]string{"5": "socket:[20133]"})
+	mkProc("300", "dnsmasq", map[string]string{"7": "socket:[18211]", "8": "pipe:[1234]"})
+
+	orig := procRoot
+	procRoot = root
+	defer func() { procRoot = orig }()
+
+	res, err := portOwners()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]procOwner{
+		"tcp:8080": {},
+		"tcp:5432": {{PID: 100, Name: "postgres"}, {PID: 200, Name: "postgres"}},
+		"udp:53":   {{PID: 300, Name: "dnsmasq"}},
+		"udp:68":   {},
+	}, res, "not listening socket 99999 skipped")
+
+	line := "   0: 00000000:ZZZZ 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21451 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp"), []byte(line), 0o600))
+	_, err = portOwners()
+	require.Error(t, err)
+
+	info, err := Service{Bindings: []Binding{{Name: "pg", Proto: "tcp", Port: 5432, Process: "postgres"}}}.Get()
+	require.NoError(t, err, "port owners error doesn't fail the whole status")
+	assert.Empty(t, info.Bindings["pg"].Owners)
+	assert.True(t, strings.HasPrefix(info.Bindings["pg"].Status, "failed: can't get listening ports owners: "),
+		info.Bindings["pg"].Status)
+}
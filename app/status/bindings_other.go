@@ -0,0 +1,10 @@
+//go:build !linux
+
+package status
+
+import "errors"
+
+// portOwners is not supported, socket owners are resolved from procfs on linux only
+func portOwners() (map[string][]procOwner, error) {
+	return nil, errors.New("port binding check is supported on linux only")
+}
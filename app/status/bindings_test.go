@@ -0,0 +1,37 @@
+package status
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkBinding(t *testing.T) {
+	nginx := []procOwner{{PID: 120, Name: "nginx"}, {PID: 100, Name: "nginx"}, {PID: 100, Name: "nginx"}}
+
+	tbl := []struct {
+		process string
+		owners  []procOwner
+		exp     []string
+		status  string
+	}{
+		{"nginx", nginx, []string{"nginx(100)", "nginx(120)"}, "ok"},
+		{"100", []procOwner{{PID: 100, Name: "nginx"}}, []string{"nginx(100)"}, "ok"},
+		{"nginx", nil, []string{}, "failed: tcp:8080 not bound, expected nginx"},
+		{"nginx", []procOwner{{PID: 321, Name: "apache2"}}, []string{"apache2(321)"},
+			"failed: tcp:8080 held by apache2(321), expected nginx"},
+		{"nginx", []procOwner{{PID: 100, Name: "nginx"}, {PID: 321, Name: "nc"}}, []string{"nginx(100)", "nc(321)"},
+			"failed: tcp:8080 held by nc(321), expected nginx"},
+		{"120", nginx, []string{"nginx(100)", "nginx(120)"}, "failed: tcp:8080 held by nginx(100), expected 120"},
+		{"php-fpm-worker-pool", []procOwner{{PID: 200, Name: "php-fpm-worker-"}}, []string{"php-fpm-worker-(200)"}, "ok"},
+	}
+
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			res := checkBinding(Binding{Name: "web", Proto: "tcp", Port: 8080, Process: tt.process}, tt.owners)
+			assert.Equal(t, Binding{Name: "web", Proto: "tcp", Port: 8080, Process: tt.process, Owners: tt.exp,
+				Status: tt.status}, res)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package status
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ConfigWatch keeps hash and modification time of the config file recorded at startup
+// to detect changes made outside of a deploy. The config is not reloaded on change.
+type ConfigWatch struct {
+	path    string
+	hash    string
+	modTime time.Time
+}
+
+// ConfigDrift contains state of the config file compared to the startup
+type ConfigDrift struct {
+	Path           string    `json:"path"`
+	Hash           string    `json:"hash"`             // sha256 at startup
+	CurrentHash    string    `json:"current_hash"`     // sha256 on disk, empty if can't be read
+	ModTime        time.Time `json:"mod_time"`         // modification time at startup
+	CurrentModTime time.Time `json:"current_mod_time"` // modification time on disk
+	Status         string    `json:"status"`           // ok, warn if touched, failed if changed or can't be read
+}
+
+// NewConfigWatch makes ConfigWatch with the current hash and modification time of the file
+func NewConfigWatch(path string) (*ConfigWatch, error) {
+	hash, modTime, err := fileHash(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash config %s: %w", path, err)
+	}
+	return &ConfigWatch{path: path, hash: hash, modTime: modTime}, nil
+}
+
+// Check compares the file on disk with the state recorded at startup
+func (w *ConfigWatch) Check() *ConfigDrift {
+	res := ConfigDrift{Path: w.path, Hash: w.hash, ModTime: w.modTime, Status: "ok"}
+	hash, modTime, err := fileHash(w.path)
+	if err != nil {
+		res.Status = fmt.Sprintf("failed: %v", err)
+		return &res
+	}
+	res.CurrentHash, res.CurrentModTime = hash, modTime
+	switch {
+	case hash != w.hash:
+		res.Status = "failed: config changed since startup"
+	case !modTime.Equal(w.modTime):
+		res.Status = "warn: config modified since startup, content unchanged"
+	}
+	return &res
+}
+
+// fileHash returns hex encoded sha256 and modification time of the file
+func fileHash(path string) (hash string, modTime time.Time, err error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path of the agent's own config
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), fi.ModTime(), nil
+}
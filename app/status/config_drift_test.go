@@ -0,0 +1,50 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigWatch_Check(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(fname, []byte("volumes:\n  - {name: root, path: /}\n"), 0o600))
+	startup := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(fname, startup, startup))
+
+	w, err := NewConfigWatch(fname)
+	require.NoError(t, err)
+
+	res := w.Check()
+	assert.Equal(t, "ok", res.Status)
+	assert.Equal(t, "733bd0973a7b4499a648e29513b54b2b7fc1a84fd17987596a3bb3eb49862cef", res.Hash)
+	assert.Equal(t, res.Hash, res.CurrentHash)
+	assert.True(t, startup.Equal(res.ModTime))
+
+	// touched, same content
+	touched := startup.Add(time.Hour)
+	require.NoError(t, os.Chtimes(fname, touched, touched))
+	res = w.Check()
+	assert.Equal(t, "warn: config modified since startup, content unchanged", res.Status)
+	assert.True(t, touched.Equal(res.CurrentModTime))
+
+	// content changed
+	require.NoError(t, os.WriteFile(fname, []byte("volumes:\n  - {name: root, path: /tmp}\n"), 0o600))
+	res = w.Check()
+	assert.Equal(t, "failed: config changed since startup", res.Status)
+	assert.NotEqual(t, res.Hash, res.CurrentHash)
+	assert.Len(t, res.CurrentHash, 64)
+
+	// removed
+	require.NoError(t, os.Remove(fname))
+	res = w.Check()
+	assert.Contains(t, res.Status, "failed: stat ")
+	assert.Equal(t, "", res.CurrentHash)
+
+	_, err = NewConfigWatch(fname)
+	require.Error(t, err)
+}
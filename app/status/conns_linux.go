@@ -0,0 +1,79 @@
+//go:build linux
+
+package status
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// procNetTCP lists procfs files with tcp sockets, tcp6 may be missing if ipv6 is disabled
+var procNetTCP = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+const tcpEstablished = "01" // connection state in /proc/net/tcp
+
+// establishedConns returns number of established tcp connections with the given local or remote port
+func establishedConns(port int) (int, error) {
+	res := 0
+	for _, fname := range procNetTCP {
+		count, err := countEstablished(fname, port)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return 0, err
+		}
+		res += count
+	}
+	return res, nil
+}
+
+// countEstablished parses /proc/net/tcp formatted file and counts established connections from or to the port.
+// Each line is "sl local_address rem_address st ...", addresses are hex "IP:PORT".
+func countEstablished(fname string, port int) (int, error) {
+	fh, err := os.Open(fname) //nolint:gosec // procfs file
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", fname, err)
+	}
+	defer fh.Close() // nolint
+
+	res := 0
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "sl" || fields[3] != tcpEstablished {
+			continue
+		}
+		for _, addr := range fields[1:3] {
+			p, err := hexPort(addr)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse %s in %q: %w", addr, scanner.Text(), err)
+			}
+			if p == port {
+				res++
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", fname, err)
+	}
+	return res, nil
+}
+
+// hexPort returns port from hex "IP:PORT" address, i.e. 0100007F:1F90 is 8080
+func hexPort(addr string) (int, error) {
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return 0, fmt.Errorf("no port in %s", addr)
+	}
+	p, err := strconv.ParseUint(addr[i+1:], 16, 16)
+	if err != nil {
+		return 0, err
+	}
+	return int(p), nil
+}
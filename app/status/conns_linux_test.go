@@ -0,0 +1,52 @@
+//go:build linux
+
+package status
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_countEstablished(t *testing.T) {
+	tbl := []struct {
+		port  int
+		count int
+	}{
+		{8080, 2}, // listen and time-wait not counted
+		{5432, 3}, // both sides of local connections counted
+		{443, 1},
+		{22, 0},
+	}
+	for _, tt := range tbl {
+		res, err := countEstablished("testdata/proc_net_tcp.txt", tt.port)
+		require.NoError(t, err)
+		assert.Equal(t, tt.count, res, "port %d", tt.port)
+	}
+
+	_, err := countEstablished("testdata/proc_net_tcp_bad.txt", 8080)
+	require.Error(t, err)
+
+	_, err = countEstablished("testdata/no-such-file.txt", 8080)
+	require.Error(t, err)
+}
+
+func Test_establishedConns(t *testing.T) {
+	res, err := establishedConns(1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, res)
+
+	orig := procNetTCP
+	defer func() { procNetTCP = orig }()
+	procNetTCP = []string{"testdata/proc_net_tcp_bad.txt"}
+	_, err = establishedConns(8080)
+	require.Error(t, err)
+
+	info, err := Service{Connections: []Connection{{Name: "web", Port: 8080, Min: 1}}}.Get()
+	require.NoError(t, err, "connections error doesn't fail the whole status")
+	assert.Equal(t, 0, info.Connections["web"].Count)
+	assert.True(t, strings.HasPrefix(info.Connections["web"].Status, "failed: can't count connections: "),
+		info.Connections["web"].Status)
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package status
+
+import (
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// establishedConns returns number of established tcp connections with the given local or remote port
+func establishedConns(port int) (int, error) {
+	conns, err := net.Connections("tcp")
+	if err != nil {
+		return 0, err
+	}
+	res := 0
+	for _, c := range conns {
+		if c.Status == "ESTABLISHED" && (c.Laddr.Port == uint32(port) || c.Raddr.Port == uint32(port)) {
+			res++
+		}
+	}
+	return res, nil
+}
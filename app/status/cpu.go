@@ -0,0 +1,114 @@
+package status
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// cpuTimes contains cumulative busy and total time of a single core, in USER_HZ
+type cpuTimes struct {
+	busy  uint64
+	total uint64
+}
+
+// CPU contains cpu utilization sampled in background over the interval
+type CPU struct {
+	Percent  int      `json:"percent"`            // aggregate usage of all cores
+	Cores    []int    `json:"cores"`              // usage of each core, ordered by core index
+	Interval string   `json:"interval"`           // sampling window, i.e. "1s"
+	LoadAvg  *LoadAvg `json:"load_avg,omitempty"` // unix only
+}
+
+// LoadAvg contains 1, 5 and 15 minutes load average
+type LoadAvg struct {
+	One     float64 `json:"one"`
+	Five    float64 `json:"five"`
+	Fifteen float64 `json:"fifteen"`
+}
+
+// CPUSampler samples cpu times in background with the interval and keeps the last utilization,
+// so the status request doesn't wait for the sampling window
+type CPUSampler struct {
+	Interval time.Duration
+
+	lock sync.Mutex
+	last *CPU
+}
+
+// Run samples cpu times until the context is canceled, the first utilization is ready after the interval.
+// Failed sample is logged and skipped, the last good one is kept.
+func (c *CPUSampler) Run(ctx context.Context) {
+	prev, err := coreTimes()
+	if err != nil {
+		log.Printf("[WARN] failed to sample cpu times: %v", err)
+	}
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		curr, err := coreTimes()
+		if err != nil {
+			log.Printf("[WARN] failed to sample cpu times: %v", err)
+			continue
+		}
+		if prev != nil {
+			c.lock.Lock()
+			c.last = cpuUsage(prev, curr, c.Interval)
+			c.lock.Unlock()
+		}
+		prev = curr
+	}
+}
+
+// Last returns the last sampled utilization, nil if not sampled yet
+func (c *CPUSampler) Last() *CPU {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.last == nil {
+		return nil
+	}
+	res := *c.last
+	res.Cores = append([]int{}, c.last.Cores...)
+	return &res
+}
+
+// cpuUsage makes utilization of each core and the aggregate one between two samples
+func cpuUsage(prev, curr []cpuTimes, interval time.Duration) *CPU {
+	res := &CPU{Cores: []int{}, Interval: interval.String()}
+	for _, u := range coresDelta(prev, curr) {
+		res.Cores = append(res.Cores, int(u))
+	}
+	var busy, total float64
+	for i := range curr {
+		if i >= len(prev) || curr[i].total <= prev[i].total {
+			continue
+		}
+		busy += float64(curr[i].busy) - float64(prev[i].busy)
+		total += float64(curr[i].total - prev[i].total)
+	}
+	if total > 0 {
+		res.Percent = int(100 * busy / total)
+	}
+	return res
+}
+
+// coresDelta returns usage percent for each core between two samples
+func coresDelta(prev, curr []cpuTimes) []float64 {
+	res := make([]float64, 0, len(curr))
+	for i := range curr {
+		if i >= len(prev) || curr[i].total <= prev[i].total {
+			res = append(res, 0)
+			continue
+		}
+		busy := float64(curr[i].busy) - float64(prev[i].busy)
+		total := float64(curr[i].total - prev[i].total)
+		res = append(res, 100*busy/total)
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package status
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const procStat = "/proc/stat"
+
+// coresUsage samples /proc/stat twice with the given interval and returns usage percent for each core
+func coresUsage(interval time.Duration) ([]float64, error) {
+	prev, err := readCoreTimes(procStat)
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(interval)
+	curr, err := readCoreTimes(procStat)
+	if err != nil {
+		return nil, err
+	}
+	return coresDelta(prev, curr), nil
+}
+
+// coreTimes returns cumulative times of each core from /proc/stat
+func coreTimes() ([]cpuTimes, error) {
+	return readCoreTimes(procStat)
+}
+
+// readCoreTimes parses per-core lines (cpu0, cpu1, ...) of /proc/stat formatted file.
+// Each line is "cpuN user nice system idle iowait irq softirq steal guest guest_nice",
+// guest times are already included in user and nice, so not counted.
+func readCoreTimes(fname string) ([]cpuTimes, error) {
+	fh, err := os.Open(fname) //nolint:gosec // procfs file
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", fname, err)
+	}
+	defer fh.Close() // nolint
+
+	res := []cpuTimes{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		var times cpuTimes
+		for i, f := range fields[1:] {
+			if i >= 8 { // skip guest and guest_nice
+				break
+			}
+			v, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s in %q: %w", f, scanner.Text(), err)
+			}
+			times.total += v
+			if i != 3 && i != 4 { // idle and iowait are not busy
+				times.busy += v
+			}
+		}
+		res = append(res, times)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fname, err)
+	}
+	return res, nil
+}
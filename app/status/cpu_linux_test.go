@@ -0,0 +1,55 @@
+//go:build linux
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readCoreTimes(t *testing.T) {
+	res, err := readCoreTimes("testdata/proc_stat_1.txt")
+	require.NoError(t, err)
+	require.Equal(t, 4, len(res))
+	assert.Equal(t, cpuTimes{busy: 1393280 + 32966 + 572056 + 17875, total: 1393280 + 32966 + 572056 + 13343292 + 6130 + 17875},
+		res[0])
+
+	_, err = readCoreTimes("testdata/bad.txt")
+	require.Error(t, err)
+}
+
+func Test_coresDelta(t *testing.T) {
+	prev, err := readCoreTimes("testdata/proc_stat_1.txt")
+	require.NoError(t, err)
+	curr, err := readCoreTimes("testdata/proc_stat_2.txt")
+	require.NoError(t, err)
+
+	res := coresDelta(prev, curr)
+	require.Equal(t, 4, len(res))
+	assert.InDelta(t, 20.0, res[0], 0.01)
+	assert.InDelta(t, 16.67, res[1], 0.01)
+	assert.InDelta(t, 100.0, res[2], 0.01)
+	assert.InDelta(t, 46.875, res[3], 0.01)
+
+	assert.Equal(t, []float64{0, 0, 0, 0}, coresDelta(curr, curr), "no time passed")
+	assert.Equal(t, []float64{20, 0, 0, 0}, roundAll(coresDelta(prev[:1], curr)), "new cores")
+}
+
+func Test_coresUsage(t *testing.T) {
+	res, err := coresUsage(50 * time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, len(res) > 0)
+	for _, v := range res {
+		assert.True(t, v >= 0 && v <= 100, v)
+	}
+}
+
+func roundAll(vals []float64) []float64 {
+	for i, v := range vals {
+		vals[i] = float64(int(v))
+	}
+	return vals
+}
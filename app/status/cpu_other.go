@@ -0,0 +1,29 @@
+//go:build !linux
+
+package status
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// coresUsage returns usage percent for each core sampled over the given interval
+func coresUsage(interval time.Duration) ([]float64, error) {
+	return cpu.Percent(interval, true)
+}
+
+// coreTimes returns cumulative times of each core, in hundredths of a second
+func coreTimes() ([]cpuTimes, error) {
+	times, err := cpu.Times(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cpu times: %w", err)
+	}
+	res := make([]cpuTimes, 0, len(times))
+	for _, t := range times {
+		total := t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+		res = append(res, cpuTimes{busy: uint64((total - t.Idle - t.Iowait) * 100), total: uint64(total * 100)})
+	}
+	return res, nil
+}
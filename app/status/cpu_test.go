@@ -0,0 +1,59 @@
+package status
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cpuUsage(t *testing.T) {
+	prev := []cpuTimes{{busy: 100, total: 1000}, {busy: 200, total: 1000}, {busy: 300, total: 1000}}
+	curr := []cpuTimes{{busy: 150, total: 1100}, {busy: 300, total: 1100}, {busy: 300, total: 1100}, {busy: 10, total: 20}}
+	res := cpuUsage(prev, curr, time.Second)
+	assert.Equal(t, &CPU{Percent: 50, Cores: []int{50, 100, 0, 0}, Interval: "1s"}, res)
+
+	assert.Equal(t, &CPU{Cores: []int{0, 0, 0}, Interval: "1s"}, cpuUsage(prev, prev, time.Second), "no time passed")
+}
+
+func TestCPUSampler_Run(t *testing.T) {
+	c := CPUSampler{Interval: 20 * time.Millisecond}
+	assert.Nil(t, c.Last(), "not sampled yet")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	var res *CPU
+	require.Eventually(t, func() bool {
+		res = c.Last()
+		return res != nil
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, "20ms", res.Interval)
+	assert.NotEmpty(t, res.Cores)
+	assert.True(t, res.Percent >= 0 && res.Percent <= 100, res.Percent)
+
+	svc := Service{CPU: &c, CoresSample: time.Hour}
+	st := time.Now()
+	info, err := svc.Get()
+	require.NoError(t, err)
+	assert.Less(t, time.Since(st), time.Second, "per-core usage from the sampler, doesn't wait for cores sample")
+	require.NotNil(t, info.CPU)
+	require.NotNil(t, info.CPUCores)
+	assert.Equal(t, len(res.Cores), len(info.CPUCores.Usage))
+	assert.Equal(t, len(res.Cores), len(info.CPU.Cores))
+	if runtime.GOOS == "windows" {
+		assert.Nil(t, info.CPU.LoadAvg)
+		return
+	}
+	require.NotNil(t, info.CPU.LoadAvg)
+	assert.Equal(t, info.Loads.One, info.CPU.LoadAvg.One)
+}
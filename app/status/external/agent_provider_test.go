@@ -0,0 +1,165 @@
+package external
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestAgentProvider_Status(t *testing.T) {
+	remoteStatus := `{"hostname":"edge1","cpu_percent":12,"mem_percent":34,"services":{
+		"web":{"name":"web","status_code":200,"body":{"text":"pong"}},
+		"docker":{"name":"docker","status_code":200,"body":{"status":"failed: required container(s) not running"}},
+		"cert":{"name":"cert","status_code":200,"body":{"status":"ok","key_status":"ok"}},
+		"db":{"name":"db","status_code":500},
+		"file":{"name":"file","status_code":200,"body":{"status":"warn: file not modified"}}},
+		"labels":{"dc":"eu1"},"load_average":{"one":1.5},
+		"volumes":{"root":{"name":"root","status":"failed: read-only file system"},"data":{"name":"data"}},
+		"systemd":{"failed":["nginx.service"],"count":1,"status":"failed: units nginx.service"},
+		"cpu_cores":{"usage":[97,3],"status":"warn: pegged cores 0"},
+		"mounts":{"data":{"name":"data","status":"ok"}}}`
+	okStatus := `{"hostname":"edge2","cpu_percent":1,"mem_percent":2,"services":{
+		"web":{"name":"web","status_code":200,"body":{"text":"pong"}}}}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/status", r.URL.Path)
+		user, passwd, ok := r.BasicAuth()
+		if !ok {
+			_, _ = w.Write([]byte(okStatus))
+			return
+		}
+		if user != "admin" || passwd != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(remoteStatus))
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	p := AgentProvider{TimeOut: time.Second}
+
+	{ // remote with failed and warn services
+		resp, err := p.Status(Request{Name: "edge1", URL: "agent://admin:secret@" + host})
+		require.NoError(t, err)
+		t.Logf("%+v", resp)
+		assert.Equal(t, "edge1", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "edge1", resp.Body["hostname"])
+		assert.Equal(t, 12, resp.Body["cpu_percent"])
+		assert.Equal(t, 34, resp.Body["mem_percent"])
+		assert.Equal(t, 5, resp.Body["services"])
+		assert.Equal(t, []string{"db", "docker", "systemd", "volumes/root"}, resp.Body["failed"])
+		assert.Equal(t, []string{"cpu_cores", "file"}, resp.Body["warn"])
+		assert.Equal(t, "failed: db,docker,systemd,volumes/root", resp.Body["status"])
+		assert.Equal(t, "json", resp.Body["format"], "remote doesn't support msgpack")
+		assert.Equal(t, "edge1: 5 services, 4 failed, 2 warn (failed)", resp.Summary)
+	}
+
+	{ // healthy remote without auth
+		resp, err := p.Status(Request{Name: "edge2", URL: "agent://" + host})
+		require.NoError(t, err)
+		assert.Equal(t, "edge2", resp.Body["hostname"])
+		assert.Equal(t, 1, resp.Body["services"])
+		assert.Equal(t, []string{}, resp.Body["failed"])
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "edge2: 1 services, 0 failed, 0 warn (ok)", resp.Summary)
+	}
+
+	{ // wrong credentials
+		_, err := p.Status(Request{Name: "edge1", URL: "agent://admin:bad@" + host})
+		require.EqualError(t, err, "agent request failed: edge1 "+ts.URL+"/status: 401 Unauthorized")
+	}
+
+	{ // no host
+		_, err := p.Status(Request{Name: "edge1", URL: "agent://"})
+		require.EqualError(t, err, "agent host is empty: edge1 agent://")
+	}
+}
+
+func TestAgentProvider_StatusMsgpack(t *testing.T) {
+	remote := map[string]interface{}{"hostname": "edge1", "cpu_percent": 12, "mem_percent": 34,
+		"services": map[string]Response{
+			"web":  {Name: "web", StatusCode: 200, Body: map[string]interface{}{"text": "pong"}},
+			"file": {Name: "file", StatusCode: 200, Body: map[string]interface{}{"status": "warn: file not modified"}},
+			"db":   {Name: "db", StatusCode: 500},
+		},
+		"zfs": map[string]interface{}{"tank": map[string]interface{}{"name": "tank", "status": "warn: 3 device errors"}},
+		"ups": map[string]interface{}{"state": "ONLINE", "status": "ok"},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/msgpack, application/json;q=0.9", r.Header.Get("Accept"))
+		buf := bytes.Buffer{}
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		enc.UseCompactInts(true)
+		require.NoError(t, enc.Encode(remote))
+		w.Header().Set("Content-Type", MsgpackContentType)
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	p := AgentProvider{TimeOut: time.Second}
+	resp, err := p.Status(Request{Name: "edge1", URL: "agent://" + strings.TrimPrefix(ts.URL, "http://")})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"hostname": "edge1", "cpu_percent": 12, "mem_percent": 34, "services": 3,
+		"failed": []string{"db"}, "warn": []string{"file", "zfs/tank"}, "format": "msgpack", "status": "failed: db"}, resp.Body)
+	assert.Equal(t, "edge1: 3 services, 1 failed, 2 warn (failed)", resp.Summary)
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", MsgpackContentType)
+		_, _ = w.Write([]byte(`{"hostname":"edge1"}`))
+	})
+	_, err = p.Status(Request{Name: "edge1", URL: "agent://" + strings.TrimPrefix(ts.URL, "http://")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent response decode failed: edge1 "+ts.URL+"/status")
+}
+
+func TestAgentProvider_StatusTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	p := AgentProvider{TimeOut: 50 * time.Millisecond}
+	_, err := p.Status(Request{Name: "edge", URL: "agent://" + strings.TrimPrefix(ts.URL, "http://")})
+	require.Error(t, err)
+}
+
+func TestAgentProvider_StatusBodyLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"hostname":"edge1","labels":{"pad":"` + strings.Repeat("a", maxBodyBytes) + `"}}`))
+	}))
+	defer ts.Close()
+
+	p := AgentProvider{TimeOut: time.Second}
+	_, err := p.Status(Request{Name: "edge1", URL: "agent://" + strings.TrimPrefix(ts.URL, "http://")})
+	require.Error(t, err, "body truncated at the limit")
+	assert.Contains(t, err.Error(), "agent response decode failed")
+}
+
+func Test_remoteHealth(t *testing.T) {
+	tbl := []struct {
+		resp Response
+		res  string
+	}{
+		{Response{StatusCode: 200}, "ok"},
+		{Response{StatusCode: 500}, "failed"},
+		{Response{StatusCode: 404, Body: map[string]interface{}{"status": "ok"}}, "failed"},
+		{Response{StatusCode: 200, Body: map[string]interface{}{"status": "ok"}}, "ok"},
+		{Response{StatusCode: 200, Body: map[string]interface{}{"status": "failed: blah"}}, "failed"},
+		{Response{StatusCode: 200, Body: map[string]interface{}{"status": "warn: blah"}}, "warn"},
+		{Response{StatusCode: 200, Body: map[string]interface{}{"status": 123}}, "ok"},
+	}
+	for i, tt := range tbl {
+		assert.Equal(t, tt.res, remoteHealth(tt.resp), "case #%d", i)
+	}
+}
@@ -0,0 +1,85 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApacheProvider_Status(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/server-status", r.URL.Path)
+		assert.Equal(t, "auto", r.URL.RawQuery)
+		if user, passwd, ok := r.BasicAuth(); ok && (user != "admin" || passwd != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, err := os.ReadFile("testdata/apache_status.txt")
+		require.NoError(t, err)
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	p := ApacheProvider{TimeOut: time.Second}
+
+	exp := map[string]interface{}{"busy_workers": 12, "idle_workers": 38, "requests_per_sec": 18.0021,
+		"total_accesses": 1823471, "status": "ok"}
+
+	resp, err := p.Status(Request{Name: "web", URL: "apache://" + host + "/server-status"})
+	require.NoError(t, err)
+	assert.Equal(t, "web", resp.Name)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, exp, resp.Body)
+	assert.Equal(t, "apache 12 busy, 38 idle workers (ok)", resp.Summary)
+
+	resp, err = p.Status(Request{Name: "web", URL: "apache://admin:secret@" + host + "/server-status"})
+	require.NoError(t, err)
+	assert.Equal(t, exp, resp.Body)
+
+	resp, err = p.Status(Request{Name: "web", URL: "apache://admin:bad@" + host + "/server-status"})
+	require.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode)
+	assert.Equal(t, "apache status 401 (failed)", resp.Summary)
+
+	_, err = p.Status(Request{Name: "web", URL: "apache://"})
+	require.EqualError(t, err, "apache host is empty: web apache://")
+}
+
+func TestApacheProvider_StatusNoIdleWorkers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("BusyWorkers: 150\nIdleWorkers: 0\n"))
+	}))
+	defer ts.Close()
+
+	p := ApacheProvider{TimeOut: time.Second}
+	resp, err := p.Status(Request{Name: "web", URL: "apache://" + strings.TrimPrefix(ts.URL, "http://") + "/server-status"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"busy_workers": 150, "idle_workers": 0,
+		"status": "warn: no idle workers, 150 busy"}, resp.Body)
+	assert.Equal(t, "apache 150 busy, 0 idle workers (warn)", resp.Summary)
+}
+
+func Test_parseApacheStatus(t *testing.T) {
+	tbl := []struct {
+		in  string
+		err string
+	}{
+		{"IdleWorkers: 3\n", "no BusyWorkers in response"},
+		{"BusyWorkers: 3\n", "no IdleWorkers in response"},
+		{"BusyWorkers: x\nIdleWorkers: 3\n", `failed to parse BusyWorkers "x"`},
+		{"BusyWorkers: 1\nIdleWorkers: 3\nReqPerSec: fast\n", `failed to parse ReqPerSec "fast"`},
+		{"<html>Forbidden</html>", "no BusyWorkers in response"},
+	}
+	for _, tt := range tbl {
+		_, err := parseApacheStatus(strings.NewReader(tt.in))
+		require.Error(t, err, tt.in)
+		assert.Contains(t, err.Error(), tt.err)
+	}
+}
@@ -0,0 +1,97 @@
+package external
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	availabilityWindow = time.Hour       // samples older than this are dropped
+	availabilityMaxGap = 5 * time.Minute // max time a single sample accounts for, sparse samples don't fill the window
+)
+
+// availabilitySample is a result of a single service check
+type availabilitySample struct {
+	ts time.Time
+	up bool
+}
+
+// availabilityHistory keeps service check results within the window
+type availabilityHistory struct {
+	samples []availabilitySample // ordered by time
+}
+
+// add records the sample
+func (h *availabilityHistory) add(ts time.Time, up bool) {
+	h.samples = append(h.samples, availabilitySample{ts: ts, up: up})
+}
+
+// trim drops samples older than the window
+func (h *availabilityHistory) trim(now time.Time) {
+	i := 0
+	for i < len(h.samples) && now.Sub(h.samples[i].ts) > availabilityWindow {
+		i++
+	}
+	h.samples = h.samples[i:]
+}
+
+// percent returns the share of time the service was up, in percents rounded to 0.01. Each sample accounts for the
+// time until the next one, capped with availabilityMaxGap, so the long gap between checks, i.e. the agent or
+// the status requests stopped, doesn't count as up or down. The last sample accounts for the same time as
+// the previous one. Falls back to the share of up samples if all of them taken at once.
+func (h *availabilityHistory) percent() float64 {
+	if len(h.samples) == 0 {
+		return 0
+	}
+	var upTime, total time.Duration
+	upCount := 0
+	for i, s := range h.samples {
+		if s.up {
+			upCount++
+		}
+		var w time.Duration
+		switch {
+		case i < len(h.samples)-1:
+			w = h.samples[i+1].ts.Sub(s.ts)
+		case i > 0:
+			w = s.ts.Sub(h.samples[i-1].ts)
+		}
+		if w > availabilityMaxGap {
+			w = availabilityMaxGap
+		}
+		total += w
+		if s.up {
+			upTime += w
+		}
+	}
+	res := float64(upCount) / float64(len(h.samples))
+	if total > 0 {
+		res = float64(upTime) / float64(total)
+	}
+	return math.Round(res*10000) / 100
+}
+
+// recordAvailability adds results of the round to the history and sets availability of each response.
+// Skipped services, by gate or offline, are not recorded, availability is reported from the history collected before.
+func (s *Service) recordAvailability(res []Response, now time.Time) {
+	s.availability.once.Do(func() {
+		s.availability.history = make(map[string]*availabilityHistory)
+	})
+	s.availability.lock.Lock()
+	defer s.availability.lock.Unlock()
+	for i, r := range res {
+		h, ok := s.availability.history[r.Name]
+		if !ok {
+			h = &availabilityHistory{}
+			s.availability.history[r.Name] = h
+		}
+		if r.Skipped == "" {
+			h.add(now, isUp(r))
+		}
+		h.trim(now)
+		if len(h.samples) > 0 {
+			pct := h.percent()
+			res[i].Availability = &pct
+		}
+	}
+}
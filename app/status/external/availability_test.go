@@ -0,0 +1,74 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailabilityHistory_percent(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	series := func(step time.Duration, ups ...bool) *availabilityHistory {
+		h := &availabilityHistory{}
+		for i, up := range ups {
+			h.add(start.Add(time.Duration(i)*step), up)
+		}
+		return h
+	}
+
+	tbl := []struct {
+		name string
+		h    *availabilityHistory
+		exp  float64
+	}{
+		{"all up", series(time.Minute, true, true, true, true), 100},
+		{"all down", series(time.Minute, false, false), 0},
+		{"mix", series(time.Minute, true, true, false, true, true, true, true, false), 75},
+		{"single sample", series(time.Minute, false), 0},
+		{"one of three", series(time.Minute, true, false, false), 33.33},
+		{"same time, by count", series(0, true, true, false, true), 75},
+		{"empty", &availabilityHistory{}, 0},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.exp, tt.h.percent(), 0.001)
+		})
+	}
+
+	{ // sparse sampling, the long gap accounts for availabilityMaxGap only
+		h := &availabilityHistory{}
+		h.add(start, false)
+		h.add(start.Add(40*time.Minute), true) // down sample accounts for 5m, not for 40m
+		for i := 1; i <= 5; i++ {
+			h.add(start.Add(40*time.Minute+time.Duration(i)*time.Minute), true)
+		}
+		assert.InDelta(t, 54.55, h.percent(), 0.001, "5m down, 6m up")
+	}
+
+	{ // irregular sampling, weighted by time
+		h := &availabilityHistory{}
+		h.add(start, true)
+		h.add(start.Add(4*time.Minute), false)
+		h.add(start.Add(5*time.Minute), true)
+		h.add(start.Add(5*time.Minute+10*time.Second), true)
+		h.add(start.Add(5*time.Minute+20*time.Second), true)
+		assert.InDelta(t, 81.82, h.percent(), 0.001, "4m30s up with the last sample for 10s, 1m down")
+	}
+}
+
+func TestAvailabilityHistory_trim(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	h := &availabilityHistory{}
+	for i := 0; i < 90; i++ {
+		h.add(start.Add(time.Duration(i)*time.Minute), i >= 30)
+	}
+	h.trim(start.Add(89 * time.Minute))
+	require.Equal(t, 61, len(h.samples), "samples within the last hour")
+	assert.Equal(t, start.Add(29*time.Minute), h.samples[0].ts)
+	assert.InDelta(t, 98.36, h.percent(), 0.001, "one failed sample of 61")
+
+	h.trim(start.Add(3 * time.Hour))
+	assert.Empty(t, h.samples)
+}
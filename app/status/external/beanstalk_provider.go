@@ -0,0 +1,125 @@
+package external
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BeanstalkProvider is a status provider that checks depth of a beanstalkd tube
+type BeanstalkProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: beanstalk://example.com:11300?tube=default&maxReady=100. Port 11300 used unless set explicitly,
+// tube is "default" if not set. It issues stats-tube and reports ready and buried jobs. Status is failed if ready jobs
+// exceed optional maxReady or any job is buried.
+func (b *BeanstalkProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("beanstalk url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if uu.Hostname() == "" {
+		return nil, fmt.Errorf("beanstalk host is empty: %s %s", req.Name, req.URL)
+	}
+	addr := uu.Host
+	if uu.Port() == "" {
+		addr = net.JoinHostPort(uu.Hostname(), "11300")
+	}
+	tube := uu.Query().Get("tube")
+	if tube == "" {
+		tube = "default"
+	}
+	maxReady := -1
+	if v := uu.Query().Get("maxReady"); v != "" {
+		if maxReady, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("beanstalk maxReady parse failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	}
+
+	stats, err := b.statsTube(addr, tube)
+	if err != nil {
+		return nil, fmt.Errorf("beanstalk stats-tube failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	ready, buried := stats["current-jobs-ready"], stats["current-jobs-buried"]
+
+	body := map[string]interface{}{
+		"tube":                tube,
+		"current_jobs_ready":  ready,
+		"current_jobs_buried": buried,
+		"status":              "ok",
+	}
+	switch {
+	case maxReady >= 0 && ready > maxReady:
+		body["status"] = fmt.Sprintf("failed: %d ready jobs, expected at most %d", ready, maxReady)
+	case buried > 0:
+		body["status"] = fmt.Sprintf("failed: %d buried jobs", buried)
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("tube %s: %d ready, %d buried (%s)", tube, ready, buried,
+			level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// statsTube sends stats-tube command and returns numeric stats of the tube.
+// The response is "OK <bytes>\r\n<yaml>\r\n", or "NOT_FOUND\r\n" for unknown tube.
+func (b *BeanstalkProvider) statsTube(addr, tube string) (map[string]int, error) {
+	conn, err := net.DialTimeout("tcp", addr, b.TimeOut)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close() // nolint
+	if err = conn.SetDeadline(time.Now().Add(b.TimeOut)); err != nil {
+		return nil, err
+	}
+
+	if _, err = fmt.Fprintf(conn, "stats-tube %s\r\n", tube); err != nil {
+		return nil, err
+	}
+	rd := bufio.NewReader(conn)
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSpace(line)
+	if line == "NOT_FOUND" {
+		return nil, fmt.Errorf("tube %s not found", tube)
+	}
+	var size int
+	if _, err = fmt.Sscanf(line, "OK %d", &size); err != nil {
+		return nil, fmt.Errorf("unexpected response %q", line)
+	}
+	data := make([]byte, size+2) // with trailing \r\n
+	if _, err = io.ReadFull(rd, data); err != nil {
+		return nil, err
+	}
+	return parseBeanstalkStats(string(data)), nil
+}
+
+// parseBeanstalkStats parses flat yaml of stats, "key: value" per line. Non-numeric values are skipped.
+func parseBeanstalkStats(data string) map[string]int {
+	res := map[string]int{}
+	for _, line := range strings.Split(data, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			res[strings.TrimSpace(k)] = n
+		}
+	}
+	return res
+}
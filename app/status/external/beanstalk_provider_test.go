@@ -0,0 +1,122 @@
+package external
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeanstalkProvider_Status(t *testing.T) {
+	stats, err := os.ReadFile("testdata/beanstalk_stats_tube.yml")
+	require.NoError(t, err)
+	addr := startTestBeanstalkd(t, map[string]string{
+		"default": string(stats),
+		"idle":    "---\nname: idle\ncurrent-jobs-ready: 0\ncurrent-jobs-buried: 0\n",
+	})
+
+	p := BeanstalkProvider{TimeOut: time.Second}
+
+	tbl := []struct {
+		url     string
+		tube    string
+		ready   int
+		buried  int
+		status  string
+		summary string
+	}{
+		{"beanstalk://" + addr + "?tube=idle", "idle", 0, 0, "ok", "tube idle: 0 ready, 0 buried (ok)"},
+		{"beanstalk://" + addr + "?tube=idle&maxReady=0", "idle", 0, 0, "ok", "tube idle: 0 ready, 0 buried (ok)"},
+		{"beanstalk://" + addr, "default", 12, 2, "failed: 2 buried jobs", "tube default: 12 ready, 2 buried (failed)"},
+		{"beanstalk://" + addr + "?tube=default&maxReady=10", "default", 12, 2,
+			"failed: 12 ready jobs, expected at most 10", "tube default: 12 ready, 2 buried (failed)"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "jobs", URL: tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, "jobs", resp.Name)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, map[string]interface{}{"tube": tt.tube, "current_jobs_ready": tt.ready,
+				"current_jobs_buried": tt.buried, "status": tt.status}, resp.Body)
+			assert.Equal(t, tt.summary, resp.Summary)
+		})
+	}
+}
+
+func TestBeanstalkProvider_StatusFailed(t *testing.T) {
+	addr := startTestBeanstalkd(t, map[string]string{})
+	p := BeanstalkProvider{TimeOut: time.Second}
+
+	_, err := p.Status(Request{Name: "jobs", URL: "beanstalk://" + addr + "?tube=nope"})
+	require.EqualError(t, err, "beanstalk stats-tube failed: jobs beanstalk://"+addr+"?tube=nope: tube nope not found")
+
+	_, err = p.Status(Request{Name: "jobs", URL: "beanstalk://" + addr + "?maxReady=bad"})
+	require.Error(t, err)
+
+	_, err = p.Status(Request{Name: "jobs", URL: "beanstalk://"})
+	require.EqualError(t, err, "beanstalk host is empty: jobs beanstalk://")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	_, err = p.Status(Request{Name: "jobs", URL: "beanstalk://" + closedAddr})
+	require.Error(t, err)
+}
+
+func TestBeanstalkProvider_StatusTimeout(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(500 * time.Millisecond) // never responds in time
+	}()
+
+	p := BeanstalkProvider{TimeOut: 100 * time.Millisecond}
+	st := time.Now()
+	_, err = p.Status(Request{Name: "jobs", URL: "beanstalk://" + lis.Addr().String()})
+	require.Error(t, err)
+	assert.Less(t, time.Since(st), 400*time.Millisecond)
+}
+
+// startTestBeanstalkd starts tcp server responding to stats-tube with stats of the known tubes
+func startTestBeanstalkd(t *testing.T, tubes map[string]string) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				stats, ok := tubes[strings.TrimPrefix(strings.TrimSpace(line), "stats-tube ")]
+				if !ok {
+					_, _ = conn.Write([]byte("NOT_FOUND\r\n"))
+					return
+				}
+				_, _ = fmt.Fprintf(conn, "OK %d\r\n%s\r\n", len(stats), stats)
+			}()
+		}
+	}()
+	return lis.Addr().String()
+}
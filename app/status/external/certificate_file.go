@@ -0,0 +1,184 @@
+package external
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// certFileScheme is the url prefix of local certificates check, i.e. cert+file:///etc/ssl/mycerts/*.pem
+const certFileScheme = "cert+file://"
+
+// fileStatus checks local PEM certificates matched by the glob of the url path, or all *.pem files of the directory.
+// Each file is reported with the earliest expiration of its certificates, the body has the soonest one of all files.
+// Status is the worst of files, failed if expired, expiring sooner than the threshold or can't be parsed.
+// The path of a single file is checked by singleFileStatus, the same way as the certificates of the tls connection.
+func (c *CertificateProvider) fileStatus(req Request, uu *url.URL, threshold time.Duration, st time.Time) (*Response, error) {
+	pattern := uu.Path
+	fi, err := os.Stat(pattern)
+	if err == nil && fi.Mode().IsRegular() {
+		return c.singleFileStatus(req, uu, threshold, st)
+	}
+	if err == nil && fi.IsDir() {
+		pattern = filepath.Join(pattern, "*.pem")
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cert files glob failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	sort.Strings(files)
+
+	body := map[string]interface{}{"status": "ok"}
+	if len(files) == 0 {
+		body["status"] = "failed: no certificate files match " + pattern
+		return &Response{Name: req.Name, StatusCode: 200, Body: body, ResponseTime: time.Since(st).Milliseconds(),
+			Summary: "cert files, no files (failed)"}, nil
+	}
+
+	perFile := map[string]interface{}{}
+	statuses := make([]string, 0, len(files))
+	var soonest string
+	var soonestExpire time.Time
+	for _, f := range files {
+		info, exp := certFileInfo(f, threshold)
+		perFile[filepath.Base(f)] = info
+		statuses = append(statuses, info["status"].(string))
+		if !exp.IsZero() && (soonest == "" || exp.Before(soonestExpire)) {
+			soonest, soonestExpire = filepath.Base(f), exp
+		}
+	}
+	body["files"] = perFile
+
+	lvl := level(statuses...)
+	if lvl != "ok" {
+		var bad []string
+		for _, f := range files {
+			if s := perFile[filepath.Base(f)].(map[string]interface{})["status"].(string); level(s) == lvl {
+				bad = append(bad, filepath.Base(f))
+			}
+		}
+		body["status"] = lvl + ": " + strings.Join(bad, ",")
+	}
+
+	summary := fmt.Sprintf("cert files %d, no valid certificates (%s)", len(files), lvl)
+	if soonest != "" {
+		daysLeft := int(time.Until(soonestExpire).Hours() / 24)
+		body["expire"], body["days_left"], body["soonest_file"] = soonestExpire.Format(time.RFC3339), daysLeft, soonest
+		summary = fmt.Sprintf("cert files %d, %s expires in %d days (%s)", len(files), soonest, daysLeft, lvl)
+	}
+
+	return &Response{Name: req.Name, StatusCode: 200, Body: body, ResponseTime: time.Since(st).Milliseconds(),
+		Summary: summary}, nil
+}
+
+// singleFileStatus checks certificates of the PEM file with the same expiry, key and chain checks as the tls connection.
+// The first certificate of the file is the leaf one, the rest are intermediates. The host name is not known for the file,
+// so the leaf checked against it only if set with "host" query param, i.e. cert+file:///etc/ssl/cert.pem?host=example.com
+// Verification is skipped with "insecure=true". Unlike the glob check, the file that can't be read is an error.
+func (c *CertificateProvider) singleFileStatus(req Request, uu *url.URL, threshold time.Duration, st time.Time) (*Response, error) {
+	minKeyBits, minECKeyBits, err := minKeyBitsParams(uu)
+	if err != nil {
+		return nil, fmt.Errorf("cert key bits parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	certs, err := readCertFile(uu.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cert file read failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
+	expire := earliestExpire(certs)
+	daysLeft := int(time.Until(expire).Hours() / 24)
+	body := map[string]interface{}{
+		"file":             uu.Path,
+		"subject":          certs[0].Subject.CommonName,
+		"issuer":           certs[0].Issuer.CommonName,
+		"expire":           expire.Format(time.RFC3339),
+		"not_after":        expire.Format(time.RFC3339),
+		"days_left":        daysLeft,
+		"expires_in_hours": int(time.Until(expire).Hours()),
+		"status":           "ok",
+	}
+	expiring := time.Until(expire) < threshold
+	if expiring {
+		body["status"] = expiringStatus(expire, threshold)
+	}
+	if expire.Before(time.Now()) {
+		body["status"] = "expired"
+	}
+	for k, v := range c.keyInfo(certs, minKeyBits, minECKeyBits) {
+		body[k] = v
+	}
+
+	statuses := []string{body["key_status"].(string)}
+	if uu.Query().Get("insecure") != "true" {
+		for k, v := range c.verify(certs, uu.Query().Get("host")) {
+			body[k] = v
+		}
+		if vs := body["verify_status"].(string); vs != "ok" && body["status"] != "expired" {
+			setStatus(body, "failed", strings.TrimPrefix(vs, "failed: "))
+		}
+		statuses = append(statuses, body["verify_status"].(string))
+	}
+
+	lvl := level(statuses...)
+	if expiring {
+		lvl = "failed"
+	}
+	return &Response{Name: req.Name, StatusCode: 200, Body: body, ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("cert file %s expires in %d days (%s)", filepath.Base(uu.Path), daysLeft, lvl)}, nil
+}
+
+// readCertFile reads and parses all certificates of the PEM file, failed if the file has no certificates
+func readCertFile(fname string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(fname) //nolint:gosec // file from the configured glob
+	if err != nil {
+		return nil, fmt.Errorf("can't read: %w", err)
+	}
+
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue // private keys and other blocks can be in the same file
+		}
+		cert, e := x509.ParseCertificate(block.Bytes)
+		if e != nil {
+			return nil, fmt.Errorf("can't parse certificate: %w", e)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates")
+	}
+	return certs, nil
+}
+
+// certFileInfo reads PEM file and returns expiration of the earliest expiring certificate in it with the subject of
+// the first one and status. The expiration time is returned as well, zero if the file has no valid certificates.
+func certFileInfo(fname string, threshold time.Duration) (map[string]interface{}, time.Time) {
+	certs, err := readCertFile(fname)
+	if err != nil {
+		return map[string]interface{}{"status": "failed: " + err.Error()}, time.Time{}
+	}
+
+	expire := earliestExpire(certs)
+	daysLeft := int(time.Until(expire).Hours() / 24)
+	res := map[string]interface{}{
+		"subject":          certs[0].Subject.CommonName,
+		"expire":           expire.Format(time.RFC3339),
+		"days_left":        daysLeft,
+		"expires_in_hours": int(time.Until(expire).Hours()),
+		"status":           "ok",
+	}
+	switch {
+	case expire.Before(time.Now()):
+		res["status"] = "failed: expired"
+	case time.Until(expire) < threshold:
+		res["status"] = expiringStatus(expire, threshold)
+	}
+	return res, expire
+}
@@ -0,0 +1,173 @@
+package external
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateProvider_StatusFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCert(t, filepath.Join(dir, "api.pem"), "api.example.com", 90*24*time.Hour)
+	writeTestCert(t, filepath.Join(dir, "web.pem"), "web.example.com", 30*24*time.Hour)
+	writeTestCert(t, filepath.Join(dir, "web.crt"), "other.example.com", -time.Hour) // not matched by *.pem
+
+	cp := CertificateProvider{TimeOut: time.Second}
+
+	{ // all valid, directory is checked for *.pem
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + dir})
+		require.NoError(t, err)
+		assert.Equal(t, "local", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "web.pem", resp.Body["soonest_file"])
+		assert.Equal(t, 29, resp.Body["days_left"])
+		files := resp.Body["files"].(map[string]interface{})
+		require.Len(t, files, 2)
+		assert.Equal(t, "api.example.com", files["api.pem"].(map[string]interface{})["subject"])
+		assert.Equal(t, 89, files["api.pem"].(map[string]interface{})["days_left"])
+		assert.Equal(t, "cert files 2, web.pem expires in 29 days (ok)", resp.Summary)
+	}
+
+	writeTestCert(t, filepath.Join(dir, "soon.pem"), "soon.example.com", 2*24*time.Hour+time.Hour)
+	{ // one expiring sooner than default threshold
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + dir + "/*.pem"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: soon.pem", resp.Body["status"])
+		assert.Equal(t, "soon.pem", resp.Body["soonest_file"])
+		files := resp.Body["files"].(map[string]interface{})
+		assert.Equal(t, "failed: expiring in 48h, threshold 168h", files["soon.pem"].(map[string]interface{})["status"])
+		assert.Equal(t, 48, files["soon.pem"].(map[string]interface{})["expires_in_hours"])
+		assert.Equal(t, "cert files 3, soon.pem expires in 2 days (failed)", resp.Summary)
+	}
+
+	{ // custom threshold
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + dir + "/*.pem?expiryThreshold=24h"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "cert files 3, soon.pem expires in 2 days (ok)", resp.Summary)
+
+		resp, err = cp.Status(Request{Name: "local", URL: "cert+file://" + dir + "/*.pem?expiryThreshold=1000h"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: soon.pem,web.pem", resp.Body["status"])
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a cert"), 0o600))
+	{ // failed file and an expired one, the worst reported
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + dir + "/*"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: broken.pem,soon.pem,web.crt", resp.Body["status"])
+		assert.Equal(t, "web.crt", resp.Body["soonest_file"])
+		files := resp.Body["files"].(map[string]interface{})
+		require.Len(t, files, 5)
+		assert.Equal(t, map[string]interface{}{"status": "failed: no certificates"}, files["broken.pem"])
+		assert.Equal(t, "failed: expired", files["web.crt"].(map[string]interface{})["status"])
+		assert.Equal(t, "cert files 5, web.crt expires in 0 days (failed)", resp.Summary)
+	}
+
+	{ // nothing matched
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + dir + "/*.cer"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: no certificate files match "+dir+"/*.cer", resp.Body["status"])
+		assert.Equal(t, "cert files, no files (failed)", resp.Summary)
+	}
+}
+
+func TestCertificateProvider_StatusSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "cert.pem")
+	writeTestCert(t, valid, "api.example.com", 90*24*time.Hour)
+	data, err := os.ReadFile(valid) //nolint:gosec // test file
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	{ // valid, chain verified against the roots
+		cp := CertificateProvider{TimeOut: time.Second, roots: roots}
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + valid})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "api.example.com", resp.Body["subject"])
+		assert.Equal(t, "api.example.com", resp.Body["issuer"])
+		assert.Equal(t, leaf.NotAfter.Format(time.RFC3339), resp.Body["not_after"])
+		assert.Equal(t, 89, resp.Body["days_left"])
+		assert.Equal(t, true, resp.Body["chain_valid"])
+		assert.NotContains(t, resp.Body, "hostname_valid")
+		assert.Equal(t, "ok", resp.Body["key_status"])
+		assert.Equal(t, "cert file cert.pem expires in 89 days (ok)", resp.Summary)
+	}
+
+	{ // hostname checked
+		cp := CertificateProvider{TimeOut: time.Second, roots: roots}
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + valid + "?host=web.example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, true, resp.Body["chain_valid"])
+		assert.Equal(t, false, resp.Body["hostname_valid"])
+		assert.Contains(t, resp.Body["status"], "failed: hostname: ")
+		assert.Equal(t, "cert file cert.pem expires in 89 days (failed)", resp.Summary)
+	}
+
+	{ // system roots, chain not valid
+		cp := CertificateProvider{TimeOut: time.Second}
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + valid})
+		require.NoError(t, err)
+		assert.Equal(t, false, resp.Body["chain_valid"])
+		assert.Contains(t, resp.Body["status"], "failed: chain: ")
+
+		resp, err = cp.Status(Request{Name: "local", URL: "cert+file://" + valid + "?insecure=true"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.NotContains(t, resp.Body, "chain_valid")
+	}
+
+	{ // expiring sooner than the threshold
+		cp := CertificateProvider{TimeOut: time.Second, roots: roots}
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + valid + "?expiryThreshold=2400h"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: expiring in 2159h, threshold 2400h", resp.Body["status"])
+		assert.Equal(t, "cert file cert.pem expires in 89 days (failed)", resp.Summary)
+	}
+
+	{ // expired
+		expired := filepath.Join(dir, "expired.pem")
+		writeTestCert(t, expired, "old.example.com", -time.Hour)
+		cp := CertificateProvider{TimeOut: time.Second}
+		resp, err := cp.Status(Request{Name: "local", URL: "cert+file://" + expired + "?insecure=true"})
+		require.NoError(t, err)
+		assert.Equal(t, "expired", resp.Body["status"])
+	}
+
+	{ // not a certificate
+		broken := filepath.Join(dir, "broken.pem")
+		require.NoError(t, os.WriteFile(broken, []byte("not a cert"), 0o600))
+		cp := CertificateProvider{TimeOut: time.Second}
+		_, err := cp.Status(Request{Name: "local", URL: "cert+file://" + broken})
+		require.EqualError(t, err, "cert file read failed: local cert+file://"+broken+": no certificates")
+	}
+}
+
+// writeTestCert writes self-signed PEM certificate with the given common name, valid for ttl from now,
+// negative ttl makes the expired one
+func writeTestCert(t *testing.T, fname, cn string, ttl time.Duration) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: cn},
+		NotBefore: time.Now().Add(-48 * time.Hour), NotAfter: time.Now().Add(ttl)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fname, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+}
@@ -1,22 +1,72 @@
 package external
 
 import (
+	"crypto/dsa" //nolint:staticcheck // deprecated, but dsa keys are still found in old certificates
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	defaultMinKeyBits   = 2048 // minimal RSA/DSA key size
+	defaultMinECKeyBits = 256  // minimal ECDSA key size
+
+	resumptionTicketWait = 250 * time.Millisecond // max wait for TLS 1.3 session ticket sent after the handshake
+
+	defaultExpiryThreshold = 7 * 24 * time.Hour // failed if certificate expires sooner
+)
+
 // CertificateProvider is a status provider that check SSL certificate
 type CertificateProvider struct {
 	TimeOut time.Duration
+	roots   *x509.CertPool // root CAs to verify certificates, system roots if nil
 }
 
-// Status url looks like: cert://example.com. It will try to get SSL certificate and check if it is valid and not going to expire soon
+// Status url looks like: cert://example.com. It will try to get SSL certificate and check if it is valid and not going to expire soon.
+// Port 443 used unless set explicitly, i.e. cert://example.com:8443.
+// Key size and signature algorithm are checked as well, minimal key size can be set with minKeyBits (RSA, default 2048)
+// and minECKeyBits (ECDSA, default 256) query params.
+// The chain is verified against system roots and the leaf certificate against the host name, failed if any of them
+// is not valid. Verification can be skipped with "insecure=true", i.e. for self-signed internal certificates.
+// With "resumption=true" the second connection is made to check if it resumes the TLS session of the first one.
+// Status is failed if the certificate expires sooner than "expiryThreshold", 168h by default, i.e. ?expiryThreshold=720h
+// Local PEM files are checked with cert+file:///etc/ssl/mycerts/*.pem url, see fileStatus.
 func (c *CertificateProvider) Status(req Request) (*Response, error) {
 	st := time.Now()
-	addr := strings.TrimPrefix(req.URL, "cert://") + ":443"
-	conn, err := tls.Dial("tcp", addr, &tls.Config{}) //nolint:gosec // we don't care about cert version
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("cert url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	threshold := defaultExpiryThreshold
+	if v := uu.Query().Get("expiryThreshold"); v != "" {
+		if threshold, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("cert expiryThreshold parse failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	}
+	if strings.HasPrefix(req.URL, certFileScheme) {
+		return c.fileStatus(req, uu, threshold, st)
+	}
+	addr := uu.Host
+	if uu.Port() == "" {
+		addr = net.JoinHostPort(uu.Host, "443")
+	}
+
+	minKeyBits, minECKeyBits, err := minKeyBitsParams(uu)
+	if err != nil {
+		return nil, fmt.Errorf("cert key bits parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
+	// verification is done after the handshake, so the metadata of the invalid certificate is reported as well
+	tlsCfg := &tls.Config{ServerName: uu.Hostname(), InsecureSkipVerify: true} //nolint:gosec // verified by verify
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: c.TimeOut}, "tcp", addr, tlsCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
@@ -26,32 +76,223 @@ func (c *CertificateProvider) Status(req Request) (*Response, error) {
 	defer conn.Close() // nolint
 
 	certs := conn.ConnectionState().PeerCertificates
-	earlierCert := time.Date(2150, 1, 1, 0, 0, 0, 0, time.UTC)
-	for _, cert := range certs {
-		if cert.NotAfter.Before(earlierCert) {
-			earlierCert = cert.NotAfter
-		}
-	}
+	earlierCert := earliestExpire(certs)
 
 	daysLeft := int(time.Until(earlierCert).Hours() / 24)
 	body := map[string]interface{}{
-		"expire":    earlierCert.Format(time.RFC3339),
-		"days_left": daysLeft,
-		"host":      strings.Replace(req.URL, "cert://", "https://", 1),
-		"status":    "ok",
+		"expire":           earlierCert.Format(time.RFC3339),
+		"not_after":        earlierCert.Format(time.RFC3339),
+		"days_left":        daysLeft,
+		"expires_in_hours": int(time.Until(earlierCert).Hours()),
+		"host":             "https://" + uu.Host,
+		"status":           "ok",
 	}
-	if daysLeft < 5 {
-		body["status"] = fmt.Sprintf("expiring soon, in %d days", daysLeft)
+	expiring := time.Until(earlierCert) < threshold
+	if expiring {
+		body["status"] = expiringStatus(earlierCert, threshold)
 	}
 	if earlierCert.Before(time.Now()) {
 		body["status"] = "expired"
 	}
+	for k, v := range c.keyInfo(certs, minKeyBits, minECKeyBits) {
+		body[k] = v
+	}
+
+	insecure := uu.Query().Get("insecure") == "true"
+	if !insecure {
+		for k, v := range c.verify(certs, uu.Hostname()) {
+			body[k] = v
+		}
+		if vs := body["verify_status"].(string); vs != "ok" && body["status"] != "expired" {
+			setStatus(body, "failed", strings.TrimPrefix(vs, "failed: "))
+		}
+	}
+
+	statuses := []string{body["key_status"].(string)}
+	if !insecure {
+		statuses = append(statuses, body["verify_status"].(string))
+	}
+	if uu.Query().Get("resumption") == "true" {
+		resumed, err := c.resumed(addr, uu.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("cert resumption check failed: %s %s: %w", req.Name, req.URL, err)
+		}
+		body["resumed"], body["resumption_status"] = resumed, "ok"
+		if !resumed {
+			body["resumption_status"] = "warn: tls session not resumed"
+		}
+		statuses = append(statuses, body["resumption_status"].(string))
+	}
+
+	lvl := level(statuses...)
+	if expiring {
+		lvl = "failed"
+	}
 
 	result := Response{
 		Name:         req.Name,
 		StatusCode:   200,
 		Body:         body,
 		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      fmt.Sprintf("cert %s expires in %d days (%s)", uu.Hostname(), daysLeft, lvl),
 	}
 	return &result, nil
 }
+
+// minKeyBitsParams returns minimal RSA and ECDSA key sizes from minKeyBits and minECKeyBits query params, or defaults
+func minKeyBitsParams(uu *url.URL) (minKeyBits, minECKeyBits int, err error) {
+	minKeyBits, minECKeyBits = defaultMinKeyBits, defaultMinECKeyBits
+	if v := uu.Query().Get("minKeyBits"); v != "" {
+		if minKeyBits, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("minKeyBits: %w", err)
+		}
+	}
+	if v := uu.Query().Get("minECKeyBits"); v != "" {
+		if minECKeyBits, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("minECKeyBits: %w", err)
+		}
+	}
+	return minKeyBits, minECKeyBits, nil
+}
+
+// earliestExpire returns expiration of the earliest expiring certificate
+func earliestExpire(certs []*x509.Certificate) time.Time {
+	res := time.Date(2150, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, cert := range certs {
+		if cert.NotAfter.Before(res) {
+			res = cert.NotAfter
+		}
+	}
+	return res
+}
+
+// expiringStatus returns failed status of the certificate expiring sooner than the threshold
+func expiringStatus(notAfter time.Time, threshold time.Duration) string {
+	return fmt.Sprintf("failed: expiring in %dh, threshold %dh", int(time.Until(notAfter).Hours()), int(threshold.Hours()))
+}
+
+// resumed makes two connections with shared session cache and returns true if the second one resumed the session.
+// TLS 1.3 session tickets are sent after the handshake, so the first connection waits for them with a short read.
+func (c *CertificateProvider) resumed(addr, serverName string) (bool, error) {
+	cfg := &tls.Config{ServerName: serverName, InsecureSkipVerify: true, ClientSessionCache: tls.NewLRUClientSessionCache(1)} //nolint:gosec // same as above
+	dialer := &net.Dialer{Timeout: c.TimeOut}
+
+	first, err := tls.DialWithDialer(dialer, "tcp", addr, cfg)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	_ = first.SetReadDeadline(time.Now().Add(resumptionTicketWait))
+	_, _ = first.Read(make([]byte, 1)) // timeout is expected, nothing but tickets is sent by the server
+	first.Close()                      // nolint
+
+	second, err := tls.DialWithDialer(dialer, "tcp", addr, cfg)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer second.Close() // nolint
+	return second.ConnectionState().DidResume, nil
+}
+
+// verify checks the chain of the leaf certificate against root CAs and the leaf against the host name.
+// It returns "chain_valid", "hostname_valid" and "verify_status", failed with the reason if any check failed.
+// The host name is not checked and "hostname_valid" not returned for empty host.
+func (c *CertificateProvider) verify(certs []*x509.Certificate, host string) map[string]interface{} {
+	if len(certs) == 0 {
+		return map[string]interface{}{"chain_valid": false, "hostname_valid": false, "verify_status": "failed: no certificates"}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	var reasons []string
+	_, chainErr := certs[0].Verify(x509.VerifyOptions{Roots: c.roots, Intermediates: intermediates})
+	if chainErr != nil {
+		reasons = append(reasons, "chain: "+chainErr.Error())
+	}
+	res := map[string]interface{}{"chain_valid": chainErr == nil, "verify_status": "ok"}
+	if host != "" {
+		hostErr := certs[0].VerifyHostname(host)
+		if hostErr != nil {
+			reasons = append(reasons, "hostname: "+hostErr.Error())
+		}
+		res["hostname_valid"] = hostErr == nil
+	}
+	if len(reasons) > 0 {
+		res["verify_status"] = "failed: " + strings.Join(reasons, ", ")
+	}
+	return res
+}
+
+// keyInfo returns key algorithm, key size and signature algorithm of the leaf certificate with "key_status".
+// The status is failed if the leaf key is smaller than the minimum, or the leaf is signed with MD5 or SHA-1.
+// Weak signature of intermediate certificates reported as warn. Self-signed roots are not checked.
+func (c *CertificateProvider) keyInfo(certs []*x509.Certificate, minKeyBits, minECKeyBits int) map[string]interface{} {
+	if len(certs) == 0 {
+		return map[string]interface{}{"key_status": "failed: no certificates"}
+	}
+
+	leaf := certs[0]
+	bits := keyBits(leaf.PublicKey)
+	res := map[string]interface{}{
+		"key_algorithm":       leaf.PublicKeyAlgorithm.String(),
+		"key_bits":            bits,
+		"signature_algorithm": leaf.SignatureAlgorithm.String(),
+		"key_status":          "ok",
+	}
+
+	switch leaf.PublicKeyAlgorithm {
+	case x509.RSA, x509.DSA:
+		if bits < minKeyBits {
+			res["key_status"] = fmt.Sprintf("failed: %s key %d bits, expected at least %d", leaf.PublicKeyAlgorithm, bits, minKeyBits)
+			return res
+		}
+	case x509.ECDSA:
+		if bits < minECKeyBits {
+			res["key_status"] = fmt.Sprintf("failed: %s key %d bits, expected at least %d", leaf.PublicKeyAlgorithm, bits, minECKeyBits)
+			return res
+		}
+	}
+
+	if weakSignature(leaf.SignatureAlgorithm) {
+		res["key_status"] = "failed: weak signature algorithm " + leaf.SignatureAlgorithm.String()
+		return res
+	}
+
+	var weak []string
+	for _, cert := range certs[1:] {
+		if cert.Subject.String() == cert.Issuer.String() {
+			continue // self-signed root, signature is not used for verification
+		}
+		if weakSignature(cert.SignatureAlgorithm) {
+			weak = append(weak, fmt.Sprintf("%s (%s)", cert.Subject.CommonName, cert.SignatureAlgorithm))
+		}
+	}
+	if len(weak) > 0 {
+		res["key_status"] = "warn: weak intermediate signature " + strings.Join(weak, ",")
+	}
+	return res
+}
+
+// keyBits returns size of the public key in bits, 0 for unknown key types
+func keyBits(key interface{}) int {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *dsa.PublicKey:
+		return k.P.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+func weakSignature(alg x509.SignatureAlgorithm) bool {
+	switch alg {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,21 @@
 package external
 
 import (
+	"crypto"
+	"crypto/dsa" //nolint:staticcheck // deprecated, used to check key size of old certificates
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +37,7 @@ func TestCertificateProvider_Status(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, exp.After(time.Now().Add(5*24*time.Hour)))
 	t.Logf("expire: %+v", exp)
+	assert.Regexp(t, `^cert umputun.com expires in \d+ days \(ok\)$`, resp.Summary)
 }
 
 func TestCertificateProvider_StatusFailed(t *testing.T) {
@@ -29,3 +45,213 @@ func TestCertificateProvider_StatusFailed(t *testing.T) {
 	_, err := cp.Status(Request{Name: "test", URL: "cert://127.0.0.1"})
 	require.Error(t, err)
 }
+
+func TestCertificateProvider_keyInfo(t *testing.T) {
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecP256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecP224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+
+	makeCert := func(cn string, key crypto.Signer, sigAlg x509.SignatureAlgorithm) *x509.Certificate {
+		tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: cn},
+			NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+		parent := &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "test ca"}}
+		der, e := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), key)
+		require.NoError(t, e)
+		cert, e := x509.ParseCertificate(der)
+		require.NoError(t, e)
+		if sigAlg != x509.UnknownSignatureAlgorithm {
+			cert.SignatureAlgorithm = sigAlg // go refuses to sign with weak algorithms, simulate it
+		}
+		return cert
+	}
+	// go can't create certificates with dsa keys, only parse them, so the parsed one is simulated
+	dsaCert := func(bits int) *x509.Certificate {
+		key := &dsa.PublicKey{Parameters: dsa.Parameters{P: new(big.Int).Lsh(big.NewInt(1), uint(bits-1))}}
+		return &x509.Certificate{Subject: pkix.Name{CommonName: "leaf"}, PublicKey: key,
+			PublicKeyAlgorithm: x509.DSA, SignatureAlgorithm: x509.DSAWithSHA256}
+	}
+
+	p := CertificateProvider{}
+	tbl := []struct {
+		name   string
+		certs  []*x509.Certificate
+		alg    string
+		bits   int
+		sig    string
+		status string
+	}{
+		{"rsa 2048", []*x509.Certificate{makeCert("leaf", rsa2048, 0)}, "RSA", 2048, "SHA256-RSA", "ok"},
+		{"rsa 1024", []*x509.Certificate{makeCert("leaf", rsa1024, 0)}, "RSA", 1024, "SHA256-RSA",
+			"failed: RSA key 1024 bits, expected at least 2048"},
+		{"ecdsa p256", []*x509.Certificate{makeCert("leaf", ecP256, 0)}, "ECDSA", 256, "ECDSA-SHA256", "ok"},
+		{"ecdsa p224", []*x509.Certificate{makeCert("leaf", ecP224, 0)}, "ECDSA", 224, "ECDSA-SHA256",
+			"failed: ECDSA key 224 bits, expected at least 256"},
+		{"dsa 2048", []*x509.Certificate{dsaCert(2048)}, "DSA", 2048, "DSA-SHA256", "ok"},
+		{"dsa 1024", []*x509.Certificate{dsaCert(1024)}, "DSA", 1024, "DSA-SHA256",
+			"failed: DSA key 1024 bits, expected at least 2048"},
+		{"sha1 leaf", []*x509.Certificate{makeCert("leaf", rsa2048, x509.SHA1WithRSA)}, "RSA", 2048, "SHA1-RSA",
+			"failed: weak signature algorithm SHA1-RSA"},
+		{"sha1 intermediate", []*x509.Certificate{makeCert("leaf", rsa2048, 0), makeCert("inter", rsa2048, x509.SHA1WithRSA)},
+			"RSA", 2048, "SHA256-RSA", "warn: weak intermediate signature inter (SHA1-RSA)"},
+		{"no certs", nil, "", 0, "", "failed: no certificates"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			res := p.keyInfo(tt.certs, defaultMinKeyBits, defaultMinECKeyBits)
+			assert.Equal(t, tt.status, res["key_status"])
+			if tt.certs == nil {
+				return
+			}
+			assert.Equal(t, tt.alg, res["key_algorithm"])
+			assert.Equal(t, tt.bits, res["key_bits"])
+			assert.Equal(t, tt.sig, res["signature_algorithm"])
+		})
+	}
+
+	{ // custom minimums
+		res := p.keyInfo([]*x509.Certificate{makeCert("leaf", rsa2048, 0)}, 4096, 384)
+		assert.Equal(t, "failed: RSA key 2048 bits, expected at least 4096", res["key_status"])
+		res = p.keyInfo([]*x509.Certificate{makeCert("leaf", rsa1024, 0)}, 1024, 256)
+		assert.Equal(t, "ok", res["key_status"])
+	}
+}
+
+func TestCertificateProvider_StatusExpiryThreshold(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	cp := CertificateProvider{TimeOut: time.Second, roots: roots}
+	host := strings.TrimPrefix(ts.URL, "https://")
+	notAfter := ts.Certificate().NotAfter
+
+	resp, err := cp.Status(Request{Name: "edge", URL: "cert://" + host})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"])
+	assert.Equal(t, notAfter.Format(time.RFC3339), resp.Body["not_after"])
+	assert.Equal(t, int(time.Until(notAfter).Hours()), resp.Body["expires_in_hours"])
+	assert.True(t, strings.HasSuffix(resp.Summary, "(ok)"), resp.Summary)
+
+	threshold := time.Until(notAfter) + 24*time.Hour
+	resp, err = cp.Status(Request{Name: "edge", URL: "cert://" + host + "?expiryThreshold=" + threshold.String()})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("failed: expiring in %dh, threshold %dh", int(time.Until(notAfter).Hours()),
+		int(threshold.Hours())), resp.Body["status"])
+	assert.True(t, strings.HasSuffix(resp.Summary, "(failed)"), resp.Summary)
+
+	_, err = cp.Status(Request{Name: "edge", URL: "cert://" + host + "?expiryThreshold=month"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cert expiryThreshold parse failed")
+}
+
+func TestCertificateProvider_StatusWeakSignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "legacy.example.com"},
+		DNSNames: []string{"localhost"}, NotBefore: time.Now(), NotAfter: time.Now().Add(30 * 24 * time.Hour),
+		SignatureAlgorithm: x509.SHA1WithRSA}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}} //nolint:gosec
+	ts.StartTLS()
+	defer ts.Close()
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(ts.URL, "https://"))
+	require.NoError(t, err)
+
+	cp := CertificateProvider{TimeOut: time.Second}
+	resp, err := cp.Status(Request{Name: "legacy", URL: "cert://localhost:" + port + "?insecure=true"})
+	require.NoError(t, err)
+	assert.Equal(t, "RSA", resp.Body["key_algorithm"])
+	assert.Equal(t, 2048, resp.Body["key_bits"])
+	assert.Equal(t, "SHA1-RSA", resp.Body["signature_algorithm"])
+	assert.Equal(t, "failed: weak signature algorithm SHA1-RSA", resp.Body["key_status"])
+	assert.Equal(t, "cert localhost expires in 29 days (failed)", resp.Summary)
+}
+
+func TestCertificateProvider_StatusVerify(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(ts.URL, "https://"))
+	require.NoError(t, err)
+
+	tbl := []struct {
+		name          string
+		roots         *x509.CertPool
+		url           string
+		chainValid    interface{}
+		hostnameValid interface{}
+		status        string // prefix, error messages vary between go versions
+	}{
+		{"valid", roots, "cert://127.0.0.1:" + port, true, true, "ok"},
+		{"unknown authority", nil, "cert://127.0.0.1:" + port, false, true,
+			"failed: chain: x509: certificate signed by unknown authority"},
+		{"wrong hostname", roots, "cert://localhost:" + port, true, false,
+			"failed: hostname: x509: certificate is valid for example.com"},
+		{"insecure", nil, "cert://localhost:" + port + "?insecure=true", nil, nil, "ok"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := CertificateProvider{TimeOut: time.Second, roots: tt.roots}
+			resp, err := cp.Status(Request{Name: "edge", URL: tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, tt.chainValid, resp.Body["chain_valid"])
+			assert.Equal(t, tt.hostnameValid, resp.Body["hostname_valid"])
+			assert.True(t, strings.HasPrefix(resp.Body["status"].(string), tt.status), resp.Body["status"])
+			assert.True(t, strings.HasSuffix(resp.Summary, "("+level(tt.status)+")"), resp.Summary)
+			// metadata reported regardless of verification
+			assert.Equal(t, ts.Certificate().NotAfter.Format(time.RFC3339), resp.Body["not_after"])
+			assert.Equal(t, "RSA", resp.Body["key_algorithm"])
+		})
+	}
+}
+
+func TestCertificateProvider_StatusResumption(t *testing.T) {
+	tbl := []struct {
+		name             string
+		ticketsDisabled  bool
+		maxVersion       uint16
+		resumed          bool
+		resumptionStatus string
+		summaryLevel     string
+	}{
+		{"tls13 enabled", false, tls.VersionTLS13, true, "ok", "ok"},
+		{"tls13 disabled", true, tls.VersionTLS13, false, "warn: tls session not resumed", "warn"},
+		{"tls12 enabled", false, tls.VersionTLS12, true, "ok", "ok"},
+		{"tls12 disabled", true, tls.VersionTLS12, false, "warn: tls session not resumed", "warn"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			ts.TLS = &tls.Config{SessionTicketsDisabled: tt.ticketsDisabled, MaxVersion: tt.maxVersion} //nolint:gosec
+			ts.StartTLS()
+			defer ts.Close()
+
+			roots := x509.NewCertPool()
+			roots.AddCert(ts.Certificate())
+			cp := CertificateProvider{TimeOut: time.Second, roots: roots}
+			host := strings.TrimPrefix(ts.URL, "https://")
+
+			resp, err := cp.Status(Request{Name: "edge", URL: "cert://" + host + "?resumption=true"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.resumed, resp.Body["resumed"])
+			assert.Equal(t, tt.resumptionStatus, resp.Body["resumption_status"])
+			assert.True(t, strings.HasSuffix(resp.Summary, "("+tt.summaryLevel+")"), resp.Summary)
+
+			resp, err = cp.Status(Request{Name: "edge", URL: "cert://" + host})
+			require.NoError(t, err)
+			assert.NotContains(t, resp.Body, "resumed", "not checked without resumption param")
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DNSProvider is a status provider that looks up a record with the given resolver
+type DNSProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: dns://8.8.8.8/example.com?type=MX. It looks up the record of the type, A (default), AAAA,
+// CNAME, MX or TXT, with the resolver from url and reports the answer in "records". Port 53 used unless set explicitly.
+// Status is failed if the resolver returns error or times out, or the answer is empty.
+func (d *DNSProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("dns url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	name := strings.Trim(uu.Path, "/")
+	if uu.Hostname() == "" || name == "" {
+		return nil, fmt.Errorf("dns resolver or name is empty: %s %s", req.Name, req.URL)
+	}
+	server := uu.Host
+	if uu.Port() == "" {
+		server = net.JoinHostPort(uu.Hostname(), "53")
+	}
+	rtype := strings.ToUpper(uu.Query().Get("type"))
+	if rtype == "" {
+		rtype = "A"
+	}
+	switch rtype {
+	case "A", "AAAA", "CNAME", "MX", "TXT":
+	default:
+		return nil, fmt.Errorf("dns type should be A, AAAA, CNAME, MX or TXT: %s %s", req.Name, req.URL)
+	}
+
+	// dial goes to the resolver from url regardless of the system resolv.conf
+	resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		dialer := net.Dialer{Timeout: d.TimeOut}
+		return dialer.DialContext(ctx, network, server)
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), d.TimeOut)
+	defer cancel()
+
+	records, err := lookupRecords(ctx, resolver, name, rtype)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// not found is an empty answer, and other errors refer to the system resolver instead of the dialed one
+		if dnsErr.IsNotFound {
+			err = nil
+		} else {
+			err = fmt.Errorf("lookup %s on %s: %s", name, server, dnsErr.Err)
+		}
+	}
+	body := map[string]interface{}{"resolver": server, "name": name, "type": rtype, "records": records, "status": "ok"}
+	switch {
+	case err != nil:
+		body["status"] = "failed: " + err.Error()
+	case len(records) == 0:
+		body["status"] = fmt.Sprintf("failed: no %s records", rtype)
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("dns %s %s: %d record(s) from %s (%s)", rtype, name, len(records), server,
+			level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// lookupRecords returns sorted answer for the record type, MX formatted as "pref host".
+// Missing CNAME is reported as empty answer, as net.Resolver returns the name itself if it has address records.
+func lookupRecords(ctx context.Context, r *net.Resolver, name, rtype string) ([]string, error) {
+	res := []string{}
+	switch rtype {
+	case "A", "AAAA":
+		network := map[string]string{"A": "ip4", "AAAA": "ip6"}[rtype]
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return res, err
+		}
+		for _, ip := range ips {
+			res = append(res, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return res, err
+		}
+		if strings.TrimSuffix(cname, ".") != strings.TrimSuffix(name, ".") {
+			res = append(res, cname)
+		}
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return res, err
+		}
+		for _, mx := range mxs {
+			res = append(res, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, name)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, txts...)
+	}
+	sort.Strings(res)
+	return res, nil
+}
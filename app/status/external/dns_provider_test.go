@@ -0,0 +1,104 @@
+package external
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSProvider_Status(t *testing.T) {
+	addr := startTestDNSServer(t, func(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource) {
+		hdr := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: 60}
+		if q.Name.String() == "broken.example.com." {
+			return dnsmessage.RCodeServerFailure, nil
+		}
+		switch {
+		case q.Name.String() == "www.example.com." && q.Type == dnsmessage.TypeCNAME:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: hdr, Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("web.example.com.")}}}
+		case q.Name.String() == "www.example.com.":
+			cname := hdr
+			cname.Type = dnsmessage.TypeCNAME
+			target := hdr
+			target.Name = dnsmessage.MustNewName("web.example.com.")
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: cname, Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("web.example.com.")}},
+				{Header: target, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 3}}},
+			}
+		case q.Type == dnsmessage.TypeA && q.Name.String() == "web.example.com.":
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}},
+				{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+			}
+		case q.Type == dnsmessage.TypeAAAA && q.Name.String() == "web.example.com.":
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: hdr, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}}}
+		case q.Type == dnsmessage.TypeMX:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: hdr, Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mx1.example.com.")}},
+				{Header: hdr, Body: &dnsmessage.MXResource{Pref: 20, MX: dnsmessage.MustNewName("mx2.example.com.")}},
+			}
+		case q.Type == dnsmessage.TypeTXT && q.Name.String() == "example.com.":
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: hdr, Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}}}
+		}
+		return dnsmessage.RCodeSuccess, nil
+	})
+
+	p := DNSProvider{TimeOut: time.Second}
+
+	tbl := []struct {
+		url     string
+		records []string
+		status  string
+	}{
+		{"dns://" + addr + "/web.example.com", []string{"10.0.0.1", "10.0.0.2"}, "ok"},
+		{"dns://" + addr + "/web.example.com?type=aaaa", []string{"2001:db8::1"}, "ok"},
+		{"dns://" + addr + "/www.example.com?type=CNAME", []string{"web.example.com."}, "ok"},
+		{"dns://" + addr + "/example.com?type=MX", []string{"10 mx1.example.com.", "20 mx2.example.com."}, "ok"},
+		{"dns://" + addr + "/example.com?type=TXT", []string{"v=spf1 -all"}, "ok"},
+		{"dns://" + addr + "/empty.example.com?type=TXT", []string{}, "failed: no TXT records"},
+		{"dns://" + addr + "/web.example.com?type=CNAME", []string{}, "failed: no CNAME records"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "dns", URL: tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, "dns", resp.Name)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, addr, resp.Body["resolver"])
+			assert.Equal(t, tt.records, resp.Body["records"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.Contains(t, resp.Summary, "("+level(tt.status)+")")
+		})
+	}
+
+	resp, err := p.Status(Request{Name: "dns", URL: "dns://" + addr + "/broken.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "failed: lookup broken.example.com on "+addr+": server misbehaving", resp.Body["status"])
+}
+
+func TestDNSProvider_StatusTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close() // never answers
+
+	p := DNSProvider{TimeOut: 100 * time.Millisecond}
+	st := time.Now()
+	resp, err := p.Status(Request{Name: "dns", URL: "dns://" + conn.LocalAddr().String() + "/example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "failed: lookup example.com on "+conn.LocalAddr().String()+": i/o timeout", resp.Body["status"])
+	assert.Less(t, time.Since(st), time.Second)
+}
+
+func TestDNSProvider_StatusErrors(t *testing.T) {
+	p := DNSProvider{TimeOut: time.Second}
+	_, err := p.Status(Request{Name: "dns", URL: "dns://8.8.8.8/example.com?type=SOA"})
+	require.EqualError(t, err, "dns type should be A, AAAA, CNAME, MX or TXT: dns dns://8.8.8.8/example.com?type=SOA")
+	_, err = p.Status(Request{Name: "dns", URL: "dns:///example.com"})
+	require.EqualError(t, err, "dns resolver or name is empty: dns dns:///example.com")
+}
@@ -0,0 +1,183 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSZoneProvider is a status provider that checks SOA serial consistency across zone nameservers
+type DNSZoneProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: dnszone://example.com?ns=ns1.example.com,ns2.example.com:5353
+// It queries SOA record of the zone from each nameserver and reports failed status if serials are not the same.
+// If "ns" is not set, authoritative nameservers of the zone are discovered with NS lookup.
+func (d *DNSZoneProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), d.TimeOut)
+	defer cancel()
+
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("dnszone url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	zone := uu.Host
+	if zone == "" {
+		return nil, fmt.Errorf("dnszone zone is empty: %s %s", req.Name, req.URL)
+	}
+
+	var nameservers []string
+	for _, ns := range strings.Split(uu.Query().Get("ns"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			nameservers = append(nameservers, ns)
+		}
+	}
+	if len(nameservers) == 0 {
+		nss, err := net.DefaultResolver.LookupNS(ctx, zone)
+		if err != nil {
+			return nil, fmt.Errorf("dnszone ns lookup failed: %s %s: %w", req.Name, req.URL, err)
+		}
+		for _, ns := range nss {
+			nameservers = append(nameservers, strings.TrimSuffix(ns.Host, "."))
+		}
+	}
+
+	// nameservers queried concurrently, so a dead one doesn't take the timeout from the others
+	serials := map[string]uint32{}
+	errs := map[string]string{}
+	uniq := map[uint32]bool{}
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	for _, ns := range nameservers {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			serial, err := d.soaSerial(ctx, zone, ns)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs[ns] = err.Error()
+				return
+			}
+			serials[ns] = serial
+			uniq[serial] = true
+		}(ns)
+	}
+	wg.Wait()
+
+	body := map[string]interface{}{
+		"zone":    zone,
+		"serials": serials,
+		"status":  "ok",
+	}
+	if len(errs) > 0 {
+		body["errors"] = errs
+	}
+
+	switch {
+	case len(errs) > 0:
+		failed := make([]string, 0, len(errs))
+		for ns := range errs {
+			failed = append(failed, ns)
+		}
+		sort.Strings(failed)
+		body["status"] = "failed: no serial from " + strings.Join(failed, ",")
+	case len(uniq) > 1:
+		body["status"] = fmt.Sprintf("failed: serial mismatch, %d different serials", len(uniq))
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("zone %s: %d serial(s) from %d of %d nameservers (%s)", zone, len(uniq), len(serials),
+			len(nameservers), level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// soaSerial queries nameserver for SOA record of the zone and returns its serial.
+// Nameserver can be set with port, default port is 53. The query is not recursive, and the answer should be
+// authoritative, a cached answer of a recursive resolver would hide the stale serial of the nameserver.
+func (d *DNSZoneProvider) soaSerial(ctx context.Context, zone, nameserver string) (uint32, error) {
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		nameserver = net.JoinHostPort(nameserver, "53")
+	}
+
+	resp, err := dnsQuery(ctx, nameserver, zone, dnsmessage.TypeSOA, false)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Header.RCode != dnsmessage.RCodeSuccess {
+		return 0, fmt.Errorf("dns response from %s: %s", nameserver, resp.Header.RCode)
+	}
+	if !resp.Header.Authoritative {
+		return 0, errors.New("not authoritative response from " + nameserver)
+	}
+	for _, ans := range resp.Answers {
+		if soa, ok := ans.Body.(*dnsmessage.SOAResource); ok {
+			return soa.Serial, nil
+		}
+	}
+	return 0, errors.New("no SOA record in response from " + nameserver)
+}
+
+// dnsQuery sends a single udp query for the name to nameserver (host:port) and returns the response.
+// Recursion is desired with recursive set. Response code is not checked, it is up to the caller.
+func dnsQuery(ctx context.Context, nameserver, name string, qtype dnsmessage.Type, recursive bool) (*dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid name %s: %w", name, err)
+	}
+
+	id := uint16(rand.Intn(1 << 16)) //nolint:gosec // dns message id doesn't need crypto rand
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: recursive},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("can't pack dns query: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s: %w", nameserver, err)
+	}
+	defer conn.Close() // nolint
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err = conn.Write(packed); err != nil {
+		return nil, fmt.Errorf("can't send dns query to %s: %w", nameserver, err)
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, fmt.Errorf("can't read dns response from %s: %w", nameserver, err)
+	}
+
+	var resp dnsmessage.Message
+	if err = resp.Unpack(buf[:n]); err != nil {
+		return nil, fmt.Errorf("can't unpack dns response from %s: %w", nameserver, err)
+	}
+	if resp.Header.ID != id {
+		return nil, fmt.Errorf("unexpected dns response id from %s", nameserver)
+	}
+	return &resp, nil
+}
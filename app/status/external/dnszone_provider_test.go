@@ -0,0 +1,144 @@
+package external
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSZoneProvider_Status(t *testing.T) {
+	ns1 := startTestDNSServer(t, soaAnswer(2024010101))
+	ns2 := startTestDNSServer(t, soaAnswer(2024010101))
+	ns3 := startTestDNSServer(t, soaAnswer(2024010100))
+
+	p := DNSZoneProvider{TimeOut: time.Second}
+
+	{ // all serials match
+		resp, err := p.Status(Request{Name: "zone", URL: "dnszone://example.com?ns=" + ns1 + "," + ns2})
+		require.NoError(t, err)
+		t.Logf("%+v", resp)
+		assert.Equal(t, "zone", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "example.com", resp.Body["zone"])
+		assert.Equal(t, map[string]uint32{ns1: 2024010101, ns2: 2024010101}, resp.Body["serials"])
+		assert.Equal(t, "zone example.com: 1 serial(s) from 2 of 2 nameservers (ok)", resp.Summary)
+	}
+
+	{ // secondary is behind
+		resp, err := p.Status(Request{Name: "zone", URL: "dnszone://example.com?ns=" + ns1 + "," + ns3})
+		require.NoError(t, err)
+		t.Logf("%+v", resp)
+		assert.Equal(t, "failed: serial mismatch, 2 different serials", resp.Body["status"])
+		assert.Equal(t, map[string]uint32{ns1: 2024010101, ns3: 2024010100}, resp.Body["serials"])
+		assert.Equal(t, "zone example.com: 2 serial(s) from 2 of 2 nameservers (failed)", resp.Summary)
+	}
+}
+
+func TestDNSZoneProvider_StatusFailed(t *testing.T) {
+	ns1 := startTestDNSServer(t, soaAnswer(2024010101))
+	nsRefused := startTestDNSServer(t, func(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource) {
+		return dnsmessage.RCodeRefused, nil
+	})
+
+	p := DNSZoneProvider{TimeOut: 500 * time.Millisecond}
+	resp, err := p.Status(Request{Name: "zone", URL: "dnszone://example.com?ns=" + ns1 + "," + nsRefused})
+	require.NoError(t, err)
+	t.Logf("%+v", resp)
+	assert.Equal(t, "failed: no serial from "+nsRefused, resp.Body["status"])
+	assert.Equal(t, map[string]uint32{ns1: 2024010101}, resp.Body["serials"])
+	assert.Equal(t, "zone example.com: 1 serial(s) from 1 of 2 nameservers (failed)", resp.Summary)
+	assert.Contains(t, resp.Body["errors"].(map[string]string)[nsRefused], "RCodeRefused")
+
+	_, err = p.Status(Request{Name: "zone", URL: "dnszone://?ns=" + ns1})
+	require.Error(t, err)
+}
+
+func TestDNSZoneProvider_StatusNotAuthoritative(t *testing.T) {
+	ns1 := startTestDNSServer(t, soaAnswer(2024010101))
+	// recursive resolver answers from cache, not authoritative
+	resolver := startTestDNSServerWith(t, func(req dnsmessage.Header) bool {
+		assert.False(t, req.RecursionDesired, "recursion not desired")
+		return false
+	}, soaAnswer(2024010101))
+
+	p := DNSZoneProvider{TimeOut: 500 * time.Millisecond}
+	resp, err := p.Status(Request{Name: "zone", URL: "dnszone://example.com?ns=" + ns1 + "," + resolver})
+	require.NoError(t, err)
+	assert.Equal(t, "failed: no serial from "+resolver, resp.Body["status"])
+	assert.Equal(t, map[string]uint32{ns1: 2024010101}, resp.Body["serials"])
+	assert.Equal(t, "not authoritative response from "+resolver, resp.Body["errors"].(map[string]string)[resolver])
+}
+
+func TestDNSZoneProvider_StatusDeadNameserver(t *testing.T) {
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0") // never responds
+	require.NoError(t, err)
+	defer dead.Close()
+	ns1 := startTestDNSServer(t, soaAnswer(2024010101))
+	ns2 := startTestDNSServer(t, soaAnswer(2024010101))
+
+	p := DNSZoneProvider{TimeOut: 300 * time.Millisecond}
+	st := time.Now()
+	resp, err := p.Status(Request{Name: "zone", URL: "dnszone://example.com?ns=" + dead.LocalAddr().String() + "," +
+		ns1 + "," + ns2})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(st), 600*time.Millisecond, "nameservers queried concurrently")
+	assert.Equal(t, "failed: no serial from "+dead.LocalAddr().String(), resp.Body["status"])
+	assert.Equal(t, map[string]uint32{ns1: 2024010101, ns2: 2024010101}, resp.Body["serials"],
+		"dead nameserver doesn't take the timeout from the others")
+}
+
+func soaAnswer(serial uint32) func(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource) {
+	return func(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource) {
+		return dnsmessage.RCodeSuccess, []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body: &dnsmessage.SOAResource{NS: dnsmessage.MustNewName("ns1.example.com."),
+				MBox: dnsmessage.MustNewName("admin.example.com."), Serial: serial, Refresh: 3600, Retry: 600,
+				Expire: 86400, MinTTL: 60},
+		}}
+	}
+}
+
+// startTestDNSServer starts udp dns server on random port responding with answers from fn, returns its address
+func startTestDNSServer(t *testing.T, fn func(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource)) string {
+	return startTestDNSServerWith(t, func(req dnsmessage.Header) bool { return true }, fn)
+}
+
+// startTestDNSServerWith starts udp dns server like startTestDNSServer, auth is called with the header of each
+// request and returns authoritative flag of the response
+func startTestDNSServerWith(t *testing.T, auth func(req dnsmessage.Header) bool,
+	fn func(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource)) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err = req.Unpack(buf[:n]); err != nil || len(req.Questions) == 0 {
+				continue
+			}
+			rcode, answers := fn(req.Questions[0])
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.Header.ID, Response: true, Authoritative: auth(req.Header), RCode: rcode},
+				Questions: req.Questions,
+				Answers:   answers,
+			}
+			packed, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
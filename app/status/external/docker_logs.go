@@ -0,0 +1,157 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultLogLines   = 100     // lines to tail from container logs
+	maxLogLines       = 10000   // upper limit for requested lines
+	maxLogBytes       = 1 << 20 // max bytes of logs read per container
+	defaultLogPattern = `(?i)\b(error|fatal|panic)\b`
+)
+
+// dockerLogsCheck defines log error check, set by "logs" query param with containers separated by ":"
+type dockerLogsCheck struct {
+	containers []string
+	lines      int
+	pattern    *regexp.Regexp
+	maxErrors  int
+}
+
+// logsCheck makes log error check from "logs", "logLines", "logPattern" and "maxLogErrors" query params
+func (d *DockerProvider) logsCheck(q url.Values) (dockerLogsCheck, error) {
+	res := dockerLogsCheck{lines: defaultLogLines}
+	for _, c := range strings.Split(q.Get("logs"), ":") {
+		if c = strings.TrimSpace(c); c != "" {
+			res.containers = append(res.containers, c)
+		}
+	}
+
+	var err error
+	if v := q.Get("logLines"); v != "" {
+		if res.lines, err = strconv.Atoi(v); err != nil {
+			return res, fmt.Errorf("logLines: %w", err)
+		}
+		if res.lines <= 0 || res.lines > maxLogLines {
+			return res, fmt.Errorf("logLines should be in 1..%d range, got %d", maxLogLines, res.lines)
+		}
+	}
+	if v := q.Get("maxLogErrors"); v != "" {
+		if res.maxErrors, err = strconv.Atoi(v); err != nil {
+			return res, fmt.Errorf("maxLogErrors: %w", err)
+		}
+	}
+	pattern := defaultLogPattern
+	if v := q.Get("logPattern"); v != "" {
+		pattern = v
+	}
+	if res.pattern, err = regexp.Compile(pattern); err != nil {
+		return res, fmt.Errorf("logPattern: %w", err)
+	}
+	return res, nil
+}
+
+// checkLogs tails logs of all containers from the check and counts lines matching the error pattern.
+// It sets "log_errors" with the count for each container and "logs" status: failed if any container has more
+// than maxErrors matching lines, warn if any errors found within the limit, otherwise ok.
+func (d *DockerProvider) checkLogs(client *http.Client, dkinfo map[string]interface{}, check dockerLogsCheck) error {
+	counts := map[string]int{}
+	var exceeded, found []string
+	for _, cname := range check.containers {
+		count, err := d.logErrors(client, cname, check)
+		if err != nil {
+			return err
+		}
+		counts[cname] = count
+		switch {
+		case count > check.maxErrors:
+			exceeded = append(exceeded, fmt.Sprintf("%s:%d", cname, count))
+		case count > 0:
+			found = append(found, fmt.Sprintf("%s:%d", cname, count))
+		}
+	}
+	sort.Strings(exceeded)
+	sort.Strings(found)
+
+	dkinfo["log_errors"] = counts
+	dkinfo["logs"] = "ok"
+	switch {
+	case len(exceeded) > 0:
+		dkinfo["logs"] = "failed: " + strings.Join(exceeded, ",")
+	case len(found) > 0:
+		dkinfo["logs"] = "warn: " + strings.Join(found, ",")
+	}
+	return nil
+}
+
+// logErrors returns number of lines matching the error pattern in the last lines of the container logs.
+// Both stdout and stderr are read, the read is limited to maxLogBytes.
+func (d *DockerProvider) logErrors(client *http.Client, container string, check dockerLogsCheck) (int, error) {
+	dkURL := fmt.Sprintf("http://localhost/v%s/containers/%s/logs?stdout=1&stderr=1&tail=%d",
+		dockerClientVersion, url.PathEscape(container), check.lines)
+	resp, err := client.Get(dkURL)
+	if err != nil {
+		return 0, fmt.Errorf("docker logs request failed for %s: %w", container, err)
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("docker logs failed for %s: %s", container, resp.Status)
+	}
+
+	data, err := readDockerLogs(io.LimitReader(resp.Body, maxLogBytes))
+	if err != nil {
+		return 0, fmt.Errorf("docker logs read failed for %s: %w", container, err)
+	}
+
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogBytes)
+	for scanner.Scan() {
+		if check.pattern.Match(scanner.Bytes()) {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// readDockerLogs returns log content of the docker logs stream. Containers without tty have stdout and stderr
+// multiplexed into frames with 8 bytes header: stream type, 3 zero bytes and big-endian payload size.
+// Containers with tty send raw content. Truncated last frame, i.e. by the read limit, is returned as is.
+func readDockerLogs(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !isLogFrameHeader(data) {
+		return data, nil
+	}
+
+	var res bytes.Buffer
+	for len(data) >= 8 && isLogFrameHeader(data) {
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			size = len(data)
+		}
+		res.Write(data[:size])
+		data = data[size:]
+	}
+	return res.Bytes(), nil
+}
+
+// isLogFrameHeader checks if data starts with multiplexed stream header, stdin(0), stdout(1) or stderr(2)
+func isLogFrameHeader(data []byte) bool {
+	return len(data) >= 8 && data[0] <= 2 && data[1] == 0 && data[2] == 0 && data[3] == 0
+}
@@ -0,0 +1,125 @@
+package external
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerProvider_StatusWithLogs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1.24/containers/json" {
+			data, err := os.ReadFile("testdata/containers.json")
+			require.NoError(t, err)
+			_, _ = w.Write(data)
+			return
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1.24/containers/"), "/logs")
+		data, err := os.ReadFile("testdata/logs/" + name + ".log")
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "1", r.URL.Query().Get("stdout"))
+		assert.Equal(t, "1", r.URL.Query().Get("stderr"))
+		lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+		if tail, err := strconv.Atoi(r.URL.Query().Get("tail")); err == nil && tail < len(lines) {
+			lines = lines[len(lines)-tail:]
+		}
+		if name == "blah" { // container with tty, raw stream
+			_, _ = w.Write([]byte(strings.Join(lines, "")))
+			return
+		}
+		for i, line := range lines { // multiplexed stream, errors to stderr
+			stream := byte(1)
+			if i%2 == 1 {
+				stream = 2
+			}
+			_, _ = w.Write(logFrame(stream, line))
+		}
+	}))
+	defer ts.Close()
+
+	p := DockerProvider{TimeOut: time.Second}
+	u := strings.Replace(ts.URL, "http://", "tcp://", 1)
+
+	tbl := []struct {
+		name   string
+		query  string
+		counts map[string]int
+		logs   string
+		lvl    string
+	}{
+		{"no errors", "?logs=nginx", map[string]int{"nginx": 0}, "ok", "ok"},
+		{"errors above max", "?logs=nginx:weather:blah", map[string]int{"nginx": 0, "weather": 4, "blah": 1},
+			"failed: blah:1,weather:4", "failed"},
+		{"errors within max", "?logs=nginx:weather:blah&maxLogErrors=5", map[string]int{"nginx": 0, "weather": 4, "blah": 1},
+			"warn: blah:1,weather:4", "warn"},
+		{"mixed", "?logs=weather : blah&maxLogErrors=2", map[string]int{"weather": 4, "blah": 1},
+			"failed: weather:4", "failed"},
+		{"tail", "?logs=weather&logLines=2", map[string]int{"weather": 2}, "failed: weather:2", "failed"},
+		{"custom pattern", "?logs=nginx:weather&logPattern=%5C%5BWARN%5C%5D&maxLogErrors=1",
+			map[string]int{"nginx": 0, "weather": 1}, "warn: weather:1", "warn"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "d1", URL: u + tt.query})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.counts, resp.Body["log_errors"])
+			assert.Equal(t, tt.logs, resp.Body["logs"])
+			assert.Equal(t, "docker 3 of 3 containers running ("+tt.lvl+")", resp.Summary)
+		})
+	}
+
+	{ // without logs no log checks made
+		resp, err := p.Status(Request{Name: "d1", URL: u})
+		require.NoError(t, err)
+		_, found := resp.Body["logs"]
+		assert.False(t, found)
+	}
+
+	for _, q := range []string{"?logs=nginx&logLines=bad", "?logs=nginx&logLines=0", "?logs=nginx&logLines=20000",
+		"?logs=nginx&maxLogErrors=bad", "?logs=nginx&logPattern=%5B", "?logs=unknown"} {
+		_, err := p.Status(Request{Name: "d1", URL: u + q})
+		require.Error(t, err, q)
+	}
+}
+
+func Test_readDockerLogs(t *testing.T) {
+	tbl := []struct {
+		name string
+		data []byte
+		res  string
+	}{
+		{"raw", []byte("line1\nline2\n"), "line1\nline2\n"},
+		{"empty", []byte{}, ""},
+		{"multiplexed", append(logFrame(1, "out line\n"), logFrame(2, "err line\n")...), "out line\nerr line\n"},
+		{"truncated", append(logFrame(1, "out line\n"), logFrame(2, "err line\n")[:12]...), "out line\nerr "},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := readDockerLogs(bytes.NewReader(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, string(res))
+		})
+	}
+}
+
+// logFrame makes a frame of docker multiplexed logs stream
+func logFrame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
@@ -9,7 +9,10 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,11 +21,28 @@ const dockerClientVersion = "1.24"
 // DockerProvider is a status provider that uses docker
 type DockerProvider struct {
 	TimeOut time.Duration
+
+	lastRestarts struct {
+		counts map[string]int
+		once   sync.Once
+		lock   sync.Mutex
+	}
+}
+
+type dockerContainer struct {
+	Name         string `json:"name"`
+	State        string `json:"state"`
+	Status       string `json:"status"`
+	RestartCount int    `json:"restart_count"` // inspected with maxRestarts only, 0 otherwise
 }
 
 // Status the url looks like: docker:///var/run/docker.sock or docker://1.2.3.4:2375
 // optionally the url can contain a query param "required" with a comma separated list of required container names
 // i.e. docker:///var/run/docker.sock?containers=foo,bar
+// optional "maxRestarts" query param enables restart count check for all containers, i.e. docker:///var/run/docker.sock?maxRestarts=5
+// optional "logs" query param enables log error check for the listed containers, i.e. docker:///var/run/docker.sock?logs=foo:bar.
+// The last "logLines" (default 100) lines are checked for "logPattern" (default error, fatal or panic words), failed if
+// more than "maxLogErrors" (default 0) lines matched, see checkLogs.
 func (d *DockerProvider) Status(req Request) (*Response, error) {
 
 	st := time.Now()
@@ -75,11 +95,35 @@ func (d *DockerProvider) Status(req Request) (*Response, error) {
 		return nil, fmt.Errorf("docker parsing failed: %s %s: %w", req.Name, req.URL, err)
 	}
 
+	if uu.Query().Get("maxRestarts") != "" {
+		maxRestarts, err := strconv.Atoi(uu.Query().Get("maxRestarts"))
+		if err != nil {
+			return nil, fmt.Errorf("docker maxRestarts parse failed: %s %s: %w", req.Name, req.URL, err)
+		}
+		if err := d.checkRestarts(&client, req.Name, dkinfo, maxRestarts); err != nil {
+			return nil, fmt.Errorf("docker restarts check failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	}
+
+	if uu.Query().Get("logs") != "" {
+		check, err := d.logsCheck(uu.Query())
+		if err != nil {
+			return nil, fmt.Errorf("docker logs options parse failed: %s %s: %w", req.Name, req.URL, err)
+		}
+		if err := d.checkLogs(&client, dkinfo, check); err != nil {
+			return nil, fmt.Errorf("docker logs check failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	}
+
+	restarts, _ := dkinfo["restarts"].(string)
+	logs, _ := dkinfo["logs"].(string)
 	result := Response{
 		Name:         req.Name,
 		StatusCode:   resp.StatusCode,
 		Body:         dkinfo,
 		ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("docker %d of %d containers running (%s)", dkinfo["running"], dkinfo["total"],
+			level(dkinfo["required"].(string), restarts, logs)),
 	}
 	return &result, nil
 }
@@ -97,20 +141,14 @@ func (d *DockerProvider) parseDockerResponse(r io.Reader, required []string) (ma
 		return nil, fmt.Errorf("docker ummarshal failed: %w", err)
 	}
 
-	type container struct {
-		Name   string `json:"name"`
-		State  string `json:"state"`
-		Status string `json:"status"`
-	}
-
-	containers := map[string]container{}
+	containers := map[string]dockerContainer{}
 	running, healthy, unhealthy := 0, 0, 0
 	for _, r := range dkResp {
 		if len(r.Names) == 0 || r.Names[0] == "/" {
 			continue
 		}
 		name := strings.TrimPrefix(r.Names[0], "/")
-		containers[name] = container{
+		containers[name] = dockerContainer{
 			Name:   name,
 			State:  r.State,
 			Status: r.Status,
@@ -154,3 +192,82 @@ func (d *DockerProvider) parseDockerResponse(r io.Reader, required []string) (ma
 	log.Printf("[DEBUG] required containers %+v, failed: %+v", required, requiredNotFound)
 	return res, nil
 }
+
+// checkRestarts inspects all containers from dkinfo and sets restart_count for each of them.
+// It sets "restarts" to failed if any container restarted more than maxRestarts times,
+// or to warn if restart count increased since the previous check. Counts of containers gone since the previous check
+// are dropped, so removed containers don't pile up and a container recreated with the same name starts from scratch.
+func (d *DockerProvider) checkRestarts(client *http.Client, name string, dkinfo map[string]interface{}, maxRestarts int) error {
+	d.lastRestarts.once.Do(func() {
+		d.lastRestarts.counts = make(map[string]int)
+	})
+
+	containers, ok := dkinfo["containers"].(map[string]dockerContainer)
+	if !ok {
+		return fmt.Errorf("unexpected containers type %T", dkinfo["containers"])
+	}
+
+	var exceeded, increased []string
+	for cname, c := range containers {
+		count, err := d.restartCount(client, cname)
+		if err != nil {
+			return err
+		}
+		c.RestartCount = count
+		containers[cname] = c
+
+		if count > maxRestarts {
+			exceeded = append(exceeded, fmt.Sprintf("%s:%d", cname, count))
+		}
+
+		key := name + "/" + cname
+		d.lastRestarts.lock.Lock()
+		if last, found := d.lastRestarts.counts[key]; found && count > last {
+			increased = append(increased, fmt.Sprintf("%s:+%d", cname, count-last))
+		}
+		d.lastRestarts.counts[key] = count
+		d.lastRestarts.lock.Unlock()
+	}
+
+	d.lastRestarts.lock.Lock()
+	for key := range d.lastRestarts.counts {
+		cname, found := strings.CutPrefix(key, name+"/")
+		if _, ok := containers[cname]; found && !ok {
+			delete(d.lastRestarts.counts, key)
+		}
+	}
+	d.lastRestarts.lock.Unlock()
+	sort.Strings(exceeded)
+	sort.Strings(increased)
+
+	dkinfo["restarts"] = "ok"
+	switch {
+	case len(exceeded) > 0:
+		dkinfo["restarts"] = "failed: " + strings.Join(exceeded, ",")
+	case len(increased) > 0:
+		dkinfo["restarts"] = "warn: " + strings.Join(increased, ",")
+	}
+	return nil
+}
+
+// restartCount returns RestartCount of the container from docker inspect api
+func (d *DockerProvider) restartCount(client *http.Client, container string) (int, error) {
+	dkURL := fmt.Sprintf("http://localhost/v%s/containers/%s/json", dockerClientVersion, url.PathEscape(container))
+	resp, err := client.Get(dkURL)
+	if err != nil {
+		return 0, fmt.Errorf("docker inspect request failed for %s: %w", container, err)
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("docker inspect failed for %s: %s", container, resp.Status)
+	}
+
+	var inspect struct {
+		RestartCount int
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return 0, fmt.Errorf("docker inspect unmarshal failed for %s: %w", container, err)
+	}
+	return inspect.RestartCount, nil
+}
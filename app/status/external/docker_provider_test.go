@@ -1,6 +1,7 @@
 package external
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -34,6 +35,7 @@ func TestDockerProvider_Status(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 200, resp.StatusCode)
 	assert.True(t, resp.ResponseTime > 1, resp.ResponseTime)
+	assert.Equal(t, "docker 3 of 3 containers running (ok)", resp.Summary)
 }
 
 func TestDockerProvider_StatusWithRequired(t *testing.T) {
@@ -60,6 +62,7 @@ func TestDockerProvider_StatusWithRequired(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 200, resp.StatusCode)
 		assert.Equal(t, "failed: c1,c2", resp.Body["required"])
+		assert.Equal(t, "docker 3 of 3 containers running (failed)", resp.Summary)
 	}
 
 	{
@@ -80,8 +83,8 @@ func TestDockerProvider_parseDockerResponse(t *testing.T) {
 	require.NoError(t, err)
 	t.Logf("%+v", res)
 	assert.Equal(t, 7, len(res))
-	assert.Equal(t, "map[blah:{blah running Up 21 hours (unhealthy)} nginx:{nginx running Up 2 seconds} "+
-		"weather:{weather running Up 2 hours (healthy)}]", fmt.Sprintf("%v", res["containers"]),
+	assert.Equal(t, "map[blah:{blah running Up 21 hours (unhealthy) 0} nginx:{nginx running Up 2 seconds 0} "+
+		"weather:{weather running Up 2 hours (healthy) 0}]", fmt.Sprintf("%v", res["containers"]),
 	)
 	assert.Equal(t, 3, res["total"])
 	assert.Equal(t, 3, res["running"])
@@ -90,3 +93,94 @@ func TestDockerProvider_parseDockerResponse(t *testing.T) {
 	assert.Equal(t, 0, res["failed"])
 	assert.Equal(t, "ok", res["required"])
 }
+
+func TestDockerProvider_StatusWithRestarts(t *testing.T) {
+	bump := 0 // added to nginx restart count to simulate restarts between checks
+	ts := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v1.24/containers/json" {
+					data, err := os.ReadFile("testdata/containers.json")
+					require.NoError(t, err)
+					_, e := w.Write(data)
+					require.NoError(t, e)
+					return
+				}
+				name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1.24/containers/"), "/json")
+				data, err := os.ReadFile("testdata/inspect/" + name + ".json")
+				if err != nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				inspect := map[string]interface{}{}
+				require.NoError(t, json.Unmarshal(data, &inspect))
+				if name == "nginx" {
+					inspect["RestartCount"] = inspect["RestartCount"].(float64) + float64(bump)
+				}
+				require.NoError(t, json.NewEncoder(w).Encode(inspect))
+			},
+		),
+	)
+	defer ts.Close()
+
+	p := DockerProvider{TimeOut: time.Second}
+	u := strings.Replace(ts.URL, "http://", "tcp://", 1)
+
+	{ // blah restarted 7 times, above threshold
+		resp, err := p.Status(Request{Name: "d1", URL: u + "?maxRestarts=5"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "failed: blah:7", resp.Body["restarts"])
+		assert.Equal(t, "docker 3 of 3 containers running (failed)", resp.Summary)
+		containers := resp.Body["containers"].(map[string]dockerContainer)
+		assert.Equal(t, 0, containers["nginx"].RestartCount)
+		assert.Equal(t, 2, containers["weather"].RestartCount)
+		assert.Equal(t, 7, containers["blah"].RestartCount)
+		data, err := json.Marshal(containers["nginx"])
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"restart_count":0`, "zero count reported")
+	}
+
+	{ // all below threshold, first check for d2, nothing to compare with
+		resp, err := p.Status(Request{Name: "d2", URL: u + "?maxRestarts=10"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["restarts"])
+	}
+
+	{ // same counts as before
+		resp, err := p.Status(Request{Name: "d2", URL: u + "?maxRestarts=10"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["restarts"])
+	}
+
+	{ // nginx restarted twice since the last check
+		bump = 2
+		resp, err := p.Status(Request{Name: "d2", URL: u + "?maxRestarts=10"})
+		require.NoError(t, err)
+		assert.Equal(t, "warn: nginx:+2", resp.Body["restarts"])
+		assert.Equal(t, 2, resp.Body["containers"].(map[string]dockerContainer)["nginx"].RestartCount)
+	}
+
+	{ // counts of removed containers dropped for the same service only
+		p.lastRestarts.counts["d2/removed"] = 3
+		p.lastRestarts.counts["d1/removed"] = 3
+		resp, err := p.Status(Request{Name: "d2", URL: u + "?maxRestarts=10"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["restarts"])
+		assert.NotContains(t, p.lastRestarts.counts, "d2/removed")
+		assert.Contains(t, p.lastRestarts.counts, "d1/removed")
+		assert.Contains(t, p.lastRestarts.counts, "d2/nginx")
+	}
+
+	{ // without maxRestarts no inspect calls made and no restarts reported
+		resp, err := p.Status(Request{Name: "d3", URL: u})
+		require.NoError(t, err)
+		_, found := resp.Body["restarts"]
+		assert.False(t, found)
+	}
+
+	{
+		_, err := p.Status(Request{Name: "d4", URL: u + "?maxRestarts=bad"})
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,151 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fastcgi record types and constants, see https://fastcgi-archives.github.io/FastCGI_Specification.html
+const (
+	fcgiVersion      = 1
+	fcgiBeginRequest = 1
+	fcgiEndRequest   = 3
+	fcgiParams       = 4
+	fcgiStdin        = 5
+	fcgiStdout       = 6
+	fcgiStderr       = 7
+	fcgiResponder    = 1
+	fcgiRequestID    = 1
+	fcgiMaxContent   = 65535
+)
+
+// fcgiGet makes GET request to fastcgi responder at network address, "tcp" or "unix", and returns status code with
+// the body of the response. Script path and query are passed as SCRIPT_NAME, SCRIPT_FILENAME and QUERY_STRING,
+// this is what php-fpm uses to match status path. The connection is not reused.
+func fcgiGet(network, addr, path, query string, timeout time.Duration) (int, []byte, error) {
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer conn.Close() // nolint
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	uri := path
+	if query != "" {
+		uri += "?" + query
+	}
+	params := [][2]string{
+		{"GATEWAY_INTERFACE", "CGI/1.1"}, {"SERVER_PROTOCOL", "HTTP/1.1"}, {"REQUEST_METHOD", "GET"},
+		{"SCRIPT_NAME", path}, {"SCRIPT_FILENAME", path}, {"REQUEST_URI", uri}, {"QUERY_STRING", query},
+		{"SERVER_SOFTWARE", "sys-agent"},
+	}
+	if err = fcgiWriteRequest(conn, params); err != nil {
+		return 0, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	stdout, err := fcgiReadStdout(conn)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return fcgiParseResponse(stdout)
+}
+
+// fcgiWriteRequest writes begin request, params and empty stdin records
+func fcgiWriteRequest(w io.Writer, params [][2]string) error {
+	buf := bytes.Buffer{}
+	// role and flags, no keep-alive, reserved bytes are zero
+	begin := []byte{0, fcgiResponder, 0, 0, 0, 0, 0, 0}
+	fcgiWriteRecord(&buf, fcgiBeginRequest, begin)
+
+	p := bytes.Buffer{}
+	for _, kv := range params {
+		fcgiWriteLen(&p, len(kv[0]))
+		fcgiWriteLen(&p, len(kv[1]))
+		p.WriteString(kv[0])
+		p.WriteString(kv[1])
+	}
+	if p.Len() > fcgiMaxContent {
+		return errors.New("params too long")
+	}
+	fcgiWriteRecord(&buf, fcgiParams, p.Bytes())
+	fcgiWriteRecord(&buf, fcgiParams, nil) // end of params
+	fcgiWriteRecord(&buf, fcgiStdin, nil)  // end of stdin
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func fcgiWriteRecord(buf *bytes.Buffer, recType byte, content []byte) {
+	hdr := [8]byte{fcgiVersion, recType}
+	binary.BigEndian.PutUint16(hdr[2:4], fcgiRequestID)
+	binary.BigEndian.PutUint16(hdr[4:6], uint16(len(content))) //nolint:gosec // checked by caller
+	buf.Write(hdr[:])
+	buf.Write(content)
+}
+
+// fcgiWriteLen writes name-value length, one byte for short values, four bytes with the high bit set otherwise
+func fcgiWriteLen(buf *bytes.Buffer, n int) {
+	if n < 128 {
+		buf.WriteByte(byte(n))
+		return
+	}
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(n)|1<<31) //nolint:gosec // params length is limited
+	buf.Write(b)
+}
+
+// fcgiReadStdout reads records up to end request and returns collected stdout. Stderr is ignored.
+func fcgiReadStdout(r io.Reader) ([]byte, error) {
+	rd := bufio.NewReader(r)
+	stdout := bytes.Buffer{}
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(rd, hdr[:]); err != nil {
+			return nil, err
+		}
+		content := make([]byte, int(binary.BigEndian.Uint16(hdr[4:6]))+int(hdr[6])) // content with padding
+		if _, err := io.ReadFull(rd, content); err != nil {
+			return nil, err
+		}
+		content = content[:binary.BigEndian.Uint16(hdr[4:6])]
+		switch hdr[1] {
+		case fcgiStdout:
+			stdout.Write(content)
+		case fcgiStderr:
+		case fcgiEndRequest:
+			return stdout.Bytes(), nil
+		default:
+			return nil, fmt.Errorf("unexpected record type %d", hdr[1])
+		}
+	}
+}
+
+// fcgiParseResponse splits cgi response to status code, from "Status" header, 200 by default, and body
+func fcgiParseResponse(data []byte) (int, []byte, error) {
+	rd := bufio.NewReader(bytes.NewReader(data))
+	hdr, err := textproto.NewReader(rd).ReadMIMEHeader()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse response headers: %w", err)
+	}
+	code := 200
+	if st := hdr.Get("Status"); st != "" {
+		if code, err = strconv.Atoi(strings.Fields(st)[0]); err != nil {
+			return 0, nil, fmt.Errorf("failed to parse status %q: %w", st, err)
+		}
+	}
+	body, err := io.ReadAll(rd)
+	if err != nil {
+		return 0, nil, err
+	}
+	return code, body, nil
+}
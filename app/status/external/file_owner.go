@@ -0,0 +1,27 @@
+//go:build !windows
+
+package external
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner returns user and group of the file, names resolved if possible
+func fileOwner(fi os.FileInfo) (ownerInfo, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ownerInfo{}, fmt.Errorf("unsupported file info type %T", fi.Sys())
+	}
+	res := ownerInfo{uid: strconv.FormatUint(uint64(st.Uid), 10), gid: strconv.FormatUint(uint64(st.Gid), 10)}
+	if u, err := user.LookupId(res.uid); err == nil {
+		res.user = u.Username
+	}
+	if g, err := user.LookupGroupId(res.gid); err == nil {
+		res.group = g.Name
+	}
+	return res, nil
+}
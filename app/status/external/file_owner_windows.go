@@ -0,0 +1,13 @@
+//go:build windows
+
+package external
+
+import (
+	"errors"
+	"os"
+)
+
+// fileOwner is not supported on windows
+func fileOwner(_ os.FileInfo) (ownerInfo, error) {
+	return ownerInfo{}, errors.New("file owner is not supported on windows")
+}
@@ -1,9 +1,14 @@
 package external
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +27,13 @@ type FileProvider struct {
 
 // Status returns the status of the file
 // url looks like this: file://blah/foo.txt (relative path) or file:///blah/foo.txt (absolute path)
+// Optional "expectMode" (octal, i.e. 0600) and "expectOwner" (user[:group], names or ids) params check permissions
+// and ownership of the file or directory, status is failed on mismatch.
+// Optional "maxAge" (duration, i.e. 1h) fails the status if the file was modified earlier than that, i.e. sentinel
+// file touched by cron job. Optional "minSize" and "maxSize" (bytes or with KB, MB, GB, TB suffix, i.e. 10MB) fail
+// the status if the file size is out of the range, i.e. a backup that suddenly shrunk.
+// Optional "contains" (substring) and "matchRegex" params fail the status if no line of the file matches, with both set
+// the same line should match both. The file is streamed line by line, the first matched line is returned in the body.
 func (f *FileProvider) Status(req Request) (*Response, error) {
 	f.lastInfo.once.Do(func() {
 		f.lastInfo.files = make(map[string]os.FileInfo)
@@ -29,7 +41,12 @@ func (f *FileProvider) Status(req Request) (*Response, error) {
 
 	st := time.Now()
 
-	fname := strings.TrimPrefix(req.URL, "file://")
+	fname, rawQuery, _ := strings.Cut(strings.TrimPrefix(req.URL, "file://"), "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("file url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
 	fi, err := os.Stat(fname)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return nil, fmt.Errorf("file stat failed: %s %s: %w", req.Name, fname, err)
@@ -47,6 +64,7 @@ func (f *FileProvider) Status(req Request) (*Response, error) {
 			StatusCode:   200,
 			Body:         map[string]interface{}{"status": "not found"},
 			ResponseTime: time.Since(st).Milliseconds(),
+			Summary:      fmt.Sprintf("file %s not found (failed)", fname),
 		}
 		return &result, nil
 	}
@@ -66,6 +84,26 @@ func (f *FileProvider) Status(req Request) (*Response, error) {
 	}
 	f.lastInfo.lock.Unlock()
 
+	if err = f.checkPerms(body, fi, query); err != nil {
+		return nil, fmt.Errorf("file perms check failed: %s %s: %w", req.Name, fname, err)
+	}
+	if err = f.checkAge(body, fi, query); err != nil {
+		return nil, fmt.Errorf("file age check failed: %s %s: %w", req.Name, fname, err)
+	}
+	if err = f.checkSize(body, fi, query); err != nil {
+		return nil, fmt.Errorf("file size check failed: %s %s: %w", req.Name, fname, err)
+	}
+
+	if fi.IsDir() {
+		if query.Get("contains") != "" || query.Get("matchRegex") != "" {
+			return nil, fmt.Errorf("file content check failed: %s %s: can't match content of directory", req.Name, fname)
+		}
+		summary := fmt.Sprintf("file %s, %d bytes, modified %s ago (%s)", fname, fi.Size(),
+			time.Since(fi.ModTime()).Truncate(time.Second), level(body["status"].(string)))
+		return &Response{Name: req.Name, StatusCode: 200, Body: body, ResponseTime: time.Since(st).Milliseconds(),
+			Summary: summary}, nil
+	}
+
 	fh, err := os.Open(fname) //nolint:gosec // open file for reading, this is trusted file from the provider config
 	if err != nil {
 		return nil, fmt.Errorf("file open failed: %s %s: %w", req.Name, fname, err)
@@ -74,17 +112,195 @@ func (f *FileProvider) Status(req Request) (*Response, error) {
 
 	data := make([]byte, 100)
 	n, err := fh.Read(data)
-	if err != nil {
+	if err != nil && !errors.Is(err, io.EOF) {
 		return nil, fmt.Errorf("file read failed: %s %s: %w", req.Name, fname, err)
 	}
 	body["content"] = string(data[:n])
 
+	if err = f.checkContent(body, fh, query); err != nil {
+		return nil, fmt.Errorf("file content check failed: %s %s: %w", req.Name, fname, err)
+	}
+
+	summary := fmt.Sprintf("file %s, %d bytes, modified %s ago (%s)", fname, fi.Size(),
+		time.Since(fi.ModTime()).Truncate(time.Second), level(body["status"].(string)))
 	result := Response{
 		Name:         req.Name,
 		StatusCode:   200,
 		Body:         body,
 		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      summary,
 	}
 	return &result, nil
 
 }
+
+// checkPerms sets mode and owner in the body and checks them against expectMode and expectOwner query params
+func (f *FileProvider) checkPerms(body map[string]interface{}, fi os.FileInfo, query url.Values) error {
+	mode := fi.Mode().Perm()
+	body["mode"] = fmt.Sprintf("%04o", mode)
+
+	owner, err := fileOwner(fi)
+	if err != nil && query.Get("expectOwner") != "" {
+		return err
+	}
+	if err == nil {
+		body["owner"] = owner.String()
+	}
+
+	var mismatch []string
+	if v := query.Get("expectMode"); v != "" {
+		expMode, e := strconv.ParseUint(v, 8, 32)
+		if e != nil {
+			return fmt.Errorf("invalid expectMode %q: %w", v, e)
+		}
+		if uint32(mode) != uint32(expMode) {
+			mismatch = append(mismatch, fmt.Sprintf("mode %04o, expected %04o", mode, expMode))
+		}
+	}
+	if v := query.Get("expectOwner"); v != "" && !owner.matches(v) {
+		mismatch = append(mismatch, fmt.Sprintf("owner %s, expected %s", owner, v))
+	}
+	if len(mismatch) > 0 {
+		body["status"] = "failed: " + strings.Join(mismatch, ", ")
+	}
+	return nil
+}
+
+// checkAge sets age_seconds in the body and checks it against maxAge query param
+func (f *FileProvider) checkAge(body map[string]interface{}, fi os.FileInfo, query url.Values) error {
+	age := time.Since(fi.ModTime())
+	body["age_seconds"] = int64(age.Seconds())
+	v := query.Get("maxAge")
+	if v == "" {
+		return nil
+	}
+	maxAge, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid maxAge %q: %w", v, err)
+	}
+	if age > maxAge {
+		setStatus(body, "failed", fmt.Sprintf("age %s, expected at most %s", age.Truncate(time.Second), maxAge))
+	}
+	return nil
+}
+
+// checkSize sets size_bytes in the body and checks it against minSize and maxSize query params
+func (f *FileProvider) checkSize(body map[string]interface{}, fi os.FileInfo, query url.Values) error {
+	body["size_bytes"] = fi.Size()
+	for _, p := range []struct {
+		param string
+		check func(limit int64) bool
+		rel   string
+	}{
+		{"minSize", func(limit int64) bool { return fi.Size() >= limit }, "at least"},
+		{"maxSize", func(limit int64) bool { return fi.Size() <= limit }, "at most"},
+	} {
+		v := query.Get(p.param)
+		if v == "" {
+			continue
+		}
+		limit, err := parseSize(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", p.param, v, err)
+		}
+		if !p.check(limit) {
+			setStatus(body, "failed", fmt.Sprintf("size %d, expected %s %d", fi.Size(), p.rel, limit))
+		}
+	}
+	return nil
+}
+
+// checkContent streams the file and checks if any line matches contains and matchRegex query params.
+// The first matched line is set as matched_line in the body. Lines longer than maxMatchLine can't be matched.
+func (f *FileProvider) checkContent(body map[string]interface{}, fh io.ReadSeeker, query url.Values) error {
+	contains, matchRegex := query.Get("contains"), query.Get("matchRegex")
+	if contains == "" && matchRegex == "" {
+		return nil
+	}
+	var re *regexp.Regexp
+	if matchRegex != "" {
+		var err error
+		if re, err = regexp.Compile(matchRegex); err != nil {
+			return fmt.Errorf("invalid matchRegex %q: %w", matchRegex, err)
+		}
+	}
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("can't rewind: %w", err)
+	}
+
+	const maxMatchLine = 1024 * 1024
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMatchLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if contains != "" && !strings.Contains(line, contains) {
+			continue
+		}
+		if re != nil && !re.MatchString(line) {
+			continue
+		}
+		body["matched_line"] = line
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("can't read: %w", err)
+	}
+
+	var patterns []string
+	if contains != "" {
+		patterns = append(patterns, fmt.Sprintf("containing %q", contains))
+	}
+	if matchRegex != "" {
+		patterns = append(patterns, fmt.Sprintf("matching %q", matchRegex))
+	}
+	setStatus(body, "failed", "no line "+strings.Join(patterns, " and "))
+	return nil
+}
+
+// parseSize parses size in bytes, with optional B, KB, MB, GB or TB suffix, case-insensitive.
+// Suffixes are multiples of 1024, as most of the tools report file sizes.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("can't parse size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// ownerInfo contains ids and names of file's user and group, names are empty if can't be resolved
+type ownerInfo struct {
+	uid, gid    string
+	user, group string
+}
+
+// String returns owner as user:group, ids used for unresolved names
+func (o ownerInfo) String() string {
+	u, g := o.user, o.group
+	if u == "" {
+		u = o.uid
+	}
+	if g == "" {
+		g = o.gid
+	}
+	return u + ":" + g
+}
+
+// matches checks owner against expected user[:group], each part can be name or id
+func (o ownerInfo) matches(expected string) bool {
+	expUser, expGroup, hasGroup := strings.Cut(expected, ":")
+	if expUser != o.uid && expUser != o.user {
+		return false
+	}
+	return !hasGroup || expGroup == o.gid || expGroup == o.group
+}
@@ -1,8 +1,12 @@
 package external
 
 import (
+	"net/url"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,6 +37,7 @@ func TestFileProvider_Status(t *testing.T) {
 		assert.True(t, resp.Body["since_modif"].(int64) > 100)
 		assert.Equal(t, int64(4), resp.Body["size_change"])
 		assert.Equal(t, int64(0), resp.Body["modif_change"])
+		assert.Equal(t, "file "+fname+", 4 bytes, modified 0s ago (ok)", resp.Summary)
 	}
 
 	{ // check size change, not changed
@@ -69,5 +74,212 @@ func TestFileProvider_Status(t *testing.T) {
 		require.NoError(t, err)
 		t.Logf("%+v", resp)
 		assert.Equal(t, "not found", resp.Body["status"])
+		assert.Equal(t, "file testdata/bad.txt not found (failed)", resp.Summary)
 	}
 }
+
+func TestFileProvider_StatusPerms(t *testing.T) {
+	p := FileProvider{TimeOut: time.Second}
+	dir := t.TempDir()
+	require.NoError(t, os.Chmod(dir, 0o700))
+	fname := filepath.Join(dir, "secret.txt")
+	require.NoError(t, os.WriteFile(fname, []byte("password"), 0o600))
+	require.NoError(t, os.Chmod(fname, 0o600)) // ignore umask
+	wide := filepath.Join(dir, "wide.txt")
+	require.NoError(t, os.WriteFile(wide, []byte("password"), 0o644))
+	require.NoError(t, os.Chmod(wide, 0o644))
+
+	uid, gid := strconv.Itoa(os.Getuid()), strconv.Itoa(os.Getgid())
+	u, err := user.Current()
+	require.NoError(t, err)
+
+	tbl := []struct {
+		url    string
+		status string
+		level  string
+	}{
+		{"file://" + fname, "found", "ok"},
+		{"file://" + fname + "?expectMode=0600", "found", "ok"},
+		{"file://" + fname + "?expectMode=600&expectOwner=" + uid + ":" + gid, "found", "ok"},
+		{"file://" + fname + "?expectOwner=" + u.Username, "found", "ok"},
+		{"file://" + wide + "?expectMode=0600", "failed: mode 0644, expected 0600", "failed"},
+		{"file://" + fname + "?expectOwner=12345:12345",
+			"failed: owner " + u.Username + ":" + groupName(t, gid) + ", expected 12345:12345", "failed"},
+		{"file://" + wide + "?expectMode=0600&expectOwner=" + uid + ":12345",
+			"failed: mode 0644, expected 0600, owner " + u.Username + ":" + groupName(t, gid) + ", expected " + uid + ":12345",
+			"failed"},
+		{"file://" + dir + "?expectMode=0700", "found", "ok"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "perms", URL: tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.Equal(t, u.Username+":"+groupName(t, gid), resp.Body["owner"])
+			assert.True(t, strings.HasSuffix(resp.Summary, "("+tt.level+")"), resp.Summary)
+		})
+	}
+
+	resp, err := p.Status(Request{Name: "perms", URL: "file://" + wide})
+	require.NoError(t, err)
+	assert.Equal(t, "0644", resp.Body["mode"])
+	assert.Equal(t, "password", resp.Body["content"])
+
+	_, err = p.Status(Request{Name: "perms", URL: "file://" + fname + "?expectMode=rw"})
+	require.Error(t, err)
+}
+
+func TestFileProvider_StatusMaxAge(t *testing.T) {
+	p := FileProvider{TimeOut: time.Second}
+	fname := filepath.Join(t.TempDir(), "sentinel")
+	require.NoError(t, os.WriteFile(fname, []byte("done"), 0o600))
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(fname, old, old))
+
+	{ // no maxAge, existence only
+		resp, err := p.Status(Request{Name: "cron", URL: "file://" + fname})
+		require.NoError(t, err)
+		assert.Equal(t, "found", resp.Body["status"])
+		assert.InDelta(t, 7200, resp.Body["age_seconds"], 5)
+	}
+
+	{ // fresh enough
+		resp, err := p.Status(Request{Name: "cron", URL: "file://" + fname + "?maxAge=3h"})
+		require.NoError(t, err)
+		assert.Equal(t, "found", resp.Body["status"])
+		assert.True(t, strings.HasSuffix(resp.Summary, "(ok)"), resp.Summary)
+	}
+
+	{ // stale
+		resp, err := p.Status(Request{Name: "cron", URL: "file://" + fname + "?maxAge=1h"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(resp.Body["status"].(string), "failed: age 2h0m"), resp.Body["status"])
+		assert.True(t, strings.HasSuffix(resp.Body["status"].(string), ", expected at most 1h0m0s"), resp.Body["status"])
+		assert.True(t, strings.HasSuffix(resp.Summary, "(failed)"), resp.Summary)
+	}
+
+	{ // stale with perms mismatch, the first failure reported
+		resp, err := p.Status(Request{Name: "cron", URL: "file://" + fname + "?maxAge=1h&expectMode=0644"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: mode 0600, expected 0644", resp.Body["status"])
+	}
+
+	_, err := p.Status(Request{Name: "cron", URL: "file://" + fname + "?maxAge=day"})
+	require.Error(t, err)
+}
+
+func TestFileProvider_StatusSize(t *testing.T) {
+	p := FileProvider{TimeOut: time.Second}
+	fname := filepath.Join(t.TempDir(), "backup")
+	require.NoError(t, os.WriteFile(fname, make([]byte, 2048), 0o600))
+
+	{ // no limits
+		resp, err := p.Status(Request{Name: "backup", URL: "file://" + fname})
+		require.NoError(t, err)
+		assert.Equal(t, "found", resp.Body["status"])
+		assert.Equal(t, int64(2048), resp.Body["size_bytes"])
+	}
+
+	{ // in range
+		resp, err := p.Status(Request{Name: "backup", URL: "file://" + fname + "?minSize=1KB&maxSize=1mb"})
+		require.NoError(t, err)
+		assert.Equal(t, "found", resp.Body["status"])
+	}
+
+	{ // too small
+		resp, err := p.Status(Request{Name: "backup", URL: "file://" + fname + "?minSize=4096"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: size 2048, expected at least 4096", resp.Body["status"])
+		assert.True(t, strings.HasSuffix(resp.Summary, "(failed)"), resp.Summary)
+	}
+
+	{ // too large
+		resp, err := p.Status(Request{Name: "backup", URL: "file://" + fname + "?maxSize=1.5KB"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: size 2048, expected at most 1536", resp.Body["status"])
+	}
+
+	_, err := p.Status(Request{Name: "backup", URL: "file://" + fname + "?minSize=big"})
+	require.EqualError(t, err, "file size check failed: backup "+fname+`: invalid minSize "big": can't parse size "BIG"`)
+}
+
+func TestFileProvider_StatusContent(t *testing.T) {
+	p := FileProvider{TimeOut: time.Second}
+	fname := filepath.Join(t.TempDir(), "state")
+	content := strings.Repeat("starting up, some noise\n", 10000) + "state: ready\nstate: ok\n"
+	require.NoError(t, os.WriteFile(fname, []byte(content), 0o600))
+
+	{ // substring found
+		resp, err := p.Status(Request{Name: "app", URL: "file://" + fname + "?contains=state:"})
+		require.NoError(t, err)
+		assert.Equal(t, "found", resp.Body["status"])
+		assert.Equal(t, "state: ready", resp.Body["matched_line"])
+		assert.True(t, strings.HasSuffix(resp.Summary, "(ok)"), resp.Summary)
+	}
+
+	{ // both patterns should match the same line
+		resp, err := p.Status(Request{Name: "app", URL: "file://" + fname + "?contains=state:&matchRegex=" +
+			url.QueryEscape("ok$")})
+		require.NoError(t, err)
+		assert.Equal(t, "found", resp.Body["status"])
+		assert.Equal(t, "state: ok", resp.Body["matched_line"])
+	}
+
+	{ // not found
+		resp, err := p.Status(Request{Name: "app", URL: "file://" + fname + "?contains=state:&matchRegex=failed"})
+		require.NoError(t, err)
+		assert.Equal(t, `failed: no line containing "state:" and matching "failed"`, resp.Body["status"])
+		assert.NotContains(t, resp.Body, "matched_line")
+		assert.True(t, strings.HasSuffix(resp.Summary, "(failed)"), resp.Summary)
+	}
+
+	_, err := p.Status(Request{Name: "app", URL: "file://" + fname + "?matchRegex=" + url.QueryEscape("[a-")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid matchRegex "[a-"`)
+
+	_, err = p.Status(Request{Name: "app", URL: "file://" + filepath.Dir(fname) + "?contains=ok"})
+	require.EqualError(t, err, "file content check failed: app "+filepath.Dir(fname)+": can't match content of directory")
+}
+
+func Test_parseSize(t *testing.T) {
+	tbl := []struct {
+		in  string
+		out int64
+		err bool
+	}{
+		{"0", 0, false}, {"1048576", 1048576, false}, {"10B", 10, false}, {"10kb", 10240, false},
+		{"10MB", 10 << 20, false}, {"1.5GB", 3 << 29, false}, {"2 TB", 2 << 40, false},
+		{"", 0, true}, {"MB", 0, true}, {"-1KB", 0, true}, {"10XB", 0, true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.in, func(t *testing.T) {
+			res, err := parseSize(tt.in)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.out, res)
+		})
+	}
+}
+
+func groupName(t *testing.T, gid string) string {
+	g, err := user.LookupGroupId(gid)
+	if err != nil {
+		return gid
+	}
+	return g.Name
+}
+
+func TestOwnerInfo_matches(t *testing.T) {
+	o := ownerInfo{uid: "0", gid: "0", user: "root", group: "wheel"}
+	tbl := map[string]bool{"root": true, "0": true, "root:wheel": true, "0:0": true, "root:0": true, "root:root": false,
+		"nobody": false, "1:0": false}
+	for exp, res := range tbl {
+		assert.Equal(t, res, o.matches(exp), exp)
+	}
+	assert.Equal(t, "root:wheel", o.String())
+	assert.Equal(t, "1000:100", ownerInfo{uid: "1000", gid: "100"}.String())
+}
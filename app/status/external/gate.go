@@ -0,0 +1,84 @@
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// gate is a condition to run a service check, checked before each round.
+// It refers to the result of another service, i.e. "pg_role", or to a host predicate, i.e. "file:/var/run/replica"
+// or "hostname:db-replica-*". Service names can't have ":", so predicates don't clash with them.
+// Leading "!" negates the condition.
+type gate struct {
+	cond    string // original condition, for reporting
+	negate  bool
+	service string // name of the referenced service, empty for host predicates
+	kind    string // host predicate kind, "file" or "hostname"
+	arg     string // host predicate argument, path or hostname glob
+}
+
+// hostname returns the host name for "hostname" predicate, can be changed for tests
+var hostname = os.Hostname
+
+// parseGate parses gate condition, "[!]service", "[!]file:path" or "[!]hostname:glob"
+func parseGate(cond string) (gate, error) {
+	g := gate{cond: cond}
+	c := strings.TrimSpace(cond)
+	if strings.HasPrefix(c, "!") {
+		g.negate, c = true, strings.TrimSpace(c[1:])
+	}
+	kind, arg, found := strings.Cut(c, ":")
+	switch {
+	case c == "":
+		return gate{}, fmt.Errorf("empty condition")
+	case !found:
+		g.service = c
+	case kind == "file" || kind == "hostname":
+		if arg == "" {
+			return gate{}, fmt.Errorf("empty %s in %q", kind, cond)
+		}
+		if _, err := path.Match(arg, ""); kind == "hostname" && err != nil {
+			return gate{}, fmt.Errorf("invalid hostname pattern %q: %w", arg, err)
+		}
+		g.kind, g.arg = kind, arg
+	default:
+		return gate{}, fmt.Errorf("unknown predicate %q, should be file or hostname", kind)
+	}
+	return g, nil
+}
+
+// eval checks the condition, results are responses of services checked in this round so far.
+// Service condition is true if the service check is not failed and not skipped, by gate or offline. Returns ready=false
+// if the referenced service is not checked yet.
+func (g gate) eval(results map[string]Response) (passed, ready bool, err error) {
+	switch {
+	case g.service != "":
+		r, ok := results[g.service]
+		if !ok {
+			return false, false, nil
+		}
+		passed = isUp(r) && r.Skipped == ""
+	case g.kind == "file":
+		_, e := os.Stat(g.arg)
+		passed = e == nil
+	case g.kind == "hostname":
+		h, e := hostname()
+		if e != nil {
+			return false, true, fmt.Errorf("failed to get hostname: %w", e)
+		}
+		passed, _ = path.Match(g.arg, h)
+	}
+	return passed != g.negate, true, nil
+}
+
+// SkippedStatus marks the response of a service check skipped by the gate, set in Skipped and body status
+const SkippedStatus = "skipped"
+
+// skippedResponse makes response of the service skipped because the gate is false
+func skippedResponse(name string, g gate) Response {
+	return Response{Name: name, StatusCode: http.StatusOK, Summary: fmt.Sprintf("skipped, %s is false (ok)", g.cond),
+		Skipped: SkippedStatus, Body: map[string]interface{}{"status": SkippedStatus, "when": g.cond}}
+}
@@ -0,0 +1,94 @@
+package external
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseGate(t *testing.T) {
+	tbl := []struct {
+		cond string
+		res  gate
+		err  string
+	}{
+		{"pg_role", gate{cond: "pg_role", service: "pg_role"}, ""},
+		{"! pg_role", gate{cond: "! pg_role", negate: true, service: "pg_role"}, ""},
+		{"file:/var/run/replica", gate{cond: "file:/var/run/replica", kind: "file", arg: "/var/run/replica"}, ""},
+		{"!hostname:db-*", gate{cond: "!hostname:db-*", negate: true, kind: "hostname", arg: "db-*"}, ""},
+		{"", gate{}, "empty condition"},
+		{"!", gate{}, "empty condition"},
+		{"file:", gate{}, `empty file in "file:"`},
+		{"hostname:[db", gate{}, `invalid hostname pattern "[db": syntax error in pattern`},
+		{"label:role=db", gate{}, `unknown predicate "label", should be file or hostname`},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.cond, func(t *testing.T) {
+			res, err := parseGate(tt.cond)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+}
+
+func Test_gateEval(t *testing.T) {
+	origHostname := hostname
+	defer func() { hostname = origHostname }()
+	hostname = func() (string, error) { return "db-replica-2", nil }
+
+	marker := filepath.Join(t.TempDir(), "replica")
+	require.NoError(t, os.WriteFile(marker, []byte("1"), 0o600))
+
+	results := map[string]Response{
+		"ok":      {Name: "ok", StatusCode: 200, Summary: "http 200 (ok)"},
+		"warn":    {Name: "warn", StatusCode: 200, Summary: "cert expires in 10 days (warn)"},
+		"failed":  {Name: "failed", StatusCode: 200, Summary: "file blah not found (failed)"},
+		"error":   {Name: "error", StatusCode: 500, Summary: "check error"},
+		"skipped": skippedResponse("skipped", gate{cond: "file:/blah"}),
+		"spoofed": {Name: "spoofed", StatusCode: 200, Summary: "http 200 (ok)", Body: map[string]interface{}{"status": SkippedStatus}},
+	}
+
+	tbl := []struct {
+		cond          string
+		passed, ready bool
+	}{
+		{"ok", true, true},
+		{"warn", true, true},
+		{"failed", false, true},
+		{"error", false, true},
+		{"skipped", false, true},
+		{"spoofed", true, true}, // remote body status is not a skip marker
+		{"!failed", true, true},
+		{"not_yet", false, false},
+		{"file:" + marker, true, true},
+		{"!file:" + marker, false, true},
+		{"file:" + marker + ".missing", false, true},
+		{"hostname:db-replica-*", true, true},
+		{"hostname:db-primary", false, true},
+		{"!hostname:db-primary", true, true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.cond, func(t *testing.T) {
+			g, err := parseGate(tt.cond)
+			require.NoError(t, err)
+			passed, ready, err := g.eval(results)
+			require.NoError(t, err)
+			assert.Equal(t, tt.passed, passed)
+			assert.Equal(t, tt.ready, ready)
+		})
+	}
+
+	hostname = func() (string, error) { return "", errors.New("no hostname") }
+	g, err := parseGate("hostname:db-*")
+	require.NoError(t, err)
+	_, _, err = g.eval(results)
+	require.EqualError(t, err, "failed to get hostname: no hostname")
+}
@@ -0,0 +1,127 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// GraphiteProvider is a status provider that checks graphite carbon reachability and metric freshness
+type GraphiteProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: graphite://example.com:2003?render=servers.web1.cpu&maxAge=5m
+// Carbon plaintext port (default 2003) checked for reachability. With "render" param the metric is requested from
+// render api and the status is failed if the newest datapoint is older than "maxAge" (default 5m).
+// Render api is on http://host by default, can be set with "web" param, i.e. web=http://example.com:8080
+func (g *GraphiteProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("graphite url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if uu.Hostname() == "" {
+		return nil, fmt.Errorf("graphite host is empty: %s %s", req.Name, req.URL)
+	}
+	addr := uu.Host
+	if uu.Port() == "" {
+		addr = net.JoinHostPort(uu.Hostname(), "2003")
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, g.TimeOut)
+	if err != nil {
+		return nil, fmt.Errorf("graphite carbon connect failed: %s %s: %w", req.Name, addr, err)
+	}
+	_ = conn.Close()
+
+	body := map[string]interface{}{"carbon": addr, "status": "ok"}
+
+	if metric := uu.Query().Get("render"); metric != "" {
+		maxAge := 5 * time.Minute
+		if v := uu.Query().Get("maxAge"); v != "" {
+			if maxAge, err = time.ParseDuration(v); err != nil {
+				return nil, fmt.Errorf("graphite maxAge parse failed: %s %s: %w", req.Name, req.URL, err)
+			}
+		}
+		web := uu.Query().Get("web")
+		if web == "" {
+			web = "http://" + uu.Hostname()
+		}
+		last, err := g.lastDatapoint(web, metric, maxAge)
+		if err != nil {
+			return nil, fmt.Errorf("graphite render failed: %s %s: %w", req.Name, metric, err)
+		}
+
+		body["metric"] = metric
+		switch {
+		case last.IsZero():
+			body["status"] = fmt.Sprintf("failed: no datapoints for %s in %s", metric, 2*maxAge)
+		case time.Since(last) > maxAge:
+			body["last_datapoint"] = last.Format(time.RFC3339)
+			body["status"] = fmt.Sprintf("failed: last datapoint %s old, expected within %s",
+				time.Since(last).Truncate(time.Second), maxAge)
+		default:
+			body["last_datapoint"] = last.Format(time.RFC3339)
+		}
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      fmt.Sprintf("carbon %s reachable (%s)", addr, level(body["status"].(string))),
+	}
+	if last, ok := body["last_datapoint"].(string); ok {
+		result.Summary = fmt.Sprintf("%s last datapoint at %s (%s)", body["metric"], last, level(body["status"].(string)))
+	} else if metric, ok := body["metric"]; ok {
+		result.Summary = fmt.Sprintf("%s no datapoints (failed)", metric)
+	}
+	return &result, nil
+}
+
+// lastDatapoint gets the metric from render api for twice the maxAge and returns timestamp of the newest
+// non-null datapoint, zero time if there are none
+func (g *GraphiteProvider) lastDatapoint(web, metric string, maxAge time.Duration) (time.Time, error) {
+	q := url.Values{}
+	q.Set("target", metric)
+	q.Set("format", "json")
+	q.Set("from", fmt.Sprintf("-%ds", int((2*maxAge).Seconds())))
+
+	client := http.Client{Timeout: g.TimeOut}
+	resp, err := client.Get(web + "/render?" + q.Encode())
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	// each series is {"target": "name", "datapoints": [[value, timestamp], ...]}, value is null if no data
+	series := []struct {
+		Target     string        `json:"target"`
+		Datapoints [][2]*float64 `json:"datapoints"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&series); err != nil {
+		return time.Time{}, fmt.Errorf("can't decode render response: %w", err)
+	}
+
+	var last int64
+	for _, s := range series {
+		for _, dp := range s.Datapoints {
+			if dp[0] != nil && dp[1] != nil && int64(*dp[1]) > last {
+				last = int64(*dp[1])
+			}
+		}
+	}
+	if last == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(last, 0), nil
+}
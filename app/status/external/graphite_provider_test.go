@@ -0,0 +1,98 @@
+package external
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphiteProvider_Status(t *testing.T) {
+	carbon, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer carbon.Close()
+	go func() {
+		for {
+			conn, e := carbon.Accept()
+			if e != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	now := time.Now().Unix()
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/render", r.URL.Path)
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		assert.Equal(t, "-600s", r.URL.Query().Get("from"))
+		switch r.URL.Query().Get("target") {
+		case "fresh.metric":
+			_, _ = fmt.Fprintf(w, `[{"target":"fresh.metric","datapoints":[[1.5,%d],[2.5,%d],[null,%d]]}]`, now-120, now-60, now)
+		case "stale.metric":
+			_, _ = fmt.Fprintf(w, `[{"target":"stale.metric","datapoints":[[1.5,%d],[null,%d]]}]`, now-500, now-60)
+		case "empty.metric":
+			_, _ = fmt.Fprintf(w, `[{"target":"empty.metric","datapoints":[[null,%d]]}]`, now-60)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer web.Close()
+
+	p := GraphiteProvider{TimeOut: time.Second}
+	base := "graphite://" + carbon.Addr().String()
+
+	{ // carbon only
+		resp, err := p.Status(Request{Name: "graphite", URL: base})
+		require.NoError(t, err)
+		assert.Equal(t, "graphite", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"carbon": carbon.Addr().String(), "status": "ok"}, resp.Body)
+		assert.Equal(t, "carbon "+carbon.Addr().String()+" reachable (ok)", resp.Summary)
+	}
+
+	tbl := []struct {
+		metric  string
+		last    interface{}
+		status  string
+		summary string
+	}{
+		{"fresh.metric", time.Unix(now-60, 0).Format(time.RFC3339), "ok",
+			"fresh.metric last datapoint at " + time.Unix(now-60, 0).Format(time.RFC3339) + " (ok)"},
+		{"stale.metric", time.Unix(now-500, 0).Format(time.RFC3339), "failed: last datapoint 8m",
+			"stale.metric last datapoint at " + time.Unix(now-500, 0).Format(time.RFC3339) + " (failed)"},
+		{"empty.metric", nil, "failed: no datapoints for empty.metric in 10m0s", "empty.metric no datapoints (failed)"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.metric, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "graphite", URL: base + "?render=" + tt.metric + "&maxAge=5m&web=" + web.URL})
+			require.NoError(t, err)
+			assert.Equal(t, tt.metric, resp.Body["metric"])
+			assert.Equal(t, tt.last, resp.Body["last_datapoint"])
+			assert.Contains(t, resp.Body["status"], tt.status)
+			assert.Equal(t, tt.summary, resp.Summary)
+		})
+	}
+
+	_, err = p.Status(Request{Name: "graphite", URL: base + "?render=bad.metric&maxAge=5m&web=" + web.URL})
+	require.EqualError(t, err, "graphite render failed: graphite bad.metric: unexpected status 400 Bad Request")
+}
+
+func TestGraphiteProvider_StatusFailed(t *testing.T) {
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lst.Addr().String()
+	require.NoError(t, lst.Close())
+
+	p := GraphiteProvider{TimeOut: 100 * time.Millisecond}
+	_, err = p.Status(Request{Name: "graphite", URL: "graphite://" + addr})
+	require.Error(t, err, "carbon is not listening")
+
+	_, err = p.Status(Request{Name: "graphite", URL: "graphite://"})
+	require.EqualError(t, err, "graphite host is empty: graphite graphite://")
+}
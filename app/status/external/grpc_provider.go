@@ -0,0 +1,243 @@
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCProvider is a status provider that checks grpc services implementing the standard grpc.health.v1 Health service
+type GRPCProvider struct {
+	TimeOut time.Duration
+}
+
+// maxGRPCSamples limits number of health checks in latency sampling mode, to keep the load on the service small
+const maxGRPCSamples = 50
+
+// Status url looks like: grpc://host:port/service.Name?tls=true, it calls Health/Check for the service.
+// The service name is optional, without it the overall health of the server is requested.
+// Plaintext is used by default, "tls=true" enables tls with the system root certificates.
+// Status is failed if the serving status is not SERVING.
+// With "stream=pkg.Service/Method" the server-streaming method is called instead of the health check, see checkStream.
+// With "samples=N" the health check is repeated N times to report latency percentiles, see checkLatency.
+func (g *GRPCProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("grpc url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if uu.Host == "" {
+		return nil, fmt.Errorf("grpc host is empty: %s %s", req.Name, req.URL)
+	}
+
+	creds := insecure.NewCredentials()
+	if uu.Query().Get("tls") == "true" {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.TimeOut)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, uu.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("grpc dial failed: %s %s: %w", req.Name, uu.Host, err)
+	}
+	defer conn.Close() // nolint
+
+	if method := uu.Query().Get("stream"); method != "" {
+		return g.checkStream(ctx, conn, req, method, uu.Query().Get("messages"), st)
+	}
+
+	samples, maxP95, err := grpcLatencyParams(uu.Query())
+	if err != nil {
+		return nil, fmt.Errorf("grpc latency params failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
+	service := strings.TrimPrefix(uu.Path, "/")
+	health := grpc_health_v1.NewHealthClient(conn)
+	resp, err := health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+	if err != nil {
+		return nil, fmt.Errorf("grpc health check failed: %s %s: %w", req.Name, uu.Host, err)
+	}
+
+	servingStatus := resp.GetStatus().String()
+	body := map[string]interface{}{"service": service, "serving_status": servingStatus, "status": "ok"}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		body["status"] = "failed: " + servingStatus
+	}
+	if samples > 0 {
+		if err = checkLatency(ctx, health, service, body, samples, maxP95); err != nil {
+			return nil, fmt.Errorf("grpc latency check failed: %s %s: %w", req.Name, uu.Host, err)
+		}
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      fmt.Sprintf("grpc %s (%s)", servingStatus, level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// grpcLatencyParams returns number of samples and p95 threshold for latency sampling mode, samples is 0 if disabled.
+// "maxP95" is a duration, i.e. 50ms, not checked if not set.
+func grpcLatencyParams(q url.Values) (samples int, maxP95 time.Duration, err error) {
+	if v := q.Get("samples"); v != "" {
+		if samples, err = strconv.Atoi(v); err != nil || samples < 1 || samples > maxGRPCSamples {
+			return 0, 0, fmt.Errorf("samples %q should be a number from 1 to %d", v, maxGRPCSamples)
+		}
+	}
+	if v := q.Get("maxP95"); v != "" {
+		if maxP95, err = time.ParseDuration(v); err != nil {
+			return 0, 0, fmt.Errorf("maxP95 %q: %w", v, err)
+		}
+		if samples == 0 {
+			return 0, 0, fmt.Errorf("maxP95 requires samples")
+		}
+	}
+	return samples, maxP95, nil
+}
+
+// checkLatency calls Health/Check n times sequentially after the first check, which includes the connection setup
+// and is not counted, and sets number of samples and p50, p95 and max latency in milliseconds. Sampling is bounded
+// by the timeout of the whole check, samples not made within it are skipped and the status is warn. Status is warn
+// if p95 is above maxP95, and failed if any sample is not SERVING.
+func checkLatency(ctx context.Context, health grpc_health_v1.HealthClient, service string, body map[string]interface{},
+	n int, maxP95 time.Duration) error {
+	latencies := make([]time.Duration, 0, n)
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		st := time.Now()
+		resp, err := health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if status.Code(err) == codes.DeadlineExceeded {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i+1, err)
+		}
+		latencies = append(latencies, time.Since(st))
+		if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			setStatus(body, "failed", fmt.Sprintf("sample %d %s", i+1, resp.GetStatus()))
+		}
+	}
+
+	body["samples"] = len(latencies)
+	if len(latencies) == 0 {
+		setStatus(body, "warn", fmt.Sprintf("0 of %d samples within timeout", n))
+		return nil
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	ms := func(d time.Duration) float64 { return math.Round(float64(d)/float64(time.Millisecond)*100) / 100 }
+	p95 := percentile(latencies, 95)
+	body["latency_p50_ms"], body["latency_p95_ms"] = ms(percentile(latencies, 50)), ms(p95)
+	body["latency_max_ms"] = ms(latencies[len(latencies)-1])
+	if len(latencies) < n {
+		setStatus(body, "warn", fmt.Sprintf("%d of %d samples within timeout", len(latencies), n))
+	}
+	if maxP95 > 0 && p95 > maxP95 {
+		setStatus(body, "warn", fmt.Sprintf("p95 latency %s, expected at most %s", p95.Round(10*time.Microsecond), maxP95))
+	}
+	return nil
+}
+
+// percentile returns the nearest-rank percentile of sorted values, i.e. p95 of 10 values is the 10th one
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+// checkStream opens the server-streaming method with the empty request and waits for the expected number of messages,
+// 1 by default, within the timeout. Messages are not decoded, so no generated stubs are needed. Status is failed if
+// the stream errors, is closed by the server or goes silent before all messages received.
+func (g *GRPCProvider) checkStream(ctx context.Context, conn *grpc.ClientConn, req Request, method, messages string,
+	st time.Time) (*Response, error) {
+	expected := 1
+	if messages != "" {
+		n, err := strconv.Atoi(messages)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("grpc messages %q should be a positive number: %s %s", messages, req.Name, req.URL)
+		}
+		expected = n
+	}
+
+	desc := &grpc.StreamDesc{StreamName: method, ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, "/"+strings.TrimPrefix(method, "/"), grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return nil, fmt.Errorf("grpc stream open failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if err = stream.SendMsg(&[]byte{}); err != nil && !errors.Is(err, io.EOF) { // io.EOF means the error is in RecvMsg
+		return nil, fmt.Errorf("grpc stream send failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, fmt.Errorf("grpc stream close send failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
+	body := map[string]interface{}{"stream": method, "expected_messages": expected, "status": "ok"}
+	received := 0
+	for received < expected {
+		var msg []byte
+		if err = stream.RecvMsg(&msg); err != nil {
+			break
+		}
+		received++
+	}
+	body["messages"] = received
+	switch {
+	case received >= expected:
+	case errors.Is(err, io.EOF):
+		body["status"] = fmt.Sprintf("failed: stream closed after %d of %d messages", received, expected)
+	case status.Code(err) == codes.DeadlineExceeded:
+		body["status"] = fmt.Sprintf("failed: stream silent after %d of %d messages", received, expected)
+	default:
+		body["status"] = fmt.Sprintf("failed: stream error after %d of %d messages: %s", received, expected, status.Convert(err).Message())
+		body["error_code"] = status.Code(err).String()
+	}
+
+	return &Response{Name: req.Name, StatusCode: 200, Body: body, ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("grpc stream %d of %d messages (%s)", received, expected, level(body["status"].(string)))}, nil
+}
+
+// rawCodec passes messages as is, used to call methods without generated stubs.
+// The empty message is a valid protobuf encoding of any request with default fields.
+type rawCodec struct{}
+
+// Marshal returns the message bytes, v should be *[]byte
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+// Unmarshal copies the message bytes to v, should be *[]byte
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name is the content subtype, "proto" to be accepted by any grpc server
+func (rawCodec) Name() string { return "proto" }
@@ -0,0 +1,278 @@
+package external
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCProvider_Status(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("app.Orders", grpc_health_v1.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("app.Billing", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	p := GRPCProvider{TimeOut: time.Second}
+	addr := lis.Addr().String()
+
+	{ // serving service
+		resp, err := p.Status(Request{Name: "orders", URL: "grpc://" + addr + "/app.Orders"})
+		require.NoError(t, err)
+		assert.Equal(t, "orders", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"service": "app.Orders", "serving_status": "SERVING", "status": "ok"}, resp.Body)
+		assert.Equal(t, "grpc SERVING (ok)", resp.Summary)
+	}
+
+	{ // overall server health, set by health server for empty service
+		resp, err := p.Status(Request{Name: "server", URL: "grpc://" + addr})
+		require.NoError(t, err)
+		assert.Equal(t, "", resp.Body["service"])
+		assert.Equal(t, "ok", resp.Body["status"])
+	}
+
+	{ // not serving service
+		resp, err := p.Status(Request{Name: "billing", URL: "grpc://" + addr + "/app.Billing"})
+		require.NoError(t, err)
+		assert.Equal(t, "NOT_SERVING", resp.Body["serving_status"])
+		assert.Equal(t, "failed: NOT_SERVING", resp.Body["status"])
+		assert.Equal(t, "grpc NOT_SERVING (failed)", resp.Summary)
+	}
+
+	{ // unknown service
+		_, err := p.Status(Request{Name: "blah", URL: "grpc://" + addr + "/app.Blah"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "grpc health check failed: blah "+addr)
+		assert.Contains(t, err.Error(), "unknown service")
+	}
+
+	{ // tls to plaintext server
+		_, err := p.Status(Request{Name: "orders", URL: "grpc://" + addr + "/app.Orders?tls=true"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "grpc health check failed")
+	}
+
+	_, err = p.Status(Request{Name: "orders", URL: "grpc:///app.Orders"})
+	require.EqualError(t, err, "grpc host is empty: orders grpc:///app.Orders")
+}
+
+// slowHealthServer answers SERVING to all checks, delaying each call by the result of delay for the call number.
+// Call 0 is the first check, made before sampling.
+type slowHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	calls int32
+	delay func(call int) time.Duration
+}
+
+func (s *slowHealthServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	time.Sleep(s.delay(int(atomic.AddInt32(&s.calls, 1) - 1)))
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func TestGRPCProvider_StatusLatency(t *testing.T) {
+	// serve starts a health server with injected latency and returns its address
+	serve := func(hs *slowHealthServer) string {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		srv := grpc.NewServer()
+		grpc_health_v1.RegisterHealthServer(srv, hs)
+		go func() { _ = srv.Serve(lis) }()
+		t.Cleanup(srv.Stop)
+		return lis.Addr().String()
+	}
+	p := GRPCProvider{TimeOut: time.Second}
+
+	{ // one slow sample out of 10 is p95 and max, above threshold
+		hs := &slowHealthServer{delay: func(call int) time.Duration {
+			if call == 3 {
+				return 60 * time.Millisecond
+			}
+			return 2 * time.Millisecond
+		}}
+		addr := serve(hs)
+		resp, err := p.Status(Request{Name: "orders", URL: "grpc://" + addr + "/app.Orders?samples=10&maxP95=30ms"})
+		require.NoError(t, err)
+		assert.Equal(t, int32(11), atomic.LoadInt32(&hs.calls), "first check and 10 samples")
+		assert.Equal(t, 10, resp.Body["samples"])
+		p50, p95, maxLat := resp.Body["latency_p50_ms"].(float64), resp.Body["latency_p95_ms"].(float64),
+			resp.Body["latency_max_ms"].(float64)
+		assert.GreaterOrEqual(t, p50, 2.0)
+		assert.Less(t, p50, 60.0)
+		assert.GreaterOrEqual(t, p95, 60.0)
+		assert.Equal(t, maxLat, p95)
+		assert.True(t, strings.HasPrefix(resp.Body["status"].(string), "warn: p95 latency "), resp.Body["status"])
+		assert.Contains(t, resp.Body["status"], "expected at most 30ms")
+		assert.Equal(t, "grpc SERVING (warn)", resp.Summary)
+	}
+
+	{ // one slow sample out of 20 is max only, p95 is fast
+		addr := serve(&slowHealthServer{delay: func(call int) time.Duration {
+			if call == 5 {
+				return 60 * time.Millisecond
+			}
+			return 2 * time.Millisecond
+		}})
+		resp, err := p.Status(Request{Name: "orders", URL: "grpc://" + addr + "/app.Orders?samples=20&maxP95=30ms"})
+		require.NoError(t, err)
+		assert.Equal(t, 20, resp.Body["samples"])
+		assert.Less(t, resp.Body["latency_p95_ms"].(float64), 60.0)
+		assert.GreaterOrEqual(t, resp.Body["latency_max_ms"].(float64), 60.0)
+		assert.Equal(t, "ok", resp.Body["status"])
+	}
+
+	{ // sampling cut by timeout
+		addr := serve(&slowHealthServer{delay: func(int) time.Duration { return 40 * time.Millisecond }})
+		pt := GRPCProvider{TimeOut: 150 * time.Millisecond}
+		resp, err := pt.Status(Request{Name: "orders", URL: "grpc://" + addr + "/app.Orders?samples=10"})
+		require.NoError(t, err)
+		n := resp.Body["samples"].(int)
+		assert.Less(t, n, 10)
+		assert.Contains(t, resp.Body["status"], "warn: "+strconv.Itoa(n)+" of 10 samples within timeout")
+	}
+
+	addr := serve(&slowHealthServer{delay: func(int) time.Duration { return 0 }})
+	for _, q := range []string{"samples=0", "samples=51", "samples=blah", "maxP95=10ms", "samples=5&maxP95=blah"} {
+		_, err := p.Status(Request{Name: "orders", URL: "grpc://" + addr + "/app.Orders?" + q})
+		require.Error(t, err, q)
+		assert.Contains(t, err.Error(), "grpc latency params failed", q)
+	}
+}
+
+func Test_percentile(t *testing.T) {
+	ms := func(vals ...int) []time.Duration {
+		res := make([]time.Duration, len(vals))
+		for i, v := range vals {
+			res[i] = time.Duration(v) * time.Millisecond
+		}
+		return res
+	}
+	tbl := []struct {
+		vals     []time.Duration
+		p        float64
+		expected time.Duration
+	}{
+		{ms(5), 50, 5 * time.Millisecond},
+		{ms(5), 95, 5 * time.Millisecond},
+		{ms(1, 2, 3, 4), 50, 2 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 50, 5 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 95, 10 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20), 95, 19 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20), 100, 20 * time.Millisecond},
+		{ms(1, 2, 3), 0, 1 * time.Millisecond},
+	}
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.expected, percentile(tt.vals, tt.p))
+		})
+	}
+}
+
+func TestGRPCProvider_StatusStream(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	// app.Events/Ticks sends messages as requested by the method suffix, i.e. Ticks3 sends 3 and closes the stream,
+	// Broken fails after the first message
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		var req []byte
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		switch {
+		case method == "/app.Events/Broken":
+			_ = stream.SendMsg(&[]byte{0x08, 0x01})
+			return status.Error(codes.Internal, "feed is broken")
+		case strings.HasPrefix(method, "/app.Events/Ticks"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(method, "/app.Events/Ticks"))
+			for i := 0; i < n; i++ {
+				if err := stream.SendMsg(&[]byte{0x08, byte(i)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return status.Error(codes.Unimplemented, "unknown method "+method)
+	}), grpc.ForceServerCodec(rawCodec{}))
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	// health Watch sends the current status and waits for changes
+	healthLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	healthSrv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(healthSrv, health.NewServer())
+	go func() { _ = healthSrv.Serve(healthLis) }()
+	defer healthSrv.Stop()
+
+	p := GRPCProvider{TimeOut: 300 * time.Millisecond}
+	addr, healthAddr := lis.Addr().String(), healthLis.Addr().String()
+
+	{ // enough messages
+		resp, err := p.Status(Request{Name: "events", URL: "grpc://" + addr + "?stream=app.Events/Ticks5&messages=3"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"stream": "app.Events/Ticks5", "expected_messages": 3, "messages": 3,
+			"status": "ok"}, resp.Body)
+		assert.Equal(t, "grpc stream 3 of 3 messages (ok)", resp.Summary)
+	}
+
+	{ // health watch sends the current status, one message by default
+		resp, err := p.Status(Request{Name: "watch", URL: "grpc://" + healthAddr + "?stream=grpc.health.v1.Health/Watch"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, resp.Body["messages"])
+		assert.Equal(t, "ok", resp.Body["status"])
+	}
+
+	{ // health watch goes silent after the first message
+		st := time.Now()
+		resp, err := p.Status(Request{Name: "watch", URL: "grpc://" + healthAddr + "?stream=grpc.health.v1.Health/Watch&messages=2"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, resp.Body["messages"])
+		assert.Equal(t, "failed: stream silent after 1 of 2 messages", resp.Body["status"])
+		assert.Equal(t, "grpc stream 1 of 2 messages (failed)", resp.Summary)
+		assert.True(t, time.Since(st) >= 300*time.Millisecond, "waits for the timeout")
+	}
+
+	{ // stream closed by the server
+		resp, err := p.Status(Request{Name: "events", URL: "grpc://" + addr + "?stream=app.Events/Ticks2&messages=3"})
+		require.NoError(t, err)
+		assert.Equal(t, 2, resp.Body["messages"])
+		assert.Equal(t, "failed: stream closed after 2 of 3 messages", resp.Body["status"])
+	}
+
+	{ // stream error
+		resp, err := p.Status(Request{Name: "events", URL: "grpc://" + addr + "?stream=app.Events/Broken&messages=2"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, resp.Body["messages"])
+		assert.Equal(t, "failed: stream error after 1 of 2 messages: feed is broken", resp.Body["status"])
+		assert.Equal(t, "Internal", resp.Body["error_code"])
+	}
+
+	{ // unknown method
+		resp, err := p.Status(Request{Name: "events", URL: "grpc://" + addr + "?stream=app.Events/Blah"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, resp.Body["messages"])
+		assert.Equal(t, "Unimplemented", resp.Body["error_code"])
+	}
+
+	_, err = p.Status(Request{Name: "events", URL: "grpc://" + addr + "?stream=app.Events/Ticks1&messages=0"})
+	require.EqualError(t, err, `grpc messages "0" should be a positive number: events grpc://`+addr+"?stream=app.Events/Ticks1&messages=0")
+}
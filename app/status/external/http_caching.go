@@ -0,0 +1,125 @@
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheExpectation is a directive expected in Cache-Control, i.e. "public", "!private" or "max-age>=3600"
+type cacheExpectation struct {
+	directive string // lowercase directive name
+	forbidden bool   // directive should not be set
+	op        string // comparison of the value, "=", "<", "<=", ">" or ">=", empty for presence only
+	value     string // expected value, number for numeric comparison
+}
+
+// parseCacheExpectations parses comma separated directives, "!" prefix for forbidden ones and optional comparison
+// of the value, i.e. "public,!private,max-age>=3600,s-maxage=600". Directive names are case-insensitive.
+func parseCacheExpectations(expected string) ([]cacheExpectation, error) {
+	var res []cacheExpectation
+	for _, e := range strings.Split(expected, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			return nil, fmt.Errorf("empty directive in %q", expected)
+		}
+		exp := cacheExpectation{directive: e}
+		if strings.HasPrefix(e, "!") {
+			exp.forbidden, exp.directive = true, strings.TrimSpace(e[1:])
+		}
+		for _, o := range append(jsonPathOperators, "=") {
+			if i := strings.Index(exp.directive, o); i >= 0 {
+				exp.directive, exp.op, exp.value = exp.directive[:i], o, strings.TrimSpace(exp.directive[i+len(o):])
+				break
+			}
+		}
+		exp.directive = strings.ToLower(strings.TrimSpace(exp.directive))
+		switch {
+		case exp.directive == "":
+			return nil, fmt.Errorf("empty directive name in %q", e)
+		case exp.forbidden && exp.op != "":
+			return nil, fmt.Errorf("forbidden directive %q can't have value", e)
+		case exp.op != "" && exp.op != "=":
+			if _, err := strconv.ParseFloat(exp.value, 64); err != nil {
+				return nil, fmt.Errorf("invalid limit in %q: %w", e, err)
+			}
+		}
+		res = append(res, exp)
+	}
+	return res, nil
+}
+
+// parseCacheControl parses Cache-Control headers to lowercase directives with values, empty for directives
+// without value, i.e. "public, max-age=3600" to {"public": "", "max-age": "3600"}. Quotes of values are dropped.
+func parseCacheControl(headers []string) map[string]string {
+	res := map[string]string{}
+	for _, h := range headers {
+		for _, d := range strings.Split(h, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				res[name] = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+		}
+	}
+	return res
+}
+
+// checkCaching sets cache_control in the body with directives of the response, and expires_in, seconds until
+// Expires relative to Date (or now), if Expires is set. Status is failed if any expected directive is missing
+// or its value doesn't match, or any forbidden directive is set. Without max-age in Cache-Control the expected
+// max-age is compared with expires_in, the same way caches do it. Status set to ok if not set already.
+func checkCaching(body map[string]interface{}, header http.Header, expected string, now time.Time) error {
+	expectations, err := parseCacheExpectations(expected)
+	if err != nil {
+		return err
+	}
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+
+	directives := parseCacheControl(header.Values("Cache-Control"))
+	body["cache_control"] = directives
+	expiresIn := ""
+	if v := header.Get("Expires"); v != "" {
+		date := now
+		if d, e := http.ParseTime(header.Get("Date")); e == nil {
+			date = d
+		}
+		secs := int64(0) // invalid date, i.e. "0", means already expired
+		if t, e := http.ParseTime(v); e == nil {
+			secs = int64(t.Sub(date).Seconds())
+		}
+		body["expires_in"] = secs
+		expiresIn = strconv.FormatInt(secs, 10)
+	}
+
+	var problems []string
+	for _, exp := range expectations {
+		value, found := directives[exp.directive]
+		if !found && exp.directive == "max-age" && exp.op != "" && expiresIn != "" {
+			value, found = expiresIn, true
+		}
+		switch {
+		case exp.forbidden && found:
+			problems = append(problems, exp.directive+" is set")
+		case exp.forbidden:
+		case !found:
+			problems = append(problems, "no "+exp.directive)
+		case exp.op == "=" && value != exp.value:
+			problems = append(problems, fmt.Sprintf("%s is %q, expected %q", exp.directive, value, exp.value))
+		case exp.op != "" && exp.op != "=":
+			num, e := strconv.ParseFloat(value, 64)
+			limit, _ := strconv.ParseFloat(exp.value, 64)
+			passed := map[string]bool{"<": num < limit, "<=": num <= limit, ">": num > limit, ">=": num >= limit}[exp.op]
+			if e != nil || !passed {
+				problems = append(problems, fmt.Sprintf("%s is %q, expected %s%s", exp.directive, value, exp.op, exp.value))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		setStatus(body, "failed", "cache-control "+strings.Join(problems, "; "))
+	}
+	return nil
+}
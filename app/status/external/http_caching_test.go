@@ -0,0 +1,76 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusCacheControl(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("cacheControl"), "provider option is not sent")
+		switch r.URL.Path {
+		case "/static":
+			w.Header().Set("Cache-Control", "public, max-age=86400, immutable")
+		case "/short":
+			w.Header().Set("Cache-Control", "public, max-age=600")
+		case "/private":
+			w.Header().Add("Cache-Control", "private")
+			w.Header().Add("Cache-Control", `no-cache="Set-Cookie"`)
+		case "/expires":
+			w.Header().Set("Cache-Control", "public")
+			w.Header().Set("Date", "Wed, 14 Oct 2026 10:00:00 GMT")
+			w.Header().Set("Expires", "Wed, 14 Oct 2026 12:00:00 GMT")
+		case "/expired":
+			w.Header().Set("Expires", "0")
+		}
+		_, _ = w.Write([]byte(`pong`))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		path, expected string
+		directives     map[string]string
+		expiresIn      interface{}
+		status         string
+	}{
+		{"/static", "public,max-age>=3600", map[string]string{"public": "", "max-age": "86400", "immutable": ""}, nil, "ok"},
+		{"/static", "Public, !private, !no-store, immutable", map[string]string{"public": "", "max-age": "86400",
+			"immutable": ""}, nil, "ok"},
+		{"/short", "public,max-age>=3600", map[string]string{"public": "", "max-age": "600"}, nil,
+			`failed: cache-control max-age is "600", expected >=3600`},
+		{"/short", "max-age=600,s-maxage>60", map[string]string{"public": "", "max-age": "600"}, nil,
+			"failed: cache-control no s-maxage"},
+		{"/private", "public,!private,max-age>=3600", map[string]string{"private": "", "no-cache": "Set-Cookie"}, nil,
+			"failed: cache-control no public; private is set; no max-age"},
+		{"/private", "no-cache=Set-Cookie", map[string]string{"private": "", "no-cache": "Set-Cookie"}, nil, "ok"},
+		{"/expires", "public,max-age>=3600", map[string]string{"public": ""}, int64(7200), "ok"},
+		{"/expires", "max-age>=86400", map[string]string{"public": ""}, int64(7200),
+			`failed: cache-control max-age is "7200", expected >=86400`},
+		{"/expired", "max-age>0", map[string]string{}, int64(0), `failed: cache-control max-age is "0", expected >0`},
+		{"/none", "public", map[string]string{}, nil, "failed: cache-control no public"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.path+" "+tt.expected, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "cdn", URL: ts.URL + tt.path + "?cacheControl=" + url.QueryEscape(tt.expected)})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.directives, resp.Body["cache_control"])
+			assert.Equal(t, tt.expiresIn, resp.Body["expires_in"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+		})
+	}
+
+	for _, bad := range []string{"public,,max-age>1", "max-age>=abc", "!private=1", "=1"} {
+		_, err := p.Status(Request{Name: "cdn", URL: ts.URL + "/static?cacheControl=" + url.QueryEscape(bad)})
+		require.Error(t, err, bad)
+		assert.Contains(t, err.Error(), "http cacheControl parse failed", bad)
+	}
+}
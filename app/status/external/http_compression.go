@@ -0,0 +1,110 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// doWithEncoding makes request with Accept-Encoding set to the given encoding, "gzip", "br" or "deflate".
+// The body of the response is not decoded by the transport, as the header is set explicitly.
+func (h *HTTPProvider) doWithEncoding(req *http.Request, encoding string) (*http.Response, error) {
+	switch encoding {
+	case "gzip", "br", "deflate":
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q, should be gzip, br or deflate", encoding)
+	}
+	req.Header.Set("Accept-Encoding", encoding)
+	return h.Do(req)
+}
+
+// readDecoded reads the body compressed with content encoding, the body is read as is if not compressed.
+// Decoded body is limited to maxBodyBytes+1 to let the caller detect truncation, tiny body can be a bomb.
+// Compressed input is limited the same way, and the stream cut by this limit is reported as truncated.
+// Returns the number of compressed bytes read, and the raw body along with the error if it can't be decoded.
+func readDecoded(r io.Reader, contentEncoding string) (body []byte, compressed int, truncated bool, err error) {
+	raw := bytes.Buffer{}
+	src := bufio.NewReader(io.TeeReader(io.LimitReader(r, maxBodyBytes+1), &raw))
+	drain := func() int { // reads the rest of the input, to report the whole compressed size or raw body
+		_, _ = io.Copy(io.Discard, src)
+		return raw.Len()
+	}
+
+	rd, err := decoder(src, contentEncoding)
+	if err != nil {
+		compressed = drain()
+		return raw.Bytes(), compressed, compressed > maxBodyBytes, err
+	}
+	body, err = io.ReadAll(io.LimitReader(rd, maxBodyBytes+1))
+	compressed = drain()
+	if errors.Is(err, io.ErrUnexpectedEOF) && compressed > maxBodyBytes {
+		return body, compressed, true, nil // stream cut by the input limit, not broken
+	}
+	if err != nil {
+		return raw.Bytes(), compressed, compressed > maxBodyBytes, err
+	}
+	return body, compressed, len(body) > maxBodyBytes, nil
+}
+
+// decoder makes reader decoding the stream compressed with content encoding.
+// Deflate is a zlib stream by the spec, but some servers send raw deflate data without zlib wrapper, both accepted.
+func decoder(r *bufio.Reader, contentEncoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return gz, nil
+	case "br":
+		return brotli.NewReader(r), nil
+	case "deflate":
+		if hdr, err := r.Peek(2); err == nil && hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0 {
+			return zlib.NewReader(r) // zlib header: deflate method and the check bits
+		}
+		return flate.NewReader(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+}
+
+// checkCompression sets content_encoding, compressed_bytes, uncompressed_bytes and compression_ratio in the body.
+// Status is warn if the response is not compressed, failed if compressed with other encoding or can't be decoded.
+func checkCompression(body map[string]interface{}, contentEncoding, expected string, compressed, uncompressed int,
+	decodeErr error) {
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	body["content_encoding"] = contentEncoding
+	body["compressed_bytes"] = compressed
+
+	enc := strings.ToLower(strings.TrimSpace(contentEncoding))
+	switch {
+	case enc == "" || enc == "identity":
+		setStatus(body, "warn", fmt.Sprintf("response not compressed, expected %s", expected))
+		return
+	case decodeErr != nil:
+		setStatus(body, "failed", fmt.Sprintf("%s decode: %v", contentEncoding, decodeErr))
+		return
+	}
+
+	body["uncompressed_bytes"] = uncompressed
+	if compressed > 0 {
+		body["compression_ratio"] = math.Round(float64(uncompressed)/float64(compressed)*100) / 100
+	}
+	if enc != expected && !(enc == "x-gzip" && expected == "gzip") {
+		setStatus(body, "failed", fmt.Sprintf("content encoding %s, expected %s", contentEncoding, expected))
+	}
+}
@@ -0,0 +1,190 @@
+package external
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProvider_StatusWithEncoding(t *testing.T) {
+	payload := `{"data":"` + strings.Repeat("abcd", 1000) + `"}`
+	compressed := map[string][]byte{}
+	{
+		buf := bytes.Buffer{}
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write([]byte(payload))
+		require.NoError(t, gz.Close())
+		compressed["gzip"] = buf.Bytes()
+	}
+	{
+		buf := bytes.Buffer{}
+		br := brotli.NewWriter(&buf)
+		_, _ = br.Write([]byte(payload))
+		require.NoError(t, br.Close())
+		compressed["br"] = buf.Bytes()
+	}
+	{
+		buf := bytes.Buffer{}
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write([]byte(payload))
+		require.NoError(t, zw.Close())
+		compressed["deflate"] = buf.Bytes()
+	}
+	{
+		buf := bytes.Buffer{}
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		_, _ = fw.Write([]byte(payload))
+		require.NoError(t, fw.Close())
+		compressed["raw-deflate"] = buf.Bytes()
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.URL.Query().Get("encoding"), "option not passed to the service")
+		enc := r.Header.Get("Accept-Encoding")
+		switch r.URL.Path {
+		case "/plain":
+			_, _ = w.Write([]byte(payload))
+		case "/gzip-only":
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(compressed["gzip"])
+		case "/raw-deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			_, _ = w.Write(compressed["raw-deflate"])
+		case "/broken":
+			w.Header().Set("Content-Encoding", enc)
+			_, _ = w.Write([]byte("not compressed"))
+		default:
+			w.Header().Set("Content-Encoding", enc)
+			_, _ = w.Write(compressed[enc])
+		}
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		path, encoding string
+		contentEnc     string
+		status         string
+	}{
+		{"/route", "gzip", "gzip", "ok"},
+		{"/route", "br", "br", "ok"},
+		{"/route", "deflate", "deflate", "ok"},
+		{"/gzip-only", "br", "gzip", "failed: content encoding gzip, expected br"},
+		{"/plain", "gzip", "", "warn: response not compressed, expected gzip"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.path+"/"+tt.encoding, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "web", URL: ts.URL + tt.path + "?encoding=" + tt.encoding})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, strings.Repeat("abcd", 1000), resp.Body["data"], "body decoded")
+			assert.Equal(t, tt.contentEnc, resp.Body["content_encoding"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+			if tt.contentEnc == "" {
+				assert.Equal(t, len(payload), resp.Body["compressed_bytes"])
+				assert.NotContains(t, resp.Body, "compression_ratio")
+				return
+			}
+			assert.Equal(t, len(compressed[tt.contentEnc]), resp.Body["compressed_bytes"])
+			assert.Equal(t, len(payload), resp.Body["uncompressed_bytes"])
+			assert.Greater(t, resp.Body["compression_ratio"], 10.0)
+		})
+	}
+
+	resp, err := p.Status(Request{Name: "web", URL: ts.URL + "/raw-deflate?encoding=deflate"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"], "deflate without zlib wrapper")
+	assert.Equal(t, strings.Repeat("abcd", 1000), resp.Body["data"])
+	assert.Equal(t, len(compressed["raw-deflate"]), resp.Body["compressed_bytes"])
+
+	resp, err = p.Status(Request{Name: "web", URL: ts.URL + "/broken?encoding=gzip"})
+	require.NoError(t, err)
+	assert.Equal(t, "not compressed", resp.Body["text"])
+	assert.Equal(t, "failed: gzip decode: gzip: invalid header", resp.Body["status"])
+
+	_, err = p.Status(Request{Name: "web", URL: ts.URL + "/route?encoding=lzma"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported encoding "lzma", should be gzip, br or deflate`)
+}
+
+func TestHTTPProvider_StatusWithEncodingBomb(t *testing.T) {
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write(bytes.Repeat([]byte("a"), 64*maxBodyBytes))
+	require.NoError(t, gz.Close())
+	require.Less(t, buf.Len(), maxBodyBytes/5)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	resp, err := p.Status(Request{Name: "bomb", URL: ts.URL + "/?encoding=gzip"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"])
+	assert.Equal(t, true, resp.Body["body_truncated"])
+	assert.Equal(t, maxBodyBytes, resp.Body["uncompressed_bytes"], "decoded body limited")
+	assert.Equal(t, buf.Len(), resp.Body["compressed_bytes"])
+}
+
+func TestHTTPProvider_StatusWithEncodingLarge(t *testing.T) {
+	data := make([]byte, 3*maxBodyBytes/2)
+	_, err := rand.Read(data) // incompressible, compressed body is over the limit as well
+	require.NoError(t, err)
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write(data)
+	require.NoError(t, gz.Close())
+	require.Greater(t, buf.Len(), maxBodyBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	resp, err := p.Status(Request{Name: "large", URL: ts.URL + "/?encoding=gzip"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"], "valid stream over the limit is not a decode error")
+	assert.Equal(t, true, resp.Body["body_truncated"])
+	assert.Equal(t, maxBodyBytes+1, resp.Body["compressed_bytes"], "compressed input limited")
+	assert.Greater(t, resp.Body["uncompressed_bytes"], maxBodyBytes*9/10, "decoded up to the input limit")
+}
+
+func Test_readDecoded(t *testing.T) {
+	payload := bytes.Repeat([]byte("abcd"), 100)
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write(payload)
+	require.NoError(t, gz.Close())
+
+	body, n, truncated, err := readDecoded(bytes.NewReader(buf.Bytes()), "gzip")
+	require.NoError(t, err)
+	assert.Equal(t, payload, body)
+	assert.Equal(t, buf.Len(), n)
+	assert.False(t, truncated)
+
+	// stream cut before the limit is broken
+	_, _, _, err = readDecoded(bytes.NewReader(buf.Bytes()[:buf.Len()/2]), "gzip")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, _, _, err = readDecoded(bytes.NewReader(buf.Bytes()), "lzma")
+	assert.EqualError(t, err, `unsupported content encoding "lzma"`)
+}
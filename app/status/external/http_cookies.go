@@ -0,0 +1,65 @@
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// cookieAttrs are security attributes of a cookie reported with cookieFlags option
+type cookieAttrs struct {
+	Secure   bool     `json:"secure"`
+	HTTPOnly bool     `json:"http_only"`
+	SameSite string   `json:"same_site"`         // Strict, Lax, None or empty if not set
+	Missing  []string `json:"missing,omitempty"` // required attributes not set
+}
+
+// checkCookies sets cookies in the body with security attributes of each cookie from Set-Cookie headers.
+// required is a comma separated list of secure, httponly and samesite, status is failed if any cookie misses
+// a required attribute, warn if the response has no cookies. Status set to ok if not set already.
+func checkCookies(body map[string]interface{}, cookies []*http.Cookie, required string) error {
+	req := map[string]bool{}
+	for _, f := range strings.Split(required, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f != "secure" && f != "httponly" && f != "samesite" {
+			return fmt.Errorf("unknown cookie flag %q, should be secure, httponly or samesite", f)
+		}
+		req[f] = true
+	}
+
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	res := map[string]cookieAttrs{}
+	body["cookies"] = res
+	if len(cookies) == 0 {
+		setStatus(body, "warn", "no cookies set")
+		return nil
+	}
+
+	for _, c := range cookies {
+		attrs := cookieAttrs{Secure: c.Secure, HTTPOnly: c.HttpOnly}
+		switch c.SameSite {
+		case http.SameSiteStrictMode:
+			attrs.SameSite = "Strict"
+		case http.SameSiteLaxMode:
+			attrs.SameSite = "Lax"
+		case http.SameSiteNoneMode:
+			attrs.SameSite = "None"
+		}
+		if req["secure"] && !attrs.Secure {
+			attrs.Missing = append(attrs.Missing, "Secure")
+		}
+		if req["httponly"] && !attrs.HTTPOnly {
+			attrs.Missing = append(attrs.Missing, "HttpOnly")
+		}
+		if req["samesite"] && attrs.SameSite == "" {
+			attrs.Missing = append(attrs.Missing, "SameSite")
+		}
+		if len(attrs.Missing) > 0 {
+			setStatus(body, "failed", fmt.Sprintf("cookie %s missing %s", c.Name, strings.Join(attrs.Missing, ", ")))
+		}
+		res[c.Name] = attrs
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusCookies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("cookieFlags"), "provider option is not sent")
+		switch r.URL.Path {
+		case "/compliant":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Secure: true, HttpOnly: true,
+				SameSite: http.SameSiteStrictMode})
+			http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "c1", Secure: true, HttpOnly: true,
+				SameSite: http.SameSiteLaxMode})
+		case "/weak":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", HttpOnly: true})
+			http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "c1", Secure: true, SameSite: http.SameSiteNoneMode})
+		}
+		_, _ = w.Write([]byte(`pong`))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		path    string
+		cookies map[string]cookieAttrs
+		status  string
+	}{
+		{"/compliant?cookieFlags=secure,httponly,samesite", map[string]cookieAttrs{
+			"session": {Secure: true, HTTPOnly: true, SameSite: "Strict"},
+			"csrf":    {Secure: true, HTTPOnly: true, SameSite: "Lax"},
+		}, "ok"},
+		{"/weak?cookieFlags=secure,httponly,samesite", map[string]cookieAttrs{
+			"session": {HTTPOnly: true, Missing: []string{"Secure", "SameSite"}},
+			"csrf":    {Secure: true, SameSite: "None", Missing: []string{"HttpOnly"}},
+		}, "failed: cookie session missing Secure, SameSite"},
+		{"/weak?cookieFlags=HttpOnly", map[string]cookieAttrs{
+			"session": {HTTPOnly: true},
+			"csrf":    {Secure: true, SameSite: "None", Missing: []string{"HttpOnly"}},
+		}, "failed: cookie csrf missing HttpOnly"},
+		{"/none?cookieFlags=secure", map[string]cookieAttrs{}, "warn: no cookies set"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "auth", URL: ts.URL + tt.path})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.cookies, resp.Body["cookies"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+		})
+	}
+
+	_, err := p.Status(Request{Name: "auth", URL: ts.URL + "/compliant?cookieFlags=secure,partitioned"})
+	require.EqualError(t, err, "http cookieFlags parse failed: auth "+ts.URL+
+		`/compliant?cookieFlags=secure,partitioned: unknown cookie flag "partitioned", should be secure, httponly or samesite`)
+}
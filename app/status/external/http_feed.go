@@ -0,0 +1,99 @@
+package external
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// feedDoc covers rss, atom and sitemap documents, only fields used for freshness check
+type feedDoc struct {
+	XMLName xml.Name
+	Items   []struct {
+		PubDate string `xml:"pubDate"`
+		Date    string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	} `xml:"channel>item"` // rss
+	Entries []struct {
+		Updated   string `xml:"updated"`
+		Published string `xml:"published"`
+	} `xml:"entry"` // atom
+	URLs []struct {
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"` // sitemap
+}
+
+// feedDateLayouts lists date formats used by rss (RFC822/RFC1123 variants) and atom/sitemap (RFC3339, W3C date)
+var feedDateLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822, time.RFC3339, "2006-01-02",
+	"Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "2 Jan 2006 15:04:05 -0700"}
+
+// checkFeed parses rss, atom or sitemap xml and sets feed_type, feed_items and feed_newest in the body.
+// Status is failed if xml is malformed or not a feed, warn if there are no items or the newest is older than maxAge.
+// maxAge 0 disables the age check. Status set to ok if not set already.
+func checkFeed(body map[string]interface{}, data []byte, maxAge time.Duration) {
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+
+	var doc feedDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		setStatus(body, "failed", "invalid feed xml, "+err.Error())
+		return
+	}
+
+	var dates []string
+	switch doc.XMLName.Local {
+	case "rss":
+		for _, item := range doc.Items {
+			dates = append(dates, item.PubDate+item.Date) // only one of them is expected
+		}
+	case "feed":
+		for _, entry := range doc.Entries {
+			d := entry.Updated
+			if d == "" {
+				d = entry.Published
+			}
+			dates = append(dates, d)
+		}
+	case "urlset":
+		for _, u := range doc.URLs {
+			dates = append(dates, u.LastMod)
+		}
+	default:
+		setStatus(body, "failed", fmt.Sprintf("unknown feed type %q", doc.XMLName.Local))
+		return
+	}
+
+	body["feed_type"] = map[string]string{"rss": "rss", "feed": "atom", "urlset": "sitemap"}[doc.XMLName.Local]
+	body["feed_items"] = len(dates)
+	if len(dates) == 0 {
+		setStatus(body, "warn", "feed has no items")
+		return
+	}
+
+	var newest time.Time
+	for _, d := range dates {
+		if ts, ok := parseFeedDate(d); ok && ts.After(newest) {
+			newest = ts
+		}
+	}
+	if newest.IsZero() {
+		setStatus(body, "warn", "no valid item dates in feed")
+		return
+	}
+	body["feed_newest"] = newest.Format(time.RFC3339)
+
+	if age := time.Since(newest); maxAge > 0 && age > maxAge {
+		setStatus(body, "warn", fmt.Sprintf("newest item is %s old, expected within %s", age.Truncate(time.Minute), maxAge))
+	}
+}
+
+func parseFeedDate(d string) (time.Time, bool) {
+	d = strings.TrimSpace(d)
+	for _, layout := range feedDateLayouts {
+		if ts, err := time.Parse(layout, d); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
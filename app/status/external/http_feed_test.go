@@ -0,0 +1,95 @@
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusFeed(t *testing.T) {
+	fresh := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	stale := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+
+	rss := func(ts ...time.Time) string {
+		items := ""
+		for _, t := range ts {
+			items += fmt.Sprintf("<item><title>post</title><pubDate>%s</pubDate></item>", t.Format(time.RFC1123Z))
+		}
+		return `<?xml version="1.0"?><rss version="2.0"><channel><title>blog</title>` + items + `</channel></rss>`
+	}
+	feeds := map[string]string{
+		"/rss/fresh": rss(stale, fresh),
+		"/rss/stale": rss(stale, stale.Add(-time.Hour)),
+		"/rss/empty": rss(),
+		"/atom": `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>blog</title>` +
+			`<entry><title>post1</title><updated>` + fresh.Format(time.RFC3339) + `</updated></entry>` +
+			`<entry><title>post2</title><published>` + stale.Format(time.RFC3339) + `</published></entry></feed>`,
+		"/sitemap.xml": `<?xml version="1.0"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+			`<url><loc>https://example.com/</loc><lastmod>` + stale.Format("2006-01-02") + `</lastmod></url></urlset>`,
+		"/broken": `<?xml version="1.0"?><rss version="2.0"><channel><item><pubDate>blah</item></channel>`,
+		"/html":   `<html><body>not a feed</body></html>`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feeds[r.URL.Path]))
+	}))
+	defer ts.Close()
+
+	tbl := []struct {
+		path   string
+		typ    interface{}
+		items  interface{}
+		newest interface{}
+		status string
+	}{
+		{"/rss/fresh?maxFeedAge=24h", "rss", 2, fresh.Format(time.RFC3339), "ok"},
+		{"/rss/stale?maxFeedAge=24h", "rss", 2, stale.Format(time.RFC3339),
+			"warn: newest item is 72h0m0s old, expected within 24h0m0s"},
+		{"/rss/stale?feed=true", "rss", 2, stale.Format(time.RFC3339), "ok"},
+		{"/rss/empty?feed=true", "rss", 0, nil, "warn: feed has no items"},
+		{"/atom?maxFeedAge=2h", "atom", 2, fresh.Format(time.RFC3339), "ok"},
+		{"/sitemap.xml?maxFeedAge=24h", "sitemap", 1, stale.Truncate(24 * time.Hour).Format(time.RFC3339),
+			"warn: newest item is"},
+		{"/broken?feed=true", nil, nil, nil, "failed: invalid feed xml, XML syntax error on line 1"},
+		{"/html?feed=true", nil, nil, nil, `failed: unknown feed type "html"`},
+	}
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	for _, tt := range tbl {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "feed", URL: ts.URL + tt.path})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.typ, resp.Body["feed_type"])
+			assert.Equal(t, tt.items, resp.Body["feed_items"])
+			assert.Equal(t, tt.newest, resp.Body["feed_newest"])
+			assert.Contains(t, resp.Body["status"], tt.status)
+		})
+	}
+
+	_, err := p.Status(Request{Name: "feed", URL: ts.URL + "/rss/fresh?maxFeedAge=blah"})
+	require.Error(t, err)
+}
+
+func Test_parseFeedDate(t *testing.T) {
+	tbl := []struct {
+		inp string
+		res time.Time
+		ok  bool
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC), true},
+		{"Mon, 2 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{" 2006-01-02T15:04:05Z ", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC), true},
+		{"yesterday", time.Time{}, false},
+	}
+	for _, tt := range tbl {
+		res, ok := parseFeedDate(tt.inp)
+		assert.Equal(t, tt.ok, ok, tt.inp)
+		assert.True(t, tt.res.Equal(res), "%s: %v", tt.inp, res)
+	}
+}
@@ -0,0 +1,74 @@
+package external
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathOperators are numeric comparisons allowed in jsonpath, two-char ones first to be matched before "<" and ">"
+var jsonPathOperators = []string{"<=", ">=", "<", ">"}
+
+// parseJSONPath splits jsonpath like "$.queue<100" to dot-separated path "queue", operator "<" and limit 100.
+// Operator is empty if the jsonpath has no comparison. The "$." prefix is optional.
+func parseJSONPath(jsonPath string) (path, op string, limit float64, err error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(jsonPath, "$"), ".")
+	for _, o := range jsonPathOperators {
+		if i := strings.Index(path, o); i >= 0 {
+			if limit, err = strconv.ParseFloat(strings.TrimSpace(path[i+len(o):]), 64); err != nil {
+				return "", "", 0, fmt.Errorf("invalid limit in %q: %w", jsonPath, err)
+			}
+			path, op = strings.TrimSpace(path[:i]), o
+			break
+		}
+	}
+	if path == "" {
+		return "", "", 0, fmt.Errorf("empty path in %q", jsonPath)
+	}
+	return path, op, limit, nil
+}
+
+// checkJSONPath reads the value at the path of the json body and sets it as jsonpath_value. Status is failed
+// if the body is not decoded json, the value is missing, differs from the expected one, or, for path with operator, is not a number matching the limit.
+// Status set to ok if not set already.
+func checkJSONPath(body map[string]interface{}, decoded bool, jsonPath, expected string) error {
+	path, op, limit, err := parseJSONPath(jsonPath)
+	if err != nil {
+		return err
+	}
+	if op != "" && expected != "" {
+		return fmt.Errorf("expected can't be used with comparison in %q", jsonPath)
+	}
+
+	v, found := jsonPathValue(body, path) // read before status is set, "status" can be the path
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	if !decoded {
+		setStatus(body, "failed", "body is not json object")
+		return nil
+	}
+	if !found {
+		setStatus(body, "failed", "no value at "+jsonPath)
+		return nil
+	}
+	body["jsonpath_value"] = v
+
+	if op == "" {
+		if observed := fmt.Sprintf("%v", v); observed != expected {
+			setStatus(body, "failed", fmt.Sprintf("value %s at %s, expected %s", observed, jsonPath, expected))
+		}
+		return nil
+	}
+
+	num, ok := v.(float64)
+	if !ok {
+		setStatus(body, "failed", fmt.Sprintf("value %v at %s is not numeric", v, jsonPath))
+		return nil
+	}
+	passed := map[string]bool{"<": num < limit, "<=": num <= limit, ">": num > limit, ">=": num >= limit}[op]
+	if !passed {
+		setStatus(body, "failed", fmt.Sprintf("value %v at %s", num, jsonPath))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusJSONPath(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("jsonpath"), "provider option is not sent")
+		assert.Empty(t, r.URL.Query().Get("expected"), "provider option is not sent")
+		switch r.URL.Path {
+		case "/text":
+			_, _ = w.Write([]byte("db ok"))
+		case "/degraded":
+			_, _ = w.Write([]byte(`{"db":"down","queue":250,"deps":{"cache":"ok"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"db":"ok","queue":12,"deps":{"cache":"ok"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		url    string
+		value  interface{}
+		status string
+	}{
+		{"/health?jsonpath=$.db&expected=ok", "ok", "ok"},
+		{"/health?jsonpath=deps.cache&expected=ok", "ok", "ok"},
+		{"/health?jsonpath=$.queue&expected=12", float64(12), "ok"},
+		{"/degraded?jsonpath=$.db&expected=ok", "down", "failed: value down at $.db, expected ok"},
+		{"/health?jsonpath=$.queue<100", float64(12), "ok"},
+		{"/health?jsonpath=$.queue>=12", float64(12), "ok"},
+		{"/degraded?jsonpath=$.queue<100", float64(250), "failed: value 250 at $.queue<100"},
+		{"/degraded?jsonpath=$.queue<=250", float64(250), "ok"},
+		{"/health?jsonpath=$.db>1", "ok", "failed: value ok at $.db>1 is not numeric"},
+		{"/health?jsonpath=$.deps.missing&expected=ok", nil, "failed: no value at $.deps.missing"},
+		{"/text?jsonpath=$.text&expected=db%20ok", nil, "failed: body is not json object"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "health", URL: ts.URL + tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.value, resp.Body["jsonpath_value"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+		})
+	}
+
+	_, err := p.Status(Request{Name: "health", URL: ts.URL + "/health?jsonpath=$.queue<abc"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http jsonpath parse failed")
+
+	_, err = p.Status(Request{Name: "health", URL: ts.URL + "/health?jsonpath=$.queue<100&expected=12"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected can't be used with comparison")
+}
+
+func Test_parseJSONPath(t *testing.T) {
+	tbl := []struct {
+		in    string
+		path  string
+		op    string
+		limit float64
+		err   bool
+	}{
+		{"$.db", "db", "", 0, false},
+		{"db", "db", "", 0, false},
+		{"$.deps.cache", "deps.cache", "", 0, false},
+		{"$.queue<100", "queue", "<", 100, false},
+		{"$.queue <= 99.5", "queue", "<=", 99.5, false},
+		{"$.lag>=0", "lag", ">=", 0, false},
+		{"$.lag>-1", "lag", ">", -1, false},
+		{"$", "", "", 0, true},
+		{"$.queue<", "", "", 0, true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.in, func(t *testing.T) {
+			path, op, limit, err := parseJSONPath(tt.in)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.path, path)
+			assert.Equal(t, tt.op, op)
+			assert.InDelta(t, tt.limit, limit, 0.0001)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// jwksState keeps the key set of the last scrape and the time it was seen changed
+type jwksState struct {
+	kids      string // sorted kids joined with comma
+	changedAt time.Time
+}
+
+// checkJWKS parses the body as JSON Web Key Set and sets jwks_kids, jwks_keys and jwks_changed_at in the body.
+// The key set is compared with the previous scrape, the time of the first scrape counts as the last change.
+// Status is failed if the body is not a key set, warn if the set has less than minKeys keys, or didn't change
+// longer than rotation window. Zero minKeys or rotation disables the check. Status set to ok if not set already.
+func (h *HTTPProvider) checkJWKS(name string, body map[string]interface{}, data []byte, minKeys int,
+	rotation time.Duration, now time.Time) {
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil || jwks.Keys == nil {
+		setStatus(body, "failed", "body is not jwks")
+		return
+	}
+	kids := make([]string, 0, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		kids = append(kids, k.Kid)
+	}
+	sort.Strings(kids)
+	body["jwks_kids"], body["jwks_keys"] = kids, len(kids)
+
+	h.jwks.once.Do(func() {
+		h.jwks.last = make(map[string]jwksState)
+	})
+	h.jwks.lock.Lock()
+	curr := jwksState{kids: strings.Join(kids, ","), changedAt: now}
+	if prev, ok := h.jwks.last[name]; ok && prev.kids == curr.kids {
+		curr.changedAt = prev.changedAt
+	}
+	h.jwks.last[name] = curr
+	h.jwks.lock.Unlock()
+	body["jwks_changed_at"] = curr.changedAt.Format(time.RFC3339)
+
+	if minKeys > 0 && len(kids) < minKeys {
+		setStatus(body, "warn", fmt.Sprintf("%d keys, expected at least %d", len(kids), minKeys))
+	}
+	if unchanged := now.Sub(curr.changedAt); rotation > 0 && unchanged > rotation {
+		setStatus(body, "warn", fmt.Sprintf("key set not changed for %s, expected rotation within %s",
+			unchanged.Truncate(time.Second), rotation))
+	}
+}
@@ -0,0 +1,119 @@
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusJWKS(t *testing.T) {
+	var rotations int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("jwks"), "provider option is not sent")
+		assert.Empty(t, r.URL.Query().Get("rotation"), "provider option is not sent")
+		switch r.URL.Path {
+		case "/rotating":
+			n := atomic.AddInt32(&rotations, 1)
+			_, _ = fmt.Fprintf(w, `{"keys":[{"kid":"k%d","kty":"RSA"},{"kid":"k%d","kty":"RSA"}]}`, n, n+1)
+		case "/single":
+			_, _ = w.Write([]byte(`{"keys":[{"kid":"k1","kty":"RSA"}]}`))
+		case "/text":
+			_, _ = w.Write([]byte(`not a key set`))
+		default:
+			_, _ = w.Write([]byte(`{"keys":[{"kid":"b","kty":"RSA"},{"kid":"a","kty":"EC"}]}`))
+		}
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	resp, err := p.Status(Request{Name: "auth", URL: ts.URL + "/stalled?jwks=true&minKeys=2&rotation=720h"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"])
+	assert.Equal(t, []string{"a", "b"}, resp.Body["jwks_kids"])
+	assert.Equal(t, 2, resp.Body["jwks_keys"])
+	assert.Contains(t, resp.Body, "jwks_changed_at")
+	assert.Equal(t, "http 200, 57 bytes (ok)", resp.Summary)
+
+	for i := 1; i <= 2; i++ {
+		resp, err = p.Status(Request{Name: "rotating", URL: ts.URL + "/rotating?jwks=true&rotation=1h"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, []string{fmt.Sprintf("k%d", i), fmt.Sprintf("k%d", i+1)}, resp.Body["jwks_kids"])
+	}
+
+	resp, err = p.Status(Request{Name: "single", URL: ts.URL + "/single?minKeys=2"})
+	require.NoError(t, err)
+	assert.Equal(t, "warn: 1 keys, expected at least 2", resp.Body["status"])
+
+	resp, err = p.Status(Request{Name: "text", URL: ts.URL + "/text?jwks=true"})
+	require.NoError(t, err)
+	assert.Equal(t, "failed: body is not jwks", resp.Body["status"])
+
+	_, err = p.Status(Request{Name: "auth", URL: ts.URL + "/stalled?rotation=month"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http rotation parse failed")
+	_, err = p.Status(Request{Name: "auth", URL: ts.URL + "/stalled?minKeys=two"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http minKeys parse failed")
+}
+
+func TestHttpProvider_checkJWKS(t *testing.T) {
+	rotating := func(n int) []byte {
+		return []byte(fmt.Sprintf(`{"keys":[{"kid":"k%d"},{"kid":"k%d"}]}`, n, n+1))
+	}
+	stalled := []byte(`{"keys":[{"kid":"k1"},{"kid":"k2"}]}`)
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	h := HTTPProvider{}
+
+	// the key set rotated daily stays ok with the week window
+	for i := 0; i < 10; i++ {
+		body := map[string]interface{}{}
+		now := start.Add(time.Duration(i) * 24 * time.Hour)
+		h.checkJWKS("rotating", body, rotating(i), 2, 7*24*time.Hour, now)
+		assert.Equal(t, "ok", body["status"], "day %d", i)
+		assert.Equal(t, now.Format(time.RFC3339), body["jwks_changed_at"], "day %d", i)
+	}
+
+	// the same key set is ok within the window and warn after it
+	for i := 0; i < 10; i++ {
+		body := map[string]interface{}{}
+		h.checkJWKS("stalled", body, stalled, 2, 7*24*time.Hour, start.Add(time.Duration(i)*24*time.Hour))
+		assert.Equal(t, start.Format(time.RFC3339), body["jwks_changed_at"], "day %d", i)
+		if i <= 7 {
+			assert.Equal(t, "ok", body["status"], "day %d", i)
+			continue
+		}
+		assert.Equal(t, fmt.Sprintf("warn: key set not changed for %dh0m0s, expected rotation within 168h0m0s", i*24),
+			body["status"], "day %d", i)
+	}
+
+	// rotation resets the window, keys order doesn't matter
+	body := map[string]interface{}{}
+	h.checkJWKS("stalled", body, []byte(`{"keys":[{"kid":"k3"},{"kid":"k2"}]}`), 2, 7*24*time.Hour, start.Add(240*time.Hour))
+	assert.Equal(t, "ok", body["status"])
+	body = map[string]interface{}{}
+	h.checkJWKS("stalled", body, []byte(`{"keys":[{"kid":"k2"},{"kid":"k3"}]}`), 2, 7*24*time.Hour, start.Add(250*time.Hour))
+	assert.Equal(t, "ok", body["status"])
+	assert.Equal(t, start.Add(240*time.Hour).Format(time.RFC3339), body["jwks_changed_at"])
+
+	// dropped key and stalled set, the first problem is reported
+	body = map[string]interface{}{}
+	h.checkJWKS("stalled", body, []byte(`{"keys":[{"kid":"k3"}]}`), 2, 7*24*time.Hour, start.Add(260*time.Hour))
+	assert.Equal(t, "warn: 1 keys, expected at least 2", body["status"])
+	body = map[string]interface{}{}
+	h.checkJWKS("stalled", body, []byte(`{"keys":[{"kid":"k3"}]}`), 2, 7*24*time.Hour, start.Add(500*time.Hour))
+	assert.Equal(t, "warn: 1 keys, expected at least 2", body["status"])
+
+	// failed status set before is kept
+	body = map[string]interface{}{"status": "failed: http 500"}
+	h.checkJWKS("rotating", body, rotating(20), 2, time.Hour, start)
+	assert.Equal(t, "failed: http 500", body["status"])
+	assert.Equal(t, 2, body["jwks_keys"])
+}
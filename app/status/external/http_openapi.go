@@ -0,0 +1,58 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDoc is a part of OpenAPI 3 document used for validation
+type openAPIDoc struct {
+	OpenAPI string `json:"openapi" yaml:"openapi"`
+	Info    *struct {
+		Title   string `json:"title" yaml:"title"`
+		Version string `json:"version" yaml:"version"`
+	} `json:"info" yaml:"info"`
+	Paths map[string]interface{} `json:"paths" yaml:"paths"`
+}
+
+// checkOpenAPI parses json or yaml OpenAPI 3 document and sets openapi_spec, openapi_title, openapi_version
+// and openapi_paths in the body. Status is failed if the spec is missing, can't be parsed or is not a valid OpenAPI 3.
+// Status set to ok if not set already.
+func checkOpenAPI(body map[string]interface{}, data []byte, statusCode int) {
+	if statusCode != http.StatusOK {
+		setStatus(body, "failed", fmt.Sprintf("openapi spec not available, status %d", statusCode))
+		return
+	}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		if yerr := yaml.Unmarshal(data, &doc); yerr != nil {
+			setStatus(body, "failed", "invalid openapi spec, "+err.Error())
+			return
+		}
+	}
+
+	switch {
+	case doc.OpenAPI == "":
+		setStatus(body, "failed", "invalid openapi spec, no openapi version")
+		return
+	case !strings.HasPrefix(doc.OpenAPI, "3."):
+		setStatus(body, "failed", "invalid openapi spec, unsupported version "+doc.OpenAPI)
+		return
+	case doc.Info == nil || doc.Info.Title == "" || doc.Info.Version == "":
+		setStatus(body, "failed", "invalid openapi spec, info title and version required")
+		return
+	}
+
+	body["openapi_spec"] = doc.OpenAPI
+	body["openapi_title"] = doc.Info.Title
+	body["openapi_version"] = doc.Info.Version
+	body["openapi_paths"] = len(doc.Paths)
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+}
@@ -0,0 +1,77 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusOpenAPI(t *testing.T) {
+	jsonSpec, err := os.ReadFile("testdata/openapi.json")
+	require.NoError(t, err)
+	yamlSpec, err := os.ReadFile("testdata/openapi.yaml")
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi.json":
+			_, _ = w.Write(jsonSpec)
+		case "/openapi.yaml":
+			_, _ = w.Write(yamlSpec)
+		case "/malformed.json":
+			_, _ = w.Write([]byte(`{"openapi": "3.0.3", "info": {"title": "Pets API"`))
+		case "/swagger.json":
+			_, _ = w.Write([]byte(`{"swagger": "2.0", "info": {"title": "Old API", "version": "1.0"}, "paths": {}}`))
+		case "/noinfo.json":
+			_, _ = w.Write([]byte(`{"openapi": "3.0.0", "paths": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	{ // valid json spec
+		resp, err := p.Status(Request{Name: "api", URL: ts.URL + "/openapi.json?openapi=true"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "3.0.3", resp.Body["openapi_spec"])
+		assert.Equal(t, "Pets API", resp.Body["openapi_title"])
+		assert.Equal(t, "1.2.0", resp.Body["openapi_version"])
+		assert.Equal(t, 2, resp.Body["openapi_paths"])
+	}
+
+	{ // valid yaml spec
+		resp, err := p.Status(Request{Name: "api", URL: ts.URL + "/openapi.yaml?openapi=true"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "3.1.0", resp.Body["openapi_spec"])
+		assert.Equal(t, "Orders API", resp.Body["openapi_title"])
+		assert.Equal(t, 1, resp.Body["openapi_paths"])
+	}
+
+	tbl := []struct {
+		path   string
+		status string
+	}{
+		{"/malformed.json", "failed: invalid openapi spec, unexpected end of JSON input"},
+		{"/swagger.json", "failed: invalid openapi spec, no openapi version"},
+		{"/noinfo.json", "failed: invalid openapi spec, info title and version required"},
+		{"/missing.json", "failed: openapi spec not available, status 404"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "api", URL: ts.URL + tt.path + "?openapi=true"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.Nil(t, resp.Body["openapi_title"])
+		})
+	}
+}
@@ -1,47 +1,412 @@
 package external
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 // HTTPProvider is an external service that checks the status of a HTTP endpoint
 type HTTPProvider struct {
 	http.Client
+
+	validators struct {
+		last map[string]cacheValidators // by request name
+		once sync.Once
+		lock sync.Mutex
+	}
+
+	jwks struct {
+		last map[string]jwksState // by request name
+		once sync.Once
+		lock sync.Mutex
+	}
 }
 
-// Status returns the status of the external service via HTTP GET
+// maxBodyBytes limits the response body read and checked by match, bodyMatch, bodyNotMatch and others
+const maxBodyBytes = 1024 * 1024
+
+// httpOptionPrefix marks a query param used by the provider itself if the endpoint has a param with the same name,
+// i.e. ?version=2&sa.version=1.2.3 sends version=2 and checks the served version is 1.2.3
+const httpOptionPrefix = "sa."
+
+// httpOptions lists query params used by the provider itself. They are removed from the url before the request.
+var httpOptions = []string{"minBodyBytes", "feed", "maxFeedAge", "openapi", "etag", "expectChange", "httpVersion",
+	"bodyMatch", "bodyNotMatch", "match", "matchRegex", "cookieFlags", "version", "versionHeader", "versionPath", "encoding",
+	"jsonpath", "xpath", "expected", "method", "header", "jwks", "minKeys", "rotation", "cacheControl",
+	"expectedCodes", "retries", "retryDelay"}
+
+// Status returns the status of the external service via HTTP GET, or the method set with "method" query param.
+// Options are removed from the url, other query params are sent to the service as is. If the endpoint has its own param
+// with the option name, the option is set with "sa." prefix, i.e. ?version=2&sa.version=1.2.3.
+// Repeated "header" query param adds request headers, i.e. ?method=POST&header=Authorization:Bearer%20abc
+// Optional "minBodyBytes" query param sets failed status if the response body is shorter than expected.
+// With "feed=true" the body is checked to be a valid rss, atom or sitemap, and "maxFeedAge" (implies feed)
+// sets warn status if the newest item is older than the given duration, i.e. ?maxFeedAge=24h
+// With "openapi=true" the body is checked to be a valid OpenAPI 3 document, json or yaml.
+// With "etag=true" ETag (or Last-Modified) is compared with the previous scrape, "expectChange" (implies etag)
+// sets failed status if it changed with expectChange=false, or didn't change with expectChange=true.
+// With "httpVersion=1.0" or "httpVersion=1.1" the request is sent with this protocol version, without redirects,
+// and status is failed if the response doesn't honor it.
+// "bodyMatch" and "bodyNotMatch" regex set failed status if the body doesn't match or matches, i.e. ?bodyMatch=healthy
+// "match" substring sets failed status if the body doesn't contain it, "matchRegex" is an alias of bodyMatch,
+// not allowed along with it
+// "cookieFlags" sets failed status if any cookie misses required attributes, i.e. ?cookieFlags=secure,httponly,samesite
+// "version" sets failed status if the served version doesn't match, the version is read from "versionHeader" header
+// or "versionPath" json path of the body, "version" field by default, i.e. ?version=1.2.3&versionPath=build.version
+// "jsonpath" sets failed status if the json value at the path is missing or doesn't equal "expected", the path can have
+// numeric comparison instead, i.e. ?jsonpath=$.db&expected=ok or ?jsonpath=$.queue<100
+// "xpath" does the same for xml body, i.e. ?xpath=/health/db/@status&expected=ok or ?xpath=//queue/size<100
+// "expectedCodes" lists status codes counted as healthy, i.e. ?expectedCodes=200,204,401, any code below 400 if not set
+// "retries" repeats failed request, error or 5xx, waiting "retryDelay" (500ms by default) before the first retry, doubled
+// for each next one. All attempts together are limited by the timeout, i.e. ?retries=3&retryDelay=1s
+// With "jwks=true" the body is checked to be a JSON Web Key Set, "minKeys" sets warn status if it has fewer keys,
+// and "rotation" (duration) if the set of kids didn't change within the window, i.e. ?jwks=true&minKeys=2&rotation=720h
+// "encoding" sends Accept-Encoding and checks the response is compressed with it, gzip, br or deflate, i.e. ?encoding=br
+// "cacheControl" sets failed status if Cache-Control doesn't have the expected directives, "!" prefix for forbidden ones,
+// max-age falls back to Expires, i.e. ?cacheControl=public,!no-store,max-age>=3600
 func (h *HTTPProvider) Status(req Request) (*Response, error) {
+	target, opts, err := splitOptions(req.URL, httpOptionPrefix, httpOptions)
+	if err != nil {
+		return nil, fmt.Errorf("http url parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+	}
+
+	retries, retryDelay, err := retryOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("http retries parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+	}
+
+	encoding, httpVersion := opts.Get("encoding"), opts.Get("httpVersion")
+	if httpVersion != "" && (opts.Get("method") != "" || len(opts["header"]) > 0) {
+		return nil, fmt.Errorf("http httpVersion can't be used with method or header: %s %s", req.Name, maskSecrets(req.URL))
+	}
+	httpReq, err := newHTTPRequest(target, opts.Get("method"), opts["header"])
+	if err != nil {
+		return nil, fmt.Errorf("http request make failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if h.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), h.Timeout) // limits all attempts together
+	}
+	defer cancel()
 
 	st := time.Now()
-	resp, err := h.Get(req.URL)
+	resp, attempts, err := retry(ctx, retries, retryDelay, func() (*http.Response, error) {
+		switch {
+		case httpVersion != "":
+			return h.getWithVersion(ctx, target, httpVersion)
+		case encoding != "":
+			return h.doWithEncoding(httpReq.Clone(ctx), encoding)
+		}
+		return h.Do(httpReq.Clone(ctx))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("http request failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, fmt.Errorf("http request failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
 	}
 	defer func() {
 		if e := resp.Body.Close(); e != nil {
-			log.Printf("[WARN] http response close failed: %s %s: %s", req.Name, req.URL, e)
+			log.Printf("[WARN] http response close failed: %s %s: %s", req.Name, maskSecrets(req.URL), e)
 		}
 	}()
 
-	bodyStr, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("http read failed: %s %s: %w", req.Name, req.URL, err)
+	var bodyStr []byte
+	var truncated bool
+	var compressedLen int
+	var decodeErr error
+	if encoding != "" {
+		// transport doesn't decode the body if Accept-Encoding is set by the caller
+		bodyStr, compressedLen, truncated, decodeErr = readDecoded(resp.Body, resp.Header.Get("Content-Encoding"))
+	} else {
+		if bodyStr, err = io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes+1)); err != nil {
+			return nil, fmt.Errorf("http read failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+		truncated = len(bodyStr) > maxBodyBytes
+	}
+	if len(bodyStr) > maxBodyBytes {
+		bodyStr = bodyStr[:maxBodyBytes]
 	}
 
 	var bodyJSON map[string]interface{}
-	if err := json.Unmarshal(bodyStr, &bodyJSON); err != nil {
+	jsonErr := json.Unmarshal(bodyStr, &bodyJSON)
+	if jsonErr != nil || bodyJSON == nil { // json null decoded to nil map
 		bodyJSON = map[string]interface{}{"text": string(bodyStr)}
 	}
+	if retries > 0 {
+		bodyJSON["attempts"] = attempts
+	}
+	if truncated {
+		bodyJSON["body_truncated"] = true // only the first maxBodyBytes checked
+	}
+
+	if opts.Get("jsonpath") != "" && opts.Get("xpath") != "" {
+		return nil, fmt.Errorf("http jsonpath can't be used with xpath: %s %s", req.Name, maskSecrets(req.URL))
+	}
+	if v := opts.Get("xpath"); v != "" {
+		if err := checkXPath(bodyJSON, bodyStr, v, opts.Get("expected")); err != nil {
+			return nil, fmt.Errorf("http xpath parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+	}
+	if v := opts.Get("jsonpath"); v != "" {
+		if err := checkJSONPath(bodyJSON, jsonErr == nil, v, opts.Get("expected")); err != nil {
+			return nil, fmt.Errorf("http jsonpath parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+	}
+
+	if v := opts.Get("minBodyBytes"); v != "" {
+		minBytes, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("http minBodyBytes parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+		bodyJSON["body_length"] = len(bodyStr)
+		if len(bodyStr) < minBytes {
+			setStatus(bodyJSON, "failed", fmt.Sprintf("body %d bytes, expected at least %d", len(bodyStr), minBytes))
+		}
+	}
+
+	if v := opts.Get("match"); v != "" {
+		checkBodyContains(bodyJSON, bodyStr, v)
+	}
+
+	if opts.Get("bodyMatch") != "" && opts.Get("matchRegex") != "" {
+		return nil, fmt.Errorf("http bodyMatch can't be used with matchRegex: %s %s", req.Name, maskSecrets(req.URL))
+	}
+	match := opts.Get("bodyMatch")
+	if match == "" {
+		match = opts.Get("matchRegex")
+	}
+	if match != "" || opts.Get("bodyNotMatch") != "" {
+		if err := checkBodyMatch(bodyJSON, bodyStr, match, opts.Get("bodyNotMatch")); err != nil {
+			return nil, fmt.Errorf("http body regex parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+	}
+
+	if opts.Get("feed") == "true" || opts.Get("maxFeedAge") != "" {
+		var maxAge time.Duration
+		if v := opts.Get("maxFeedAge"); v != "" {
+			if maxAge, err = time.ParseDuration(v); err != nil {
+				return nil, fmt.Errorf("http maxFeedAge parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+			}
+		}
+		checkFeed(bodyJSON, bodyStr, maxAge)
+	}
+
+	if opts.Get("openapi") == "true" {
+		checkOpenAPI(bodyJSON, bodyStr, resp.StatusCode)
+	}
+
+	if opts.Get("etag") == "true" || opts.Get("expectChange") != "" {
+		if v := opts.Get("expectChange"); v != "" && v != "true" && v != "false" {
+			return nil, fmt.Errorf("http expectChange should be true or false: %s %s", req.Name, maskSecrets(req.URL))
+		}
+		h.checkValidators(req.Name, bodyJSON, resp.Header, opts.Get("expectChange"))
+	}
+
+	if opts.Get("jwks") == "true" || opts.Get("minKeys") != "" || opts.Get("rotation") != "" {
+		minKeys := 0
+		if v := opts.Get("minKeys"); v != "" {
+			if minKeys, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("http minKeys parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+			}
+		}
+		var rotation time.Duration
+		if v := opts.Get("rotation"); v != "" {
+			if rotation, err = time.ParseDuration(v); err != nil {
+				return nil, fmt.Errorf("http rotation parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+			}
+		}
+		h.checkJWKS(req.Name, bodyJSON, bodyStr, minKeys, rotation, time.Now())
+	}
+
+	if v := opts.Get("cookieFlags"); v != "" {
+		if err := checkCookies(bodyJSON, resp.Cookies(), v); err != nil {
+			return nil, fmt.Errorf("http cookieFlags parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+	}
+
+	if v := opts.Get("cacheControl"); v != "" {
+		if err := checkCaching(bodyJSON, resp.Header, v, time.Now()); err != nil {
+			return nil, fmt.Errorf("http cacheControl parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+	}
+
+	if v := opts.Get("version"); v != "" {
+		checkRolloutVersion(bodyJSON, resp.Header, v, opts.Get("versionHeader"), opts.Get("versionPath"))
+	}
+
+	if v := opts.Get("httpVersion"); v != "" {
+		checkHTTPVersion(bodyJSON, resp, v)
+	}
+
+	if encoding != "" {
+		checkCompression(bodyJSON, resp.Header.Get("Content-Encoding"), encoding, compressedLen, len(bodyStr), decodeErr)
+	}
+
+	statusCode := resp.StatusCode
+	if v := opts.Get("expectedCodes"); v != "" {
+		matched, err := checkExpectedCodes(bodyJSON, resp.StatusCode, v)
+		if err != nil {
+			return nil, fmt.Errorf("http expectedCodes parse failed: %s %s: %w", req.Name, maskSecrets(req.URL), err)
+		}
+		if matched {
+			statusCode = http.StatusOK // expected code is healthy, even 4xx or 5xx, actual one is in the body
+		}
+	}
+
 	result := Response{
 		Name:         req.Name,
-		StatusCode:   resp.StatusCode,
+		StatusCode:   statusCode,
 		Body:         bodyJSON,
 		ResponseTime: time.Since(st).Milliseconds(),
 	}
+	result.Summary = fmt.Sprintf("http %d, %d bytes (%s)", resp.StatusCode, len(bodyStr), remoteHealth(result))
 	return &result, nil
 }
+
+// checkExpectedCodes checks the status code is one of comma-separated expected codes, sets response_code and
+// code_matched in the body. Status is failed if the code is not expected. Returns true if the code matched.
+func checkExpectedCodes(body map[string]interface{}, code int, expected string) (bool, error) {
+	matched := false
+	for _, v := range strings.Split(expected, ",") {
+		c, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return false, err
+		}
+		if c == code {
+			matched = true
+		}
+	}
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	body["response_code"], body["code_matched"] = code, matched
+	if !matched {
+		setStatus(body, "failed", fmt.Sprintf("status code %d, expected %s", code, expected))
+	}
+	return matched, nil
+}
+
+// newHTTPRequest makes request with the given method, GET if empty, and headers in "Name:value" form.
+// Later header with the same name replaces earlier one, "Host" header sets host of the request.
+func newHTTPRequest(target, method string, headers []string) (*http.Request, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), target, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	for _, hdr := range headers {
+		name, value, ok := strings.Cut(hdr, ":")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, should be Name:value", hdr)
+		}
+		if strings.EqualFold(name, "Host") {
+			req.Host = strings.TrimSpace(value)
+			continue
+		}
+		req.Header.Set(name, strings.TrimSpace(value))
+	}
+	return req, nil
+}
+
+// splitOptions extracts provider options from the url query and returns the url without them. An option with
+// the prefix, if set, takes precedence and the param without the prefix is kept in the url, for the endpoint's
+// own param with the same name. Options are returned by keys without the prefix. The url returned as is if it has
+// no options.
+func splitOptions(rawURL, prefix string, keys []string) (target string, opts url.Values, err error) {
+	uu, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+	opts = url.Values{}
+	query := uu.Query()
+	for _, k := range keys {
+		if vals, ok := query[prefix+k]; ok {
+			opts[k] = vals
+			query.Del(prefix + k)
+			continue
+		}
+		if vals, ok := query[k]; ok {
+			opts[k] = vals
+			query.Del(k)
+		}
+	}
+	if len(opts) == 0 {
+		return rawURL, opts, nil
+	}
+	uu.RawQuery = query.Encode()
+	return uu.String(), opts, nil
+}
+
+// setStatus sets "status" in the body to "level: reason", i.e. "failed: body is empty".
+// The existing failed status is not overridden by warn, and the first reason of the same level is kept.
+func setStatus(body map[string]interface{}, level, reason string) {
+	if curr, ok := body["status"].(string); ok {
+		if strings.HasPrefix(curr, "failed") || (level == "warn" && strings.HasPrefix(curr, "warn")) {
+			return
+		}
+	}
+	body["status"] = level + ": " + reason
+}
+
+// checkBodyContains checks the first maxBodyBytes of the body contain the substring and sets body_contains.
+// Status is failed if the substring is not found.
+func checkBodyContains(body map[string]interface{}, data []byte, substr string) {
+	if len(data) > maxBodyBytes {
+		data = data[:maxBodyBytes]
+	}
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	found := bytes.Contains(data, []byte(substr))
+	body["body_contains"] = found
+	if !found {
+		setStatus(body, "failed", fmt.Sprintf("body doesn't contain %q", substr))
+	}
+}
+
+// checkBodyMatch checks the first maxBodyBytes of the body with regexes and sets body_match and body_not_match
+// to true if the corresponding pattern matched. Status is failed if bodyMatch doesn't match or bodyNotMatch matches.
+// Empty pattern is not checked.
+func checkBodyMatch(body map[string]interface{}, data []byte, match, notMatch string) error {
+	if len(data) > maxBodyBytes {
+		data = data[:maxBodyBytes]
+	}
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	if match != "" {
+		re, err := regexp.Compile(match)
+		if err != nil {
+			return err
+		}
+		matched := re.Match(data)
+		body["body_match"] = matched
+		if !matched {
+			setStatus(body, "failed", fmt.Sprintf("body doesn't match %q", match))
+		}
+	}
+	if notMatch != "" {
+		re, err := regexp.Compile(notMatch)
+		if err != nil {
+			return err
+		}
+		matched := re.Match(data)
+		body["body_not_match"] = matched
+		if matched {
+			setStatus(body, "failed", fmt.Sprintf("body matches %q", notMatch))
+		}
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package external
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -46,4 +49,304 @@ func TestHttpProvider_StatusHttpNoJson(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode)
 	assert.True(t, resp.ResponseTime > 0)
 	assert.Equal(t, map[string]interface{}{"text": "pong"}, resp.Body)
+	assert.Equal(t, "http 200, 4 bytes (ok)", resp.Summary)
+}
+
+func TestHttpProvider_StatusNullJson(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	resp, err := p.Status(Request{Name: "r1", URL: ts.URL + "?retries=1&minBodyBytes=10&match=ok"})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "null", resp.Body["text"])
+	assert.Equal(t, 1, resp.Body["attempts"])
+	assert.Equal(t, "failed: body 4 bytes, expected at least 10", resp.Body["status"])
+	assert.Equal(t, "http 200, 4 bytes (failed)", resp.Summary)
+}
+
+func TestHttpProvider_StatusMinBodyBytes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("minBodyBytes"), "provider option is not sent")
+		if r.URL.Query().Get("truncated") == "yes" {
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok", "items": ["one", "two", "three", "four", "five", "six", "seven"]}`))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	{ // full body
+		resp, err := p.Status(Request{Name: "r1", URL: ts.URL + "?minBodyBytes=50"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, 82, resp.Body["body_length"])
+	}
+
+	{ // truncated body, target query params preserved
+		resp, err := p.Status(Request{Name: "r1", URL: ts.URL + "?truncated=yes&minBodyBytes=50"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "failed: body 16 bytes, expected at least 50", resp.Body["status"])
+		assert.Equal(t, 16, resp.Body["body_length"])
+		assert.Equal(t, "http 200, 16 bytes (failed)", resp.Summary)
+	}
+
+	{
+		_, err := p.Status(Request{Name: "r1", URL: ts.URL + "?minBodyBytes=bad"})
+		require.Error(t, err)
+	}
+}
+
+func TestHttpProvider_StatusBodyMatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("bodyMatch"), "provider option is not sent")
+		assert.Empty(t, r.URL.Query().Get("bodyNotMatch"), "provider option is not sent")
+		if r.URL.Path == "/maintenance" {
+			_, _ = w.Write([]byte("<html>site is under maintenance</html>"))
+			return
+		}
+		_, _ = w.Write([]byte("<html>all systems healthy</html>"))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		url  string
+		body map[string]interface{}
+	}{
+		{"/?bodyMatch=healthy", map[string]interface{}{"body_match": true, "status": "ok"}},
+		{"/maintenance?bodyMatch=healthy", map[string]interface{}{"body_match": false,
+			"status": `failed: body doesn't match "healthy"`}},
+		{"/?bodyNotMatch=maint(enance)?", map[string]interface{}{"body_not_match": false, "status": "ok"}},
+		{"/maintenance?bodyNotMatch=maint(enance)?", map[string]interface{}{"body_not_match": true,
+			"status": `failed: body matches "maint(enance)?"`}},
+		{"/?bodyMatch=systems%5Cs%2Bhealthy&bodyNotMatch=maintenance", map[string]interface{}{"body_match": true,
+			"body_not_match": false, "status": "ok"}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "page", URL: ts.URL + tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			delete(resp.Body, "text")
+			assert.Equal(t, tt.body, resp.Body)
+		})
+	}
+
+	_, err := p.Status(Request{Name: "page", URL: ts.URL + "/?bodyMatch=%5B"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http body regex parse failed")
+}
+
+func TestHttpProvider_StatusMatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("match"), "provider option is not sent")
+		assert.Empty(t, r.URL.Query().Get("matchRegex"), "provider option is not sent")
+		if r.URL.Path == "/error" {
+			_, _ = w.Write([]byte("<html>internal error (code 42)</html>"))
+			return
+		}
+		_, _ = w.Write([]byte("<html>status: healthy (all 3 nodes)</html>"))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		url  string
+		body map[string]interface{}
+	}{
+		{"/?match=healthy", map[string]interface{}{"body_contains": true, "status": "ok"}},
+		{"/?match=healthy%20(all", map[string]interface{}{"body_contains": true, "status": "ok"}},
+		{"/error?match=healthy", map[string]interface{}{"body_contains": false,
+			"status": `failed: body doesn't contain "healthy"`}},
+		{"/?matchRegex=all%20%5Cd%2B%20nodes", map[string]interface{}{"body_match": true, "status": "ok"}},
+		{"/error?matchRegex=all%20%5Cd%2B%20nodes", map[string]interface{}{"body_match": false,
+			"status": `failed: body doesn't match "all \\d+ nodes"`}},
+		{"/error?match=healthy&matchRegex=code%20%5Cd%2B", map[string]interface{}{"body_contains": false, "body_match": true,
+			"status": `failed: body doesn't contain "healthy"`}},
+		{"/", map[string]interface{}{}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "page", URL: ts.URL + tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			delete(resp.Body, "text")
+			assert.Equal(t, tt.body, resp.Body)
+		})
+	}
+
+	_, err := p.Status(Request{Name: "page", URL: ts.URL + "/?bodyMatch=healthy&matchRegex=nodes"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bodyMatch can't be used with matchRegex")
+
+	_, err = p.Status(Request{Name: "page", URL: ts.URL + "/?matchRegex=%5B"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http body regex parse failed")
+}
+
+func TestHttpProvider_StatusBodyLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("a"), maxBodyBytes))
+		_, _ = w.Write([]byte("healthy"))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	resp, err := p.Status(Request{Name: "page", URL: ts.URL + "/?match=healthy"})
+	require.NoError(t, err)
+	assert.Equal(t, true, resp.Body["body_truncated"])
+	assert.Equal(t, maxBodyBytes, len(resp.Body["text"].(string)))
+	assert.Equal(t, `failed: body doesn't contain "healthy"`, resp.Body["status"], "beyond the limit")
+	assert.Equal(t, fmt.Sprintf("http 200, %d bytes (failed)", maxBodyBytes), resp.Summary)
+}
+
+func TestHttpProvider_StatusMethodAndHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("method"), "provider option is not sent")
+		assert.Empty(t, r.URL.Query().Get("header"), "provider option is not sent")
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"method":"` + r.Method + `","token":"` + r.Header.Get("X-Token") + `","host":"` + r.Host + `"}`))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	resp, err := p.Status(Request{Name: "health", URL: ts.URL + "/health"})
+	require.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode, "no auth header by default")
+
+	resp, err = p.Status(Request{Name: "health", URL: ts.URL + "/health?method=post&header=Authorization:Bearer%20abc" +
+		"&header=X-Token:%20first&header=X-Token:second&header=Host:app.example.com&q=1"})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"method": "POST", "token": "second", "host": "app.example.com"}, resp.Body)
+
+	_, err = p.Status(Request{Name: "health", URL: ts.URL + "/health?header=Authorization"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid header "Authorization", should be Name:value`)
+
+	_, err = p.Status(Request{Name: "health", URL: ts.URL + "/health?header=Authorization:Bearer%20abc&httpVersion=1.0"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "httpVersion can't be used with method or header")
+	assert.Contains(t, err.Error(), "header=Authorization:****", "credentials masked in the error")
+}
+
+func TestHttpProvider_StatusExpectedCodes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("expectedCodes"), "provider option is not sent")
+		switch r.URL.Path {
+		case "/private":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte("resp"))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		url     string
+		code    int
+		body    map[string]interface{}
+		summary string
+	}{
+		{"/private", 401, map[string]interface{}{"text": "resp"}, "http 401, 4 bytes (failed)"},
+		{"/private?expectedCodes=200,204,401", 200, map[string]interface{}{"text": "resp", "response_code": 401,
+			"code_matched": true, "status": "ok"}, "http 401, 4 bytes (ok)"},
+		{"/gone?expectedCodes=200,%20401", 404, map[string]interface{}{"text": "resp", "response_code": 404,
+			"code_matched": false, "status": "failed: status code 404, expected 200, 401"}, "http 404, 4 bytes (failed)"},
+		{"/?expectedCodes=204", 200, map[string]interface{}{"text": "resp", "response_code": 200, "code_matched": false,
+			"status": "failed: status code 200, expected 204"}, "http 200, 4 bytes (failed)"},
+		{"/?expectedCodes=200", 200, map[string]interface{}{"text": "resp", "response_code": 200, "code_matched": true,
+			"status": "ok"}, "http 200, 4 bytes (ok)"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "page", URL: ts.URL + tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, resp.StatusCode)
+			assert.Equal(t, tt.body, resp.Body)
+			assert.Equal(t, tt.summary, resp.Summary)
+		})
+	}
+
+	_, err := p.Status(Request{Name: "page", URL: ts.URL + "/?expectedCodes=2xx"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http expectedCodes parse failed")
+}
+
+func Test_checkBodyContains(t *testing.T) {
+	data := append(make([]byte, maxBodyBytes), []byte("healthy")...)
+	body := map[string]interface{}{}
+	checkBodyContains(body, data, "healthy")
+	assert.Equal(t, map[string]interface{}{"body_contains": false, "status": `failed: body doesn't contain "healthy"`}, body,
+		"substring beyond the limit is not found")
+}
+
+func Test_checkBodyMatch(t *testing.T) {
+	data := append(make([]byte, maxBodyBytes), []byte("healthy")...)
+	body := map[string]interface{}{}
+	require.NoError(t, checkBodyMatch(body, data, "healthy", ""))
+	assert.Equal(t, map[string]interface{}{"body_match": false, "status": `failed: body doesn't match "healthy"`}, body,
+		"pattern beyond the limit is not matched")
+}
+
+func Test_splitOptions(t *testing.T) {
+	tbl := []struct {
+		url    string
+		target string
+		opts   url.Values
+	}{
+		{"http://example.com/ping", "http://example.com/ping", url.Values{}},
+		{"http://example.com/ping?b=2&a=1", "http://example.com/ping?b=2&a=1", url.Values{}},
+		{"http://example.com/ping?minBodyBytes=10", "http://example.com/ping", url.Values{"minBodyBytes": {"10"}}},
+		{"http://example.com/ping?a=1&minBodyBytes=10&b=2", "http://example.com/ping?a=1&b=2",
+			url.Values{"minBodyBytes": {"10"}}},
+		{"http://example.com/ping?sa.minBodyBytes=10", "http://example.com/ping", url.Values{"minBodyBytes": {"10"}}},
+		{"http://example.com/ping?version=2&sa.version=1.2.3", "http://example.com/ping?version=2",
+			url.Values{"version": {"1.2.3"}}},
+		{"http://example.com/ping?header=a:1&sa.version=1.2.3&header=b:2", "http://example.com/ping",
+			url.Values{"version": {"1.2.3"}, "header": {"a:1", "b:2"}}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			target, opts, err := splitOptions(tt.url, httpOptionPrefix, httpOptions)
+			require.NoError(t, err)
+			assert.Equal(t, tt.target, target)
+			assert.Equal(t, tt.opts, opts)
+		})
+	}
+}
+
+func Test_setStatus(t *testing.T) {
+	body := map[string]interface{}{"status": "remote status"}
+	setStatus(body, "warn", "w1")
+	assert.Equal(t, "warn: w1", body["status"])
+	setStatus(body, "warn", "w2")
+	assert.Equal(t, "warn: w1", body["status"])
+	setStatus(body, "failed", "f1")
+	assert.Equal(t, "failed: f1", body["status"])
+	setStatus(body, "failed", "f2")
+	assert.Equal(t, "failed: f1", body["status"])
+	setStatus(body, "warn", "w3")
+	assert.Equal(t, "failed: f1", body["status"])
+
+	body = map[string]interface{}{"text": "pong"}
+	setStatus(body, "failed", "f1")
+	assert.Equal(t, "failed: f1", body["status"])
 }
@@ -0,0 +1,58 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// defaultRetryDelay is the delay between attempts if retries set without retryDelay
+const defaultRetryDelay = 500 * time.Millisecond
+
+// retryOptions returns number of retries, 0 if not set, and delay between attempts from the options
+func retryOptions(opts url.Values) (retries int, delay time.Duration, err error) {
+	delay = defaultRetryDelay
+	if v := opts.Get("retries"); v != "" {
+		if retries, err = strconv.Atoi(v); err != nil {
+			return 0, 0, err
+		}
+		if retries < 0 {
+			return 0, 0, fmt.Errorf("negative retries %d", retries)
+		}
+	}
+	if v := opts.Get("retryDelay"); v != "" {
+		if delay, err = time.ParseDuration(v); err != nil {
+			return 0, 0, err
+		}
+	}
+	return retries, delay, nil
+}
+
+// retry calls send up to retries+1 times, until it returns a response without 5xx status, waiting delay before
+// the first retry and doubling it for each next one. No new attempt is made if it would start after the deadline
+// of the context, the result of the last attempt is returned with the number of attempts made.
+func retry(ctx context.Context, retries int, delay time.Duration, send func() (*http.Response, error)) (*http.Response, int, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := send()
+		if (err == nil && resp.StatusCode < 500) || attempt > retries {
+			return resp, attempt, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return resp, attempt, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close() // nolint
+		}
+		select {
+		case <-ctx.Done():
+			return nil, attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,115 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusRetries(t *testing.T) {
+	var calls, failFirst int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("retries"), "provider option is not sent")
+		assert.Empty(t, r.URL.Query().Get("retryDelay"), "provider option is not sent")
+		if atomic.AddInt32(&calls, 1) <= atomic.LoadInt32(&failFirst) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		url       string
+		failFirst int32
+		code      int
+		attempts  interface{}
+	}{
+		{"/", 0, 200, nil},
+		{"/", 2, 503, nil},
+		{"/?retries=3&retryDelay=10ms", 0, 200, 1},
+		{"/?retries=3&retryDelay=10ms", 2, 200, 3},
+		{"/?retries=3&retryDelay=10ms", 5, 503, 4},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			atomic.StoreInt32(&failFirst, tt.failFirst)
+			resp, err := p.Status(Request{Name: "web", URL: ts.URL + tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, resp.StatusCode)
+			assert.Equal(t, tt.attempts, resp.Body["attempts"])
+		})
+	}
+
+	_, err := p.Status(Request{Name: "web", URL: ts.URL + "/?retries=-1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http retries parse failed")
+}
+
+func TestHttpProvider_StatusRetriesTimeout(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: 300 * time.Millisecond}}
+	st := time.Now()
+	resp, err := p.Status(Request{Name: "web", URL: ts.URL + "/?retries=10&retryDelay=100ms"})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(st), 300*time.Millisecond, "retries stopped before the timeout")
+	assert.Equal(t, 502, resp.StatusCode)
+	assert.Equal(t, 2, resp.Body["attempts"], "third attempt would start after 100ms+200ms delays")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	ts.Close()
+	_, err = p.Status(Request{Name: "web", URL: ts.URL + "/?retries=2&retryDelay=10ms"})
+	require.Error(t, err, "all attempts failed to connect")
+}
+
+func TestHttpProvider_StatusRetriesTimeoutWithVersion(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			time.Sleep(500 * time.Millisecond) // longer than the time left for the retry
+		} else {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: 300 * time.Millisecond}}
+	st := time.Now()
+	_, err := p.Status(Request{Name: "web", URL: ts.URL + "/?retries=1&retryDelay=10ms&httpVersion=1.1"})
+	require.Error(t, err)
+	assert.Less(t, time.Since(st), 450*time.Millisecond, "retry limited by the time left, not the full timeout")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func Test_retryOptions(t *testing.T) {
+	retries, delay, err := retryOptions(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, retries)
+	assert.Equal(t, defaultRetryDelay, delay)
+
+	retries, delay, err = retryOptions(url.Values{"retries": {"3"}, "retryDelay": {"1s"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, retries)
+	assert.Equal(t, time.Second, delay)
+
+	_, _, err = retryOptions(url.Values{"retries": {"many"}})
+	require.Error(t, err)
+	_, _, err = retryOptions(url.Values{"retryDelay": {"soon"}})
+	require.Error(t, err)
+}
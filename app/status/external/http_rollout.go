@@ -0,0 +1,63 @@
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// defaultVersionPath is the json path of the version in the body if neither versionHeader nor versionPath set
+const defaultVersionPath = "version"
+
+// checkRolloutVersion reads the version served by the endpoint from the header, if set, or from the dot-separated
+// json path in the body, i.e. "build.version", and sets it as observed_version in the body. Status is failed if the
+// version doesn't match the expected one or can't be found. Status set to ok if not set already.
+func checkRolloutVersion(body map[string]interface{}, hdr http.Header, expected, header, path string) {
+	var observed, missing string
+	switch {
+	case header != "":
+		if observed = hdr.Get(header); observed == "" {
+			missing = "no version header " + header
+		}
+	default:
+		if path == "" {
+			path = defaultVersionPath
+		}
+		v, ok := jsonPathValue(body, path) // read before status is set, "status" can be the path
+		if !ok {
+			missing = "no version at " + path
+		}
+		if v != nil {
+			observed = fmt.Sprintf("%v", v)
+		}
+	}
+
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	if missing != "" {
+		setStatus(body, "failed", missing)
+		return
+	}
+
+	body["observed_version"] = observed
+	if observed != expected {
+		setStatus(body, "failed", fmt.Sprintf("version %s, expected %s", observed, expected))
+	}
+}
+
+// jsonPathValue returns value of the dot-separated path in decoded json, i.e. "build.version".
+// Returns false if any element of the path is missing or not an object.
+func jsonPathValue(data map[string]interface{}, path string) (interface{}, bool) {
+	var curr interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := curr.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if curr, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return curr, true
+}
@@ -0,0 +1,82 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("sa.version"), "provider option is not sent")
+		if v := r.URL.Query().Get("version"); v != "" { // param of the service, not the option
+			_, _ = w.Write([]byte(`{"version": "` + v + `"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/new":
+			w.Header().Set("X-App-Version", "1.2.3")
+			_, _ = w.Write([]byte(`{"version": "1.2.3", "build": {"version": "1.2.3", "number": 42}}`))
+		case "/stale":
+			w.Header().Set("X-App-Version", "1.2.2")
+			_, _ = w.Write([]byte(`{"version": "1.2.2", "build": {"version": "1.2.2", "number": 41}}`))
+		default:
+			_, _ = w.Write([]byte(`pong`))
+		}
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		path     string
+		observed interface{}
+		status   interface{}
+	}{
+		{"/new?version=1.2.3", "1.2.3", "ok"},
+		{"/stale?version=1.2.3", "1.2.2", "failed: version 1.2.2, expected 1.2.3"},
+		{"/new?version=1.2.3&versionHeader=X-App-Version", "1.2.3", "ok"},
+		{"/stale?version=1.2.3&versionHeader=X-App-Version", "1.2.2", "failed: version 1.2.2, expected 1.2.3"},
+		{"/new?version=1.2.3&versionPath=build.version", "1.2.3", "ok"},
+		{"/new?version=42&versionPath=build.number", "42", "ok"},
+		{"/stale?version=42&versionPath=build.number", "41", "failed: version 41, expected 42"},
+		{"/new?version=1.2.3&versionPath=build.tag", nil, "failed: no version at build.tag"},
+		{"/new?version=1.2.3&versionHeader=X-Build", nil, "failed: no version header X-Build"},
+		{"/text?version=1.2.3", nil, "failed: no version at version"},
+		{"/api?version=2&sa.version=2", "2", "ok"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "app", URL: ts.URL + tt.path})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.observed, resp.Body["observed_version"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+		})
+	}
+}
+
+func Test_jsonPathValue(t *testing.T) {
+	data := map[string]interface{}{"a": map[string]interface{}{"b": "c", "n": 1.5}, "s": "v"}
+	tbl := []struct {
+		path string
+		val  interface{}
+		ok   bool
+	}{
+		{"s", "v", true},
+		{"a.b", "c", true},
+		{"a.n", 1.5, true},
+		{"a.x", nil, false},
+		{"s.x", nil, false},
+		{"x", nil, false},
+	}
+	for _, tt := range tbl {
+		v, ok := jsonPathValue(data, tt.path)
+		assert.Equal(t, tt.ok, ok, tt.path)
+		assert.Equal(t, tt.val, v, tt.path)
+	}
+}
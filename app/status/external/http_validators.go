@@ -0,0 +1,55 @@
+package external
+
+import (
+	"net/http"
+)
+
+// cacheValidators keeps ETag and Last-Modified of the last response to detect changes between scrapes
+type cacheValidators struct {
+	etag         string
+	lastModified string
+}
+
+// value returns ETag if set, Last-Modified otherwise. ETag is preferred as it changes with content, not time
+func (v cacheValidators) value() string {
+	if v.etag != "" {
+		return v.etag
+	}
+	return v.lastModified
+}
+
+// checkValidators sets etag, last_modified and etag_changed in the body, comparing validators with the previous scrape.
+// etag_changed is not set on the first scrape. With expectChange "true" status is failed if validator didn't change,
+// with "false" if it changed. Empty expectChange only reports. Status set to ok if not set already.
+func (h *HTTPProvider) checkValidators(name string, body map[string]interface{}, hdr http.Header, expectChange string) {
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+
+	curr := cacheValidators{etag: hdr.Get("ETag"), lastModified: hdr.Get("Last-Modified")}
+	body["etag"], body["last_modified"] = curr.etag, curr.lastModified
+	if curr.value() == "" {
+		setStatus(body, "warn", "no etag or last-modified header")
+		return
+	}
+
+	h.validators.once.Do(func() {
+		h.validators.last = make(map[string]cacheValidators)
+	})
+	h.validators.lock.Lock()
+	prev, ok := h.validators.last[name]
+	h.validators.last[name] = curr
+	h.validators.lock.Unlock()
+	if !ok {
+		return // first scrape, nothing to compare with
+	}
+
+	changed := prev.value() != curr.value()
+	body["etag_changed"] = changed
+	switch {
+	case expectChange == "false" && changed:
+		setStatus(body, "failed", "changed from "+prev.value()+" to "+curr.value())
+	case expectChange == "true" && !changed:
+		setStatus(body, "failed", "not changed, still "+curr.value())
+	}
+}
@@ -0,0 +1,79 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusETag(t *testing.T) {
+	var version int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/churn":
+			w.Header().Set("ETag", `"v`+strconv.Itoa(int(atomic.AddInt32(&version, 1)))+`"`)
+		case "/stable":
+			w.Header().Set("ETag", `"v1"`)
+		case "/modified":
+			w.Header().Set("Last-Modified", "Wed, 14 Oct 2026 10:00:00 GMT")
+		}
+		_, _ = w.Write([]byte(`pong`))
+	}))
+	defer ts.Close()
+
+	tbl := []struct {
+		path   string
+		first  map[string]interface{}
+		second map[string]interface{}
+	}{
+		{"/churn?etag=true",
+			map[string]interface{}{"etag": `"v1"`, "last_modified": "", "status": "ok"},
+			map[string]interface{}{"etag": `"v2"`, "last_modified": "", "etag_changed": true, "status": "ok"}},
+		{"/churn?expectChange=false",
+			map[string]interface{}{"etag": `"v3"`, "last_modified": "", "status": "ok"},
+			map[string]interface{}{"etag": `"v4"`, "last_modified": "", "etag_changed": true,
+				"status": `failed: changed from "v3" to "v4"`}},
+		{"/churn?expectChange=true",
+			map[string]interface{}{"etag": `"v5"`, "last_modified": "", "status": "ok"},
+			map[string]interface{}{"etag": `"v6"`, "last_modified": "", "etag_changed": true, "status": "ok"}},
+		{"/stable?expectChange=false",
+			map[string]interface{}{"etag": `"v1"`, "last_modified": "", "status": "ok"},
+			map[string]interface{}{"etag": `"v1"`, "last_modified": "", "etag_changed": false, "status": "ok"}},
+		{"/stable?expectChange=true",
+			map[string]interface{}{"etag": `"v1"`, "last_modified": "", "status": "ok"},
+			map[string]interface{}{"etag": `"v1"`, "last_modified": "", "etag_changed": false,
+				"status": `failed: not changed, still "v1"`}},
+		{"/modified?expectChange=false",
+			map[string]interface{}{"etag": "", "last_modified": "Wed, 14 Oct 2026 10:00:00 GMT", "status": "ok"},
+			map[string]interface{}{"etag": "", "last_modified": "Wed, 14 Oct 2026 10:00:00 GMT", "etag_changed": false,
+				"status": "ok"}},
+		{"/none?etag=true",
+			map[string]interface{}{"etag": "", "last_modified": "", "status": "warn: no etag or last-modified header"},
+			map[string]interface{}{"etag": "", "last_modified": "", "status": "warn: no etag or last-modified header"}},
+	}
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	for _, tt := range tbl {
+		t.Run(tt.path, func(t *testing.T) {
+			req := Request{Name: tt.path, URL: ts.URL + tt.path}
+			resp, err := p.Status(req)
+			require.NoError(t, err)
+			delete(resp.Body, "text")
+			assert.Equal(t, tt.first, resp.Body, "first scrape")
+
+			resp, err = p.Status(req)
+			require.NoError(t, err)
+			delete(resp.Body, "text")
+			assert.Equal(t, tt.second, resp.Body, "second scrape")
+		})
+	}
+
+	_, err := p.Status(Request{Name: "bad", URL: ts.URL + "/stable?expectChange=maybe"})
+	require.EqualError(t, err, "http expectChange should be true or false: bad "+ts.URL+"/stable?expectChange=maybe")
+}
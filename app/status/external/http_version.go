@@ -0,0 +1,107 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// getWithVersion makes GET request with the given protocol version, "1.0" or "1.1", over a new connection.
+// The request is written directly as http.Transport always sends HTTP/1.1 (or HTTP/2). Redirects are not followed.
+// Dial, write and read of the response are limited by the deadline of the context.
+func (h *HTTPProvider) getWithVersion(ctx context.Context, target, version string) (*http.Response, error) {
+	if version != "1.0" && version != "1.1" {
+		return nil, fmt.Errorf("unsupported http version %q, should be 1.0 or 1.1", version)
+	}
+	uu, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	port := uu.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[uu.Scheme]
+	}
+	addr := net.JoinHostPort(uu.Hostname(), port)
+
+	var conn net.Conn
+	switch uu.Scheme {
+	case "http":
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	case "https":
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: uu.Hostname(), MinVersion: tls.VersionTLS12}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", uu.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// connection is closed after the response, so keep-alive of HTTP/1.1 is not used
+	_, err = fmt.Fprintf(conn, "GET %s HTTP/%s\r\nHost: %s\r\nUser-Agent: sys-agent\r\nConnection: close\r\n\r\n",
+		uu.RequestURI(), version, uu.Host)
+	if err != nil {
+		conn.Close() // nolint
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodGet, URL: uu})
+	if err != nil {
+		conn.Close() // nolint
+		return nil, err
+	}
+	resp.Body = &connBody{ReadCloser: resp.Body, conn: conn}
+	return resp, nil
+}
+
+// connBody closes the connection with the response body
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+// Close closes body and the underlying connection
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	if e := b.conn.Close(); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// checkHTTPVersion sets http_version in the body to the protocol of the response and sets failed status
+// if the forced version is not honored. HTTP/1.1 request expects HTTP/1.1 response. HTTP/1.0 request may be answered
+// with HTTP/1.1 status line (nginx does it), but the response can't use chunked transfer encoding unknown to 1.0 clients.
+func checkHTTPVersion(body map[string]interface{}, resp *http.Response, version string) {
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	body["http_version"] = resp.Proto
+
+	switch version {
+	case "1.0":
+		if resp.ProtoMajor != 1 {
+			setStatus(body, "failed", fmt.Sprintf("%s response to HTTP/1.0 request", resp.Proto))
+			return
+		}
+		for _, te := range resp.TransferEncoding {
+			if te == "chunked" {
+				setStatus(body, "failed", "chunked response to HTTP/1.0 request")
+				return
+			}
+		}
+	case "1.1":
+		if !resp.ProtoAtLeast(1, 1) || resp.ProtoMajor != 1 {
+			setStatus(body, "failed", fmt.Sprintf("%s response to HTTP/1.1 request", resp.Proto))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package external
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProvider_StatusWithVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.URL.Query().Get("httpVersion"), "option not passed to the service")
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		version string
+		body    map[string]interface{}
+	}{
+		{"1.0", map[string]interface{}{"text": "HTTP/1.0", "http_version": "HTTP/1.0", "status": "ok"}},
+		{"1.1", map[string]interface{}{"text": "HTTP/1.1", "http_version": "HTTP/1.1", "status": "ok"}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.version, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "legacy", URL: ts.URL + "/ping?httpVersion=" + tt.version})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.body, resp.Body)
+		})
+	}
+
+	_, err := p.Status(Request{Name: "legacy", URL: ts.URL + "/ping?httpVersion=2"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported http version "2"`)
+}
+
+func TestHTTPProvider_StatusWithVersionNotHonored(t *testing.T) {
+	tbl := []struct {
+		name     string
+		version  string
+		response string
+		proto    string
+		status   string
+	}{
+		{"1.1 status line to 1.0", "1.0", "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok", "HTTP/1.1", "ok"},
+		{"chunked to 1.0", "1.0", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n0\r\n\r\n",
+			"HTTP/1.1", "failed: chunked response to HTTP/1.0 request"},
+		{"1.0 to 1.1", "1.1", "HTTP/1.0 200 OK\r\nContent-Length: 2\r\n\r\nok", "HTTP/1.0",
+			"failed: HTTP/1.0 response to HTTP/1.1 request"},
+	}
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startRawHTTPServer(t, tt.response)
+			resp, err := p.Status(Request{Name: "legacy", URL: "http://" + addr + "/?httpVersion=" + tt.version})
+			require.NoError(t, err)
+			assert.Equal(t, "ok", resp.Body["text"])
+			assert.Equal(t, tt.proto, resp.Body["http_version"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+		})
+	}
+}
+
+// startRawHTTPServer starts tcp server responding to any request with the given raw response
+func startRawHTTPServer(t *testing.T, response string) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte(response))
+			}()
+		}
+	}()
+	return lis.Addr().String()
+}
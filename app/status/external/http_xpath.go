@@ -0,0 +1,326 @@
+package external
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xmlNode is an element of the parsed xml document
+type xmlNode struct {
+	name     string // local name, namespace prefix dropped
+	attrs    map[string]string
+	text     strings.Builder // string value, all text inside the element in document order
+	parent   *xmlNode
+	children []*xmlNode
+}
+
+// xpathStep is a location step of the xpath, i.e. "item[@id='a']" in "/health/item[@id='a']"
+type xpathStep struct {
+	descendant bool   // step follows "//"
+	name       string // element name or "*"
+	preds      []string
+}
+
+// checkXPath parses the body as xml, reads the value at the xpath and sets it as xpath_value. Status is failed
+// if the body is not xml, nothing matches the xpath, the value differs from the expected one, or, for xpath
+// with operator, is not a number matching the limit. Status set to ok if not set already.
+func checkXPath(body map[string]interface{}, data []byte, xpath, expected string) error {
+	expr, op, limit, err := splitXPathComparison(xpath)
+	if err != nil {
+		return err
+	}
+	if op != "" && expected != "" {
+		return fmt.Errorf("expected can't be used with comparison in %q", xpath)
+	}
+	steps, attr, err := parseXPath(expr)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := body["status"]; !ok {
+		body["status"] = "ok"
+	}
+	root, err := parseXML(data)
+	if err != nil {
+		setStatus(body, "failed", "body is not xml")
+		return nil
+	}
+	v, found := xpathValue(root, steps, attr)
+	if !found {
+		setStatus(body, "failed", "no value at "+xpath)
+		return nil
+	}
+	body["xpath_value"] = v
+
+	if op == "" {
+		if v != expected {
+			setStatus(body, "failed", fmt.Sprintf("value %s at %s, expected %s", v, xpath, expected))
+		}
+		return nil
+	}
+
+	num, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		setStatus(body, "failed", fmt.Sprintf("value %s at %s is not numeric", v, xpath))
+		return nil
+	}
+	passed := map[string]bool{"<": num < limit, "<=": num <= limit, ">": num > limit, ">=": num >= limit}[op]
+	if !passed {
+		setStatus(body, "failed", fmt.Sprintf("value %v at %s", num, xpath))
+	}
+	return nil
+}
+
+// splitXPathComparison splits xpath like "/health/queue<100" to expression, operator and limit.
+// Operators inside predicates and quotes are not comparisons of the value and ignored.
+func splitXPathComparison(xpath string) (expr, op string, limit float64, err error) {
+	depth, quote := 0, rune(0)
+	for i, c := range xpath {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+			continue
+		case c == '[':
+			depth++
+			continue
+		case c == ']':
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+		for _, o := range jsonPathOperators {
+			if strings.HasPrefix(xpath[i:], o) {
+				if limit, err = strconv.ParseFloat(strings.TrimSpace(xpath[i+len(o):]), 64); err != nil {
+					return "", "", 0, fmt.Errorf("invalid limit in %q: %w", xpath, err)
+				}
+				return strings.TrimSpace(xpath[:i]), o, limit, nil
+			}
+		}
+	}
+	return xpath, "", 0, nil
+}
+
+// parseXPath parses the subset of xpath, absolute or relative to the document, with "/" and "//" steps.
+// Step is the element name or "*", with optional predicates: position [2], attribute [@id] or [@id='a'],
+// and child text [status='ok']. The last step can be an attribute "@name" or "text()".
+// Namespace prefixes are dropped, elements are matched by local name.
+func parseXPath(xpath string) (steps []xpathStep, attr string, err error) {
+	rest := strings.TrimSpace(xpath)
+	if rest == "" {
+		return nil, "", fmt.Errorf("empty xpath")
+	}
+	for rest != "" {
+		descendant := false
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			descendant, rest = true, rest[2:]
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		}
+		end := xpathStepEnd(rest)
+		token := rest[:end]
+		rest = rest[end:]
+		if token == "" {
+			return nil, "", fmt.Errorf("empty step in %q", xpath)
+		}
+
+		if strings.HasPrefix(token, "@") || token == "text()" {
+			if rest != "" || descendant {
+				return nil, "", fmt.Errorf("%s should be the last step in %q", token, xpath)
+			}
+			if token == "@" {
+				return nil, "", fmt.Errorf("empty attribute in %q", xpath)
+			}
+			if strings.HasPrefix(token, "@") {
+				attr = localName(token[1:])
+			}
+			break
+		}
+
+		step := xpathStep{descendant: descendant}
+		name, preds, _ := strings.Cut(token, "[")
+		step.name = localName(name)
+		if preds != "" {
+			preds = "[" + preds
+			for preds != "" {
+				i := strings.Index(preds, "]")
+				if !strings.HasPrefix(preds, "[") || i < 0 {
+					return nil, "", fmt.Errorf("invalid predicate in %q", xpath)
+				}
+				step.preds = append(step.preds, strings.TrimSpace(preds[1:i]))
+				preds = preds[i+1:]
+			}
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, "", fmt.Errorf("no elements in %q", xpath)
+	}
+	return steps, attr, nil
+}
+
+// xpathStepEnd returns the end of the first step, the next "/" outside of predicates and quotes
+func xpathStepEnd(s string) int {
+	depth, quote := 0, rune(0)
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// localName drops namespace prefix, i.e. "soap:Body" to "Body"
+func localName(name string) string {
+	if _, local, ok := strings.Cut(name, ":"); ok {
+		return local
+	}
+	return name
+}
+
+// parseXML parses the document and returns the virtual root node with the document element as the only child
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil } // ascii-compatible only
+	root := &xmlNode{}
+	curr := root
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, attrs: map[string]string{}, parent: curr}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			curr.children = append(curr.children, n)
+			curr = n
+		case xml.EndElement:
+			curr = curr.parent
+		case xml.CharData:
+			for n := curr; n != root && n != nil; n = n.parent {
+				n.text.Write(t)
+			}
+		}
+	}
+	if len(root.children) != 1 {
+		return nil, fmt.Errorf("expected one document element, got %d", len(root.children))
+	}
+	return root, nil
+}
+
+// xpathValue returns the trimmed text, or the attribute if set, of the first node matched by steps
+func xpathValue(root *xmlNode, steps []xpathStep, attr string) (string, bool) {
+	nodes := []*xmlNode{root}
+	for _, step := range steps {
+		var next []*xmlNode
+		seen := map[*xmlNode]bool{}
+		for _, n := range nodes {
+			ctx := []*xmlNode{n}
+			if step.descendant {
+				ctx = descendantsOrSelf(n)
+			}
+			for _, c := range ctx {
+				for _, m := range matchXPathStep(c, step) {
+					if !seen[m] {
+						seen[m] = true
+						next = append(next, m)
+					}
+				}
+			}
+		}
+		if nodes = next; len(nodes) == 0 {
+			return "", false
+		}
+	}
+
+	for _, n := range nodes {
+		if attr == "" {
+			return strings.TrimSpace(n.text.String()), true
+		}
+		if v, ok := n.attrs[attr]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// matchXPathStep returns children of the node matching the step name and all its predicates
+func matchXPathStep(n *xmlNode, step xpathStep) []*xmlNode {
+	var res []*xmlNode
+	for _, c := range n.children {
+		if step.name == "*" || c.name == step.name {
+			res = append(res, c)
+		}
+	}
+	for _, pred := range step.preds {
+		if pos, err := strconv.Atoi(pred); err == nil {
+			if pos < 1 || pos > len(res) {
+				return nil
+			}
+			res = res[pos-1 : pos]
+			continue
+		}
+		var filtered []*xmlNode
+		for _, c := range res {
+			if matchXPathPredicate(c, pred) {
+				filtered = append(filtered, c)
+			}
+		}
+		res = filtered
+	}
+	return res
+}
+
+// matchXPathPredicate checks the node has the attribute, [@id] or [@id='a'], or the child with the text, [status='ok']
+func matchXPathPredicate(n *xmlNode, pred string) bool {
+	key, val, hasVal := strings.Cut(pred, "=")
+	key = strings.TrimSpace(key)
+	val = strings.Trim(strings.TrimSpace(val), `'"`)
+	if strings.HasPrefix(key, "@") {
+		v, ok := n.attrs[localName(key[1:])]
+		return ok && (!hasVal || v == val)
+	}
+	for _, c := range n.children {
+		if c.name == localName(key) && (!hasVal || strings.TrimSpace(c.text.String()) == val) {
+			return true
+		}
+	}
+	return false
+}
+
+// descendantsOrSelf returns the node and all its descendants in document order
+func descendantsOrSelf(n *xmlNode) []*xmlNode {
+	res := []*xmlNode{n}
+	for _, c := range n.children {
+		res = append(res, descendantsOrSelf(c)...)
+	}
+	return res
+}
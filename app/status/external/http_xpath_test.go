@@ -0,0 +1,116 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpProvider_StatusXPath(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("xpath"), "provider option is not sent")
+		assert.Empty(t, r.URL.Query().Get("expected"), "provider option is not sent")
+		w.Header().Set("Content-Type", "text/xml")
+		switch r.URL.Path {
+		case "/text":
+			_, _ = w.Write([]byte("db ok"))
+		case "/degraded":
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <health version="2">
+      <db status="down">primary</db>
+      <queue><size>250</size></queue>
+      <dep name="cache">ok</dep>
+      <dep name="search">down</dep>
+    </health>
+  </soap:Body>
+</soap:Envelope>`))
+		default:
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <health version="2">
+      <db status="ok">primary</db>
+      <queue><size>12</size></queue>
+      <dep name="cache">ok</dep>
+      <dep name="search">ok</dep>
+    </health>
+  </soap:Body>
+</soap:Envelope>`))
+		}
+	}))
+	defer ts.Close()
+
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	tbl := []struct {
+		xpath, expected string
+		path            string
+		value           interface{}
+		status          string
+	}{
+		{"/Envelope/Body/health/db/@status", "ok", "/health", "ok", "ok"},
+		{"/soap:Envelope/soap:Body/health/db", "primary", "/health", "primary", "ok"},
+		{"//health/@version", "2", "/health", "2", "ok"},
+		{"//dep[@name='search']", "ok", "/health", "ok", "ok"},
+		{"//dep[2]/text()", "ok", "/health", "ok", "ok"},
+		{"//health[db='primary']/queue/size", "12", "/health", "12", "ok"},
+		{"//*[@status]/@status", "ok", "/health", "ok", "ok"},
+		{"//db/@status", "ok", "/degraded", "down", "failed: value down at //db/@status, expected ok"},
+		{"//dep[@name='search']", "ok", "/degraded", "down", "failed: value down at //dep[@name='search'], expected ok"},
+		{"//queue/size<100", "", "/health", "12", "ok"},
+		{"//queue/size>=12", "", "/health", "12", "ok"},
+		{"//queue/size<100", "", "/degraded", "250", "failed: value 250 at //queue/size<100"},
+		{"//db>1", "", "/health", "primary", "failed: value primary at //db>1 is not numeric"},
+		{"//dep[@name='missing']", "ok", "/health", nil, "failed: no value at //dep[@name='missing']"},
+		{"//db/@missing", "ok", "/health", nil, "failed: no value at //db/@missing"},
+		{"//dep[3]", "ok", "/health", nil, "failed: no value at //dep[3]"},
+		{"/health/db", "ok", "/health", nil, "failed: no value at /health/db"},
+		{"//db", "ok", "/text", nil, "failed: body is not xml"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.path+tt.xpath, func(t *testing.T) {
+			u := ts.URL + tt.path + "?xpath=" + url.QueryEscape(tt.xpath)
+			if tt.expected != "" {
+				u += "&expected=" + url.QueryEscape(tt.expected)
+			}
+			resp, err := p.Status(Request{Name: "health", URL: u})
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.value, resp.Body["xpath_value"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+		})
+	}
+
+	_, err := p.Status(Request{Name: "health", URL: ts.URL + "/health?xpath=" + url.QueryEscape("//size<abc")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http xpath parse failed")
+
+	_, err = p.Status(Request{Name: "health", URL: ts.URL + "/health?xpath=//db&jsonpath=$.db"})
+	require.EqualError(t, err, "http jsonpath can't be used with xpath: health "+ts.URL+"/health?xpath=//db&jsonpath=$.db")
+}
+
+func Test_parseXPath(t *testing.T) {
+	steps, attr, err := parseXPath("/a//b[@id='x/y'][2]/@c")
+	require.NoError(t, err)
+	assert.Equal(t, []xpathStep{{name: "a"}, {descendant: true, name: "b", preds: []string{"@id='x/y'", "2"}}}, steps)
+	assert.Equal(t, "c", attr)
+
+	for _, xp := range []string{"", "/a//", "/a/@b/c", "/@b", "/a/b[1", "/a/@"} {
+		_, _, err = parseXPath(xp)
+		assert.Error(t, err, xp)
+	}
+
+	_, _, _, err = splitXPathComparison("//a[@n<1]")
+	require.NoError(t, err)
+	_, op, limit, err := splitXPathComparison("//a[@n<1]/b>=2.5")
+	require.NoError(t, err)
+	assert.Equal(t, ">=", op)
+	assert.InDelta(t, 2.5, limit, 0.001)
+}
@@ -0,0 +1,114 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaProvider is a status provider that checks kafka brokers and topic partitions from the cluster metadata
+type KafkaProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: kafka://broker:9092?topic=events&minPartitions=3. It connects to the broker,
+// fetches cluster metadata and reports the number of brokers in the cluster. With "topic" the number of topic partitions reported
+// as well, and partitions without leader as offline. Topics are never created by the check.
+// No responding brokers returned as error. Status is failed if the topic doesn't exist, has offline partitions,
+// or has fewer partitions than "minPartitions".
+func (k *KafkaProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("kafka url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if uu.Host == "" {
+		return nil, fmt.Errorf("kafka host is empty: %s %s", req.Name, req.URL)
+	}
+	minPartitions := 0
+	if v := uu.Query().Get("minPartitions"); v != "" {
+		if minPartitions, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("kafka minPartitions parse failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.ClientID = "sys-agent"
+	cfg.Net.DialTimeout, cfg.Net.ReadTimeout, cfg.Net.WriteTimeout = k.TimeOut, k.TimeOut, k.TimeOut
+
+	broker := sarama.NewBroker(uu.Host)
+	if err = broker.Open(cfg); err != nil {
+		return nil, fmt.Errorf("kafka connect failed: %s %s: %w", req.Name, uu.Host, err)
+	}
+	defer broker.Close() // nolint
+
+	topic := uu.Query().Get("topic")
+	metaReq := sarama.NewMetadataRequest(cfg.Version, []string{}) // empty list for brokers only, nil means all topics
+	if topic != "" {
+		metaReq.Topics = []string{topic}
+	}
+	metaReq.AllowAutoTopicCreation = false
+	meta, err := broker.GetMetadata(metaReq)
+	if err != nil {
+		return nil, fmt.Errorf("kafka metadata failed: %s %s: %w", req.Name, uu.Host, err)
+	}
+
+	body := map[string]interface{}{"brokers": len(meta.Brokers), "status": "ok"}
+	summary := fmt.Sprintf("kafka %d brokers", len(meta.Brokers))
+	if topic != "" {
+		body["topic"] = topic
+		if err = kafkaTopic(body, meta, topic, minPartitions); err != nil {
+			return nil, fmt.Errorf("kafka topic metadata failed: %s %s: %w", req.Name, uu.Host, err)
+		}
+		summary += fmt.Sprintf(", %s %d partitions", topic, body["partitions"])
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      fmt.Sprintf("%s (%s)", summary, level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// kafkaTopic sets partitions and offline_partitions (without leader) of the topic in the body, and failed status
+// if the topic doesn't exist, has offline partitions or fewer than minPartitions. Zero minPartitions disables the check.
+// Topic errors other than unknown topic are returned.
+func kafkaTopic(body map[string]interface{}, meta *sarama.MetadataResponse, topic string, minPartitions int) error {
+	var tm *sarama.TopicMetadata
+	for _, t := range meta.Topics {
+		if t.Name == topic {
+			tm = t
+		}
+	}
+	if tm == nil || errors.Is(tm.Err, sarama.ErrUnknownTopicOrPartition) {
+		body["partitions"] = 0
+		body["status"] = fmt.Sprintf("failed: topic %s not found", topic)
+		return nil
+	}
+	if !errors.Is(tm.Err, sarama.ErrNoError) && !errors.Is(tm.Err, sarama.ErrLeaderNotAvailable) {
+		return tm.Err
+	}
+
+	offline := 0
+	for _, p := range tm.Partitions {
+		if p.Leader < 0 || errors.Is(p.Err, sarama.ErrLeaderNotAvailable) {
+			offline++
+		}
+	}
+	body["partitions"], body["offline_partitions"] = len(tm.Partitions), offline
+
+	switch {
+	case minPartitions > 0 && len(tm.Partitions) < minPartitions:
+		body["status"] = fmt.Sprintf("failed: %d partitions, expected at least %d", len(tm.Partitions), minPartitions)
+	case offline > 0:
+		body["status"] = fmt.Sprintf("failed: %d partitions offline", offline)
+	}
+	return nil
+}
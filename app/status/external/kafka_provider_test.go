@@ -0,0 +1,90 @@
+package external
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaProvider_Status(t *testing.T) {
+	b1, b2 := sarama.NewMockBroker(t, 1), sarama.NewMockBroker(t, 2)
+	defer b1.Close()
+	defer b2.Close()
+
+	// "events" has 3 partitions, "orders" has partition 1 without leader
+	meta := &sarama.MetadataResponse{Version: sarama.NewMetadataRequest(sarama.NewConfig().Version, nil).Version,
+		ControllerID: 1}
+	meta.AddBroker(b1.Addr(), b1.BrokerID())
+	meta.AddBroker(b2.Addr(), b2.BrokerID())
+	replicas := []int32{1, 2}
+	for p := int32(0); p < 3; p++ {
+		meta.AddTopicPartition("events", p, 1+p%2, replicas, replicas, nil, sarama.ErrNoError)
+	}
+	meta.AddTopicPartition("orders", 0, 1, replicas, replicas, nil, sarama.ErrNoError)
+	meta.AddTopicPartition("orders", 1, -1, replicas, nil, replicas, sarama.ErrLeaderNotAvailable)
+	meta.AddTopic("missing", sarama.ErrUnknownTopicOrPartition)
+	b1.SetHandlerByMap(map[string]sarama.MockResponse{"MetadataRequest": sarama.NewMockWrapper(meta)})
+
+	p := KafkaProvider{TimeOut: time.Second}
+
+	{ // brokers only
+		resp, err := p.Status(Request{Name: "kafka", URL: "kafka://" + b1.Addr()})
+		require.NoError(t, err)
+		assert.Equal(t, "kafka", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"brokers": 2, "status": "ok"}, resp.Body)
+		assert.Equal(t, "kafka 2 brokers (ok)", resp.Summary)
+	}
+
+	{ // topic with enough partitions
+		resp, err := p.Status(Request{Name: "kafka", URL: "kafka://" + b1.Addr() + "?topic=events&minPartitions=3"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"brokers": 2, "topic": "events", "partitions": 3, "offline_partitions": 0,
+			"status": "ok"}, resp.Body)
+		assert.Equal(t, "kafka 2 brokers, events 3 partitions (ok)", resp.Summary)
+	}
+
+	{ // topic with too few partitions
+		resp, err := p.Status(Request{Name: "kafka", URL: "kafka://" + b1.Addr() + "?topic=events&minPartitions=6"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: 3 partitions, expected at least 6", resp.Body["status"])
+		assert.Equal(t, "kafka 2 brokers, events 3 partitions (failed)", resp.Summary)
+	}
+
+	{ // topic with offline partition
+		resp, err := p.Status(Request{Name: "kafka", URL: "kafka://" + b1.Addr() + "?topic=orders"})
+		require.NoError(t, err)
+		assert.Equal(t, 2, resp.Body["partitions"])
+		assert.Equal(t, 1, resp.Body["offline_partitions"])
+		assert.Equal(t, "failed: 1 partitions offline", resp.Body["status"])
+	}
+
+	{ // missing topic
+		resp, err := p.Status(Request{Name: "kafka", URL: "kafka://" + b1.Addr() + "?topic=missing"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, resp.Body["partitions"])
+		assert.Equal(t, "failed: topic missing not found", resp.Body["status"])
+		assert.Equal(t, "kafka 2 brokers, missing 0 partitions (failed)", resp.Summary)
+	}
+
+	{ // no brokers
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		closed := lis.Addr().String()
+		require.NoError(t, lis.Close())
+		_, err = p.Status(Request{Name: "kafka", URL: "kafka://" + closed})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "kafka metadata failed: kafka "+closed)
+	}
+
+	_, err := p.Status(Request{Name: "kafka", URL: "kafka://" + b1.Addr() + "?minPartitions=many"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "kafka minPartitions parse failed")
+
+	_, err = p.Status(Request{Name: "kafka", URL: "kafka://?topic=events"})
+	require.EqualError(t, err, "kafka host is empty: kafka kafka://?topic=events")
+}
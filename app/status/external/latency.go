@@ -0,0 +1,43 @@
+package external
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	latencyAlpha     = 0.2  // ewma smoothing factor, weight of the latest sample
+	latencyWarmup    = 5    // number of samples collected before deviation checked
+	latencyMinStdDev = 0.05 // minimal stddev as a fraction of the mean, prevents alerts on jitter of stable latency
+)
+
+// latencyStats keeps exponentially weighted moving average of response time and its variance
+type latencyStats struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+// add checks the sample against the baseline and updates it. Returns "ok" or "warn: reason" if the sample
+// deviates from the mean by more than deviation*stddev. Anomalies are added to the baseline as well,
+// so a persistent latency change becomes the new normal.
+func (l *latencyStats) add(rt int64, deviation float64) string {
+	val := float64(rt)
+	if l.samples == 0 {
+		l.mean, l.samples = val, 1
+		return "ok"
+	}
+
+	status := "ok"
+	stdDev := math.Max(math.Sqrt(l.variance), math.Max(latencyMinStdDev*l.mean, 1))
+	if l.samples >= latencyWarmup && math.Abs(val-l.mean) > deviation*stdDev {
+		status = fmt.Sprintf("warn: response time %dms, baseline %.0fms, stddev %.1fms", rt, l.mean, stdDev)
+	}
+
+	diff := val - l.mean
+	incr := latencyAlpha * diff
+	l.mean += incr
+	l.variance = (1 - latencyAlpha) * (l.variance + diff*incr)
+	l.samples++
+	return status
+}
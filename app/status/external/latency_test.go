@@ -0,0 +1,38 @@
+package external
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyStats_add(t *testing.T) {
+	series := []int64{100, 102, 98, 101, 99, 100, 103, 97, 100, 400, 101, 99}
+	l := latencyStats{}
+	warns := []int{}
+	for i, rt := range series {
+		if st := l.add(rt, 3); st != "ok" {
+			assert.True(t, strings.HasPrefix(st, "warn: response time 400ms, baseline 100ms, stddev "), st)
+			warns = append(warns, i)
+		}
+	}
+	assert.Equal(t, []int{9}, warns, "only the spike flagged")
+	assert.Equal(t, len(series), l.samples)
+}
+
+func TestLatencyStats_addWarmup(t *testing.T) {
+	l := latencyStats{}
+	for _, rt := range []int64{10, 500, 10, 10} {
+		assert.Equal(t, "ok", l.add(rt, 3), "not checked before warmup")
+	}
+}
+
+func TestLatencyStats_addStable(t *testing.T) {
+	l := latencyStats{}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "ok", l.add(50, 3))
+	}
+	assert.Equal(t, "ok", l.add(52, 3), "small deviation of stable latency is fine")
+	assert.Equal(t, "warn: response time 60ms, baseline 50ms, stddev 2.5ms", l.add(60, 3))
+}
@@ -3,7 +3,10 @@ package external
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -16,15 +19,33 @@ type MysqlProvider struct {
 	TimeOut time.Duration
 }
 
-// Status returns status of mysql, checks if connection established
+// mysqlOptions lists query params used by the provider itself, removed from dsn before connecting
+var mysqlOptions = []string{"countTable", "minRows", "maxRows"}
+
+// tableNameRe matches allowed table names for row count, optionally with database, i.e. etl.sentinel
+var tableNameRe = regexp.MustCompile(`^[A-Za-z0-9_$]+(\.[A-Za-z0-9_$]+)?$`)
+
+// Status returns status of mysql, checks if connection established.
+// With "countTable" query param it runs "SELECT count(*)" for the table and sets failed status if the count is
+// out of [minRows,maxRows] range. minRows is 1 by default, maxRows 0 means no upper limit.
 func (m *MysqlProvider) Status(req Request) (*Response, error) {
 	st := time.Now()
 	log.Println("mysql provider for ", req.URL)
 	ctx, cancel := context.WithTimeout(context.Background(), m.TimeOut)
 	defer cancel()
 
+	u, opts, err := splitDSNOptions(strings.TrimPrefix(req.URL, "mysql://"), mysqlOptions)
+	if err != nil {
+		return nil, fmt.Errorf("mysql dsn parse failed: %s: %w", req.Name, err)
+	}
+	var count *rowCount
+	if table := opts.Get("countTable"); table != "" {
+		if count, err = newRowCount(table, opts.Get("minRows"), opts.Get("maxRows")); err != nil {
+			return nil, fmt.Errorf("mysql row count params failed: %s: %w", req.Name, err)
+		}
+	}
+
 	// Connect to mysql
-	u := strings.TrimPrefix(req.URL, "mysql://")
 	db, err := sql.Open("mysql", u)
 	if err != nil {
 		log.Fatal(err)
@@ -45,19 +66,105 @@ func (m *MysqlProvider) Status(req Request) (*Response, error) {
 			StatusCode:   200,
 			Body:         map[string]interface{}{"status": "error", "seconds_behind_master": -1},
 			ResponseTime: time.Since(st).Milliseconds(),
+			Summary:      "mysql replication lag unknown (failed)",
 		}
 		return &result, nil
 	}
 
+	body := map[string]interface{}{"status": "ok", "seconds_behind_master": secondsBehindMaster}
+	if count != nil {
+		count.check(ctx, db, body)
+	}
+
 	result := Response{
 		Name:         req.Name,
 		StatusCode:   200,
-		Body:         map[string]interface{}{"status": "ok", "seconds_behind_master": secondsBehindMaster},
+		Body:         body,
 		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      count.summary(body),
 	}
 	return &result, nil
 }
 
+// rowCount is a row count check for a table
+type rowCount struct {
+	table    string
+	min, max int
+}
+
+// summary returns one-line summary with replication lag and row count, if checked. Works for nil rowCount.
+func (r *rowCount) summary(body map[string]interface{}) string {
+	res := fmt.Sprintf("mysql replication lag %ds", body["seconds_behind_master"])
+	if rows, ok := body["row_count"]; ok && r != nil {
+		res += fmt.Sprintf(", %s %d rows", r.table, rows)
+	}
+	return fmt.Sprintf("%s (%s)", res, level(body["status"].(string)))
+}
+
+// newRowCount makes row count check from query params, min defaults to 1
+func newRowCount(table, minRows, maxRows string) (*rowCount, error) {
+	if !tableNameRe.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	res := rowCount{table: table, min: 1}
+	var err error
+	if minRows != "" {
+		if res.min, err = strconv.Atoi(minRows); err != nil {
+			return nil, fmt.Errorf("invalid minRows: %w", err)
+		}
+	}
+	if maxRows != "" {
+		if res.max, err = strconv.Atoi(maxRows); err != nil {
+			return nil, fmt.Errorf("invalid maxRows: %w", err)
+		}
+	}
+	return &res, nil
+}
+
+// check runs count query and sets row_count and status in the body. Query error reported as failed status.
+func (r *rowCount) check(ctx context.Context, db *sql.DB, body map[string]interface{}) {
+	quoted := "`" + strings.ReplaceAll(r.table, ".", "`.`") + "`"
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM "+quoted).Scan(&count); err != nil { //nolint:gosec // name validated
+		body["status"] = fmt.Sprintf("failed: count %s: %v", r.table, err)
+		return
+	}
+	body["row_count"] = count
+	switch {
+	case count < r.min:
+		body["status"] = fmt.Sprintf("failed: %s has %d rows, expected at least %d", r.table, count, r.min)
+	case r.max > 0 && count > r.max:
+		body["status"] = fmt.Sprintf("failed: %s has %d rows, expected at most %d", r.table, count, r.max)
+	}
+}
+
+// splitDSNOptions extracts provider options from the dsn params and returns dsn without them.
+// Unlike urls, dsn like "user:pass@tcp(host:3306)/db?parseTime=true" can't be parsed with url.Parse.
+func splitDSNOptions(dsn string, keys []string) (string, url.Values, error) {
+	base, rawQuery, found := strings.Cut(dsn, "?")
+	if !found {
+		return dsn, url.Values{}, nil
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, err
+	}
+	opts := url.Values{}
+	for _, k := range keys {
+		if vals, ok := query[k]; ok {
+			opts[k] = vals
+			query.Del(k)
+		}
+	}
+	if len(opts) == 0 {
+		return dsn, opts, nil
+	}
+	if len(query) == 0 {
+		return base, opts, nil
+	}
+	return base + "?" + query.Encode(), opts, nil
+}
+
 func getSecondsBehindMaster(db *sql.DB) (int, error) {
 	var secondsBehindMaster int
 
@@ -0,0 +1,100 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowCount_check(t *testing.T) {
+	tbl := []struct {
+		count  int
+		min    int
+		max    int
+		status string
+	}{
+		{5, 1, 0, "ok"},
+		{0, 1, 0, "failed: etl.sentinel has 0 rows, expected at least 1"},
+		{50, 10, 100, "ok"},
+		{5, 10, 100, "failed: etl.sentinel has 5 rows, expected at least 10"},
+		{500, 10, 100, "failed: etl.sentinel has 500 rows, expected at most 100"},
+	}
+
+	for _, tt := range tbl {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		mock.ExpectQuery("SELECT count\\(\\*\\) FROM `etl`.`sentinel`").
+			WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(tt.count))
+
+		body := map[string]interface{}{"status": "ok"}
+		r := rowCount{table: "etl.sentinel", min: tt.min, max: tt.max}
+		r.check(context.Background(), db, body)
+		assert.Equal(t, map[string]interface{}{"status": tt.status, "row_count": tt.count}, body)
+		body["seconds_behind_master"] = 2
+		assert.Equal(t, fmt.Sprintf("mysql replication lag 2s, etl.sentinel %d rows (%s)", tt.count, level(tt.status)),
+			r.summary(body))
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("SELECT count").WillReturnError(errors.New("table doesn't exist"))
+	body := map[string]interface{}{"status": "ok"}
+	r := rowCount{table: "sentinel", min: 1}
+	r.check(context.Background(), db, body)
+	assert.Equal(t, map[string]interface{}{"status": "failed: count sentinel: table doesn't exist"}, body)
+
+	var noCount *rowCount
+	assert.Equal(t, "mysql replication lag 0s (ok)",
+		noCount.summary(map[string]interface{}{"status": "ok", "seconds_behind_master": 0}))
+}
+
+func Test_newRowCount(t *testing.T) {
+	r, err := newRowCount("etl.sentinel", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, rowCount{table: "etl.sentinel", min: 1}, *r, "min defaults to 1")
+
+	r, err = newRowCount("sentinel", "0", "100")
+	require.NoError(t, err)
+	assert.Equal(t, rowCount{table: "sentinel", min: 0, max: 100}, *r)
+
+	_, err = newRowCount("sentinel;drop table users", "", "")
+	require.EqualError(t, err, `invalid table name "sentinel;drop table users"`)
+
+	_, err = newRowCount("sentinel", "one", "")
+	require.EqualError(t, err, `invalid minRows: strconv.Atoi: parsing "one": invalid syntax`)
+
+	_, err = newRowCount("sentinel", "", "ten")
+	require.EqualError(t, err, `invalid maxRows: strconv.Atoi: parsing "ten": invalid syntax`)
+}
+
+func Test_splitDSNOptions(t *testing.T) {
+	tbl := []struct {
+		dsn, res string
+		opts     map[string]string
+	}{
+		{"user:pass@tcp(localhost:3306)/db", "user:pass@tcp(localhost:3306)/db", map[string]string{}},
+		{"user:pass@tcp(localhost:3306)/db?parseTime=true", "user:pass@tcp(localhost:3306)/db?parseTime=true",
+			map[string]string{}},
+		{"user:pass@tcp(localhost:3306)/db?countTable=sentinel&minRows=5", "user:pass@tcp(localhost:3306)/db",
+			map[string]string{"countTable": "sentinel", "minRows": "5"}},
+		{"user:pass@tcp(localhost:3306)/db?parseTime=true&countTable=sentinel",
+			"user:pass@tcp(localhost:3306)/db?parseTime=true", map[string]string{"countTable": "sentinel"}},
+	}
+	for _, tt := range tbl {
+		res, opts, err := splitDSNOptions(tt.dsn, mysqlOptions)
+		require.NoError(t, err)
+		assert.Equal(t, tt.res, res)
+		assert.Equal(t, len(tt.opts), len(opts))
+		for k, v := range tt.opts {
+			assert.Equal(t, v, opts.Get(k))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSProvider_Status(t *testing.T) {
+	addr := startFakeNATS(t)
+	p := NATSProvider{TimeOut: 500 * time.Millisecond}
+
+	{ // ping only
+		resp, err := p.Status(Request{Name: "bus", URL: "nats://" + addr})
+		require.NoError(t, err)
+		assert.Equal(t, "bus", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "NDJWE4SOUJOJT2D6KFCF3XBM", resp.Body["server_id"])
+		assert.Equal(t, "nats-1", resp.Body["server_name"])
+		assert.Equal(t, "2.10.7", resp.Body["version"])
+		assert.Equal(t, int64(1048576), resp.Body["max_payload"])
+		assert.Equal(t, false, resp.Body["tls"])
+		assert.Contains(t, resp.Body, "rtt_ms")
+		assert.NotContains(t, resp.Body, "subject")
+		assert.True(t, strings.HasPrefix(resp.Summary, "nats 2.10.7, rtt "), resp.Summary)
+		assert.True(t, strings.HasSuffix(resp.Summary, "ms (ok)"), resp.Summary)
+	}
+
+	{ // request with reply
+		resp, err := p.Status(Request{Name: "bus", URL: "nats://user:passwd@" + addr + "?subject=health.ping"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "health.ping", resp.Body["subject"])
+		assert.Contains(t, resp.Body, "request_rtt_ms")
+		assert.Contains(t, resp.Summary, ", health.ping reply in ")
+	}
+
+	{ // no responders
+		resp, err := p.Status(Request{Name: "bus", URL: "nats://token@" + addr + "?subject=nobody"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: no responders on nobody", resp.Body["status"])
+		assert.NotContains(t, resp.Body, "request_rtt_ms")
+		assert.True(t, strings.HasSuffix(resp.Summary, "(failed)"), resp.Summary)
+	}
+
+	{ // responder doesn't reply in time
+		st := time.Now()
+		resp, err := p.Status(Request{Name: "bus", URL: "nats://" + addr + "?subject=slow"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: no reply on slow in 500ms", resp.Body["status"])
+		assert.Less(t, time.Since(st), time.Second)
+	}
+
+	_, err := p.Status(Request{Name: "bus", URL: "nats://user:wrong@" + addr})
+	require.EqualError(t, err, "nats ping failed: bus "+addr+": server error: 'Authorization Violation'")
+
+	_, err = p.Status(Request{Name: "bus", URL: "nats://?subject=x"})
+	require.EqualError(t, err, "nats host is empty: bus nats://?subject=x")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() { // accepts connection and sends nothing
+		conn, e := lis.Accept()
+		if e != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(time.Second)
+	}()
+	st := time.Now()
+	_, err = p.Status(Request{Name: "bus", URL: "nats://" + lis.Addr().String()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nats info failed")
+	assert.Less(t, time.Since(st), time.Second)
+}
+
+// startFakeNATS starts nats server with the subset of protocol used by the provider.
+// Allowed credentials are user:passwd, token or none, "health.ping" subject replied, "slow" subject never replied,
+// other subjects have no responders.
+func startFakeNATS(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	handle := func(conn net.Conn) {
+		defer conn.Close()
+		_, _ = fmt.Fprintf(conn, "INFO {\"server_id\":\"NDJWE4SOUJOJT2D6KFCF3XBM\",\"server_name\":\"nats-1\","+
+			"\"version\":\"2.10.7\",\"proto\":1,\"headers\":true,\"max_payload\":1048576,\"host\":\"0.0.0.0\","+
+			"\"port\":%d}\r\n", lis.Addr().(*net.TCPAddr).Port)
+		rd := bufio.NewReader(conn)
+		subs := map[string]string{} // sid -> inbox
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "CONNECT":
+				opts := map[string]interface{}{}
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &opts)
+				user, passwd, token := opts["user"], opts["pass"], opts["auth_token"]
+				if (user != nil || passwd != nil) && (user != "user" || passwd != "passwd") || token != nil && token != "token" {
+					_, _ = conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+					return
+				}
+				if opts["verbose"] != false || opts["no_responders"] != true {
+					_, _ = conn.Write([]byte("-ERR 'Unexpected Connect Options'\r\n"))
+					return
+				}
+			case "PING":
+				_, _ = conn.Write([]byte("PONG\r\n"))
+			case "SUB":
+				subs[fields[2]] = fields[1]
+			case "PUB":
+				_, _ = rd.ReadString('\n') // empty payload
+				subject, reply := fields[1], fields[2]
+				sid := ""
+				for k, v := range subs {
+					if v == reply {
+						sid = k
+					}
+				}
+				switch subject {
+				case "health.ping":
+					_, _ = fmt.Fprintf(conn, "MSG %s %s 4\r\npong\r\n", reply, sid)
+				case "slow":
+				default:
+					hdr := "NATS/1.0 503\r\n\r\n"
+					_, _ = fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", reply, sid, len(hdr), len(hdr), hdr)
+				}
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return lis.Addr().String()
+}
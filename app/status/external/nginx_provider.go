@@ -43,6 +43,7 @@ func (n *NginxProvider) Status(req Request) (*Response, error) {
 	result.ResponseTime = time.Since(st).Milliseconds()
 
 	if resp.StatusCode != 200 {
+		result.Summary = fmt.Sprintf("nginx status %d (failed)", resp.StatusCode)
 		return result, nil
 	}
 
@@ -51,6 +52,7 @@ func (n *NginxProvider) Status(req Request) (*Response, error) {
 		return nil, fmt.Errorf("failed to parse nginx response for %s: %w", req.URL, err)
 	}
 	result.Body = ngStats
+	result.Summary = fmt.Sprintf("nginx %d active connections (ok)", ngStats["active_connections"])
 	return result, nil
 }
 
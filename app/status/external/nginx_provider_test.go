@@ -31,6 +31,18 @@ func TestNginxProvider_Status(t *testing.T) {
 	exp := map[string]interface{}{"accepts": 1377590, "active_connections": 125, "change_handled": 1377590, "handled": 1377590,
 		"reading": 2, "requests": 1873302, "waiting": 10, "writing": 115}
 	assert.EqualValues(t, exp, res.Body)
+	assert.Equal(t, "nginx 125 active connections (ok)", res.Summary)
+}
+
+func TestNginxProvider_StatusNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	provider := NginxProvider{TimeOut: time.Second}
+	res, err := provider.Status(Request{Name: "nginx-test", URL: ts.URL + "/nginx_status"})
+	require.NoError(t, err)
+	assert.Equal(t, 404, res.StatusCode)
+	assert.Equal(t, "nginx status 404 (failed)", res.Summary)
 }
 
 func TestNginxProvider_StatusFailedTooShort(t *testing.T) {
@@ -0,0 +1,136 @@
+package external
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NomadProvider is a status provider that checks allocations of a nomad job
+type NomadProvider struct {
+	TimeOut time.Duration
+}
+
+// nomadGroup is desired and running allocations of a task group
+type nomadGroup struct {
+	Desired int `json:"desired"`
+	Running int `json:"running"`
+}
+
+// Status url looks like: nomad://token@example.com:4646?job=web. It gets the job and its allocation summary from
+// nomad api and compares running allocations with the desired count of each task group. Port 4646 used unless set
+// explicitly. ACL token is optional and sent as X-Nomad-Token, "namespace" query param sets job's namespace and
+// "tls=true" switches to https. Status is failed if any group runs less allocations than desired.
+func (n *NomadProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("nomad url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	job := uu.Query().Get("job")
+	if uu.Hostname() == "" || job == "" {
+		return nil, fmt.Errorf("nomad host or job is empty: %s %s", req.Name, req.URL)
+	}
+
+	host := uu.Host
+	if uu.Port() == "" {
+		host = net.JoinHostPort(uu.Hostname(), "4646")
+	}
+	base := url.URL{Scheme: "http", Host: host, Path: "/v1/job/" + url.PathEscape(job)}
+	if uu.Query().Get("tls") == "true" {
+		base.Scheme = "https"
+	}
+	if ns := uu.Query().Get("namespace"); ns != "" {
+		base.RawQuery = url.Values{"namespace": []string{ns}}.Encode()
+	}
+	token := ""
+	if uu.User != nil {
+		token = uu.User.Username()
+	}
+
+	var jobResp struct {
+		Status     string `json:"Status"`
+		TaskGroups []struct {
+			Name  string `json:"Name"`
+			Count int    `json:"Count"`
+		} `json:"TaskGroups"`
+	}
+	if err = n.get(base, token, &jobResp); err != nil {
+		return nil, fmt.Errorf("nomad job request failed: %s %s: %w", req.Name, job, err)
+	}
+
+	summaryURL := base
+	summaryURL.Path += "/summary"
+	var summaryResp struct {
+		Summary map[string]struct {
+			Running int `json:"Running"`
+		} `json:"Summary"`
+	}
+	if err = n.get(summaryURL, token, &summaryResp); err != nil {
+		return nil, fmt.Errorf("nomad job summary request failed: %s %s: %w", req.Name, job, err)
+	}
+
+	groups := map[string]nomadGroup{}
+	desired, running := 0, 0
+	short := []string{}
+	for _, tg := range jobResp.TaskGroups {
+		g := nomadGroup{Desired: tg.Count, Running: summaryResp.Summary[tg.Name].Running}
+		groups[tg.Name] = g
+		desired += g.Desired
+		running += g.Running
+		if g.Running < g.Desired {
+			short = append(short, fmt.Sprintf("%s %d/%d", tg.Name, g.Running, g.Desired))
+		}
+	}
+	sort.Strings(short)
+
+	body := map[string]interface{}{
+		"job":        job,
+		"job_status": jobResp.Status,
+		"desired":    desired,
+		"running":    running,
+		"groups":     groups,
+		"status":     "ok",
+	}
+	if len(short) > 0 {
+		body["status"] = "failed: running less than desired, " + strings.Join(short, ", ")
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("nomad job %s: %d of %d running (%s)", job, running, desired,
+			level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// get makes nomad api request and decodes json response to v
+func (n *NomadProvider) get(target url.URL, token string, v interface{}) error {
+	httpReq, err := http.NewRequest(http.MethodGet, target.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		httpReq.Header.Set("X-Nomad-Token", token)
+	}
+	client := http.Client{Timeout: n.TimeOut}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
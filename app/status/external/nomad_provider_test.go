@@ -0,0 +1,93 @@
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNomadProvider_Status(t *testing.T) {
+	jobData, err := os.ReadFile("testdata/nomad_job.json")
+	require.NoError(t, err)
+	summaryData, err := os.ReadFile("testdata/nomad_job_summary.json")
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Nomad-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v1/job/web" && r.URL.Query().Get("namespace") == "prod":
+			_, _ = w.Write(jobData)
+		case r.URL.Path == "/v1/job/web/summary" && r.URL.Query().Get("namespace") == "prod":
+			_, _ = w.Write(summaryData)
+		case r.URL.Path == "/v1/job/batch":
+			_, _ = w.Write([]byte(`{"Status":"running","TaskGroups":[{"Name":"run","Count":1}]}`))
+		case r.URL.Path == "/v1/job/batch/summary":
+			_, _ = w.Write([]byte(`{"Summary":{"run":{"Running":1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	p := NomadProvider{TimeOut: time.Second}
+
+	{ // group running less than desired
+		resp, err := p.Status(Request{Name: "web", URL: "nomad://secret@" + host + "?job=web&namespace=prod"})
+		require.NoError(t, err)
+		assert.Equal(t, "web", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{
+			"job":        "web",
+			"job_status": "running",
+			"desired":    5,
+			"running":    4,
+			"groups":     map[string]nomadGroup{"api": {Desired: 3, Running: 2}, "worker": {Desired: 2, Running: 2}},
+			"status":     "failed: running less than desired, api 2/3",
+		}, resp.Body)
+		assert.Equal(t, "nomad job web: 4 of 5 running (failed)", resp.Summary)
+	}
+
+	{ // all running
+		resp, err := p.Status(Request{Name: "batch", URL: "nomad://secret@" + host + "?job=batch"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "nomad job batch: 1 of 1 running (ok)", resp.Summary)
+	}
+
+	{ // no token
+		_, err := p.Status(Request{Name: "web", URL: "nomad://" + host + "?job=web&namespace=prod"})
+		require.EqualError(t, err, "nomad job request failed: web web: 403 Forbidden")
+	}
+
+	{ // unknown job
+		_, err := p.Status(Request{Name: "web", URL: "nomad://secret@" + host + "?job=web"})
+		require.EqualError(t, err, "nomad job request failed: web web: 404 Not Found")
+	}
+
+	{ // no job
+		_, err := p.Status(Request{Name: "web", URL: "nomad://" + host})
+		require.EqualError(t, err, "nomad host or job is empty: web nomad://"+host)
+	}
+}
+
+func TestNomadProvider_StatusTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	p := NomadProvider{TimeOut: 50 * time.Millisecond}
+	_, err := p.Status(Request{Name: "web", URL: "nomad://" + strings.TrimPrefix(ts.URL, "http://") + "?job=web"})
+	require.Error(t, err)
+}
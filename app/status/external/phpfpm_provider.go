@@ -0,0 +1,143 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PHPFPMProvider is a status provider that uses php-fpm status page
+type PHPFPMProvider struct {
+	TimeOut time.Duration
+
+	maxChildren struct {
+		last map[string]int // "max children reached" counter by request name
+		once sync.Once
+		lock sync.Mutex
+	}
+}
+
+// phpfpmStatus is a part of php-fpm status page json
+type phpfpmStatus struct {
+	Pool               string `json:"pool"`
+	ListenQueue        int    `json:"listen queue"`
+	IdleProcesses      int    `json:"idle processes"`
+	ActiveProcesses    int    `json:"active processes"`
+	TotalProcesses     int    `json:"total processes"`
+	MaxChildrenReached int    `json:"max children reached"`
+}
+
+// Status url looks like: phpfpm://example.com/status for status page served over http, "tls=true" switches to https.
+// With "fcgi=true" the status is requested directly from fastcgi socket, phpfpm://127.0.0.1:9000/status?fcgi=true,
+// or unix socket phpfpm:///run/php/fpm.sock?fcgi=true&path=/status, the path should match pm.status_path of the pool.
+// Status is warn if listen queue is not empty or max children reached since the previous check, and failed if
+// listen queue is longer than optional "maxQueue".
+func (p *PHPFPMProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("phpfpm url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	maxQueue := -1
+	if v := uu.Query().Get("maxQueue"); v != "" {
+		if maxQueue, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("phpfpm maxQueue parse failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	}
+
+	code, data, err := p.fetch(uu)
+	if err != nil {
+		return nil, fmt.Errorf("phpfpm request failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	result := &Response{Name: req.Name, StatusCode: code}
+	if code != http.StatusOK {
+		result.ResponseTime = time.Since(st).Milliseconds()
+		result.Summary = fmt.Sprintf("phpfpm status %d (failed)", code)
+		return result, nil
+	}
+
+	var fpm phpfpmStatus
+	if err = json.Unmarshal(data, &fpm); err != nil {
+		return nil, fmt.Errorf("phpfpm response parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
+	body := map[string]interface{}{
+		"pool":                 fpm.Pool,
+		"active_processes":     fpm.ActiveProcesses,
+		"idle_processes":       fpm.IdleProcesses,
+		"total_processes":      fpm.TotalProcesses,
+		"listen_queue":         fpm.ListenQueue,
+		"max_children_reached": fpm.MaxChildrenReached,
+		"status":               "ok",
+	}
+	switch {
+	case maxQueue >= 0 && fpm.ListenQueue > maxQueue:
+		setStatus(body, "failed", fmt.Sprintf("listen queue %d, expected at most %d", fpm.ListenQueue, maxQueue))
+	case fpm.ListenQueue > 0:
+		setStatus(body, "warn", fmt.Sprintf("listen queue %d", fpm.ListenQueue))
+	}
+	p.checkMaxChildren(req.Name, body, fpm.MaxChildrenReached)
+
+	result.Body = body
+	result.ResponseTime = time.Since(st).Milliseconds()
+	result.Summary = fmt.Sprintf("phpfpm %s %d active, %d idle, queue %d (%s)", fpm.Pool, fpm.ActiveProcesses,
+		fpm.IdleProcesses, fpm.ListenQueue, level(body["status"].(string)))
+	return result, nil
+}
+
+// fetch gets status page json over http or fastcgi and returns status code with the body
+func (p *PHPFPMProvider) fetch(uu *url.URL) (int, []byte, error) {
+	if uu.Query().Get("fcgi") == "true" {
+		if uu.Host == "" { // unix socket, path of the url is the socket
+			return fcgiGet("unix", uu.Path, pathOrDefault(uu.Query().Get("path")), "json", p.TimeOut)
+		}
+		return fcgiGet("tcp", uu.Host, pathOrDefault(uu.Path), "json", p.TimeOut)
+	}
+
+	if uu.Host == "" {
+		return 0, nil, fmt.Errorf("host is empty")
+	}
+	target := url.URL{Scheme: "http", Host: uu.Host, Path: pathOrDefault(uu.Path), RawQuery: "json"}
+	if uu.Query().Get("tls") == "true" {
+		target.Scheme = "https"
+	}
+	client := http.Client{Timeout: p.TimeOut}
+	resp, err := client.Get(target.String())
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close() // nolint
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
+
+// checkMaxChildren compares "max children reached" counter with the previous check of the same service and sets warn
+// status if it increased. The counter is reset on php-fpm restart, so a smaller value is just remembered.
+func (p *PHPFPMProvider) checkMaxChildren(name string, body map[string]interface{}, curr int) {
+	p.maxChildren.once.Do(func() {
+		p.maxChildren.last = make(map[string]int)
+	})
+	p.maxChildren.lock.Lock()
+	prev, ok := p.maxChildren.last[name]
+	p.maxChildren.last[name] = curr
+	p.maxChildren.lock.Unlock()
+	if ok && curr > prev {
+		setStatus(body, "warn", fmt.Sprintf("max children reached %d times since the last check", curr-prev))
+	}
+}
+
+// pathOrDefault returns status path or php-fpm default "/status" if empty
+func pathOrDefault(path string) string {
+	if path == "" || path == "/" {
+		return "/status"
+	}
+	return path
+}
@@ -0,0 +1,148 @@
+package external
+
+import (
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPHPFPMProvider_Status(t *testing.T) {
+	ts := httptest.NewServer(phpfpmHandler(t, "testdata/phpfpm_status.json"))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	p := PHPFPMProvider{TimeOut: time.Second}
+	resp, err := p.Status(Request{Name: "fpm", URL: "phpfpm://" + host + "/status"})
+	require.NoError(t, err)
+	assert.Equal(t, "fpm", resp.Name)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"pool": "www", "active_processes": 3, "idle_processes": 7, "total_processes": 10,
+		"listen_queue": 0, "max_children_reached": 2, "status": "ok"}, resp.Body)
+	assert.Equal(t, "phpfpm www 3 active, 7 idle, queue 0 (ok)", resp.Summary)
+
+	resp, err = p.Status(Request{Name: "fpm", URL: "phpfpm://" + host + "/missing"})
+	require.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.Equal(t, "phpfpm status 404 (failed)", resp.Summary)
+
+	_, err = p.Status(Request{Name: "fpm", URL: "phpfpm:///status"})
+	require.EqualError(t, err, "phpfpm request failed: fpm phpfpm:///status: host is empty")
+}
+
+func TestPHPFPMProvider_StatusQueueAndMaxChildren(t *testing.T) {
+	var status atomic.Value
+	status.Store(`{"pool":"www","listen queue":0,"idle processes":2,"active processes":8,"max children reached":2}`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(status.Load().(string)))
+	}))
+	defer ts.Close()
+	u := "phpfpm://" + strings.TrimPrefix(ts.URL, "http://") + "/status"
+
+	p := PHPFPMProvider{TimeOut: time.Second}
+	resp, err := p.Status(Request{Name: "fpm", URL: u})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"], "first check, counter not compared")
+
+	status.Store(`{"pool":"www","listen queue":0,"idle processes":0,"active processes":10,"max children reached":5}`)
+	resp, err = p.Status(Request{Name: "fpm", URL: u})
+	require.NoError(t, err)
+	assert.Equal(t, "warn: max children reached 3 times since the last check", resp.Body["status"])
+	assert.Equal(t, "phpfpm www 10 active, 0 idle, queue 0 (warn)", resp.Summary)
+
+	resp, err = p.Status(Request{Name: "fpm", URL: u})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"], "counter didn't change")
+
+	status.Store(`{"pool":"www","listen queue":12,"idle processes":0,"active processes":10,"max children reached":6}`)
+	resp, err = p.Status(Request{Name: "fpm", URL: u})
+	require.NoError(t, err)
+	assert.Equal(t, "warn: listen queue 12", resp.Body["status"])
+
+	resp, err = p.Status(Request{Name: "fpm", URL: u + "?maxQueue=10"})
+	require.NoError(t, err)
+	assert.Equal(t, "failed: listen queue 12, expected at most 10", resp.Body["status"])
+	assert.Equal(t, 12, resp.Body["listen_queue"])
+
+	resp, err = p.Status(Request{Name: "fpm", URL: u + "?maxQueue=20"})
+	require.NoError(t, err)
+	assert.Equal(t, "warn: listen queue 12", resp.Body["status"])
+
+	_, err = p.Status(Request{Name: "fpm", URL: u + "?maxQueue=many"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "phpfpm maxQueue parse failed")
+}
+
+func TestPHPFPMProvider_StatusFastCGI(t *testing.T) {
+	p := PHPFPMProvider{TimeOut: time.Second}
+	exp := map[string]interface{}{"pool": "www", "active_processes": 3, "idle_processes": 7, "total_processes": 10,
+		"listen_queue": 0, "max_children_reached": 2, "status": "ok"}
+
+	{ // tcp socket
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer lis.Close()
+		go func() { _ = fcgi.Serve(lis, phpfpmHandler(t, "testdata/phpfpm_status.json")) }()
+
+		resp, err := p.Status(Request{Name: "fpm", URL: "phpfpm://" + lis.Addr().String() + "/status?fcgi=true"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, exp, resp.Body)
+
+		resp, err = p.Status(Request{Name: "fpm", URL: "phpfpm://" + lis.Addr().String() + "/fpm-status?fcgi=true"})
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	}
+
+	{ // unix socket
+		sock := filepath.Join(t.TempDir(), "fpm.sock")
+		lis, err := net.Listen("unix", sock)
+		require.NoError(t, err)
+		defer lis.Close()
+		go func() { _ = fcgi.Serve(lis, phpfpmHandler(t, "testdata/phpfpm_status.json")) }()
+
+		resp, err := p.Status(Request{Name: "fpm", URL: "phpfpm://" + sock + "?fcgi=true"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, exp, resp.Body)
+	}
+}
+
+func Test_fcgiParseResponse(t *testing.T) {
+	code, body, err := fcgiParseResponse([]byte("Status: 403 Forbidden\r\nContent-Type: text/plain\r\n\r\nAccess denied."))
+	require.NoError(t, err)
+	assert.Equal(t, 403, code)
+	assert.Equal(t, "Access denied.", string(body))
+
+	code, body, err = fcgiParseResponse([]byte("Content-Type: application/json\r\n\r\n{}"))
+	require.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "{}", string(body))
+
+	_, _, err = fcgiParseResponse([]byte("Status: abc\r\n\r\n"))
+	require.Error(t, err)
+}
+
+// phpfpmHandler serves status json from the file on /status?json, like php-fpm does
+func phpfpmHandler(t *testing.T, fname string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "json", r.URL.RawQuery)
+		data, err := os.ReadFile(fname)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
@@ -0,0 +1,109 @@
+package external
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+)
+
+// PingProvider is a status provider that sends icmp echo requests and reports packet loss and round-trip time
+type PingProvider struct {
+	TimeOut time.Duration
+}
+
+const (
+	defaultPingCount = 3
+	pingInterval     = 200 * time.Millisecond // wait between echo requests
+)
+
+// Status url looks like: ping://example.com?count=5&maxLoss=20. It sends "count" echo requests, 3 by default,
+// and reports packet loss and round-trip time. Raw icmp socket is used if permitted (root or CAP_NET_RAW),
+// otherwise it falls back to unprivileged udp ping. Status is failed if the loss percent is above "maxLoss",
+// or if all packets lost when maxLoss is not set.
+func (p *PingProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ping url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if uu.Hostname() == "" {
+		return nil, fmt.Errorf("ping host is empty: %s %s", req.Name, req.URL)
+	}
+
+	count := defaultPingCount
+	if v := uu.Query().Get("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count <= 0 {
+			return nil, fmt.Errorf("ping count should be positive number: %s %s", req.Name, req.URL)
+		}
+	}
+	maxLoss := -1.0
+	if v := uu.Query().Get("maxLoss"); v != "" {
+		if maxLoss, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("ping maxLoss parse failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	}
+
+	stats, mode, err := p.ping(uu.Hostname(), count)
+	if err != nil {
+		return nil, fmt.Errorf("ping failed: %s %s: %w", req.Name, uu.Hostname(), err)
+	}
+	body := pingBody(stats, maxLoss)
+	body["mode"] = mode
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("ping %s: %v%% loss, avg %vms (%s)", uu.Hostname(), body["packet_loss_pct"],
+			body["rtt_avg_ms"], level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// ping sends echo requests with raw icmp socket, and with udp socket if raw one is not permitted.
+// Returns statistics and the mode used, "icmp" or "udp".
+func (p *PingProvider) ping(host string, count int) (stats *probing.Statistics, mode string, err error) {
+	for _, privileged := range []bool{true, false} {
+		pinger, e := probing.NewPinger(host)
+		if e != nil {
+			return nil, "", e
+		}
+		pinger.Count, pinger.Interval, pinger.Timeout = count, pingInterval, p.TimeOut
+		pinger.SetPrivileged(privileged)
+		if err = pinger.Run(); err != nil {
+			continue // raw socket requires root or CAP_NET_RAW, try unprivileged udp
+		}
+		mode = "udp"
+		if privileged {
+			mode = "icmp"
+		}
+		return pinger.Statistics(), mode, nil
+	}
+	return nil, "", err
+}
+
+// pingBody makes response body from ping statistics, maxLoss < 0 means failed on full loss only
+func pingBody(stats *probing.Statistics, maxLoss float64) map[string]interface{} {
+	ms := func(d time.Duration) float64 { return math.Round(float64(d)/float64(time.Millisecond)*100) / 100 }
+	loss := math.Round(stats.PacketLoss*100) / 100
+	body := map[string]interface{}{
+		"packets_sent":    stats.PacketsSent,
+		"packets_recv":    stats.PacketsRecv,
+		"packet_loss_pct": loss,
+		"rtt_avg_ms":      ms(stats.AvgRtt),
+		"rtt_max_ms":      ms(stats.MaxRtt),
+		"status":          "ok",
+	}
+	switch {
+	case stats.PacketsRecv == 0:
+		body["status"] = fmt.Sprintf("failed: all %d packets lost", stats.PacketsSent)
+	case maxLoss >= 0 && loss > maxLoss:
+		body["status"] = fmt.Sprintf("failed: %v%% packet loss, expected at most %v%%", loss, maxLoss)
+	}
+	return body
+}
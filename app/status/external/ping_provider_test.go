@@ -0,0 +1,69 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingProvider_Status(t *testing.T) {
+	p := PingProvider{TimeOut: 2 * time.Second}
+	resp, err := p.Status(Request{Name: "local", URL: "ping://127.0.0.1?count=2&maxLoss=20"})
+	if err != nil {
+		t.Skipf("ping is not permitted: %v", err) // neither raw socket nor ping_group_range allows it
+	}
+	assert.Equal(t, "local", resp.Name)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, 2, resp.Body["packets_sent"])
+	assert.Equal(t, 2, resp.Body["packets_recv"])
+	assert.Equal(t, 0.0, resp.Body["packet_loss_pct"])
+	assert.Contains(t, []string{"icmp", "udp"}, resp.Body["mode"])
+	assert.Equal(t, "ok", resp.Body["status"])
+	assert.Contains(t, resp.Summary, "ping 127.0.0.1: 0% loss, avg ")
+}
+
+func TestPingProvider_StatusErrors(t *testing.T) {
+	p := PingProvider{TimeOut: time.Second}
+	tbl := []struct {
+		url, err string
+	}{
+		{"ping://", "ping host is empty: bad ping://"},
+		{"ping://127.0.0.1?count=0", "ping count should be positive number: bad ping://127.0.0.1?count=0"},
+		{"ping://127.0.0.1?maxLoss=abc",
+			`ping maxLoss parse failed: bad ping://127.0.0.1?maxLoss=abc: strconv.ParseFloat: parsing "abc": invalid syntax`},
+	}
+	for _, tt := range tbl {
+		_, err := p.Status(Request{Name: "bad", URL: tt.url})
+		require.EqualError(t, err, tt.err)
+	}
+}
+
+func Test_pingBody(t *testing.T) {
+	tbl := []struct {
+		stats   probing.Statistics
+		maxLoss float64
+		status  string
+	}{
+		{probing.Statistics{PacketsSent: 5, PacketsRecv: 5, AvgRtt: 1500 * time.Microsecond, MaxRtt: 3 * time.Millisecond},
+			20, "ok"},
+		{probing.Statistics{PacketsSent: 5, PacketsRecv: 4, PacketLoss: 20, AvgRtt: time.Millisecond}, 20, "ok"},
+		{probing.Statistics{PacketsSent: 5, PacketsRecv: 3, PacketLoss: 40, AvgRtt: time.Millisecond}, 20,
+			"failed: 40% packet loss, expected at most 20%"},
+		{probing.Statistics{PacketsSent: 5, PacketsRecv: 3, PacketLoss: 40, AvgRtt: time.Millisecond}, -1, "ok"},
+		{probing.Statistics{PacketsSent: 5, PacketLoss: 100}, -1, "failed: all 5 packets lost"},
+	}
+	for _, tt := range tbl {
+		body := pingBody(&tt.stats, tt.maxLoss)
+		assert.Equal(t, tt.status, body["status"])
+		assert.Equal(t, tt.stats.PacketsSent, body["packets_sent"])
+		assert.Equal(t, tt.stats.PacketLoss, body["packet_loss_pct"])
+	}
+
+	body := pingBody(&probing.Statistics{PacketsSent: 5, PacketsRecv: 5, AvgRtt: 1234567 * time.Nanosecond,
+		MaxRtt: 3 * time.Millisecond}, -1)
+	assert.Equal(t, 1.23, body["rtt_avg_ms"])
+	assert.Equal(t, 3.0, body["rtt_max_ms"])
+}
@@ -0,0 +1,203 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_pgStatus(t *testing.T) {
+	tbl := []struct {
+		name   string
+		replay bool
+		lag    interface{}
+		maxLag int64
+		body   map[string]interface{}
+	}{
+		{"primary", false, nil, 0, map[string]interface{}{"role": "primary", "in_recovery": false, "status": "ok"}},
+		{"replica", true, int64(1024), 0,
+			map[string]interface{}{"role": "replica", "in_recovery": true, "lag_bytes": int64(1024), "status": "ok"}},
+		{"replica in range", true, int64(1024), 4096,
+			map[string]interface{}{"role": "replica", "in_recovery": true, "lag_bytes": int64(1024), "status": "ok"}},
+		{"replica behind", true, int64(8192), 4096, map[string]interface{}{"role": "replica", "in_recovery": true,
+			"lag_bytes": int64(8192), "status": "failed: lag 8192 bytes, expected at most 4096"}},
+		{"replica without receiver", true, nil, 4096, map[string]interface{}{"role": "replica", "in_recovery": true,
+			"lag_bytes": int64(-1), "status": "failed: no wal receiver"}},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+			mock.ExpectQuery("SELECT pg_is_in_recovery\\(\\)").
+				WillReturnRows(sqlmock.NewRows([]string{"pg_is_in_recovery"}).AddRow(tt.replay))
+			if tt.replay {
+				mock.ExpectQuery("SELECT pg_wal_lsn_diff").
+					WillReturnRows(sqlmock.NewRows([]string{"pg_wal_lsn_diff"}).AddRow(tt.lag))
+			}
+
+			body, err := pgStatus(context.Background(), db, tt.maxLag)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, body)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("SELECT pg_is_in_recovery").WillReturnError(errors.New("permission denied"))
+	_, err = pgStatus(context.Background(), db, 0)
+	require.EqualError(t, err, "recovery state: permission denied")
+}
+
+func Test_pgWraparound(t *testing.T) {
+	tbl := []struct {
+		name   string
+		xidAge int64
+		status string
+		pct    float64
+	}{
+		{"young", 200_000_000, "ok", 9.31},
+		{"warn", 1_200_000_000, "warn: 55.88% toward xid wraparound in app, expected at most 50%", 55.88},
+		{"failed", 1_800_000_000, "failed: 83.82% toward xid wraparound in app, expected at most 75%", 83.82},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectQuery("SELECT datname, age\\(datfrozenxid\\) FROM pg_database").
+				WillReturnRows(sqlmock.NewRows([]string{"datname", "age"}).AddRow("app", tt.xidAge))
+			mock.ExpectQuery("SELECT c.oid::regclass::text, age\\(c.relfrozenxid\\) FROM pg_class").
+				WillReturnRows(sqlmock.NewRows([]string{"oid", "age"}).AddRow("public.events", tt.xidAge-1000))
+
+			body := map[string]interface{}{"role": "primary", "status": "ok"}
+			require.NoError(t, pgWraparound(context.Background(), db, body, 50, 75))
+			assert.Equal(t, map[string]interface{}{"role": "primary", "status": tt.status, "xid_age": tt.xidAge,
+				"xid_age_database": "app", "wraparound_pct": tt.pct, "oldest_table": "public.events",
+				"oldest_table_xid_age": tt.xidAge - 1000}, body)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	{ // failed replica status is kept
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery("FROM pg_database").WillReturnRows(sqlmock.NewRows([]string{"datname", "age"}).AddRow("app", 1_200_000_000))
+		mock.ExpectQuery("FROM pg_class").WillReturnRows(sqlmock.NewRows([]string{"oid", "age"}))
+		body := map[string]interface{}{"status": "failed: no wal receiver"}
+		require.NoError(t, pgWraparound(context.Background(), db, body, 50, 75))
+		assert.Equal(t, "failed: no wal receiver", body["status"])
+		assert.Equal(t, "", body["oldest_table"], "no tables")
+	}
+
+	{ // query error
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery("FROM pg_database").WillReturnError(errors.New("permission denied"))
+		err = pgWraparound(context.Background(), db, map[string]interface{}{}, 50, 75)
+		require.EqualError(t, err, "database xid age: permission denied")
+	}
+}
+
+func Test_pgSlots(t *testing.T) {
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"slot_name", "slot_type", "active", "retained"}).
+			AddRow("cdc", "logical", true, int64(2048)).
+			AddRow("old_cdc", "logical", false, int64(512)).
+			AddRow("replica1", "physical", true, int64(0))
+	}
+	tbl := []struct {
+		name         string
+		warn, fail   int64
+		status       string
+		cdc, oldCDC  string
+		replicaState string
+	}{
+		{"no thresholds", 0, 0, "warn: slot old_cdc inactive", "ok", "warn: inactive", "ok"},
+		{"retained warn", 1024, 0, "warn: slot cdc retained 2048 bytes, expected at most 1024",
+			"warn: retained 2048 bytes, expected at most 1024", "warn: inactive", "ok"},
+		{"retained failed", 256, 1024, "failed: slot cdc retained 2048 bytes, expected at most 1024",
+			"failed: retained 2048 bytes, expected at most 1024", "warn: retained 512 bytes, expected at most 256", "ok"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectQuery("FROM pg_replication_slots").WillReturnRows(rows())
+
+			body := map[string]interface{}{"role": "primary", "status": "ok"}
+			require.NoError(t, pgSlots(context.Background(), db, body, tt.warn, tt.fail))
+			assert.Equal(t, tt.status, body["status"])
+			slots := body["slots"].(map[string]pgSlot)
+			assert.Equal(t, pgSlot{Type: "logical", Active: true, RetainedBytes: 2048, Status: tt.cdc}, slots["cdc"])
+			assert.Equal(t, pgSlot{Type: "logical", Active: false, RetainedBytes: 512, Status: tt.oldCDC}, slots["old_cdc"])
+			assert.Equal(t, pgSlot{Type: "physical", Active: true, Status: tt.replicaState}, slots["replica1"])
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	{ // no slots
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery("FROM pg_replication_slots").
+			WillReturnRows(sqlmock.NewRows([]string{"slot_name", "slot_type", "active", "retained"}))
+		body := map[string]interface{}{"status": "ok"}
+		require.NoError(t, pgSlots(context.Background(), db, body, 0, 0))
+		assert.Equal(t, map[string]interface{}{"status": "ok", "slots": map[string]pgSlot{}}, body)
+	}
+
+	{ // failed replica status is kept
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery("FROM pg_replication_slots").WillReturnRows(rows())
+		body := map[string]interface{}{"status": "failed: no wal receiver"}
+		require.NoError(t, pgSlots(context.Background(), db, body, 0, 0))
+		assert.Equal(t, "failed: no wal receiver", body["status"])
+	}
+
+	{ // query error
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery("FROM pg_replication_slots").WillReturnError(errors.New("permission denied"))
+		err = pgSlots(context.Background(), db, map[string]interface{}{}, 0, 0)
+		require.EqualError(t, err, "replication slots: permission denied")
+	}
+}
+
+func TestPostgresProvider_StatusFailed(t *testing.T) {
+	p := PostgresProvider{TimeOut: 100 * time.Millisecond}
+
+	_, err := p.Status(Request{Name: "pg", URL: "postgres://127.0.0.1:1/app?sslmode=disable"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "postgres status failed: pg")
+
+	_, err = p.Status(Request{Name: "pg", URL: "postgres://127.0.0.1:1/app?maxLagBytes=big"})
+	require.EqualError(t, err, `postgres maxLagBytes parse failed: pg postgres://127.0.0.1:1/app?maxLagBytes=big: `+
+		`strconv.ParseInt: parsing "big": invalid syntax`)
+
+	_, err = p.Status(Request{Name: "pg", URL: "postgres://127.0.0.1:1/app?wraparoundWarn=half"})
+	require.EqualError(t, err, `postgres wraparoundWarn parse failed: pg postgres://127.0.0.1:1/app?wraparoundWarn=half: `+
+		`strconv.Atoi: parsing "half": invalid syntax`)
+
+	_, err = p.Status(Request{Name: "pg", URL: "postgres://127.0.0.1:1/app?slotFailBytes=1GB"})
+	require.EqualError(t, err, `postgres slotFailBytes parse failed: pg postgres://127.0.0.1:1/app?slotFailBytes=1GB: `+
+		`strconv.ParseInt: parsing "1GB": invalid syntax`)
+}
@@ -0,0 +1,73 @@
+package external
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OfflineStatus marks the response of a remote service check skipped because the pre-flight check failed,
+// set in Skipped and body status
+const OfflineStatus = "skipped_offline"
+
+// localSchemes are schemes of checks running on the host itself, not affected by the network
+var localSchemes = map[string]bool{"file": true, "program": true, "cert+file": true, "winsvc": true}
+
+// SetPreflight sets the connectivity check running before each round, i.e. "ping://10.0.0.1" for the gateway or
+// "dns://8.8.8.8/example.com". If it fails, remote checks are not run and reported as skipped_offline.
+// Any supported url can be used, empty url disables the pre-flight.
+func (s *Service) SetPreflight(u string) error {
+	if u == "" {
+		s.preflight = nil
+		return nil
+	}
+	if s.provider(u) == nil {
+		return fmt.Errorf("unsupported pre-flight url %q", maskSecrets(u))
+	}
+	s.preflight = &Request{Name: "preflight", URL: u}
+	return nil
+}
+
+// checkPreflight runs the pre-flight check, returns empty reason if it passed or not set
+func (s *Service) checkPreflight() (reason string) {
+	if s.preflight == nil {
+		return ""
+	}
+	resp, err := s.provider(s.preflight.URL).Status(*s.preflight)
+	if err != nil {
+		log.Printf("[WARN] pre-flight check failed, host is offline: %s: %v", maskSecrets(s.preflight.URL), err)
+		return "check error" // error not included, may contain credentials from the url
+	}
+	if !isUp(*resp) {
+		log.Printf("[WARN] pre-flight check failed, host is offline: %s: %s", maskSecrets(s.preflight.URL), resp.Summary)
+		return resp.Summary
+	}
+	return ""
+}
+
+// isLocal checks the service url refers to the host itself: local scheme, unix socket or loopback address
+func isLocal(u string) bool {
+	scheme, _, _ := strings.Cut(u, "://")
+	if localSchemes[scheme] {
+		return true
+	}
+	uu, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	host := uu.Hostname()
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// offlineResponse makes response of the remote service skipped because the pre-flight check failed
+func offlineResponse(name, target, reason string) Response {
+	return Response{Name: name, StatusCode: http.StatusOK, Summary: "skipped, host is offline (ok)", Skipped: OfflineStatus,
+		Body: map[string]interface{}{"status": OfflineStatus, "preflight": target, "preflight_reason": reason}}
+}
@@ -3,15 +3,21 @@ package external
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // ProgramProvider is an external service that runs a command and checks the exit code.
+// The command is executed directly, or with "sh -c" if WithShell is set.
 type ProgramProvider struct {
 	WithShell bool
 	TimeOut   time.Duration
@@ -19,6 +25,14 @@ type ProgramProvider struct {
 
 // Status returns the status of the execution of the command from the request.
 // url looks like this: program://cat?args=/tmp/foo
+// Optional "workdir" sets working directory and "env" (repeated, KEY=VALUE) adds environment variables to the inherited ones,
+// i.e. program://check.sh?workdir=/srv/app&env=MODE=prod&env=TOKEN=secret&args=-v. "args" must be the last parameter.
+// Optional "expectedExit" sets the exit code counted as healthy, 0 by default, "contains" (substring) and "matchRegex"
+// fail the status if stdout doesn't match, i.e. program://check.sh?expectedExit=2&contains=READY. On failure stdout
+// and stderr in the body are truncated to maxProgramOutput.
+// Optional "shell" (true or false) overrides WithShell for the service. Without shell the command is executed directly,
+// with args split respecting quotes, so shell metacharacters in args are passed to the program as is.
+// With shell args are interpreted by the shell, i.e. program://check.sh?shell=true&args=-v | grep READY
 func (p *ProgramProvider) Status(req Request) (*Response, error) {
 	st := time.Now()
 	ctx, cancel := context.WithTimeout(context.Background(), p.TimeOut)
@@ -29,39 +43,176 @@ func (p *ProgramProvider) Status(req Request) (*Response, error) {
 		StatusCode: 200,
 	}
 
-	command := strings.TrimPrefix(req.URL, "program://")
-	args := ""
-	if strings.Contains(command, "?args=") {
-		elems := strings.Split(command, "?args=")
-		command, args = elems[0], elems[1]
+	command, args, params, err := p.parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("program url parse failed: %s: %w", req.Name, err)
+	}
+	env := params["env"]
+	for _, e := range env {
+		if !strings.Contains(e, "=") {
+			return nil, fmt.Errorf("program env is not KEY=VALUE: %s", req.Name) // value not reported, may be a secret
+		}
+	}
+
+	expectedExit := 0
+	if v := params.Get("expectedExit"); v != "" {
+		if expectedExit, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("program expectedExit parse failed: %s: %w", req.Name, err)
+		}
 	}
+	withShell := p.WithShell
+	if v := params.Get("shell"); v != "" {
+		if withShell, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("program shell parse failed: %s: %w", req.Name, err)
+		}
+	}
+	var re *regexp.Regexp
+	if v := params.Get("matchRegex"); v != "" {
+		if re, err = regexp.Compile(v); err != nil {
+			return nil, fmt.Errorf("program matchRegex parse failed: %s: %w", req.Name, err)
+		}
+	}
+
+	log.Printf("[DEBUG] command: %s %s, workdir: %q, env: %d var(s)", command, args, params.Get("workdir"), len(env))
 
-	log.Printf("[DEBUG] command: %s %s", command, args)
+	prog := filepath.Base(command) // for summary, before shell wrapping
 
-	cmd := exec.CommandContext(ctx, command, args) //nolint:gosec // we trust the command as it comes from the config
-	if p.WithShell {
-		command = fmt.Sprintf("sh -c %q", command+" "+args)
+	cmdLine := command + " " + args
+	var cmd *exec.Cmd
+	if withShell {
+		cmd = exec.CommandContext(ctx, "sh", "-c", cmdLine) //nolint:gosec // we trust the command as it comes from the config
+		cmdLine = fmt.Sprintf("sh -c %q", cmdLine)
+	} else {
+		argv, e := splitArgs(args)
+		if e != nil {
+			return nil, fmt.Errorf("program args parse failed: %s: %w", req.Name, e)
+		}
+		cmd = exec.CommandContext(ctx, command, argv...) //nolint:gosec // we trust the command as it comes from the config
+	}
+	cmd.Dir = params.Get("workdir")
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
 	}
 	stdOut, stdErr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 	cmd.Stdout = stdOut
 	cmd.Stderr = stdErr
 	cmd.Stdin = os.Stdin
 
-	err := cmd.Run()
+	err = cmd.Run()
 	resp.ResponseTime = time.Since(st).Milliseconds()
 
 	res := map[string]interface{}{
-		"command": command + " " + args,
-		"stdout":  stdOut.String(),
-		"stderr":  stdErr.String(),
-		"status":  "ok",
+		"command":    cmdLine,
+		"stdout":     stdOut.String(),
+		"stderr":     stdErr.String(),
+		"status":     "ok",
+		"shell_used": withShell, // without shell args can't inject commands
 	}
 
-	if err != nil {
-		res["status"] = err.Error()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	res["exit_code"] = exitCode
+
+	var reason string
+	switch {
+	case err != nil && (exitErr == nil || exitCode < 0 || params.Get("expectedExit") == ""):
+		reason = err.Error() // not started, killed, or not zero exit without expected code set
+	case exitCode != expectedExit:
+		reason = fmt.Sprintf("failed: exit code %d, expected %d", exitCode, expectedExit)
+	case params.Get("contains") != "" && !strings.Contains(stdOut.String(), params.Get("contains")):
+		reason = fmt.Sprintf("failed: stdout doesn't contain %q", params.Get("contains"))
+	case re != nil && !re.Match(stdOut.Bytes()):
+		reason = fmt.Sprintf("failed: stdout doesn't match %q", re.String())
+	}
+
+	resp.Summary = fmt.Sprintf("%s exit %d (ok)", prog, exitCode)
+	if reason != "" {
+		res["status"] = reason
+		res["stdout"] = truncateOutput(stdOut.String())
+		res["stderr"] = truncateOutput(stdErr.String())
 		resp.StatusCode = 500
+		resp.Summary = fmt.Sprintf("%s %s (failed)", prog, strings.TrimPrefix(reason, "failed: "))
 	}
 
 	resp.Body = res
 	return &resp, nil
 }
+
+// maxProgramOutput limits stdout and stderr of the failed program in the body
+const maxProgramOutput = 1024
+
+// truncateOutput cuts the output to maxProgramOutput bytes, with "..." suffix if truncated
+func truncateOutput(s string) string {
+	if len(s) <= maxProgramOutput {
+		return s
+	}
+	return s[:maxProgramOutput] + "..."
+}
+
+// splitArgs splits args to argv like shell does, by spaces outside of quotes. Single quotes keep everything as is,
+// in double quotes and outside of quotes backslash escapes the next character. No expansion or substitution is done.
+func splitArgs(args string) ([]string, error) {
+	var res []string
+	var curr strings.Builder
+	inArg, quote, escaped := false, rune(0), false
+	for _, c := range args {
+		switch {
+		case escaped:
+			curr.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+				continue
+			}
+			curr.WriteRune(c)
+		case c == '\\':
+			inArg, escaped = true, true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+				continue
+			}
+			curr.WriteRune(c)
+		case c == '\'' || c == '"':
+			inArg, quote = true, c
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				res = append(res, curr.String())
+				curr.Reset()
+				inArg = false
+			}
+		default:
+			inArg = true
+			curr.WriteRune(c)
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", args)
+	}
+	if inArg {
+		res = append(res, curr.String())
+	}
+	return res, nil
+}
+
+// parse splits program url to command, args and other params. Everything after "args=" is args, as is.
+func (p *ProgramProvider) parse(u string) (command, args string, params url.Values, err error) {
+	command, query := strings.TrimPrefix(u, "program://"), ""
+	if i := strings.Index(command, "?"); i >= 0 {
+		command, query = command[:i], command[i+1:]
+	}
+	if strings.HasPrefix(query, "args=") {
+		args, query = strings.TrimPrefix(query, "args="), ""
+	}
+	if i := strings.Index(query, "&args="); i >= 0 {
+		args, query = query[i+len("&args="):], query[:i]
+	}
+	if params, err = url.ParseQuery(query); err != nil {
+		return "", "", nil, err
+	}
+	return command, args, params, nil
+}
@@ -1,6 +1,12 @@
 package external
 
 import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -19,6 +25,7 @@ func TestProgram_StatusWithShell(t *testing.T) {
 		assert.Equal(t, 200, resp.StatusCode)
 		assert.Equal(t, "ok", resp.Body["status"])
 		assert.Contains(t, resp.Body["stdout"], "program.go")
+		assert.Equal(t, "ls exit 0 (ok)", resp.Summary)
 		t.Logf("%+v", resp)
 	}
 	{
@@ -29,6 +36,7 @@ func TestProgram_StatusWithShell(t *testing.T) {
 		assert.Equal(t, 200, resp.StatusCode)
 		assert.Equal(t, "ok", resp.Body["status"])
 		assert.Contains(t, resp.Body["stdout"], "Hello, World!")
+		assert.Equal(t, "test.sh exit 0 (ok)", resp.Summary)
 	}
 	{
 		req := Request{Name: "test", URL: `program://blah?args=-la`}
@@ -36,12 +44,22 @@ func TestProgram_StatusWithShell(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, "test", resp.Name)
 		assert.Equal(t, 500, resp.StatusCode)
+		assert.Equal(t, "exit status 127", resp.Body["status"])
+		assert.Contains(t, resp.Body["stderr"], "not found")
+		assert.Equal(t, true, resp.Body["shell_used"])
+	}
+	{
+		req := Request{Name: "test", URL: `program://blah?shell=false&args=-la`}
+		resp, err := p.Status(req)
+		require.NoError(t, err)
+		assert.Equal(t, 500, resp.StatusCode)
 		assert.Contains(t, resp.Body["status"], "file not found")
+		assert.Equal(t, false, resp.Body["shell_used"])
 	}
 }
 
 func TestProgram_StatusWithoutShell(t *testing.T) {
-	p := ProgramProvider{WithShell: true, TimeOut: time.Second}
+	p := ProgramProvider{TimeOut: time.Second}
 
 	{
 		req := Request{Name: "test", URL: `program://cat?args=program.go`}
@@ -51,6 +69,8 @@ func TestProgram_StatusWithoutShell(t *testing.T) {
 		assert.Equal(t, 200, resp.StatusCode)
 		assert.Equal(t, "ok", resp.Body["status"])
 		assert.Contains(t, resp.Body["stdout"], "CommandContext")
+		assert.Equal(t, false, resp.Body["shell_used"], "direct execution by default")
+		assert.Equal(t, "cat program.go", resp.Body["command"])
 	}
 	{
 		req := Request{Name: "test", URL: `program://cat?args=blah`}
@@ -59,6 +79,200 @@ func TestProgram_StatusWithoutShell(t *testing.T) {
 		assert.Equal(t, "test", resp.Name)
 		assert.Equal(t, 500, resp.StatusCode)
 		assert.Contains(t, resp.Body["status"], "exit status 1", resp.Body["status"])
+		assert.Equal(t, "cat exit status 1 (failed)", resp.Summary)
 		t.Logf("%+v", resp)
 	}
 }
+
+func TestProgram_StatusShellOverride(t *testing.T) {
+	p := ProgramProvider{WithShell: true, TimeOut: time.Second}
+
+	{ // with shell the semicolon runs the second command
+		resp, err := p.Status(Request{Name: "test", URL: `program://echo?args=a;echo injected`})
+		require.NoError(t, err)
+		assert.Equal(t, "a\ninjected\n", resp.Body["stdout"])
+		assert.Equal(t, `sh -c "echo a;echo injected"`, resp.Body["command"])
+		assert.Equal(t, true, resp.Body["shell_used"])
+	}
+	{ // without shell it is passed to the program as is, quotes respected
+		resp, err := p.Status(Request{Name: "test", URL: `program://echo?shell=false&args=a;echo 'b  c' "$HOME"`})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "a;echo b  c $HOME\n", resp.Body["stdout"])
+		assert.Equal(t, false, resp.Body["shell_used"])
+	}
+	{ // shell enabled per service
+		p := ProgramProvider{TimeOut: time.Second}
+		resp, err := p.Status(Request{Name: "test", URL: `program://echo?shell=true&args=a;echo b`})
+		require.NoError(t, err)
+		assert.Equal(t, "a\nb\n", resp.Body["stdout"])
+	}
+
+	_, err := p.Status(Request{Name: "test", URL: `program://echo?shell=no&args=a`})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "program shell parse failed: test")
+
+	_, err = p.Status(Request{Name: "test", URL: `program://echo?shell=false&args='a`})
+	require.EqualError(t, err, `program args parse failed: test: unterminated quote or escape in "'a"`)
+}
+
+func Test_splitArgs(t *testing.T) {
+	tbl := []struct {
+		args string
+		res  []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"-la", []string{"-la"}},
+		{"-v  --name=x\tfoo", []string{"-v", "--name=x", "foo"}},
+		{`"arg1 arg2"`, []string{"arg1 arg2"}},
+		{`a 'b "c" \d' e`, []string{"a", `b "c" \d`, "e"}},
+		{`"a \"b\"" c\ d`, []string{`a "b"`, "c d"}},
+		{`x'' ""`, []string{"x", ""}},
+		{"$(rm -rf /);ls|cat", []string{"$(rm", "-rf", "/);ls|cat"}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.args, func(t *testing.T) {
+			res, err := splitArgs(tt.args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+
+	for _, args := range []string{`'a`, `"a`, `a\`} {
+		_, err := splitArgs(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestProgram_StatusWorkdirEnv(t *testing.T) {
+	p := ProgramProvider{TimeOut: time.Second}
+	script, err := filepath.Abs("testdata/env.sh")
+	require.NoError(t, err)
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	{
+		req := Request{Name: "test", URL: "program://" + script + "?workdir=" + dir + "&env=CHECK_MODE=prod&env=CHECK_TOKEN%3Dsecret"}
+		resp, err := p.Status(req)
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, "dir: "+dir+"\nmode: prod, token: secret\n", resp.Body["stdout"])
+		assert.Equal(t, script+" ", resp.Body["command"])
+	}
+	{
+		req := Request{Name: "test", URL: "program://" + script}
+		resp, err := p.Status(req)
+		require.NoError(t, err)
+		assert.Contains(t, resp.Body["stdout"], "mode: , token: \n")
+	}
+	{
+		req := Request{Name: "test", URL: "program://" + script + "?env=CHECK_MODE"}
+		_, err := p.Status(req)
+		require.EqualError(t, err, "program env is not KEY=VALUE: test")
+	}
+}
+
+func TestProgram_StatusExpectations(t *testing.T) {
+	p := ProgramProvider{TimeOut: time.Second}
+	script, err := filepath.Abs("testdata/exit.sh")
+	require.NoError(t, err)
+
+	tbl := []struct {
+		query   string
+		code    int
+		status  string
+		summary string
+	}{
+		{"?args=0", 200, "ok", "exit.sh exit 0 (ok)"},
+		{"?expectedExit=2&args=2", 200, "ok", "exit.sh exit 2 (ok)"},
+		{"?expectedExit=2&args=0", 500, "failed: exit code 0, expected 2", "exit.sh exit code 0, expected 2 (failed)"},
+		{"?expectedExit=0&args=1", 500, "failed: exit code 1, expected 0", "exit.sh exit code 1, expected 0 (failed)"},
+		{"?args=1", 500, "exit status 1", "exit.sh exit status 1 (failed)"},
+		{"?contains=degraded", 200, "ok", "exit.sh exit 0 (ok)"},
+		{"?contains=ready", 500, `failed: stdout doesn't contain "ready"`, `exit.sh stdout doesn't contain "ready" (failed)`},
+		{"?matchRegex=" + url.QueryEscape("^state: (degraded|ok)"), 200, "ok", "exit.sh exit 0 (ok)"},
+		{"?matchRegex=" + url.QueryEscape("^state: ok$"), 500, `failed: stdout doesn't match "^state: ok$"`,
+			`exit.sh stdout doesn't match "^state: ok$" (failed)`},
+		{"?expectedExit=3&contains=degraded&args=3", 200, "ok", "exit.sh exit 3 (ok)"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "test", URL: "program://" + script + tt.query})
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, resp.StatusCode)
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.Equal(t, tt.summary, resp.Summary)
+			assert.Equal(t, "state: degraded\n", resp.Body["stdout"])
+			if tt.code == 200 {
+				assert.Len(t, resp.Body["stderr"], 2000, "not truncated on success")
+				return
+			}
+			assert.Len(t, resp.Body["stderr"], maxProgramOutput+3, "truncated on failure")
+		})
+	}
+
+	resp, err := p.Status(Request{Name: "test", URL: "program://" + script + "?expectedExit=2&args=2"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Body["exit_code"])
+
+	_, err = p.Status(Request{Name: "test", URL: "program://" + script + "?expectedExit=bad"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "program expectedExit parse failed: test")
+
+	_, err = p.Status(Request{Name: "test", URL: "program://" + script + "?matchRegex=" + url.QueryEscape("[a-")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "program matchRegex parse failed: test")
+}
+
+func TestProgram_StatusEnvNotLeaked(t *testing.T) {
+	logs := bytes.NewBuffer(nil)
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	for _, shell := range []bool{true, false} {
+		p := ProgramProvider{WithShell: shell, TimeOut: time.Second}
+		for _, u := range []string{"program://true?env=DB_PASSWORD=s3cr3t-value&env=DB%5FUSER%3Ds3cr3t-user",
+			"program://false?env=DB_PASSWORD=s3cr3t-value&env=DB%5FUSER%3Ds3cr3t-user&args=-x"} {
+			resp, err := p.Status(Request{Name: "test", URL: u})
+			require.NoError(t, err)
+			body := fmt.Sprintf("%+v", resp)
+			assert.NotContains(t, body, "s3cr3t", "env value in the response")
+		}
+	}
+	assert.NotEmpty(t, logs.String())
+	assert.NotContains(t, logs.String(), "s3cr3t", "env value in the logs")
+
+	p := ProgramProvider{TimeOut: time.Second}
+	_, err := p.Status(Request{Name: "test", URL: "program://true?env=s3cr3t"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t", "env value in the error")
+}
+
+func TestProgram_parse(t *testing.T) {
+	tbl := []struct {
+		url     string
+		command string
+		args    string
+		params  url.Values
+	}{
+		{"program://ls", "ls", "", url.Values{}},
+		{"program://cat?args=/tmp/foo", "cat", "/tmp/foo", url.Values{}},
+		{`program:///usr/bin/example1?args="arg1 arg2"`, "/usr/bin/example1", `"arg1 arg2"`, url.Values{}},
+		{"program://cat?args=a&b=c", "cat", "a&b=c", url.Values{}},
+		{"program://check.sh?workdir=/srv&env=A=1&env=B%3D2&args=-v", "check.sh", "-v",
+			url.Values{"workdir": {"/srv"}, "env": {"A=1", "B=2"}}},
+		{"program://check.sh?workdir=/srv", "check.sh", "", url.Values{"workdir": {"/srv"}}},
+	}
+	p := ProgramProvider{}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			command, args, params, err := p.parse(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.command, command)
+			assert.Equal(t, tt.args, args)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+}
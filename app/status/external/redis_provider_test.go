@@ -0,0 +1,142 @@
+package external
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisProvider_Status(t *testing.T) {
+	srv := miniredis.RunT(t)
+	srv.RequireUserAuth("monitor", "secret")
+	replicaInfo, err := os.ReadFile("testdata/redis_info_replica.txt")
+	require.NoError(t, err)
+	masterInfo := "# Clients\r\nconnected_clients:3\r\n# Memory\r\nused_memory:2097152\r\n# Replication\r\nrole:master\r\n"
+	info := masterInfo
+	srv.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if strings.EqualFold(cmd, "info") { // miniredis supports clients section of INFO only
+			c.WriteBulk(info)
+			return true
+		}
+		return false
+	})
+
+	p := RedisProvider{TimeOut: time.Second}
+
+	{ // master
+		resp, err := p.Status(Request{Name: "cache", URL: "redis://monitor:secret@" + srv.Addr() + "/0"})
+		require.NoError(t, err)
+		assert.Equal(t, "cache", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"role": "master", "used_memory": 2097152, "connected_clients": 3,
+			"status": "ok"}, resp.Body)
+		assert.Equal(t, "redis master, 3 clients (ok)", resp.Summary)
+	}
+
+	{ // replica with master link down
+		info = string(replicaInfo)
+		resp, err := p.Status(Request{Name: "cache", URL: "redis://monitor:secret@" + srv.Addr()})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"role": "slave", "used_memory": 1048576, "connected_clients": 7,
+			"master_link_status": "down", "master_last_io_seconds_ago": 42, "status": "failed: master link down"}, resp.Body)
+		assert.Equal(t, "redis slave, master link down (failed)", resp.Summary)
+	}
+
+	{ // wrong password
+		_, err := p.Status(Request{Name: "cache", URL: "redis://monitor:bad@" + srv.Addr()})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "WRONGPASS")
+	}
+
+	{ // bad url
+		_, err := p.Status(Request{Name: "cache", URL: "redis://" + srv.Addr() + "/db"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "redis url parse failed: cache")
+	}
+}
+
+func TestRedisProvider_StatusKey(t *testing.T) {
+	srv := miniredis.RunT(t)
+	srv.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if strings.EqualFold(cmd, "info") {
+			c.WriteBulk("# Clients\r\nconnected_clients:1\r\n# Replication\r\nrole:master\r\n")
+			return true
+		}
+		return false
+	})
+	require.NoError(t, srv.Set("flag:x", "1"))
+	require.NoError(t, srv.Set("heartbeat", "2024-01-02T03:04:05Z"))
+	srv.SetTTL("heartbeat", 45*time.Second)
+	require.NoError(t, srv.Set("stale", "x"))
+	srv.SetTTL("stale", time.Hour)
+
+	p := RedisProvider{TimeOut: time.Second}
+	base := "redis://" + srv.Addr() + "/0"
+
+	tbl := []struct {
+		name   string
+		query  string
+		body   map[string]interface{}
+		status string
+	}{
+		{"value matched", "?key=flag:x&expect=1", map[string]interface{}{"key": "flag:x", "ttl_seconds": int64(-1),
+			"value": "1"}, "ok"},
+		{"value mismatch", "?key=flag:x&expect=0", map[string]interface{}{"key": "flag:x", "ttl_seconds": int64(-1),
+			"value": "1"}, `failed: key flag:x is "1", expected "0"`},
+		{"missing key", "?key=flag:y&expect=1", map[string]interface{}{"key": "flag:y"}, "failed: key flag:y not found"},
+		{"ttl in range", "?key=heartbeat&maxTTL=60", map[string]interface{}{"key": "heartbeat", "ttl_seconds": int64(45)},
+			"ok"},
+		{"ttl too long", "?key=stale&maxTTL=1m", map[string]interface{}{"key": "stale", "ttl_seconds": int64(3600)},
+			"failed: key stale ttl 1h0m0s, expected at most 1m0s"},
+		{"no expiration", "?key=flag:x&maxTTL=60", map[string]interface{}{"key": "flag:x", "ttl_seconds": int64(-1)},
+			"failed: key flag:x has no expiration, expected ttl at most 1m0s"},
+		{"missing heartbeat", "?key=gone&maxTTL=60", map[string]interface{}{"key": "gone"}, "failed: key gone not found"},
+		{"key only", "?key=heartbeat", map[string]interface{}{"key": "heartbeat", "ttl_seconds": int64(45)}, "ok"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "flags", URL: base + tt.query})
+			require.NoError(t, err)
+			for k, v := range tt.body {
+				assert.Equal(t, v, resp.Body[k], k)
+			}
+			_, found := resp.Body["value"]
+			_, expected := tt.body["value"]
+			assert.Equal(t, expected, found, "value reported with expect only")
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.Equal(t, "redis master, 1 clients ("+level(tt.status)+")", resp.Summary)
+		})
+	}
+
+	{ // bad maxTTL
+		_, err := p.Status(Request{Name: "flags", URL: base + "?key=heartbeat&maxTTL=soon"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "redis maxTTL parse failed: flags")
+	}
+
+	{ // expect without key
+		_, err := p.Status(Request{Name: "flags", URL: base + "?expect=1"})
+		require.EqualError(t, err, "redis key is required with expect or maxTTL: flags "+base+"?expect=1")
+	}
+}
+
+func TestRedisProvider_StatusTimeout(t *testing.T) {
+	p := RedisProvider{TimeOut: 100 * time.Millisecond}
+	_, err := p.Status(Request{Name: "cache", URL: "redis://127.0.0.1:1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redis ping failed: cache 127.0.0.1:1")
+}
+
+func Test_redisInfoBody(t *testing.T) {
+	body := redisInfoBody(map[string]string{"role": "slave", "master_link_status": "up",
+		"master_last_io_seconds_ago": "1", "connected_clients": "bad"})
+	assert.Equal(t, map[string]interface{}{"role": "slave", "master_link_status": "up",
+		"master_last_io_seconds_ago": 1, "status": "ok"}, body)
+}
@@ -0,0 +1,119 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ResolverProvider is a status provider that checks a local caching resolver, i.e. coredns, dnsmasq or unbound
+type ResolverProvider struct {
+	TimeOut time.Duration
+}
+
+const defaultResolver = "127.0.0.1:53"
+
+// rcodeNames maps dns response codes to names used by dig and resolvers logs
+var rcodeNames = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+// Status url looks like: resolver://127.0.0.1:53/example.com?type=AAAA. It resolves the name with the resolver
+// and reports resolution time and addresses. Resolver is 127.0.0.1:53 if not set, i.e. resolver:///example.com,
+// and port 53 used unless set explicitly. Type is A (default) or AAAA.
+// Status is failed on timeout, error response (i.e. SERVFAIL or NXDOMAIN) or no addresses in the answer.
+func (r *ResolverProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("resolver url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	name := strings.Trim(uu.Path, "/")
+	if name == "" {
+		return nil, fmt.Errorf("resolver name is empty: %s %s", req.Name, req.URL)
+	}
+	resolver := uu.Host
+	switch {
+	case resolver == "":
+		resolver = defaultResolver
+	case uu.Port() == "":
+		resolver = net.JoinHostPort(uu.Hostname(), "53")
+	}
+
+	qtype := dnsmessage.TypeA
+	switch strings.ToUpper(uu.Query().Get("type")) {
+	case "", "A":
+	case "AAAA":
+		qtype = dnsmessage.TypeAAAA
+	default:
+		return nil, fmt.Errorf("resolver type should be A or AAAA: %s %s", req.Name, req.URL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.TimeOut)
+	defer cancel()
+	rst := time.Now()
+	addrs, err := r.resolve(ctx, resolver, name, qtype)
+	body := map[string]interface{}{
+		"resolver":     resolver,
+		"name":         name,
+		"resolve_time": time.Since(rst).Milliseconds(),
+		"addresses":    addrs,
+		"status":       "ok",
+	}
+	summary := fmt.Sprintf("%s resolved to %d address(es) in %dms (ok)", name, len(addrs), body["resolve_time"])
+	if err != nil {
+		body["status"] = "failed: " + err.Error()
+		summary = fmt.Sprintf("%s not resolved with %s (failed)", name, resolver)
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      summary,
+	}
+	return &result, nil
+}
+
+// resolve returns addresses of the name from the resolver, error if no addresses or response code is not success
+func (r *ResolverProvider) resolve(ctx context.Context, resolver, name string, qtype dnsmessage.Type) ([]string, error) {
+	addrs := []string{}
+	resp, err := dnsQuery(ctx, resolver, name, qtype, true)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return addrs, fmt.Errorf("timeout resolving %s", name)
+		}
+		return addrs, err
+	}
+	if resp.Header.RCode != dnsmessage.RCodeSuccess {
+		rcode, ok := rcodeNames[resp.Header.RCode]
+		if !ok {
+			rcode = resp.Header.RCode.String()
+		}
+		return addrs, fmt.Errorf("%s resolving %s", rcode, name)
+	}
+	for _, ans := range resp.Answers {
+		switch b := ans.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, net.IP(b.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, net.IP(b.AAAA[:]).String())
+		}
+	}
+	if len(addrs) == 0 {
+		return addrs, fmt.Errorf("no addresses for %s", name)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,95 @@
+package external
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestResolverProvider_Status(t *testing.T) {
+	addr := startTestDNSServer(t, func(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource) {
+		hdr := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: 60}
+		switch {
+		case q.Name.String() == "broken.example.com.":
+			return dnsmessage.RCodeServerFailure, nil
+		case q.Name.String() == "missing.example.com.":
+			return dnsmessage.RCodeNameError, nil
+		case q.Type == dnsmessage.TypeA:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+				{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}},
+			}
+		case q.Type == dnsmessage.TypeAAAA:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{
+				{Header: hdr, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}},
+			}
+		}
+		return dnsmessage.RCodeSuccess, nil
+	})
+
+	p := ResolverProvider{TimeOut: time.Second}
+
+	tbl := []struct {
+		url     string
+		addrs   []string
+		status  string
+		summary string
+	}{
+		{"resolver://" + addr + "/example.com", []string{"10.0.0.1", "10.0.0.2"}, "ok",
+			"example.com resolved to 2 address(es) in"},
+		{"resolver://" + addr + "/example.com?type=aaaa", []string{"2001:db8::1"}, "ok",
+			"example.com resolved to 1 address(es) in"},
+		{"resolver://" + addr + "/broken.example.com", []string{}, "failed: SERVFAIL resolving broken.example.com",
+			"broken.example.com not resolved with " + addr + " (failed)"},
+		{"resolver://" + addr + "/missing.example.com", []string{}, "failed: NXDOMAIN resolving missing.example.com",
+			"missing.example.com not resolved with " + addr + " (failed)"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "local", URL: tt.url})
+			require.NoError(t, err)
+			t.Logf("%+v", resp)
+			assert.Equal(t, "local", resp.Name)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, addr, resp.Body["resolver"])
+			assert.Equal(t, tt.addrs, resp.Body["addresses"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.GreaterOrEqual(t, resp.Body["resolve_time"], int64(0))
+			assert.Contains(t, resp.Summary, tt.summary)
+		})
+	}
+}
+
+func TestResolverProvider_StatusTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0") // wedged resolver, never responds
+	require.NoError(t, err)
+	defer conn.Close()
+
+	p := ResolverProvider{TimeOut: 100 * time.Millisecond}
+	resp, err := p.Status(Request{Name: "local", URL: "resolver://" + conn.LocalAddr().String() + "/example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "failed: timeout resolving example.com", resp.Body["status"])
+	assert.GreaterOrEqual(t, resp.Body["resolve_time"], int64(100))
+}
+
+func TestResolverProvider_StatusErrors(t *testing.T) {
+	p := ResolverProvider{TimeOut: 100 * time.Millisecond}
+
+	_, err := p.Status(Request{Name: "local", URL: "resolver://127.0.0.1"})
+	require.EqualError(t, err, "resolver name is empty: local resolver://127.0.0.1")
+
+	_, err = p.Status(Request{Name: "local", URL: "resolver://127.0.0.1/example.com?type=MX"})
+	require.EqualError(t, err, "resolver type should be A or AAAA: local resolver://127.0.0.1/example.com?type=MX")
+
+	resp, err := p.Status(Request{Name: "local", URL: "resolver:///example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:53", resp.Body["resolver"], "default resolver")
+
+	resp, err = p.Status(Request{Name: "local", URL: "resolver://127.0.0.2/example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.2:53", resp.Body["resolver"], "default port")
+}
@@ -86,5 +86,6 @@ func (h *RMQProvider) Status(req Request) (*Response, error) {
 	result.StatusCode = resp.StatusCode
 	result.ResponseTime = time.Since(st).Milliseconds()
 	result.Body = body
+	result.Summary = fmt.Sprintf("queue %s %s: %d messages, %d consumers", rec.Name, rec.State, rec.Messages, rec.Consumers)
 	return result, nil
 }
@@ -48,6 +48,7 @@ func TestRMQ_Status(t *testing.T) {
 		assert.Equal(t, 3771, resp.Body["messages_ready_ram"])
 		assert.Equal(t, 13847734, resp.Body["publish"])
 		assert.Equal(t, 56178, resp.Body["messages_delta"])
+		assert.Equal(t, "queue notification.queue running: 56178 messages, 4 consumers", resp.Summary)
 	}
 
 	{
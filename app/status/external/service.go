@@ -2,10 +2,13 @@ package external
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-pkgz/syncs"
@@ -15,9 +18,29 @@ import (
 
 // Service wraps multiple StatusProvider and multiplex their Status() calls
 type Service struct {
+	LatencyDeviation float64       // warn if response time deviates from the baseline by this multiple of stddev, disabled if 0
+	SoftStart        time.Duration // window to spread the first round of requests over, disabled if 0
+	SlowThreshold    time.Duration // log provider calls taking longer than this, disabled if 0
+	Availability     bool          // report availability over the last hour from the results of previous checks
+
 	requests    []Request
 	concurrency int
 	providers   Providers
+	firstRound  sync.Once
+	gates       map[string]gate // service name to the condition to run its check
+	preflight   *Request        // connectivity check, remote checks skipped if it fails, disabled if nil
+
+	latency struct {
+		stats map[string]*latencyStats
+		once  sync.Once
+		lock  sync.Mutex
+	}
+
+	availability struct {
+		history map[string]*availabilityHistory
+		once    sync.Once
+		lock    sync.Mutex
+	}
 }
 
 // Providers is a list of StatusProvider
@@ -31,6 +54,27 @@ type Providers struct {
 	Certificate StatusProvider
 	File        StatusProvider
 	RMQ         StatusProvider
+	NATS        StatusProvider
+	DNSZone     StatusProvider
+	SNMP        StatusProvider
+	Agent       StatusProvider
+	Graphite    StatusProvider
+	Resolver    StatusProvider
+	WinService  StatusProvider
+	Beanstalk   StatusProvider
+	Postgres    StatusProvider
+	Redis       StatusProvider
+	Nomad       StatusProvider
+	TCP         StatusProvider
+	DNS         StatusProvider
+	Ping        StatusProvider
+	Webhook     StatusProvider
+	Apache      StatusProvider
+	PHPFPM      StatusProvider
+	Varnish     StatusProvider
+	GRPC        StatusProvider
+	SMTP        StatusProvider
+	Kafka       StatusProvider
 }
 
 // StatusProvider is an interface for getting status from external services
@@ -48,8 +92,90 @@ type Request struct {
 type Response struct {
 	Name         string                 `json:"name"`
 	StatusCode   int                    `json:"status_code"`
-	ResponseTime int64                  `json:"response_time"` // milliseconds
+	ResponseTime int64                  `json:"response_time"`             // milliseconds
+	Summary      string                 `json:"summary,omitempty"`         // one-line human-readable summary, i.e. "http 200, 82 bytes (ok)"
+	Availability *float64               `json:"availability_1h,omitempty"` // percent of time up over the last hour, with Availability set
 	Body         map[string]interface{} `json:"body,omitempty"`
+	Check        map[string]interface{} `json:"check,omitempty"`   // agent's checks of the response, i.e. latency_status
+	Skipped      string                 `json:"skipped,omitempty"` // reason the check was not run, SkippedStatus or OfflineStatus
+}
+
+// Scheme describes url scheme supported by a provider
+type Scheme struct {
+	Scheme      string `json:"scheme"`
+	Provider    string `json:"provider"`
+	Description string `json:"description"`
+}
+
+// schemes is an ordered dispatch table of url prefixes to providers
+var schemes = []struct {
+	Scheme
+	provider func(p Providers) StatusProvider
+}{
+	{Scheme{"http", "HTTP", "http endpoint, GET request returning status code and body"},
+		func(p Providers) StatusProvider { return p.HTTP }},
+	{Scheme{"https", "HTTP", "https endpoint, GET request returning status code and body"},
+		func(p Providers) StatusProvider { return p.HTTP }},
+	{Scheme{"mongodb", "Mongo", "mongo availability and replica set status"},
+		func(p Providers) StatusProvider { return p.Mongo }},
+	{Scheme{"mysql", "Mysql", "mysql availability and replication lag"},
+		func(p Providers) StatusProvider { return p.Mysql }},
+	{Scheme{"docker", "Docker", "docker availability, running, required and restarting containers"},
+		func(p Providers) StatusProvider { return p.Docker }},
+	{Scheme{"program", "Program", "program or script execution and exit code"},
+		func(p Providers) StatusProvider { return p.Program }},
+	{Scheme{"nginx", "Nginx", "nginx stub_status stats"},
+		func(p Providers) StatusProvider { return p.Nginx }},
+	{Scheme{"cert", "Certificate", "tls certificate expiration"},
+		func(p Providers) StatusProvider { return p.Certificate }},
+	{Scheme{"cert+file", "Certificate", "local pem certificates expiration, files matched by glob"},
+		func(p Providers) StatusProvider { return p.Certificate }},
+	{Scheme{"file", "File", "file presence, size and modification changes"},
+		func(p Providers) StatusProvider { return p.File }},
+	{Scheme{"rmq", "RMQ", "rabbitmq queue stats from management api"},
+		func(p Providers) StatusProvider { return p.RMQ }},
+	{Scheme{"nats", "NATS", "nats server info, ping and optional request round-trip time"},
+		func(p Providers) StatusProvider { return p.NATS }},
+	{Scheme{"dnszone", "DNSZone", "dns zone SOA serial consistency across nameservers"},
+		func(p Providers) StatusProvider { return p.DNSZone }},
+	{Scheme{"snmp", "SNMP", "snmp v2c/v1 oid value with optional expected value or range"},
+		func(p Providers) StatusProvider { return p.SNMP }},
+	{Scheme{"agent", "Agent", "remote sys-agent status with failed services by name"},
+		func(p Providers) StatusProvider { return p.Agent }},
+	{Scheme{"graphite", "Graphite", "graphite carbon reachability and metric freshness from render api"},
+		func(p Providers) StatusProvider { return p.Graphite }},
+	{Scheme{"resolver", "Resolver", "local caching resolver name resolution and time"},
+		func(p Providers) StatusProvider { return p.Resolver }},
+	{Scheme{"winsvc", "WinService", "windows service state"},
+		func(p Providers) StatusProvider { return p.WinService }},
+	{Scheme{"beanstalk", "Beanstalk", "beanstalkd tube ready and buried jobs"},
+		func(p Providers) StatusProvider { return p.Beanstalk }},
+	{Scheme{"postgres", "Postgres", "postgres availability and replication lag"},
+		func(p Providers) StatusProvider { return p.Postgres }},
+	{Scheme{"redis", "Redis", "redis availability, memory and replication link"},
+		func(p Providers) StatusProvider { return p.Redis }},
+	{Scheme{"nomad", "Nomad", "nomad job running and desired allocations"},
+		func(p Providers) StatusProvider { return p.Nomad }},
+	{Scheme{"tcp", "TCP", "tcp port accepting connections"},
+		func(p Providers) StatusProvider { return p.TCP }},
+	{Scheme{"dns", "DNS", "dns record lookup with the given resolver"},
+		func(p Providers) StatusProvider { return p.DNS }},
+	{Scheme{"ping", "Ping", "icmp ping packet loss and round-trip time"},
+		func(p Providers) StatusProvider { return p.Ping }},
+	{Scheme{"webhook", "Webhook", "signed test payload to webhook receiver"},
+		func(p Providers) StatusProvider { return p.Webhook }},
+	{Scheme{"apache", "Apache", "apache mod_status busy and idle workers"},
+		func(p Providers) StatusProvider { return p.Apache }},
+	{Scheme{"phpfpm", "PHPFPM", "php-fpm pool processes and listen queue"},
+		func(p Providers) StatusProvider { return p.PHPFPM }},
+	{Scheme{"varnish", "Varnish", "varnish backends health and cache hit ratio"},
+		func(p Providers) StatusProvider { return p.Varnish }},
+	{Scheme{"grpc", "GRPC", "grpc.health.v1 serving status of grpc service"},
+		func(p Providers) StatusProvider { return p.GRPC }},
+	{Scheme{"smtp", "SMTP", "smtp greeting, ehlo extensions, starttls and recipient probe"},
+		func(p Providers) StatusProvider { return p.SMTP }},
+	{Scheme{"kafka", "Kafka", "kafka brokers and topic partitions from cluster metadata"},
+		func(p Providers) StatusProvider { return p.Kafka }},
 }
 
 // NewService creates new external service supporting multiple providers
@@ -69,65 +195,195 @@ func NewService(providers Providers, concurrency int, reqs ...string) *Service {
 			if len(req.Name) > 0 && len(req.URL) > 0 {
 				result.requests = append(result.requests, req)
 			}
-			log.Printf("[DEBUG] service: name:%s, url:%s", req.Name, req.URL)
+			log.Printf("[DEBUG] service: name:%s, url:%s", req.Name, maskSecrets(req.URL))
 		}
 	}
 	return result
 }
 
-// Status returns extended service information, runs concurrently
+// envValueRe matches values of env params, i.e. "env=TOKEN=secret" or url-encoded "env=TOKEN%3Dsecret".
+// The name can be url-encoded as well, i.e. "env=DB%5FPASS=secret".
+var envValueRe = regexp.MustCompile(`([?&]env=(?:[^=&%]|%[0-9a-fA-F]{2})+?(=|%3[dD]))[^&]*`)
+
+// secretValueRe matches values of secret params, i.e. webhook hmac "secret=s3cret"
+var secretValueRe = regexp.MustCompile(`([?&]secret=)[^&]*`)
+
+// headerValueRe matches values of credential headers set by http header option, Authorization, Proxy-Authorization,
+// Cookie and X-*-Token or X-Api-Key, i.e. "header=Authorization:Bearer%20abc" or url-encoded "sa.header=Cookie%3Asid=1"
+var headerValueRe = regexp.MustCompile(
+	`(?i)([?&](?:sa\.)?header=(?:(?:proxy-)?authorization|cookie|x-(?:[^&:%]|%[0-9a-f]{2})*token|x-api-key)(:|%3a))[^&]*`)
+
+// maskSecrets hides values of env and secret params and credential headers in the url, used for logging
+func maskSecrets(u string) string {
+	u = headerValueRe.ReplaceAllString(u, "${1}****")
+	return secretValueRe.ReplaceAllString(envValueRe.ReplaceAllString(u, "${1}****"), "${1}****")
+}
+
+// SetGates sets conditions to run service checks, service name to condition, see gate for the format.
+// A check with false condition is reported as skipped. Referenced services should be defined.
+func (s *Service) SetGates(gates map[string]string) error {
+	names := map[string]bool{}
+	for _, r := range s.requests {
+		names[r.Name] = true
+	}
+	res := map[string]gate{}
+	for name, cond := range gates {
+		if !names[name] {
+			return fmt.Errorf("gate for unknown service %q", name)
+		}
+		g, err := parseGate(cond)
+		if err != nil {
+			return fmt.Errorf("invalid gate for %q: %w", name, err)
+		}
+		if g.service != "" && !names[g.service] {
+			return fmt.Errorf("gate for %q refers to unknown service %q", name, g.service)
+		}
+		if g.service == name {
+			return fmt.Errorf("gate for %q refers to itself", name)
+		}
+		res[name] = g
+	}
+	s.gates = res
+	return nil
+}
+
+// Schemes returns list of url schemes supported by configured providers
+func (s *Service) Schemes() []Scheme {
+	res := []Scheme{}
+	for _, sc := range schemes {
+		if sc.provider(s.providers) != nil {
+			res = append(res, sc.Scheme)
+		}
+	}
+	return res
+}
+
+// provider returns provider for the given url or nil if url scheme is not supported
+func (s *Service) provider(u string) StatusProvider {
+	for _, sc := range schemes {
+		if strings.HasPrefix(u, sc.Scheme.Scheme+"://") {
+			return sc.provider(s.providers)
+		}
+	}
+	return nil
+}
+
+// Status returns extended service information, runs concurrently.
+// With SoftStart set, requests of the first round are started one by one, evenly spread over the window.
+// Checks with gates run after the checks they refer to, and are skipped if the gate is false.
+// With pre-flight set, remote checks are skipped as offline if the pre-flight fails, local ones still run.
 func (s *Service) Status() []Response {
 	if len(s.requests) == 0 {
 		return nil
 	}
-	res := make([]Response, 0, len(s.requests))
-	wg := syncs.NewSizedGroup(s.concurrency, syncs.Preemptive)
-	ch := make(chan Response, len(s.requests))
-	for _, req := range s.requests {
-		r := req
 
-		wg.Go(func(ctx context.Context) {
+	var ramp time.Duration // delay between starts of the first round requests
+	s.firstRound.Do(func() {
+		ramp = s.SoftStart / time.Duration(len(s.requests))
+	})
 
-			var (
-				resp *Response
-				err  error
-			)
+	var ungated, pending []Request
+	var offline []Response
+	offlineReason := s.checkPreflight()
+	for _, r := range s.requests {
+		if offlineReason != "" && !isLocal(r.URL) {
+			offline = append(offline, offlineResponse(r.Name, maskSecrets(s.preflight.URL), offlineReason))
+			continue
+		}
+		if _, ok := s.gates[r.Name]; ok {
+			pending = append(pending, r)
+			continue
+		}
+		ungated = append(ungated, r)
+	}
 
-			st := time.Now()
+	res := append(s.check(ungated, ramp), offline...)
+	results := map[string]Response{} // by name, for gates
+	add := func(rr ...Response) {
+		for _, r := range rr {
+			res = append(res, r)
+			results[r.Name] = r
+		}
+	}
+	for _, r := range res {
+		results[r.Name] = r
+	}
+	for len(pending) > 0 {
+		var ready, waiting []Request
+		for _, r := range pending {
+			g := s.gates[r.Name]
+			passed, ok, err := g.eval(results)
 			switch {
-			case strings.HasPrefix(r.URL, "http://") || strings.HasPrefix(r.URL, "https://"):
-				resp, err = s.providers.HTTP.Status(r)
-			case strings.HasPrefix(r.URL, "mongodb://"):
-				resp, err = s.providers.Mongo.Status(r)
-			case strings.HasPrefix(r.URL, "mysql://"):
-				resp, err = s.providers.Mysql.Status(r)
-			case strings.HasPrefix(r.URL, "docker://"):
-				resp, err = s.providers.Docker.Status(r)
-			case strings.HasPrefix(r.URL, "program://"):
-				resp, err = s.providers.Program.Status(r)
-			case strings.HasPrefix(r.URL, "nginx://"):
-				resp, err = s.providers.Nginx.Status(r)
-			case strings.HasPrefix(r.URL, "cert://"):
-				resp, err = s.providers.Certificate.Status(r)
-			case strings.HasPrefix(r.URL, "file://"):
-				resp, err = s.providers.File.Status(r)
-			case strings.HasPrefix(r.URL, "rmq://"):
-				resp, err = s.providers.RMQ.Status(r)
+			case err != nil:
+				log.Printf("[WARN] service gate failed: %s %q: %v", r.Name, g.cond, err)
+				add(Response{Name: r.Name, StatusCode: http.StatusInternalServerError, Summary: "gate error (failed)"})
+			case !ok:
+				waiting = append(waiting, r)
+			case passed:
+				ready = append(ready, r)
 			default:
-				log.Printf("[WARN] unsupported protocol for service, %s %s", r.Name, r.URL)
-				ch <- Response{Name: r.Name, StatusCode: http.StatusInternalServerError, ResponseTime: time.Since(st).Milliseconds()}
-				return
+				add(skippedResponse(r.Name, g))
+			}
+		}
+		if len(waiting) == len(pending) { // gates refer to each other, nothing can run
+			for _, r := range waiting {
+				log.Printf("[WARN] service gate refers to gated service in a loop: %s %q", r.Name, s.gates[r.Name].cond)
+				add(Response{Name: r.Name, StatusCode: http.StatusInternalServerError, Summary: "gate loop (failed)"})
 			}
+			break
+		}
+		add(s.check(ready, 0)...)
+		pending = waiting
+	}
 
+	if s.Availability {
+		s.recordAvailability(res, time.Now())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// check runs requests concurrently and returns responses, ramp is the delay between starts of requests
+func (s *Service) check(reqs []Request, ramp time.Duration) []Response {
+	res := make([]Response, 0, len(reqs))
+	wg := syncs.NewSizedGroup(s.concurrency, syncs.Preemptive)
+	ch := make(chan Response, len(reqs))
+	roundStart := time.Now()
+	for i, req := range reqs {
+		r, startAt := req, roundStart.Add(time.Duration(i)*ramp)
+
+		wg.Go(func(ctx context.Context) {
+			time.Sleep(time.Until(startAt))
+			st := time.Now()
+			provider := s.provider(r.URL)
+			if provider == nil {
+				log.Printf("[WARN] unsupported protocol for service, %s %s", r.Name, maskSecrets(r.URL))
+				ch <- Response{Name: r.Name, StatusCode: http.StatusInternalServerError, ResponseTime: time.Since(st).Milliseconds(),
+					Summary: "unsupported protocol (failed)"}
+				return
+			}
+			resp, err := provider.Status(r)
+			if dur := time.Since(st); s.SlowThreshold > 0 && dur > s.SlowThreshold {
+				log.Printf("[INFO] slow service check: %s %s took %v", r.Name, maskSecrets(r.URL), dur.Round(time.Millisecond))
+			}
 			if err != nil {
-				log.Printf("[WARN] service request failed: %s %s: %v", r.Name, r.URL, err)
-				ch <- Response{Name: r.Name, StatusCode: http.StatusInternalServerError, ResponseTime: time.Since(st).Milliseconds()}
+				log.Printf("[WARN] service request failed: %s %s: %v", r.Name, maskSecrets(r.URL), err)
+				ch <- Response{Name: r.Name, StatusCode: http.StatusInternalServerError, ResponseTime: time.Since(st).Milliseconds(),
+					Summary: "check error (failed)"} // error not included, may contain credentials from the url
 				return
 			}
 
 			resp.ResponseTime = time.Since(st).Milliseconds()
+			if s.LatencyDeviation > 0 {
+				latency := s.checkLatency(r.Name, resp.ResponseTime)
+				if resp.Check == nil {
+					resp.Check = map[string]interface{}{}
+				}
+				resp.Check["latency_status"] = latency
+				resp.Summary = withLevel(resp.Summary, latency)
+			}
 			ch <- *resp
-			log.Printf("[DEBUG] service response: %s:%s %+v", r.Name, r.URL, *resp)
+			log.Printf("[DEBUG] service response: %s:%s %+v", r.Name, maskSecrets(r.URL), *resp)
 		})
 	}
 	wg.Wait()
@@ -136,6 +392,59 @@ func (s *Service) Status() []Response {
 	for r := range ch {
 		res = append(res, r)
 	}
-	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
 	return res
 }
+
+// level returns the worst level of statuses, "failed", "warn" or "ok". Statuses not starting with failed or warn are ok.
+// Used to make summary, i.e. level("ok", "warn: weak intermediate signature") is "warn".
+func level(statuses ...string) string {
+	res := "ok"
+	for _, s := range statuses {
+		switch {
+		case strings.HasPrefix(s, "failed"):
+			return "failed"
+		case strings.HasPrefix(s, "warn"):
+			res = "warn"
+		}
+	}
+	return res
+}
+
+// withLevel returns the summary with its level raised to the level of the status, i.e. "http 200 (ok)" with
+// "warn: response time 412ms" status is "http 200 (warn)". The level is added if the summary has none.
+func withLevel(summary, status string) string {
+	lvl := level(status)
+	for _, curr := range []string{"failed", "warn", "ok"} {
+		if !strings.HasSuffix(summary, "("+curr+")") {
+			continue
+		}
+		if level(curr, lvl) == curr {
+			return summary
+		}
+		return strings.TrimSuffix(summary, "("+curr+")") + "(" + lvl + ")"
+	}
+	if lvl == "ok" {
+		return summary
+	}
+	return strings.TrimSpace(summary + " (" + lvl + ")")
+}
+
+// isUp checks the response is not failed, by status code and summary level
+func isUp(r Response) bool {
+	return r.StatusCode < http.StatusBadRequest && !strings.HasSuffix(r.Summary, "(failed)")
+}
+
+// checkLatency adds response time to the service's baseline and returns latency status
+func (s *Service) checkLatency(name string, rt int64) string {
+	s.latency.once.Do(func() {
+		s.latency.stats = make(map[string]*latencyStats)
+	})
+	s.latency.lock.Lock()
+	defer s.latency.lock.Unlock()
+	stats, ok := s.latency.stats[name]
+	if !ok {
+		stats = &latencyStats{}
+		s.latency.stats[name] = stats
+	}
+	return stats.add(rt, s.LatencyDeviation)
+}
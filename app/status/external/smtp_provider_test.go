@@ -0,0 +1,171 @@
+package external
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPProvider_Status(t *testing.T) {
+	ts := httptest.NewTLSServer(nil) // only for the self-signed certificate
+	cert := ts.TLS.Certificates[0]
+	ts.Close()
+
+	addr := startFakeSMTP(t, "220 mail.example.com ESMTP ready", cert)
+	p := SMTPProvider{TimeOut: time.Second}
+
+	{ // ehlo only
+		resp, err := p.Status(Request{Name: "mail", URL: "smtp://" + addr})
+		require.NoError(t, err)
+		assert.Equal(t, "mail", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"greeting": "mail.example.com ESMTP ready", "tls": false, "status": "ok",
+			"extensions": []string{"PIPELINING", "SIZE 10240000", "STARTTLS", "8BITMIME"}}, resp.Body)
+		assert.Equal(t, fmt.Sprintf("smtp %s, 4 extensions (ok)", addr), resp.Summary)
+	}
+
+	{ // starttls and probe of the known recipient
+		resp, err := p.Status(Request{Name: "mail", URL: "smtp://" + addr + "?starttls=true&insecure=true&probe=postmaster@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Body["status"])
+		assert.Equal(t, true, resp.Body["tls"])
+		assert.Equal(t, "TLS 1.3", resp.Body["tls_version"])
+		assert.Equal(t, []string{"PIPELINING", "SIZE 10240000", "8BITMIME", "AUTH PLAIN"}, resp.Body["extensions"])
+		assert.Equal(t, "250 2.1.5 ok", resp.Body["probe_response"])
+		assert.Equal(t, fmt.Sprintf("smtp %s, 4 extensions, tls (ok)", addr), resp.Summary)
+	}
+
+	{ // unknown recipient
+		resp, err := p.Status(Request{Name: "mail", URL: "smtp://" + addr + "?probe=nobody@example.com&from=monitor@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: recipient nobody@example.com rejected, 550 5.1.1 no such user", resp.Body["status"])
+		assert.Equal(t, "550 5.1.1 no such user", resp.Body["probe_response"])
+		assert.Equal(t, fmt.Sprintf("smtp %s, 4 extensions (failed)", addr), resp.Summary)
+	}
+
+	{ // rejected sender
+		resp, err := p.Status(Request{Name: "mail", URL: "smtp://" + addr + "?probe=postmaster@example.com&from=spam@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: sender rejected, 553 5.7.1 sender denied", resp.Body["status"])
+	}
+
+	{ // self-signed certificate not verified
+		_, err := p.Status(Request{Name: "mail", URL: "smtp://" + addr + "?starttls=true"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "smtp tls handshake failed: mail "+addr)
+	}
+
+	{ // starttls not advertised
+		noTLS := startFakeSMTP(t, "220 relay.example.com ESMTP", tls.Certificate{})
+		resp, err := p.Status(Request{Name: "relay", URL: "smtp://" + noTLS + "?starttls=true&probe=postmaster@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: starttls not advertised", resp.Body["status"])
+		assert.NotContains(t, resp.Body, "probe_response")
+	}
+
+	{ // non-2xx greeting
+		busy := startFakeSMTP(t, "554 5.3.2 too busy", tls.Certificate{})
+		_, err := p.Status(Request{Name: "busy", URL: "smtp://" + busy})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "smtp greeting failed: busy "+busy)
+		assert.Contains(t, err.Error(), "too busy")
+	}
+
+	{ // connection refused
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		closed := lis.Addr().String()
+		require.NoError(t, lis.Close())
+		_, err = p.Status(Request{Name: "mail", URL: "smtp://" + closed})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "smtp connect failed: mail "+closed)
+	}
+
+	_, err := p.Status(Request{Name: "mail", URL: "smtp://:25"})
+	require.EqualError(t, err, "smtp host is empty: mail smtp://:25")
+}
+
+// startFakeSMTP starts smtp server with the given greeting, STARTTLS is advertised if the certificate is set.
+// It knows postmaster@example.com recipient and rejects spam@example.com sender.
+func startFakeSMTP(t *testing.T, greeting string, cert tls.Certificate) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	handle := func(conn net.Conn) {
+		defer conn.Close()
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		write := func(lines ...string) {
+			for _, l := range lines {
+				_, _ = rw.WriteString(l + "\r\n")
+			}
+			_ = rw.Flush()
+		}
+		write(greeting)
+		if !strings.HasPrefix(greeting, "2") {
+			return
+		}
+		secure := false
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO "):
+				if secure {
+					write("250-mail.example.com", "250-PIPELINING", "250-SIZE 10240000", "250-8BITMIME", "250 AUTH PLAIN")
+					continue
+				}
+				if cert.Certificate == nil {
+					write("250-relay.example.com", "250-PIPELINING", "250-SIZE 10240000", "250 8BITMIME")
+					continue
+				}
+				write("250-mail.example.com", "250-PIPELINING", "250-SIZE 10240000", "250-STARTTLS", "250 8BITMIME")
+			case cmd == "STARTTLS":
+				write("220 2.0.0 ready to start tls")
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+				if tlsConn.Handshake() != nil {
+					return
+				}
+				conn, secure = tlsConn, true
+				rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+			case cmd == "MAIL FROM:<spam@example.com>":
+				write("553 5.7.1 sender denied")
+			case strings.HasPrefix(cmd, "MAIL FROM:<"):
+				write("250 2.1.0 ok")
+			case cmd == "RCPT TO:<postmaster@example.com>":
+				write("250 2.1.5 ok")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				write("550 5.1.1 no such user")
+			case cmd == "RSET":
+				write("250 2.0.0 ok")
+			case cmd == "QUIT":
+				write("221 2.0.0 bye")
+				return
+			default:
+				write("502 5.5.2 unknown command")
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return lis.Addr().String()
+}
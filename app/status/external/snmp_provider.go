@@ -0,0 +1,128 @@
+package external
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// SNMPProvider is a status provider that gets a value of SNMP OID
+type SNMPProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: snmp://community@host:161/1.3.6.1.2.1.1.3.0?expect=123
+// Community default is "public", port default is 161 and version is 2c unless "version=1" is set.
+// Optional "expect" checks the value is exactly the same, "min" and "max" check numeric value is in range.
+func (s *SNMPProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("snmp url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	oid := strings.Trim(uu.Path, "/")
+	if oid == "" {
+		return nil, fmt.Errorf("snmp oid is empty: %s %s", req.Name, req.URL)
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    uu.Hostname(),
+		Port:      161,
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   s.TimeOut,
+		Retries:   0,
+	}
+	if uu.User != nil && uu.User.Username() != "" {
+		client.Community = uu.User.Username()
+	}
+	if uu.Port() != "" {
+		port, e := strconv.ParseUint(uu.Port(), 10, 16)
+		if e != nil {
+			return nil, fmt.Errorf("snmp port parse failed: %s %s: %w", req.Name, req.URL, e)
+		}
+		client.Port = uint16(port)
+	}
+	if uu.Query().Get("version") == "1" {
+		client.Version = gosnmp.Version1
+	}
+
+	if err = client.Connect(); err != nil {
+		return nil, fmt.Errorf("snmp connect failed: %s %s: %w", req.Name, net.JoinHostPort(client.Target, uu.Port()), err)
+	}
+	defer client.Conn.Close() // nolint
+
+	pkt, err := client.Get([]string{oid})
+	if err != nil {
+		return nil, fmt.Errorf("snmp get failed: %s %s: %w", req.Name, oid, err)
+	}
+	if pkt.Error != gosnmp.NoError {
+		return nil, fmt.Errorf("snmp get failed: %s %s: %s", req.Name, oid, pkt.Error)
+	}
+	if len(pkt.Variables) == 0 {
+		return nil, fmt.Errorf("snmp get returned no variables: %s %s", req.Name, oid)
+	}
+
+	pdu := pkt.Variables[0]
+	body := map[string]interface{}{
+		"oid":    oid,
+		"type":   pdu.Type.String(),
+		"status": "ok",
+	}
+
+	switch pdu.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		body["status"] = "failed: " + pdu.Type.String()
+	case gosnmp.OctetString:
+		body["value"] = string(pdu.Value.([]byte))
+	case gosnmp.ObjectIdentifier, gosnmp.IPAddress:
+		body["value"] = pdu.Value
+	default:
+		body["value"] = gosnmp.ToBigInt(pdu.Value).Int64()
+	}
+
+	if body["status"] == "ok" {
+		body["status"] = s.checkValue(body["value"], uu.Query())
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      fmt.Sprintf("oid %s value %v (%s)", oid, body["value"], level(body["status"].(string))),
+	}
+	if _, ok := body["value"]; !ok {
+		result.Summary = fmt.Sprintf("oid %s %s (failed)", oid, pdu.Type)
+	}
+	return &result, nil
+}
+
+// checkValue checks the value against "expect", "min" and "max" query params and returns status
+func (s *SNMPProvider) checkValue(value interface{}, q url.Values) string {
+	strValue := fmt.Sprintf("%v", value)
+	if expect := q.Get("expect"); expect != "" && strValue != expect {
+		return fmt.Sprintf("failed: value %s, expected %s", strValue, expect)
+	}
+
+	if q.Get("min") == "" && q.Get("max") == "" {
+		return "ok"
+	}
+	num, err := strconv.ParseFloat(strValue, 64)
+	if err != nil {
+		return fmt.Sprintf("failed: value %s is not numeric", strValue)
+	}
+	if minVal, err := strconv.ParseFloat(q.Get("min"), 64); err == nil && num < minVal {
+		return fmt.Sprintf("failed: value %s below min %s", strValue, q.Get("min"))
+	}
+	if maxVal, err := strconv.ParseFloat(q.Get("max"), 64); err == nil && num > maxVal {
+		return fmt.Sprintf("failed: value %s above max %s", strValue, q.Get("max"))
+	}
+	return "ok"
+}
@@ -0,0 +1,103 @@
+package external
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSNMPProvider_Status(t *testing.T) {
+	addr := startTestSNMPAgent(t, "secret", map[string]gosnmp.SnmpPDU{
+		"1.3.6.1.2.1.1.3.0": {Type: gosnmp.TimeTicks, Value: uint32(123456)},
+		"1.3.6.1.2.1.1.5.0": {Type: gosnmp.OctetString, Value: []byte("switch1")},
+	})
+
+	p := SNMPProvider{TimeOut: time.Second}
+
+	tbl := []struct {
+		url    string
+		value  interface{}
+		status string
+	}{
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.3.0", int64(123456), "ok"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.5.0", "switch1", "ok"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.5.0?expect=switch1", "switch1", "ok"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.5.0?expect=switch2", "switch1",
+			"failed: value switch1, expected switch2"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.3.0?min=100&max=200000", int64(123456), "ok"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.3.0?min=200000", int64(123456),
+			"failed: value 123456 below min 200000"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.3.0?max=100", int64(123456), "failed: value 123456 above max 100"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.5.0?max=100", "switch1", "failed: value switch1 is not numeric"},
+		{"snmp://secret@" + addr + "/1.3.6.1.2.1.1.9.0", nil, "failed: NoSuchObject"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "snmp", URL: tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, "snmp", resp.Name)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.value, resp.Body["value"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.True(t, strings.HasSuffix(resp.Summary, "("+level(tt.status)+")"), resp.Summary)
+		})
+	}
+}
+
+func TestSNMPProvider_StatusFailed(t *testing.T) {
+	addr := startTestSNMPAgent(t, "secret", map[string]gosnmp.SnmpPDU{
+		"1.3.6.1.2.1.1.3.0": {Type: gosnmp.TimeTicks, Value: uint32(123456)},
+	})
+	p := SNMPProvider{TimeOut: 200 * time.Millisecond}
+
+	_, err := p.Status(Request{Name: "snmp", URL: "snmp://wrong@" + addr + "/1.3.6.1.2.1.1.3.0"})
+	require.Error(t, err, "agent doesn't respond to unknown community")
+
+	_, err = p.Status(Request{Name: "snmp", URL: "snmp://secret@" + addr})
+	require.EqualError(t, err, "snmp oid is empty: snmp snmp://secret@"+addr)
+}
+
+// startTestSNMPAgent starts udp snmp agent on random port responding to get requests with given community
+func startTestSNMPAgent(t *testing.T, community string, values map[string]gosnmp.SnmpPDU) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		decoder := &gosnmp.GoSNMP{Logger: gosnmp.NewLogger(nil)}
+		buf := make([]byte, 4096)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req, err := decoder.SnmpDecodePacket(buf[:n])
+			if err != nil || req.Community != community {
+				continue
+			}
+			resp := &gosnmp.SnmpPacket{Version: req.Version, Community: req.Community, PDUType: gosnmp.GetResponse,
+				RequestID: req.RequestID}
+			for _, v := range req.Variables {
+				name := strings.TrimPrefix(v.Name, ".")
+				pdu, ok := values[name]
+				if !ok {
+					pdu = gosnmp.SnmpPDU{Type: gosnmp.NoSuchObject}
+				}
+				pdu.Name = v.Name
+				resp.Variables = append(resp.Variables, pdu)
+			}
+			data, err := resp.MarshalMsg()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(data, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
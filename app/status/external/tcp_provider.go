@@ -0,0 +1,42 @@
+package external
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// TCPProvider is a status provider that checks if a tcp port accepts connections
+type TCPProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: tcp://example.com:5432. It connects to the port and closes the connection right after
+// the handshake, nothing is sent. Connection refused or timeout returned as error.
+func (t *TCPProvider) Status(req Request) (*Response, error) {
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("tcp url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+	if uu.Hostname() == "" || uu.Port() == "" {
+		return nil, fmt.Errorf("tcp host or port is empty: %s %s", req.Name, req.URL)
+	}
+
+	st := time.Now()
+	conn, err := net.DialTimeout("tcp", uu.Host, t.TimeOut)
+	if err != nil {
+		return nil, fmt.Errorf("tcp connect failed: %s %s: %w", req.Name, uu.Host, err)
+	}
+	connectTime := time.Since(st).Milliseconds()
+	conn.Close() // nolint
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         map[string]interface{}{"status": "ok", "connect_time_ms": connectTime},
+		ResponseTime: connectTime,
+		Summary:      fmt.Sprintf("tcp %s connected in %dms (ok)", uu.Host, connectTime),
+	}
+	return &result, nil
+}
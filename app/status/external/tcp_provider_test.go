@@ -0,0 +1,54 @@
+package external
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPProvider_Status(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	p := TCPProvider{TimeOut: time.Second}
+	resp, err := p.Status(Request{Name: "pg", URL: "tcp://" + lis.Addr().String()})
+	require.NoError(t, err)
+	assert.Equal(t, "pg", resp.Name)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "ok", resp.Body["status"])
+	assert.Equal(t, resp.ResponseTime, resp.Body["connect_time_ms"])
+	assert.Regexp(t, `^tcp 127.0.0.1:\d+ connected in \d+ms \(ok\)$`, resp.Summary)
+	assert.Empty(t, <-received, "nothing sent after handshake")
+}
+
+func TestTCPProvider_StatusFailed(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	p := TCPProvider{TimeOut: time.Second}
+	_, err = p.Status(Request{Name: "pg", URL: "tcp://" + addr})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tcp connect failed: pg "+addr)
+
+	_, err = p.Status(Request{Name: "pg", URL: "tcp://127.0.0.1"})
+	require.EqualError(t, err, "tcp host or port is empty: pg tcp://127.0.0.1")
+}
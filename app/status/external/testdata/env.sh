@@ -0,0 +1,4 @@
+#!/usr/bin/env sh
+
+echo "dir: $(pwd)"
+echo "mode: $CHECK_MODE, token: $CHECK_TOKEN"
@@ -0,0 +1,6 @@
+#!/usr/bin/env sh
+# prints state and exits with the code from the first argument, stderr gets a long line
+
+echo "state: degraded"
+head -c 2000 /dev/zero | tr '\0' 'e' >&2
+exit "${1:-0}"
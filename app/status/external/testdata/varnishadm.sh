@@ -0,0 +1,11 @@
+#!/usr/bin/env sh
+# fake varnishadm, prints backend.list fixture, "-n sick" instance has a sick backend
+dir=$(dirname "$0")
+if [ "$1" = "-n" ] && [ "$2" = "sick" ]; then
+  cat "$dir/varnish_backends_sick.txt"
+  exit 0
+fi
+case "$1" in
+  backend.list) cat "$dir/varnish_backends.txt" ;;
+  *) exit 1 ;;
+esac
@@ -0,0 +1,4 @@
+#!/usr/bin/env sh
+# fake varnishstat, prints json counters fixture
+dir=$(dirname "$0")
+cat "$dir/varnishstat.json"
@@ -0,0 +1,262 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// varnishadm and varnishstat binaries, can be changed for tests
+var (
+	varnishadmCmd  = "varnishadm"
+	varnishstatCmd = "varnishstat"
+)
+
+// VarnishProvider is a status provider that checks varnish backends health and cache hit ratio
+type VarnishProvider struct {
+	TimeOut time.Duration
+}
+
+// varnishBackend is a backend line of "backend.list" output
+type varnishBackend struct {
+	Admin  string `json:"admin"`  // probe, healthy or sick, as set with backend.set_health
+	Probe  string `json:"probe"`  // good/total probes, 0/0 without probe
+	Health string `json:"health"` // healthy or sick
+}
+
+// Status url looks like: varnish://localhost?instance=name for local varnish, it runs "varnishadm backend.list" and
+// "varnishstat -1 -j" with optional -n instance. With "method=admin" backends are requested directly from the admin
+// interface, varnish://10.0.0.13:6082?method=admin&secret=/etc/varnish/secret, secret file is optional. The admin
+// interface doesn't expose counters, so the hit ratio is reported for the local varnish only.
+// Status is failed if any backend is sick.
+// backend.list output looks like this:
+//
+//	Backend name                   Admin      Probe    Health     Last change
+//	boot.default                   probe      5/5      healthy    Wed, 14 Oct 2026 10:00:00 GMT
+//	boot.api                       probe      0/5      sick       Wed, 14 Oct 2026 10:12:40 GMT
+func (v *VarnishProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("varnish url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
+	body := map[string]interface{}{}
+	var backendsList string
+	switch method := uu.Query().Get("method"); method {
+	case "", "cli":
+		ctx, cancel := context.WithTimeout(context.Background(), v.TimeOut)
+		defer cancel()
+		if backendsList, err = v.run(ctx, varnishadmCmd, uu.Query().Get("instance"), "backend.list"); err != nil {
+			return nil, fmt.Errorf("varnish backends request failed: %s %s: %w", req.Name, req.URL, err)
+		}
+		stats, err := v.run(ctx, varnishstatCmd, uu.Query().Get("instance"), "-1", "-j")
+		if err != nil {
+			return nil, fmt.Errorf("varnish stats request failed: %s %s: %w", req.Name, req.URL, err)
+		}
+		hits, err := parseVarnishStats(stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse varnish stats for %s %s: %w", req.Name, req.URL, err)
+		}
+		for k, val := range hits {
+			body[k] = val
+		}
+	case "admin":
+		if uu.Host == "" {
+			return nil, fmt.Errorf("varnish host is empty: %s %s", req.Name, req.URL)
+		}
+		if backendsList, err = v.admin(uu.Host, uu.Query().Get("secret"), "backend.list"); err != nil {
+			return nil, fmt.Errorf("varnish admin request failed: %s %s: %w", req.Name, req.URL, err)
+		}
+	default:
+		return nil, fmt.Errorf("varnish method %q is not supported: %s %s", method, req.Name, req.URL)
+	}
+
+	backends, err := parseVarnishBackends(backendsList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse varnish backends for %s %s: %w", req.Name, req.URL, err)
+	}
+	var sick []string
+	for name, b := range backends {
+		if b.Health != "healthy" {
+			sick = append(sick, name)
+		}
+	}
+	sort.Strings(sick)
+
+	body["backends"] = backends
+	body["backends_total"] = len(backends)
+	body["backends_sick"] = len(sick)
+	body["status"] = "ok"
+	if len(sick) > 0 {
+		body["status"] = "failed: sick backends " + strings.Join(sick, ",")
+	}
+
+	summary := fmt.Sprintf("varnish %d of %d backends healthy", len(backends)-len(sick), len(backends))
+	if ratio, ok := body["hit_ratio"]; ok {
+		summary += fmt.Sprintf(", hit ratio %.1f%%", ratio)
+	}
+	return &Response{Name: req.Name, StatusCode: 200, Body: body, ResponseTime: time.Since(st).Milliseconds(),
+		Summary: fmt.Sprintf("%s (%s)", summary, level(body["status"].(string)))}, nil
+}
+
+// run executes varnish command for the optional instance and returns its output
+func (v *VarnishProvider) run(ctx context.Context, command, instance string, args ...string) (string, error) {
+	if instance != "" {
+		args = append([]string{"-n", instance}, args...)
+	}
+	out, err := exec.CommandContext(ctx, command, args...).Output() //nolint:gosec // command is fixed, args from the config
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s: %w", command, err)
+	}
+	return string(out), nil
+}
+
+// admin connects to varnish admin interface, authenticates with the content of the secret file if required,
+// and returns the response of the command. See varnish-cli(7) for the protocol.
+func (v *VarnishProvider) admin(addr, secretFile, command string) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, v.TimeOut)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close() // nolint
+	if err = conn.SetDeadline(time.Now().Add(v.TimeOut)); err != nil {
+		return "", fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	rd := bufio.NewReader(conn)
+	code, banner, err := readVarnishResponse(rd)
+	if err != nil {
+		return "", err
+	}
+	if code == 107 { // authentication required, the challenge is the first line of the banner
+		if secretFile == "" {
+			return "", fmt.Errorf("authentication required, no secret file")
+		}
+		secret, e := os.ReadFile(secretFile) //nolint:gosec // file from the config
+		if e != nil {
+			return "", fmt.Errorf("failed to read secret: %w", e)
+		}
+		challenge, _, _ := strings.Cut(banner, "\n")
+		h := sha256.New()
+		_, _ = io.WriteString(h, challenge+"\n"+string(secret)+challenge+"\n")
+		if _, err = fmt.Fprintf(conn, "auth %s\n", hex.EncodeToString(h.Sum(nil))); err != nil {
+			return "", fmt.Errorf("failed to send auth: %w", err)
+		}
+		if code, _, err = readVarnishResponse(rd); err != nil {
+			return "", err
+		}
+	}
+	if code != 200 {
+		return "", fmt.Errorf("unexpected response code %d", code)
+	}
+
+	if _, err = fmt.Fprintf(conn, "%s\n", command); err != nil {
+		return "", fmt.Errorf("failed to send %s: %w", command, err)
+	}
+	code, resp, err := readVarnishResponse(rd)
+	if err != nil {
+		return "", err
+	}
+	if code != 200 {
+		return "", fmt.Errorf("%s failed with code %d: %s", command, code, strings.TrimSpace(resp))
+	}
+	return resp, nil
+}
+
+// readVarnishResponse reads admin interface response, the status line with code and body length, then the body
+func readVarnishResponse(rd *bufio.Reader) (code int, body string, err error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read response: %w", err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return 0, "", fmt.Errorf("unexpected status line %q", strings.TrimSpace(line))
+	}
+	if code, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, "", fmt.Errorf("failed to parse code %q: %w", fields[0], err)
+	}
+	size, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse length %q: %w", fields[1], err)
+	}
+	buf := make([]byte, size+1) // the body is followed by newline
+	if _, err = io.ReadFull(rd, buf); err != nil {
+		return 0, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	return code, string(buf[:size]), nil
+}
+
+// parseVarnishBackends parses backend.list output, the header line is skipped
+func parseVarnishBackends(out string) (map[string]varnishBackend, error) {
+	res := map[string]varnishBackend{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" || strings.HasPrefix(line, "Backend name") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("unexpected backend line %q", line)
+		}
+		res[fields[0]] = varnishBackend{Admin: fields[1], Probe: fields[2], Health: fields[3]}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no backends")
+	}
+	return res, nil
+}
+
+// parseVarnishStats parses "varnishstat -1 -j" output and returns cache hits, misses and hit ratio in percents.
+// Varnish 6.5+ has counters in "counters" field, older versions at the top level.
+func parseVarnishStats(out string) (map[string]interface{}, error) {
+	type counter struct {
+		Value uint64 `json:"value"`
+	}
+	var stats struct {
+		Counters map[string]counter `json:"counters"`
+	}
+	if err := json.Unmarshal([]byte(out), &stats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	if stats.Counters == nil {
+		flat := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(out), &flat); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal: %w", err)
+		}
+		stats.Counters = map[string]counter{}
+		for k, raw := range flat {
+			var c counter
+			if json.Unmarshal(raw, &c) == nil {
+				stats.Counters[k] = c // timestamp and other non-counter fields are skipped
+			}
+		}
+	}
+
+	hit, ok := stats.Counters["MAIN.cache_hit"]
+	if !ok {
+		return nil, fmt.Errorf("no MAIN.cache_hit in stats")
+	}
+	miss, ok := stats.Counters["MAIN.cache_miss"]
+	if !ok {
+		return nil, fmt.Errorf("no MAIN.cache_miss in stats")
+	}
+	ratio := 0.0
+	if total := hit.Value + miss.Value; total > 0 {
+		ratio = math.Round(float64(hit.Value)/float64(total)*10000) / 100
+	}
+	return map[string]interface{}{"cache_hit": hit.Value, "cache_miss": miss.Value, "hit_ratio": ratio}, nil
+}
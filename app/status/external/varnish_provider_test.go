@@ -0,0 +1,149 @@
+package external
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarnishProvider_Status(t *testing.T) {
+	fakeAdm, err := filepath.Abs("testdata/varnishadm.sh")
+	require.NoError(t, err)
+	fakeStat, err := filepath.Abs("testdata/varnishstat.sh")
+	require.NoError(t, err)
+	origAdm, origStat := varnishadmCmd, varnishstatCmd
+	varnishadmCmd, varnishstatCmd = fakeAdm, fakeStat
+	defer func() { varnishadmCmd, varnishstatCmd = origAdm, origStat }()
+
+	p := VarnishProvider{TimeOut: time.Second}
+
+	{ // all backends healthy
+		resp, err := p.Status(Request{Name: "cache", URL: "varnish://localhost"})
+		require.NoError(t, err)
+		assert.Equal(t, "cache", resp.Name)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{
+			"backends": map[string]varnishBackend{
+				"boot.default": {Admin: "probe", Probe: "5/5", Health: "healthy"},
+				"boot.api":     {Admin: "probe", Probe: "4/5", Health: "healthy"},
+			},
+			"backends_total": 2, "backends_sick": 0, "cache_hit": uint64(106250), "cache_miss": uint64(18750),
+			"hit_ratio": 85.0, "status": "ok",
+		}, resp.Body)
+		assert.Equal(t, "varnish 2 of 2 backends healthy, hit ratio 85.0% (ok)", resp.Summary)
+	}
+
+	{ // sick backends of the instance
+		resp, err := p.Status(Request{Name: "cache", URL: "varnish://localhost?instance=sick"})
+		require.NoError(t, err)
+		assert.Equal(t, "failed: sick backends boot.api,boot.static", resp.Body["status"])
+		assert.Equal(t, 3, resp.Body["backends_total"])
+		assert.Equal(t, 2, resp.Body["backends_sick"])
+		assert.Equal(t, "varnish 1 of 3 backends healthy, hit ratio 85.0% (failed)", resp.Summary)
+	}
+
+	_, err = p.Status(Request{Name: "cache", URL: "varnish://localhost?method=blah"})
+	require.EqualError(t, err, `varnish method "blah" is not supported: cache varnish://localhost?method=blah`)
+
+	varnishadmCmd = "testdata/no-such-varnishadm"
+	_, err = p.Status(Request{Name: "cache", URL: "varnish://localhost"})
+	require.Error(t, err)
+}
+
+func TestVarnishProvider_StatusAdmin(t *testing.T) {
+	backends, err := os.ReadFile("testdata/varnish_backends_sick.txt")
+	require.NoError(t, err)
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("s3cret\n"), 0o600))
+
+	const challenge = "abcdefghijklmnopqrstuvwxyzabcdef"
+	h := sha256.Sum256([]byte(challenge + "\n" + "s3cret\n" + challenge + "\n"))
+	expAuth := "auth " + hex.EncodeToString(h[:])
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				write := func(code int, body string) { _, _ = fmt.Fprintf(conn, "%-3d %-8d\n%s\n", code, len(body), body) }
+				write(107, challenge+"\n\nAuthentication required.\n")
+				rd := bufio.NewReader(conn)
+				line, _ := rd.ReadString('\n')
+				if strings.TrimSpace(line) != expAuth {
+					write(107, challenge+"\n\nAuthentication required.\n")
+					return
+				}
+				write(200, "-----------------------------\nVarnish Cache CLI 1.0\n")
+				if line, _ = rd.ReadString('\n'); strings.TrimSpace(line) == "backend.list" {
+					write(200, string(backends))
+					return
+				}
+				write(101, "Unknown request.")
+			}(conn)
+		}
+	}()
+
+	p := VarnishProvider{TimeOut: time.Second}
+	resp, err := p.Status(Request{Name: "cache", URL: "varnish://" + lis.Addr().String() + "?method=admin&secret=" + secretFile})
+	require.NoError(t, err)
+	assert.Equal(t, "failed: sick backends boot.api,boot.static", resp.Body["status"])
+	assert.Equal(t, varnishBackend{Admin: "sick", Probe: "0/0", Health: "sick"},
+		resp.Body["backends"].(map[string]varnishBackend)["boot.static"])
+	assert.NotContains(t, resp.Body, "hit_ratio")
+	assert.Equal(t, "varnish 1 of 3 backends healthy (failed)", resp.Summary)
+
+	_, err = p.Status(Request{Name: "cache", URL: "varnish://" + lis.Addr().String() + "?method=admin"})
+	require.EqualError(t, err, "varnish admin request failed: cache varnish://"+lis.Addr().String()+
+		"?method=admin: authentication required, no secret file")
+
+	badSecret := filepath.Join(t.TempDir(), "bad")
+	require.NoError(t, os.WriteFile(badSecret, []byte("bad\n"), 0o600))
+	_, err = p.Status(Request{Name: "cache", URL: "varnish://" + lis.Addr().String() + "?method=admin&secret=" + badSecret})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected response code 107")
+
+	_, err = p.Status(Request{Name: "cache", URL: "varnish://?method=admin"})
+	require.EqualError(t, err, "varnish host is empty: cache varnish://?method=admin")
+}
+
+func Test_parseVarnishBackends(t *testing.T) {
+	_, err := parseVarnishBackends("Backend name   Admin   Probe   Health   Last change\n")
+	require.EqualError(t, err, "no backends")
+
+	_, err = parseVarnishBackends("boot.default probe\n")
+	require.EqualError(t, err, `unexpected backend line "boot.default probe"`)
+}
+
+func Test_parseVarnishStats(t *testing.T) {
+	// varnish before 6.5 has counters at the top level
+	res, err := parseVarnishStats(`{"timestamp": "2020-01-01T10:00:00", "MAIN.cache_hit": {"value": 30},
+		"MAIN.cache_miss": {"value": 10}}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"cache_hit": uint64(30), "cache_miss": uint64(10), "hit_ratio": 75.0}, res)
+
+	res, err = parseVarnishStats(`{"counters": {"MAIN.cache_hit": {"value": 0}, "MAIN.cache_miss": {"value": 0}}}`)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, res["hit_ratio"])
+
+	_, err = parseVarnishStats(`{"counters": {"MAIN.cache_hit": {"value": 1}}}`)
+	require.EqualError(t, err, "no MAIN.cache_miss in stats")
+
+	_, err = parseVarnishStats(`not json`)
+	require.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package external
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookProvider is a status provider that sends signed test payload to a webhook receiver
+type WebhookProvider struct {
+	TimeOut time.Duration
+}
+
+// webhookOptions lists query params used by the provider itself, removed from the receiver url
+var webhookOptions = []string{"tls", "secret", "header", "payload", "expect"}
+
+const defaultSignatureHeader = "X-Signature-256"
+
+// Status url looks like: webhook://example.com/hooks/deploy?secret=s3cret&expect=2xx. It sends POST with json payload
+// signed with HMAC-SHA256 of the secret, "sha256=<hex>" in "header" (X-Signature-256 by default), and checks the
+// receiver responds with the expected status, exact code or class, 2xx by default. The payload is "payload" json object,
+// with "dry_run":true always set to let the receiver skip side effects. "tls=true" switches to https.
+func (w *WebhookProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	// errors don't include the url, it has the secret
+	uu, err := url.Parse(req.URL)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return nil, fmt.Errorf("webhook url parse failed: %s: %w", req.Name, err)
+	}
+	if uu.Host == "" {
+		return nil, fmt.Errorf("webhook host is empty: %s", req.Name)
+	}
+	query := uu.Query()
+	for _, k := range webhookOptions {
+		query.Del(k)
+	}
+	target := url.URL{Scheme: "http", Host: uu.Host, Path: uu.Path, RawQuery: query.Encode()}
+	if uu.Query().Get("tls") == "true" {
+		target.Scheme = "https"
+	}
+
+	payload, err := webhookPayload(uu.Query().Get("payload"))
+	if err != nil {
+		return nil, fmt.Errorf("webhook payload parse failed: %s %s: %w", req.Name, target.Host, err)
+	}
+	expect := uu.Query().Get("expect")
+	if expect == "" {
+		expect = "2xx"
+	}
+	header := uu.Query().Get("header")
+	if header == "" {
+		header = defaultSignatureHeader
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("webhook request failed: %s %s: %w", req.Name, target.String(), err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if secret := uu.Query().Get("secret"); secret != "" {
+		httpReq.Header.Set(header, webhookSignature(secret, payload))
+	}
+
+	client := http.Client{Timeout: w.TimeOut}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("webhook request failed: %s %s: %w", req.Name, target.String(), err)
+	}
+	defer resp.Body.Close()               // nolint
+	_, _ = io.Copy(io.Discard, resp.Body) // drain for connection reuse
+
+	body := map[string]interface{}{"receiver_status": resp.StatusCode, "expect": expect, "status": "ok"}
+	if !statusMatch(resp.StatusCode, expect) {
+		body["status"] = fmt.Sprintf("failed: receiver responded %d, expected %s", resp.StatusCode, expect)
+	}
+
+	result := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      fmt.Sprintf("webhook %s responded %d (%s)", target.Host, resp.StatusCode, level(body["status"].(string))),
+	}
+	return &result, nil
+}
+
+// webhookPayload makes json payload from the given json object, or an empty one, with dry_run set to true
+func webhookPayload(src string) ([]byte, error) {
+	payload := map[string]interface{}{}
+	if src != "" {
+		if err := json.Unmarshal([]byte(src), &payload); err != nil {
+			return nil, err
+		}
+	}
+	if payload == nil { // json null
+		payload = map[string]interface{}{}
+	}
+	if _, ok := payload["event"]; !ok {
+		payload["event"] = "sys-agent.test"
+	}
+	payload["dry_run"] = true
+	return json.Marshal(payload)
+}
+
+// webhookSignature returns HMAC-SHA256 of the payload as "sha256=<hex>", the format used by github and others
+func webhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// statusMatch checks status code against expected code, i.e. 204, or class, i.e. 2xx
+func statusMatch(code int, expect string) bool {
+	if len(expect) == 3 && strings.HasSuffix(strings.ToLower(expect), "xx") {
+		return strconv.Itoa(code)[:1] == expect[:1]
+	}
+	return strconv.Itoa(code) == expect
+}
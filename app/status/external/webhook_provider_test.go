@@ -0,0 +1,111 @@
+package external
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookProvider_Status(t *testing.T) {
+	var received map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/hooks/deploy", r.URL.Path)
+		assert.Equal(t, "", r.URL.Query().Get("secret"), "option not passed to the receiver")
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		header := r.Header.Get("X-Signature-256")
+		if r.URL.Query().Get("src") == "github" {
+			header = r.Header.Get("X-Hub-Signature-256")
+		}
+		if !hmac.Equal([]byte(header), []byte(webhookSignature("s3cret", data))) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.NoError(t, json.Unmarshal(data, &received))
+		if received["dry_run"] != true {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	p := WebhookProvider{TimeOut: time.Second}
+
+	tbl := []struct {
+		url     string
+		code    int
+		status  string
+		summary string
+	}{
+		{"webhook://" + host + "/hooks/deploy?secret=s3cret", 204, "ok", "webhook " + host + " responded 204 (ok)"},
+		{"webhook://" + host + "/hooks/deploy?secret=s3cret&expect=204", 204, "ok", "webhook " + host + " responded 204 (ok)"},
+		{"webhook://" + host + "/hooks/deploy?src=github&secret=s3cret&header=X-Hub-Signature-256", 204, "ok",
+			"webhook " + host + " responded 204 (ok)"},
+		{"webhook://" + host + "/hooks/deploy?secret=wrong", 401, "failed: receiver responded 401, expected 2xx",
+			"webhook " + host + " responded 401 (failed)"},
+		{"webhook://" + host + "/hooks/deploy?secret=wrong&expect=4xx", 401, "ok", "webhook " + host + " responded 401 (ok)"},
+		{"webhook://" + host + "/hooks/deploy", 401, "failed: receiver responded 401, expected 2xx",
+			"webhook " + host + " responded 401 (failed)"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := p.Status(Request{Name: "hook", URL: tt.url})
+			require.NoError(t, err)
+			assert.Equal(t, "hook", resp.Name)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.code, resp.Body["receiver_status"])
+			assert.Equal(t, tt.status, resp.Body["status"])
+			assert.Equal(t, tt.summary, resp.Summary)
+		})
+	}
+
+	payload := url.QueryEscape(`{"event":"ping","repo":"infra","dry_run":false}`)
+	resp, err := p.Status(Request{Name: "hook", URL: "webhook://" + host + "/hooks/deploy?secret=s3cret&payload=" + payload})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"])
+	assert.Equal(t, map[string]interface{}{"event": "ping", "repo": "infra", "dry_run": true}, received, "dry_run forced")
+
+	received = nil
+	resp, err = p.Status(Request{Name: "hook", URL: "webhook://" + host + "/hooks/deploy?secret=s3cret&payload=null"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body["status"])
+	assert.Equal(t, map[string]interface{}{"event": "sys-agent.test", "dry_run": true}, received, "null payload")
+
+	_, err = p.Status(Request{Name: "hook", URL: "webhook://" + host + "/hooks/deploy?secret=s3cret&payload=bad"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "webhook payload parse failed: hook "+host+":")
+	assert.NotContains(t, err.Error(), "s3cret")
+
+	_, err = p.Status(Request{Name: "hook", URL: "webhook://%zz/hooks/deploy?secret=s3cret"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cret")
+}
+
+func Test_webhookSignature(t *testing.T) {
+	// example from github docs, https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		webhookSignature("It's a Secret to Everybody", []byte("Hello, World!")))
+}
+
+func Test_statusMatch(t *testing.T) {
+	assert.True(t, statusMatch(204, "2xx"))
+	assert.True(t, statusMatch(204, "2XX"))
+	assert.True(t, statusMatch(204, "204"))
+	assert.False(t, statusMatch(200, "204"))
+	assert.False(t, statusMatch(401, "2xx"))
+	assert.True(t, statusMatch(401, "4xx"))
+}
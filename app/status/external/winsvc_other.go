@@ -0,0 +1,10 @@
+//go:build !windows
+
+package external
+
+import "errors"
+
+// winServiceState is not supported on non-windows platforms
+func winServiceState(_ string) (string, error) {
+	return "", errors.New("windows services are supported on windows only")
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package external
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWinServiceProvider_StatusNotSupported(t *testing.T) {
+	p := WinServiceProvider{TimeOut: time.Second}
+	_, err := p.Status(Request{Name: "wu", URL: "winsvc://wuauserv"})
+	require.EqualError(t, err, "winsvc query failed: wu winsvc://wuauserv: windows services are supported on windows only")
+
+	_, err = p.Status(Request{Name: "wu", URL: "winsvc://"})
+	require.EqualError(t, err, "winsvc url parse failed: wu winsvc://: service name should be set as winsvc://name")
+}
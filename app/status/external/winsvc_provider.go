@@ -0,0 +1,70 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WinServiceProvider is a status provider that checks state of a windows service
+type WinServiceProvider struct {
+	TimeOut time.Duration
+}
+
+// Status url looks like: winsvc://wuauserv, with the service name (not display name) as is, i.e. winsvc://MSSQL$SQLEXPRESS.
+// It queries the service control manager and reports the service state. Status is failed if the service is not running.
+// Supported on windows only, returns error on other platforms.
+func (w *WinServiceProvider) Status(req Request) (*Response, error) {
+	st := time.Now()
+	name, err := winServiceName(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc url parse failed: %s %s: %w", req.Name, req.URL, err)
+	}
+
+	type result struct {
+		state string
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() { // service manager calls don't support timeout
+		state, e := winServiceState(name)
+		ch <- result{state: state, err: e}
+	}()
+
+	var state string
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, fmt.Errorf("winsvc query failed: %s %s: %w", req.Name, req.URL, r.err)
+		}
+		state = r.state
+	case <-time.After(w.TimeOut):
+		return nil, fmt.Errorf("winsvc query failed: %s %s: timeout", req.Name, req.URL)
+	}
+
+	body := map[string]interface{}{"service": name, "state": state, "status": "ok"}
+	if state != "running" {
+		body["status"] = "failed: service is " + state
+	}
+
+	resp := Response{
+		Name:         req.Name,
+		StatusCode:   200,
+		Body:         body,
+		ResponseTime: time.Since(st).Milliseconds(),
+		Summary:      fmt.Sprintf("winsvc %s %s (%s)", name, state, level(body["status"].(string))),
+	}
+	return &resp, nil
+}
+
+// winServiceName extracts service name from winsvc://name url. The url is not parsed with url.Parse
+// as service names may have characters not allowed in the host, i.e. $
+func winServiceName(rawURL string) (string, error) {
+	name := strings.TrimPrefix(rawURL, "winsvc://")
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return "", errors.New("service name should be set as winsvc://name")
+	}
+	return name, nil
+}
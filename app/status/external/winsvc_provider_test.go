@@ -0,0 +1,33 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_winServiceName(t *testing.T) {
+	tbl := []struct {
+		url  string
+		name string
+		err  bool
+	}{
+		{"winsvc://wuauserv", "wuauserv", false},
+		{"winsvc://MSSQL$SQLEXPRESS", "MSSQL$SQLEXPRESS", false},
+		{"winsvc://EventLog/", "EventLog", false},
+		{"winsvc://", "", true},
+		{"winsvc://a/b", "", true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			name, err := winServiceName(tt.url)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.name, name)
+		})
+	}
+}
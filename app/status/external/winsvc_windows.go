@@ -0,0 +1,43 @@
+//go:build windows
+
+package external
+
+import (
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// winStates maps service states to names reported by the provider
+var winStates = map[svc.State]string{
+	svc.Stopped:         "stopped",
+	svc.StartPending:    "start_pending",
+	svc.StopPending:     "stop_pending",
+	svc.Running:         "running",
+	svc.ContinuePending: "continue_pending",
+	svc.PausePending:    "pause_pending",
+	svc.Paused:          "paused",
+}
+
+// winServiceState connects to the service control manager and returns state of the service
+func winServiceState(name string) (string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer m.Disconnect() // nolint
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return "", err
+	}
+	defer s.Close() // nolint
+
+	st, err := s.Query()
+	if err != nil {
+		return "", err
+	}
+	if state, ok := winStates[st.State]; ok {
+		return state, nil
+	}
+	return "unknown", nil
+}
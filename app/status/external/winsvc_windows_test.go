@@ -0,0 +1,24 @@
+//go:build windows
+
+package external
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWinServiceProvider_Status(t *testing.T) {
+	p := WinServiceProvider{TimeOut: 5 * time.Second}
+
+	resp, err := p.Status(Request{Name: "eventlog", URL: "winsvc://EventLog"})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"service": "EventLog", "state": "running", "status": "ok"}, resp.Body)
+	assert.Equal(t, "winsvc EventLog running (ok)", resp.Summary)
+
+	_, err = p.Status(Request{Name: "nope", URL: "winsvc://no-such-service-sys-agent"})
+	require.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FSErrors contains error counts of filesystems reported by the kernel
+type FSErrors struct {
+	Devices map[string]FSDevice `json:"devices"` // by device name, i.e. sda1 or dm-0
+	Status  string              `json:"status"`  // ok, failed if any device failed, warn if any device warn
+}
+
+// FSDevice contains error count of a filesystem, counted by the kernel since the filesystem creation
+// and kept in the superblock, so errors are reported until fsck clears them
+type FSDevice struct {
+	FSType     string     `json:"fs_type"`
+	Errors     int        `json:"errors"`
+	FirstError *time.Time `json:"first_error,omitempty"`
+	LastError  *time.Time `json:"last_error,omitempty"`
+	Status     string     `json:"status"` // ok, failed if the last error is within the window, warn if older
+}
+
+// checkFSErrors sets the status of each device and the overall one. Device with errors is failed if the last
+// error is within the window or its time is unknown, otherwise warn, as old errors are not fixed until fsck.
+func checkFSErrors(devices map[string]FSDevice, window time.Duration, now time.Time) *FSErrors {
+	res := &FSErrors{Devices: map[string]FSDevice{}, Status: "ok"}
+	var failed, warn []string
+	for name, d := range devices {
+		d.Status = "ok"
+		switch {
+		case d.Errors == 0:
+		case d.LastError == nil || now.Sub(*d.LastError) < window:
+			d.Status = fmt.Sprintf("failed: %d errors", d.Errors)
+			failed = append(failed, name)
+		default:
+			d.Status = fmt.Sprintf("warn: %d errors, last %s ago", d.Errors, now.Sub(*d.LastError).Truncate(time.Minute))
+			warn = append(warn, name)
+		}
+		res.Devices[name] = d
+	}
+	sort.Strings(failed)
+	sort.Strings(warn)
+	switch {
+	case len(failed) > 0:
+		res.Status = "failed: errors on " + strings.Join(failed, ",")
+	case len(warn) > 0:
+		res.Status = "warn: errors on " + strings.Join(warn, ",")
+	}
+	return res
+}
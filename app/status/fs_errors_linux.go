@@ -0,0 +1,76 @@
+//go:build linux
+
+package status
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sysRoot is sysfs mount point, can be changed for tests
+var sysRoot = "/sys"
+
+// fsErrorCounts returns error counts of mounted ext4 filesystems from /sys/fs/ext4/<dev>/errors_count,
+// with the time of the first and the last error. Directories without errors_count, i.e. "features", are skipped.
+// Xfs doesn't expose error counts in sysfs.
+func fsErrorCounts() (map[string]FSDevice, error) {
+	dir := filepath.Join(sysRoot, "fs", "ext4")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]FSDevice{}, nil // ext4 not loaded, no ext4 filesystems mounted
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	res := map[string]FSDevice{}
+	for _, e := range entries {
+		count, err := readSysInt(filepath.Join(dir, e.Name(), "errors_count"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		d := FSDevice{FSType: "ext4", Errors: int(count)}
+		if d.FirstError, err = readSysTime(filepath.Join(dir, e.Name(), "first_error_time")); err != nil {
+			return nil, err
+		}
+		if d.LastError, err = readSysTime(filepath.Join(dir, e.Name(), "last_error_time")); err != nil {
+			return nil, err
+		}
+		res[e.Name()] = d
+	}
+	return res, nil
+}
+
+// readSysInt reads sysfs file with a number
+func readSysInt(fname string) (int64, error) {
+	data, err := os.ReadFile(fname) //nolint:gosec // sysfs file
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", fname, err)
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", fname, err)
+	}
+	return v, nil
+}
+
+// readSysTime reads sysfs file with unix time in seconds, nil if the file is missing or the time is 0
+func readSysTime(fname string) (*time.Time, error) {
+	v, err := readSysInt(fname)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && v == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(v, 0).UTC()
+	return &t, nil
+}
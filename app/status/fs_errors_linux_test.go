@@ -0,0 +1,46 @@
+//go:build linux
+
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fsErrorCounts(t *testing.T) {
+	origRoot := sysRoot
+	defer func() { sysRoot = origRoot }()
+
+	sysRoot = "testdata/sys"
+	res, err := fsErrorCounts()
+	require.NoError(t, err)
+	first, last := time.Date(2026, 10, 9, 6, 0, 0, 0, time.UTC), time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	nvme := time.Unix(1775000000, 0).UTC()
+	assert.Equal(t, map[string]FSDevice{
+		"sda1":      {FSType: "ext4"},
+		"dm-0":      {FSType: "ext4", Errors: 3, FirstError: &first, LastError: &last},
+		"nvme0n1p2": {FSType: "ext4", Errors: 1, FirstError: &nvme, LastError: &nvme},
+	}, res, "features dir skipped")
+
+	sysRoot = t.TempDir()
+	res, err = fsErrorCounts()
+	require.NoError(t, err)
+	assert.Empty(t, res, "no ext4")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(sysRoot, "fs", "ext4", "sda1"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(sysRoot, "fs", "ext4", "sda1", "errors_count"), []byte("bad\n"), 0o600))
+	_, err = fsErrorCounts()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "errors_count")
+
+	info, err := Service{FSErrors: time.Hour}.Get()
+	require.NoError(t, err, "filesystem errors failure doesn't fail the whole status")
+	assert.Empty(t, info.FSErrors.Devices)
+	assert.True(t, strings.HasPrefix(info.FSErrors.Status, "failed: can't read filesystem errors: "), info.FSErrors.Status)
+}
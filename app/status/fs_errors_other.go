@@ -0,0 +1,10 @@
+//go:build !linux
+
+package status
+
+import "errors"
+
+// fsErrorCounts is not supported, filesystem errors are read from sysfs on linux only
+func fsErrorCounts() (map[string]FSDevice, error) {
+	return nil, errors.New("filesystem errors check is supported on linux only")
+}
@@ -0,0 +1,34 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkFSErrors(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	recent, old := now.Add(-2*time.Hour), now.Add(-200*time.Hour-30*time.Second)
+	devices := map[string]FSDevice{
+		"sda1":      {FSType: "ext4"},
+		"dm-0":      {FSType: "ext4", Errors: 3, FirstError: &old, LastError: &recent},
+		"nvme0n1p2": {FSType: "ext4", Errors: 1, FirstError: &old, LastError: &old},
+		"sdb1":      {FSType: "ext4", Errors: 2},
+	}
+
+	res := checkFSErrors(devices, 24*time.Hour, now)
+	assert.Equal(t, "failed: errors on dm-0,sdb1", res.Status)
+	assert.Equal(t, "ok", res.Devices["sda1"].Status)
+	assert.Equal(t, "failed: 3 errors", res.Devices["dm-0"].Status)
+	assert.Equal(t, "warn: 1 errors, last 200h0m0s ago", res.Devices["nvme0n1p2"].Status)
+	assert.Equal(t, "failed: 2 errors", res.Devices["sdb1"].Status, "unknown time of the last error")
+
+	delete(devices, "dm-0")
+	delete(devices, "sdb1")
+	assert.Equal(t, "warn: errors on nvme0n1p2", checkFSErrors(devices, 24*time.Hour, now).Status)
+	assert.Equal(t, "failed: errors on nvme0n1p2", checkFSErrors(devices, 365*24*time.Hour, now).Status)
+
+	res = checkFSErrors(map[string]FSDevice{}, time.Hour, now)
+	assert.Equal(t, &FSErrors{Devices: map[string]FSDevice{}, Status: "ok"}, res)
+}
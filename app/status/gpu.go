@@ -0,0 +1,170 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nvidiaSmiCmd is nvidia-smi binary of nvidia driver, can be changed for tests
+var nvidiaSmiCmd = "nvidia-smi"
+
+// gpuQuery is the list of fields requested from nvidia-smi, order matters for parseNvidiaSmi
+const gpuQuery = "index,uuid,name,utilization.gpu,memory.used,memory.total,temperature.gpu," +
+	"ecc.errors.uncorrected.volatile.total"
+
+// GPULimits defines thresholds for gpu status, disabled if 0
+type GPULimits struct {
+	Count    int // expected number of gpus, failed if fewer
+	TempWarn int // temperature in celsius, warn if above
+	TempFail int // temperature in celsius, failed if above
+	MemWarn  int // memory used percent, warn if above
+	MemFail  int // memory used percent, failed if above
+}
+
+// GPUs contains health of gpus reported by nvidia-smi
+type GPUs struct {
+	Count   int      `json:"count"`
+	Devices []GPU    `json:"devices,omitempty"` // ordered by index
+	Errors  []string `json:"errors,omitempty"`  // errors reported by nvidia-smi, i.e. for gpu fallen off the bus
+	Status  string   `json:"status"`            // ok, failed if fewer gpus than expected or any gpu failed, warn if any gpu warn
+}
+
+// GPU contains utilization, memory and temperature of a gpu
+type GPU struct {
+	Index       int    `json:"index"`
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	UtilPercent int    `json:"util_percent"`
+	MemUsedMB   int    `json:"mem_used_mb"`
+	MemTotalMB  int    `json:"mem_total_mb"`
+	MemPercent  int    `json:"mem_percent"`
+	TempC       int    `json:"temp_c"`
+	ECCErrors   int    `json:"ecc_errors"`      // uncorrected volatile ecc errors, 0 if ecc not supported
+	Fault       string `json:"fault,omitempty"` // error value reported for any field, i.e. "GPU is lost"
+	Status      string `json:"status"`          // ok, warn or failed above thresholds, failed on ecc errors or fault
+}
+
+// gpuStatus runs nvidia-smi and returns gpus health checked against the limits. Hosts without nvidia-smi
+// or without gpus are reported with no devices, failed only if gpus are expected.
+// Failure to run nvidia-smi reported as failed status, not as error of the whole status.
+func gpuStatus(timeout time.Duration, limits GPULimits) *GPUs {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	res := &GPUs{}
+	out, err := exec.CommandContext(ctx, nvidiaSmiCmd, "--query-gpu="+gpuQuery, //nolint:gosec
+		"--format=csv,noheader,nounits").CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return &GPUs{Status: fmt.Sprintf("failed: nvidia-smi timed out after %s", timeout)}
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		// no nvidia driver, nothing to report
+	case err != nil && strings.Contains(string(out), "No devices were found"):
+		// no gpus, nothing to report
+	case err != nil && !errors.As(err, &exitErr):
+		return &GPUs{Status: fmt.Sprintf("failed: can't run nvidia-smi: %v", err)}
+	default: // nvidia-smi exits with error if any gpu failed, with the output for the rest of them
+		res.Devices, res.Errors = parseNvidiaSmi(string(out))
+		if err != nil && len(res.Errors) == 0 {
+			res.Errors = []string{"nvidia-smi " + err.Error()}
+		}
+	}
+	res.Count = len(res.Devices)
+
+	var failed, warn []string
+	for i, g := range res.Devices {
+		res.Devices[i] = checkGPU(g, limits)
+		switch level := strings.SplitN(res.Devices[i].Status, ":", 2)[0]; level {
+		case "failed":
+			failed = append(failed, strconv.Itoa(g.Index))
+		case "warn":
+			warn = append(warn, strconv.Itoa(g.Index))
+		}
+	}
+
+	res.Status = "ok"
+	switch {
+	case len(res.Errors) > 0:
+		res.Status = "failed: " + res.Errors[0]
+	case res.Count < limits.Count:
+		res.Status = fmt.Sprintf("failed: %d gpus, expected %d", res.Count, limits.Count)
+	case len(failed) > 0:
+		res.Status = "failed: gpus " + strings.Join(failed, ",")
+	case len(warn) > 0:
+		res.Status = "warn: gpus " + strings.Join(warn, ",")
+	}
+	return res
+}
+
+// checkGPU sets the status of the gpu, failed conditions checked before warn ones
+func checkGPU(g GPU, limits GPULimits) GPU {
+	g.Status = "ok"
+	switch {
+	case g.Fault != "":
+		g.Status = "failed: " + g.Fault
+	case g.ECCErrors > 0:
+		g.Status = fmt.Sprintf("failed: %d uncorrected ecc errors", g.ECCErrors)
+	case limits.TempFail > 0 && g.TempC > limits.TempFail:
+		g.Status = fmt.Sprintf("failed: temperature %dC, expected at most %dC", g.TempC, limits.TempFail)
+	case limits.MemFail > 0 && g.MemPercent > limits.MemFail:
+		g.Status = fmt.Sprintf("failed: memory used %d%%, expected at most %d%%", g.MemPercent, limits.MemFail)
+	case limits.TempWarn > 0 && g.TempC > limits.TempWarn:
+		g.Status = fmt.Sprintf("warn: temperature %dC, expected at most %dC", g.TempC, limits.TempWarn)
+	case limits.MemWarn > 0 && g.MemPercent > limits.MemWarn:
+		g.Status = fmt.Sprintf("warn: memory used %d%%, expected at most %d%%", g.MemPercent, limits.MemWarn)
+	}
+	return g
+}
+
+// parseNvidiaSmi parses "nvidia-smi --query-gpu=... --format=csv,noheader,nounits" output, a line per gpu like
+// "0, GPU-5d1b, NVIDIA A100-SXM4-40GB, 87, 30512, 40960, 64, 0". Values not supported by the gpu are "[N/A]"
+// or "[Not Supported]" and reported as 0, other bracketed values are errors, i.e. "[GPU is lost]", and set as fault.
+// Lines not matching the query, i.e. "Unable to determine the device handle for GPU0000:3B:00.0: Unknown Error",
+// are returned as errors.
+func parseNvidiaSmi(out string) (gpus []GPU, errs []string) {
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != strings.Count(gpuQuery, ",")+1 {
+			errs = append(errs, line)
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			errs = append(errs, line)
+			continue
+		}
+
+		g := GPU{Index: index, UUID: fields[1], Name: fields[2]}
+		number := func(v string) int {
+			if strings.HasPrefix(v, "[") {
+				if fault := strings.Trim(v, "[]"); fault != "N/A" && fault != "Not Supported" && g.Fault == "" {
+					g.Fault = fault
+				}
+				return 0
+			}
+			n, _ := strconv.ParseFloat(v, 64)
+			return int(n)
+		}
+		g.UtilPercent, g.MemUsedMB, g.MemTotalMB = number(fields[3]), number(fields[4]), number(fields[5])
+		g.TempC, g.ECCErrors = number(fields[6]), number(fields[7])
+		if g.MemTotalMB > 0 {
+			g.MemPercent = g.MemUsedMB * 100 / g.MemTotalMB
+		}
+		gpus = append(gpus, g)
+	}
+	return gpus, errs
+}
@@ -0,0 +1,87 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_gpuStatus(t *testing.T) {
+	fake, err := filepath.Abs("testdata/nvidia-smi.sh")
+	require.NoError(t, err)
+	orig := nvidiaSmiCmd
+	nvidiaSmiCmd = fake
+	defer func() { nvidiaSmiCmd = orig }()
+
+	limits := GPULimits{TempWarn: 80, TempFail: 90, MemWarn: 90, MemFail: 98}
+	tbl := []struct {
+		fixture string
+		limits  GPULimits
+		count   int
+		status  string
+	}{
+		{"ok", limits, 2, "ok"},
+		{"ok", GPULimits{Count: 2}, 2, "ok"},
+		{"ok", GPULimits{Count: 4}, 2, "failed: 2 gpus, expected 4"},
+		{"hot", limits, 3, "failed: gpus 1,2"},
+		{"hot", GPULimits{TempWarn: 80}, 3, "failed: gpus 1"},
+		{"hot", GPULimits{MemWarn: 90}, 3, "failed: gpus 1"},
+		{"none", limits, 0, "ok"},
+		{"none", GPULimits{Count: 1}, 0, "failed: 0 gpus, expected 1"},
+		{"lost", limits, 1, "failed: Unable to determine the device handle for GPU0000:3B:00.0: Unknown Error"},
+		{"driver", limits, 0, "failed: NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver."},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.fixture+" "+tt.status, func(t *testing.T) {
+			t.Setenv("NVIDIA_SMI_FIXTURE", tt.fixture)
+			res := gpuStatus(time.Second, tt.limits)
+			assert.Equal(t, tt.count, res.Count)
+			assert.Equal(t, tt.status, res.Status)
+		})
+	}
+
+	t.Run("devices", func(t *testing.T) {
+		t.Setenv("NVIDIA_SMI_FIXTURE", "hot")
+		res := gpuStatus(time.Second, limits)
+		assert.Equal(t, "warn: temperature 84C, expected at most 80C", res.Devices[0].Status)
+		assert.Equal(t, "failed: 3 uncorrected ecc errors", res.Devices[1].Status)
+		assert.Equal(t, "failed: temperature 93C, expected at most 90C", res.Devices[2].Status)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Setenv("NVIDIA_SMI_FIXTURE", "slow")
+		res := gpuStatus(100*time.Millisecond, limits)
+		assert.Equal(t, &GPUs{Status: "failed: nvidia-smi timed out after 100ms"}, res)
+	})
+
+	nvidiaSmiCmd = "testdata/no-such-nvidia-smi"
+	res := gpuStatus(time.Second, GPULimits{})
+	assert.Equal(t, &GPUs{Status: "ok"}, res)
+}
+
+func Test_parseNvidiaSmi(t *testing.T) {
+	out, err := os.ReadFile("testdata/nvidia_smi_hot.txt")
+	require.NoError(t, err)
+	gpus, errs := parseNvidiaSmi(string(out))
+	assert.Empty(t, errs)
+	require.Len(t, gpus, 3)
+	assert.Equal(t, GPU{Index: 0, UUID: "GPU-5d1b3c2e-8f4a-11ee-b962-0242ac120002", Name: "NVIDIA A100-SXM4-40GB",
+		UtilPercent: 100, MemUsedMB: 39800, MemTotalMB: 40960, MemPercent: 97, TempC: 84}, gpus[0])
+	assert.Equal(t, 3, gpus[1].ECCErrors)
+	assert.Equal(t, GPU{Index: 2, UUID: "GPU-7f3d5e40-8f4a-11ee-b962-0242ac120002", Name: "NVIDIA GeForce RTX 4090",
+		UtilPercent: 55, MemUsedMB: 12000, MemTotalMB: 24564, MemPercent: 48, TempC: 93}, gpus[2], "ecc not supported")
+
+	gpus, errs = parseNvidiaSmi("0, GPU-1, Tesla T4, [GPU is lost], [GPU is lost], 15360, [GPU is lost], [N/A]\n")
+	assert.Empty(t, errs)
+	assert.Equal(t, []GPU{{Index: 0, UUID: "GPU-1", Name: "Tesla T4", MemTotalMB: 15360, Fault: "GPU is lost"}}, gpus)
+	assert.Equal(t, "failed: GPU is lost", checkGPU(gpus[0], GPULimits{}).Status)
+
+	gpus, errs = parseNvidiaSmi("Failed to initialize NVML: Driver/library version mismatch\n")
+	assert.Empty(t, gpus)
+	assert.Equal(t, []string{"Failed to initialize NVML: Driver/library version mismatch"}, errs)
+}
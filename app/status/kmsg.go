@@ -0,0 +1,135 @@
+package status
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxKmsgLines = 20 // max matching lines reported, the most recent ones
+
+// DefaultKmsgFailPatterns are kernel log patterns reported as failed, OOM kills and I/O errors
+var DefaultKmsgFailPatterns = []string{
+	`oom:(?i)out of memory|oom-kill|killed process \d+`,
+	`io_error:(?i)i/o error|blk_update_request|critical medium error`,
+}
+
+// DefaultKmsgWarnPatterns are kernel log patterns reported as warn, machine check exceptions
+var DefaultKmsgWarnPatterns = []string{
+	`mce:(?i)machine check|\bmce:|hardware error`,
+}
+
+// KmsgCheck defines kernel log check, patterns are matched against messages logged within the window
+type KmsgCheck struct {
+	Window   time.Duration
+	Patterns []KmsgPattern
+}
+
+// KmsgPattern is a named kernel log pattern with the level reported on match, warn or failed
+type KmsgPattern struct {
+	Name  string
+	Level string
+	Re    *regexp.Regexp
+}
+
+// Kmsg contains kernel log events matching the patterns
+type Kmsg struct {
+	Window string         `json:"window"`
+	Counts map[string]int `json:"counts"` // matched messages by pattern name
+	Lines  []string       `json:"lines"`  // the most recent matched messages, i.e. "[5140.900123] Out of memory: Killed process 123"
+	Status string         `json:"status"` // ok, warn or failed with matched patterns
+}
+
+// NewKmsgCheck makes kernel log check with patterns in "name:regex" format, failed and warn ones.
+// Default patterns used if both lists are empty.
+func NewKmsgCheck(window time.Duration, fail, warn []string) (*KmsgCheck, error) {
+	if len(fail) == 0 && len(warn) == 0 {
+		fail, warn = DefaultKmsgFailPatterns, DefaultKmsgWarnPatterns
+	}
+	res := &KmsgCheck{Window: window}
+	for _, p := range []struct {
+		level    string
+		patterns []string
+	}{{"failed", fail}, {"warn", warn}} {
+		for _, v := range p.patterns {
+			elems := strings.SplitN(v, ":", 2)
+			if len(elems) != 2 || elems[0] == "" || elems[1] == "" {
+				return nil, fmt.Errorf("invalid kernel log pattern %q, should be <name>:<regex>", v)
+			}
+			re, err := regexp.Compile(elems[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid kernel log pattern %q: %w", v, err)
+			}
+			res.Patterns = append(res.Patterns, KmsgPattern{Name: elems[0], Level: p.level, Re: re})
+		}
+	}
+	return res, nil
+}
+
+// parseKmsg parses /dev/kmsg records and matches messages logged within the window against the patterns.
+// Each record is "priority,sequence,timestamp,flags[,...];message" with timestamp in microseconds since boot,
+// continuation lines with key=value pairs start with a space and ignored. Uptime is the current time since boot.
+func parseKmsg(r io.Reader, uptime time.Duration, check KmsgCheck) (*Kmsg, error) {
+	res := &Kmsg{Window: check.Window.String(), Counts: map[string]int{}, Lines: []string{}, Status: "ok"}
+	levels := map[string]string{} // matched pattern name to level
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, " ") {
+			continue
+		}
+		prefix, msg, found := strings.Cut(line, ";")
+		if !found {
+			return nil, fmt.Errorf("invalid kmsg record %q", line)
+		}
+		fields := strings.Split(prefix, ",")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid kmsg record prefix %q", prefix)
+		}
+		usec, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kmsg timestamp %q: %w", fields[2], err)
+		}
+		ts := time.Duration(usec) * time.Microsecond
+		if uptime-ts > check.Window {
+			continue
+		}
+
+		matched := false
+		for _, p := range check.Patterns {
+			if p.Re.MatchString(msg) {
+				res.Counts[p.Name]++
+				levels[p.Name] = p.Level
+				matched = true
+			}
+		}
+		if matched {
+			res.Lines = append(res.Lines, fmt.Sprintf("[%d.%06d] %s", usec/1e6, usec%1e6, msg))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read kmsg: %w", err)
+	}
+	if len(res.Lines) > maxKmsgLines {
+		res.Lines = res.Lines[len(res.Lines)-maxKmsgLines:]
+	}
+
+	if len(res.Counts) == 0 {
+		return res, nil
+	}
+	lvl, matched := "warn", make([]string, 0, len(res.Counts))
+	for name, count := range res.Counts {
+		matched = append(matched, fmt.Sprintf("%s:%d", name, count))
+		if levels[name] == "failed" {
+			lvl = "failed"
+		}
+	}
+	sort.Strings(matched)
+	res.Status = lvl + ": " + strings.Join(matched, ",")
+	return res, nil
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package status
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// kmsgDevice is kernel log device, can be changed for tests
+var kmsgDevice = "/dev/kmsg"
+
+// kmsgEvents reads all records available in the kernel ring buffer and matches them against the check patterns.
+// The device is read in non-blocking mode, each read returns a single record, EAGAIN is returned after the last one.
+// Reading requires CAP_SYSLOG if kernel.dmesg_restrict is set.
+func kmsgEvents(check KmsgCheck) (*Kmsg, error) {
+	uptime, err := procUptime()
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.Open(kmsgDevice, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", kmsgDevice, err)
+	}
+	defer syscall.Close(fd) // nolint
+
+	var records bytes.Buffer
+	buf := make([]byte, 8192) // max record size of printk
+	for {
+		n, err := syscall.Read(fd, buf)
+		if errors.Is(err, syscall.EPIPE) {
+			continue // record overwritten while reading, the next read returns the oldest available one
+		}
+		if errors.Is(err, syscall.EAGAIN) || (err == nil && n == 0) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", kmsgDevice, err)
+		}
+		records.Write(buf[:n])
+		if buf[n-1] != '\n' {
+			records.WriteByte('\n')
+		}
+	}
+	return parseKmsg(&records, uptime, check)
+}
+
+// procUptime returns time since boot from /proc/uptime, the first field is uptime in seconds
+func procUptime() (time.Duration, error) {
+	fname := filepath.Join(procRoot, "uptime")
+	data, err := os.ReadFile(fname) //nolint:gosec // procfs file
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", fname, err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty %s", fname)
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid uptime in %s: %w", fname, err)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_kmsgEvents(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "uptime"), []byte("88200.50 350000.12\n"), 0o600))
+	origRoot, origDevice := procRoot, kmsgDevice
+	defer func() { procRoot, kmsgDevice = origRoot, origDevice }()
+	procRoot, kmsgDevice = root, "testdata/kmsg.txt"
+
+	check, err := NewKmsgCheck(time.Hour, nil, nil)
+	require.NoError(t, err)
+	res, err := kmsgEvents(*check)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"io_error": 2, "mce": 1}, res.Counts)
+	assert.Equal(t, "failed: io_error:2,mce:1", res.Status)
+
+	kmsgDevice = filepath.Join(root, "no-such-device")
+	_, err = kmsgEvents(*check)
+	require.Error(t, err)
+
+	procRoot = filepath.Join(root, "no-such-proc")
+	_, err = kmsgEvents(*check)
+	require.Error(t, err)
+
+	info, err := Service{Kmsg: check}.Get()
+	require.NoError(t, err, "kernel log error doesn't fail the whole status")
+	assert.Equal(t, "1h0m0s", info.Kmsg.Window)
+	assert.Empty(t, info.Kmsg.Counts)
+	assert.True(t, strings.HasPrefix(info.Kmsg.Status, "failed: can't read kernel log: "), info.Kmsg.Status)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package status
+
+import "errors"
+
+// kmsgEvents is not supported, kernel log is read from /dev/kmsg on linux only
+func kmsgEvents(KmsgCheck) (*Kmsg, error) {
+	return nil, errors.New("kernel log check is supported on linux only")
+}
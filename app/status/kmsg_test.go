@@ -0,0 +1,91 @@
+package status
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseKmsg(t *testing.T) {
+	uptime := 90000 * time.Second // 25h, boot time oom is out of the window
+
+	tbl := []struct {
+		name   string
+		warn   []string // custom warn patterns, defaults if empty
+		uptime time.Duration
+		window time.Duration
+		counts map[string]int
+		lines  []string
+		status string
+	}{
+		{"recent errors", nil, uptime, time.Hour, map[string]int{"io_error": 2, "mce": 1},
+			[]string{"[87000.000000] mce: [Hardware Error]: Machine check events logged",
+				"[88100.500000] blk_update_request: I/O error, dev sdb, sector 123456 op 0x0:(READ) flags 0x0 phys_seg 1 prio class 0",
+				"[88100.600000] Buffer I/O error on dev sdb1, logical block 15432, async page read"},
+			"failed: io_error:2,mce:1"},
+		{"io errors only", nil, uptime, 40 * time.Minute, map[string]int{"io_error": 2}, nil, "failed: io_error:2"},
+		{"warn patterns only", []string{`link:link becomes ready`}, uptime, time.Hour, map[string]int{"link": 1},
+			[]string{"[89000.000000] IPv6: ADDRCONF(NETDEV_CHANGE): eth0: link becomes ready"}, "warn: link:1"},
+		{"nothing recent", nil, uptime + time.Hour, 10 * time.Minute, map[string]int{}, []string{}, "ok"},
+		{"everything since boot", nil, uptime, uptime, map[string]int{"oom": 1, "io_error": 2, "mce": 1}, nil,
+			"failed: io_error:2,mce:1,oom:1"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := NewKmsgCheck(tt.window, nil, tt.warn)
+			require.NoError(t, err)
+			fh, err := os.Open("testdata/kmsg.txt")
+			require.NoError(t, err)
+			defer fh.Close()
+			res, err := parseKmsg(fh, tt.uptime, *check)
+			require.NoError(t, err)
+			assert.Equal(t, tt.window.String(), res.Window)
+			assert.Equal(t, tt.counts, res.Counts)
+			if tt.lines != nil {
+				assert.Equal(t, tt.lines, res.Lines)
+			}
+			assert.Equal(t, tt.status, res.Status)
+		})
+	}
+
+	check, err := NewKmsgCheck(time.Hour, nil, nil)
+	require.NoError(t, err)
+	{ // only the most recent lines reported
+		records := strings.Repeat("3,1,1000000,-;Out of memory: Killed process 1 (app)\n", maxKmsgLines+5)
+		res, err := parseKmsg(strings.NewReader(records), time.Minute, *check)
+		require.NoError(t, err)
+		assert.Equal(t, maxKmsgLines+5, res.Counts["oom"])
+		assert.Len(t, res.Lines, maxKmsgLines)
+	}
+
+	for _, bad := range []string{"no separator", "3,1;short prefix", "3,1,abc,-;bad timestamp"} {
+		_, err := parseKmsg(strings.NewReader(bad+"\n"), time.Minute, *check)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestNewKmsgCheck(t *testing.T) {
+	check, err := NewKmsgCheck(time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, check.Patterns, 3)
+	assert.Equal(t, "oom", check.Patterns[0].Name)
+	assert.Equal(t, "failed", check.Patterns[0].Level)
+	assert.Equal(t, "mce", check.Patterns[2].Name)
+	assert.Equal(t, "warn", check.Patterns[2].Level)
+
+	check, err = NewKmsgCheck(time.Minute, []string{"segfault:segfault at [0-9a-f]+"}, []string{"thermal:(?i)temperature above threshold"})
+	require.NoError(t, err)
+	require.Len(t, check.Patterns, 2)
+	assert.Equal(t, KmsgPattern{Name: "thermal", Level: "warn", Re: check.Patterns[1].Re}, check.Patterns[1])
+	assert.True(t, check.Patterns[0].Re.MatchString("app[123]: segfault at 7f00 ip"))
+
+	for _, bad := range []string{"no-regex", ":regex", "name:", "bad:[a-"} {
+		_, err = NewKmsgCheck(time.Minute, []string{bad}, nil)
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,72 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Listen contains listening ports and those not in the allowlist
+type Listen struct {
+	Ports      []string     `json:"ports"`      // all listening ports as proto:port, i.e. tcp:22
+	Unexpected []ListenPort `json:"unexpected"` // listening ports not in the allowlist
+	Status     string       `json:"status"`     // ok or failed with unexpected ports
+}
+
+// ListenPort is a listening port with the process owning the socket, if known
+type ListenPort struct {
+	Proto   string `json:"proto"` // tcp or udp
+	Port    int    `json:"port"`
+	Process string `json:"process,omitempty"`
+}
+
+func (l ListenPort) String() string {
+	return l.Proto + ":" + strconv.Itoa(l.Port)
+}
+
+// checkListen compares listening ports with the allowlist. Allowlist elements are "proto:port" or "port" for both
+// tcp and udp. Ports listening on both ipv4 and ipv6 are reported once.
+func checkListen(ports []ListenPort, allowed []string) *Listen {
+	allow := map[string]bool{}
+	for _, a := range allowed {
+		if strings.Contains(a, ":") {
+			allow[a] = true
+			continue
+		}
+		allow["tcp:"+a], allow["udp:"+a] = true, true
+	}
+
+	res := &Listen{Ports: []string{}, Unexpected: []ListenPort{}, Status: "ok"}
+	seen := map[string]bool{}
+	for _, p := range ports {
+		if seen[p.String()] {
+			continue
+		}
+		seen[p.String()] = true
+		res.Ports = append(res.Ports, p.String())
+		if !allow[p.String()] {
+			res.Unexpected = append(res.Unexpected, p)
+		}
+	}
+	sort.Strings(res.Ports)
+	sort.Slice(res.Unexpected, func(i, j int) bool {
+		if res.Unexpected[i].Proto != res.Unexpected[j].Proto {
+			return res.Unexpected[i].Proto < res.Unexpected[j].Proto
+		}
+		return res.Unexpected[i].Port < res.Unexpected[j].Port
+	})
+
+	if len(res.Unexpected) > 0 {
+		unexpected := make([]string, 0, len(res.Unexpected))
+		for _, p := range res.Unexpected {
+			s := p.String()
+			if p.Process != "" {
+				s += fmt.Sprintf(" (%s)", p.Process)
+			}
+			unexpected = append(unexpected, s)
+		}
+		res.Status = "failed: unexpected ports " + strings.Join(unexpected, ", ")
+	}
+	return res
+}
@@ -0,0 +1,132 @@
+//go:build linux
+
+package status
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procRoot is procfs mount point, can be changed for tests
+var procRoot = "/proc"
+
+// procNetListen lists procfs files with sockets and the state considered as listening.
+// Unconnected udp sockets are in TCP_CLOSE (07) state, client ones as well, so udp sockets bound to
+// ephemeral ports are skipped.
+var procNetListen = []struct {
+	fname  string
+	proto  string
+	state  string
+	client bool // skip sockets on ephemeral ports
+}{
+	{"net/tcp", "tcp", "0A", false}, {"net/tcp6", "tcp", "0A", false},
+	{"net/udp", "udp", "07", true}, {"net/udp6", "udp", "07", true},
+}
+
+// defaultEphemeralPorts is linux default of ip_local_port_range
+var defaultEphemeralPorts = portRange{lo: 32768, hi: 60999}
+
+// portRange is inclusive range of ports, empty if zero
+type portRange struct {
+	lo, hi int
+}
+
+func (r portRange) contains(port int) bool {
+	return r.lo > 0 && port >= r.lo && port <= r.hi
+}
+
+// ephemeralPorts returns the range of local ports used by the kernel for client sockets,
+// the default one if ip_local_port_range can't be read or parsed
+func ephemeralPorts() portRange {
+	data, err := os.ReadFile(filepath.Join(procRoot, "sys/net/ipv4/ip_local_port_range"))
+	if err != nil {
+		return defaultEphemeralPorts
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return defaultEphemeralPorts
+	}
+	lo, errLo := strconv.Atoi(fields[0])
+	hi, errHi := strconv.Atoi(fields[1])
+	if errLo != nil || errHi != nil || lo <= 0 || hi < lo {
+		return defaultEphemeralPorts
+	}
+	return portRange{lo: lo, hi: hi}
+}
+
+// listeningPorts returns listening tcp and udp ports with names of processes owning the sockets
+func listeningPorts() ([]ListenPort, error) {
+	res := []ListenPort{}
+	inodes := map[string][]int{} // socket inode to indexes in res
+	ephemeral := ephemeralPorts()
+	for _, p := range procNetListen {
+		skip := portRange{}
+		if p.client {
+			skip = ephemeral
+		}
+		ports, err := parseListening(filepath.Join(procRoot, p.fname), p.proto, p.state, skip)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for inode, port := range ports {
+			inodes[inode] = append(inodes[inode], len(res))
+			res = append(res, port)
+		}
+	}
+
+	for inode, proc := range socketProcs(procRoot) {
+		for _, i := range inodes[inode] {
+			res[i].Process = proc
+		}
+	}
+	return res, nil
+}
+
+// parseListening parses /proc/net/{tcp,udp} formatted file and returns ports in the given state by socket inode,
+// except ports in skip range.
+// Each line is "sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode ...".
+func parseListening(fname, proto, state string, skip portRange) (map[string]ListenPort, error) {
+	fh, err := os.Open(fname) //nolint:gosec // procfs file
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", fname, err)
+	}
+	defer fh.Close() // nolint
+
+	res := map[string]ListenPort{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[0] == "sl" || fields[3] != state {
+			continue
+		}
+		port, err := hexPort(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %q: %w", fields[1], scanner.Text(), err)
+		}
+		if skip.contains(port) {
+			continue
+		}
+		res[fields[9]] = ListenPort{Proto: proto, Port: port}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fname, err)
+	}
+	return res, nil
+}
+
+// socketProcs returns process names by socket inode, the first process for sockets shared by a few of them
+func socketProcs(root string) map[string]string {
+	res := map[string]string{}
+	for inode, owners := range socketOwners(root) {
+		res[inode] = owners[0].Name
+	}
+	return res
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseListening(t *testing.T) {
+	res, err := parseListening("testdata/proc_net_tcp.txt", "tcp", "0A", portRange{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ListenPort{
+		"21451": {Proto: "tcp", Port: 8080},
+		"20133": {Proto: "tcp", Port: 5432},
+	}, res)
+
+	res, err = parseListening("testdata/proc_net_udp.txt", "udp", "07", defaultEphemeralPorts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ListenPort{
+		"18211": {Proto: "udp", Port: 53},
+		"18300": {Proto: "udp", Port: 68},
+	}, res, "connected udp socket and unconnected one on ephemeral port skipped")
+
+	res, err = parseListening("testdata/proc_net_udp.txt", "udp", "07", portRange{})
+	require.NoError(t, err)
+	assert.Equal(t, ListenPort{Proto: "udp", Port: 35404}, res["31601"], "ephemeral port not skipped without range")
+
+	bad := filepath.Join(t.TempDir(), "tcp")
+	line := "   0: 00000000:ZZZZ 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21451 1\n"
+	require.NoError(t, os.WriteFile(bad, []byte(line), 0o600))
+	_, err = parseListening(bad, "tcp", "0A", portRange{})
+	require.Error(t, err)
+
+	_, err = parseListening("testdata/no-such-file.txt", "tcp", "0A", portRange{})
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_ephemeralPorts(t *testing.T) {
+	root := t.TempDir()
+	orig := procRoot
+	procRoot = root
+	defer func() { procRoot = orig }()
+
+	assert.Equal(t, defaultEphemeralPorts, ephemeralPorts(), "no ip_local_port_range")
+
+	fname := filepath.Join(root, "sys", "net", "ipv4", "ip_local_port_range")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fname), 0o700))
+	require.NoError(t, os.WriteFile(fname, []byte("10000\t20000\n"), 0o600))
+	assert.Equal(t, portRange{lo: 10000, hi: 20000}, ephemeralPorts())
+
+	require.NoError(t, os.WriteFile(fname, []byte("20000 10000\n"), 0o600))
+	assert.Equal(t, defaultEphemeralPorts, ephemeralPorts(), "bad range")
+
+	assert.True(t, portRange{lo: 10000, hi: 20000}.contains(10000))
+	assert.True(t, portRange{lo: 10000, hi: 20000}.contains(20000))
+	assert.False(t, portRange{lo: 10000, hi: 20000}.contains(20001))
+	assert.False(t, portRange{}.contains(0))
+}
+
+func Test_socketProcs(t *testing.T) {
+	root := t.TempDir()
+	mkProc := func(pid, comm string, links map[string]string) {
+		fdDir := filepath.Join(root, pid, "fd")
+		require.NoError(t, os.MkdirAll(fdDir, 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(root, pid, "comm"), []byte(comm+"\n"), 0o600))
+		for fd, target := range links {
+			require.NoError(t, os.Symlink(target, filepath.Join(fdDir, fd)))
+		}
+	}
+	mkProc("100", "sshd", map[string]string{"0": "/dev/null", "3": "socket:[21451]"})
+	mkProc("200", "postgres", map[string]string{"5": "socket:[20133]", "6": "pipe:[1234]"})
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "self"), 0o700))
+
+	assert.Equal(t, map[string]string{"21451": "sshd", "20133": "postgres"}, socketProcs(root))
+}
+
+func Test_listeningPorts(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "net"), 0o700))
+	for src, dst := range map[string]string{"proc_net_tcp.txt": "tcp", "proc_net_udp.txt": "udp"} {
+		data, err := os.ReadFile(filepath.Join("testdata", src))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(root, "net", dst), data, 0o600))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "100", "fd"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "100", "comm"), []byte("postgres\n"), 0o600))
+	require.NoError(t, os.Symlink("socket:[20133]", filepath.Join(root, "100", "fd", "3")))
+
+	orig := procRoot
+	procRoot = root
+	defer func() { procRoot = orig }()
+
+	res, err := listeningPorts()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ListenPort{
+		{Proto: "tcp", Port: 8080},
+		{Proto: "tcp", Port: 5432, Process: "postgres"},
+		{Proto: "udp", Port: 53},
+		{Proto: "udp", Port: 68},
+	}, res, "missing tcp6 and udp6 ignored, udp client socket on ephemeral port skipped")
+
+	line := "   0: 00000000:ZZZZ 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21451 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp"), []byte(line), 0o600))
+	_, err = listeningPorts()
+	require.Error(t, err)
+
+	info, err := Service{AllowPorts: []string{"22"}}.Get()
+	require.NoError(t, err, "listening ports error doesn't fail the whole status")
+	assert.Empty(t, info.Listen.Ports)
+	assert.True(t, strings.HasPrefix(info.Listen.Status, "failed: can't get listening ports: "), info.Listen.Status)
+}
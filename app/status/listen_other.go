@@ -0,0 +1,35 @@
+//go:build !linux
+
+package status
+
+import (
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// listeningPorts returns listening tcp and unconnected udp ports with names of processes owning the sockets
+func listeningPorts() ([]ListenPort, error) {
+	conns, err := net.Connections("inet")
+	if err != nil {
+		return nil, err
+	}
+	res := []ListenPort{}
+	for _, c := range conns {
+		var p ListenPort
+		switch {
+		case c.Type == 1 && c.Status == "LISTEN": // SOCK_STREAM
+			p = ListenPort{Proto: "tcp", Port: int(c.Laddr.Port)}
+		case c.Type == 2 && c.Raddr.Port == 0: // SOCK_DGRAM
+			p = ListenPort{Proto: "udp", Port: int(c.Laddr.Port)}
+		default:
+			continue
+		}
+		if c.Pid > 0 {
+			if proc, e := process.NewProcess(c.Pid); e == nil {
+				p.Process, _ = proc.Name()
+			}
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
@@ -0,0 +1,37 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkListen(t *testing.T) {
+	ports := []ListenPort{
+		{Proto: "tcp", Port: 22, Process: "sshd"},
+		{Proto: "tcp", Port: 5432, Process: "postgres"},
+		{Proto: "tcp", Port: 5432, Process: "postgres"}, // ipv6
+		{Proto: "udp", Port: 53},
+		{Proto: "tcp", Port: 80, Process: "nginx"},
+	}
+
+	tbl := []struct {
+		allowed    []string
+		unexpected []ListenPort
+		status     string
+	}{
+		{[]string{"22", "80", "5432", "53"}, []ListenPort{}, "ok"},
+		{[]string{"tcp:22", "tcp:80", "tcp:5432", "udp:53"}, []ListenPort{}, "ok"},
+		{[]string{"22", "80", "tcp:53"}, []ListenPort{{Proto: "tcp", Port: 5432, Process: "postgres"}, {Proto: "udp", Port: 53}},
+			"failed: unexpected ports tcp:5432 (postgres), udp:53"},
+		{[]string{"udp:22", "80", "5432", "53"}, []ListenPort{{Proto: "tcp", Port: 22, Process: "sshd"}},
+			"failed: unexpected ports tcp:22 (sshd)"},
+	}
+
+	for i, tt := range tbl {
+		res := checkListen(ports, tt.allowed)
+		assert.Equal(t, []string{"tcp:22", "tcp:5432", "tcp:80", "udp:53"}, res.Ports, "case #%d", i)
+		assert.Equal(t, tt.unexpected, res.Unexpected, "case #%d", i)
+		assert.Equal(t, tt.status, res.Status, "case #%d", i)
+	}
+}
@@ -0,0 +1,67 @@
+package status
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mount contains input information for a mount point, its actual mount options and the status
+type Mount struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Require []string `json:"require,omitempty"` // options expected to be set, i.e. noexec
+	Forbid  []string `json:"forbid,omitempty"`  // options expected to be not set, i.e. rw
+	FSType  string   `json:"fs_type,omitempty"`
+	Options []string `json:"options"` // actual mount options
+	Status  string   `json:"status"`  // ok, failed if not mounted, required option missing or forbidden option set
+}
+
+// mountInfo is a mount point entry of /proc/mounts
+type mountInfo struct {
+	fsType  string
+	options []string
+}
+
+// checkMount sets the actual options and the status, mounts are mount point entries by path.
+// Option without value, i.e. "mode", matches the option with any value, i.e. "mode=1777".
+func checkMount(m Mount, mounts map[string]mountInfo) Mount {
+	mi, ok := mounts[m.Path]
+	if !ok {
+		m.Status = fmt.Sprintf("failed: %s not mounted", m.Path)
+		return m
+	}
+	m.FSType, m.Options, m.Status = mi.fsType, mi.options, "ok"
+
+	var missing, forbidden []string
+	for _, opt := range m.Require {
+		if !hasMountOption(mi.options, opt) {
+			missing = append(missing, opt)
+		}
+	}
+	for _, opt := range m.Forbid {
+		if hasMountOption(mi.options, opt) {
+			forbidden = append(forbidden, opt)
+		}
+	}
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(missing, ","))
+	}
+	if len(forbidden) > 0 {
+		problems = append(problems, "forbidden "+strings.Join(forbidden, ","))
+	}
+	if len(problems) > 0 {
+		m.Status = "failed: " + strings.Join(problems, ", ")
+	}
+	return m
+}
+
+// hasMountOption checks the option is set, exact match or by key for the option without value
+func hasMountOption(options []string, opt string) bool {
+	for _, o := range options {
+		if k, _, _ := strings.Cut(o, "="); o == opt || (!strings.Contains(opt, "=") && k == opt) {
+			return true
+		}
+	}
+	return false
+}
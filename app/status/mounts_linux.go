@@ -0,0 +1,64 @@
+//go:build linux
+
+package status
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mountPoints returns mount point entries by path from /proc/mounts
+func mountPoints() (map[string]mountInfo, error) {
+	return parseMounts(filepath.Join(procRoot, "mounts"))
+}
+
+// parseMounts parses /proc/mounts formatted file: device, mount point, fs type, options, dump and pass.
+// Spaces and other special characters in the mount point are octal escaped, i.e. "\040".
+// For the path mounted a few times the last entry reported, as it hides the previous ones.
+func parseMounts(fname string) (map[string]mountInfo, error) {
+	fh, err := os.Open(fname) //nolint:gosec // procfs file
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", fname, err)
+	}
+	defer fh.Close() // nolint
+
+	res := map[string]mountInfo{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("unexpected mount line %q", scanner.Text())
+		}
+		res[unescapeMountPath(fields[1])] = mountInfo{fsType: fields[2], options: strings.Split(fields[3], ",")}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fname, err)
+	}
+	return res, nil
+}
+
+// unescapeMountPath replaces octal escapes of /proc/mounts, i.e. "\040" with space
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+//go:build linux
+
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseMounts(t *testing.T) {
+	res, err := parseMounts("testdata/proc_mounts.txt")
+	require.NoError(t, err)
+	assert.Len(t, res, 6)
+	assert.Equal(t, mountInfo{fsType: "tmpfs", options: []string{"rw", "nosuid", "nodev", "noexec", "size=1048576k",
+		"mode=1777"}}, res["/tmp"], "the last mount of the path reported")
+	assert.Equal(t, mountInfo{fsType: "ext4", options: []string{"ro", "nosuid", "nodev", "noexec", "relatime"}},
+		res["/mnt/backup disk"], "escaped space")
+	assert.Equal(t, "xfs", res["/data"].fsType)
+
+	bad := filepath.Join(t.TempDir(), "mounts")
+	require.NoError(t, os.WriteFile(bad, []byte("/dev/sda1 / ext4\n"), 0o600))
+	_, err = parseMounts(bad)
+	require.EqualError(t, err, `unexpected mount line "/dev/sda1 / ext4"`)
+
+	_, err = parseMounts("testdata/no-such-file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_unescapeMountPath(t *testing.T) {
+	assert.Equal(t, "/mnt/a b", unescapeMountPath(`/mnt/a\040b`))
+	assert.Equal(t, "/mnt/tab\t", unescapeMountPath(`/mnt/tab\011`))
+	assert.Equal(t, `/mnt/back\slash`, unescapeMountPath(`/mnt/back\134slash`))
+	assert.Equal(t, `/mnt/bad\09`, unescapeMountPath(`/mnt/bad\09`))
+	assert.Equal(t, "/data", unescapeMountPath("/data"))
+}
+
+func Test_mountPoints(t *testing.T) {
+	root := t.TempDir()
+	data, err := os.ReadFile("testdata/proc_mounts.txt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "mounts"), data, 0o600))
+
+	origRoot := procRoot
+	procRoot = root
+	defer func() { procRoot = origRoot }()
+
+	res, err := mountPoints()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rw", "noatime", "attr2", "inode64", "logbufs=8", "noquota"}, res["/data"].options)
+
+	procRoot = filepath.Join(root, "no-such-proc")
+	info, err := Service{Mounts: []Mount{{Name: "tmp", Path: "/tmp", Require: []string{"noexec"}}}}.Get()
+	require.NoError(t, err, "mount points error doesn't fail the whole status")
+	assert.Empty(t, info.Mounts["tmp"].Options)
+	assert.True(t, strings.HasPrefix(info.Mounts["tmp"].Status, "failed: can't get mount points: "), info.Mounts["tmp"].Status)
+}
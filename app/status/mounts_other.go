@@ -0,0 +1,10 @@
+//go:build !linux
+
+package status
+
+import "errors"
+
+// mountPoints is not supported, mount options are read from procfs on linux only
+func mountPoints() (map[string]mountInfo, error) {
+	return nil, errors.New("mount options check is supported on linux only")
+}
@@ -0,0 +1,38 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkMount(t *testing.T) {
+	mounts := map[string]mountInfo{
+		"/tmp":  {fsType: "tmpfs", options: []string{"rw", "nosuid", "nodev", "noexec", "size=1048576k", "mode=1777"}},
+		"/data": {fsType: "xfs", options: []string{"rw", "noatime", "attr2"}},
+	}
+	tbl := []struct {
+		m      Mount
+		status string
+	}{
+		{Mount{Name: "tmp", Path: "/tmp", Require: []string{"noexec", "nosuid", "nodev"}}, "ok"},
+		{Mount{Name: "tmp", Path: "/tmp", Require: []string{"mode=1777", "size"}, Forbid: []string{"exec", "mode=0777"}}, "ok"},
+		{Mount{Name: "data", Path: "/data", Require: []string{"noatime"}, Forbid: []string{"ro"}}, "ok"},
+		{Mount{Name: "data", Path: "/data", Require: []string{"noexec", "noatime", "nosuid"}}, "failed: missing noexec,nosuid"},
+		{Mount{Name: "data", Path: "/data", Forbid: []string{"rw"}}, "failed: forbidden rw"},
+		{Mount{Name: "data", Path: "/data", Require: []string{"nodev"}, Forbid: []string{"rw", "attr2"}},
+			"failed: missing nodev, forbidden rw,attr2"},
+		{Mount{Name: "home", Path: "/home", Require: []string{"nodev"}}, "failed: /home not mounted"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.status, func(t *testing.T) {
+			res := checkMount(tt.m, mounts)
+			assert.Equal(t, tt.status, res.Status)
+			assert.Equal(t, tt.m.Name, res.Name)
+			if mi, ok := mounts[tt.m.Path]; ok {
+				assert.Equal(t, mi.options, res.Options)
+				assert.Equal(t, mi.fsType, res.FSType)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package status
+
+import "fmt"
+
+// SocketQueue contains input information for a listening tcp port, i.e. of socket-activated service,
+// and the length of its accept queue, connections waiting for the backend to accept them
+type SocketQueue struct {
+	Name   string `json:"name"`
+	Port   int    `json:"port"`
+	Warn   int    `json:"warn"` // no warn threshold if 0
+	Fail   int    `json:"fail"` // no fail threshold if 0
+	Queue  int    `json:"queue"`
+	Status string `json:"status"` // ok, warn or failed if above thresholds or the port is not listening
+}
+
+// checkSocketQueue sets the queue length and the status, queues are accept queue lengths by listening port
+func checkSocketQueue(q SocketQueue, queues map[int]int) SocketQueue {
+	queue, ok := queues[q.Port]
+	if !ok {
+		q.Status = fmt.Sprintf("failed: tcp:%d not listening", q.Port)
+		return q
+	}
+	q.Queue, q.Status = queue, "ok"
+	switch {
+	case q.Fail > 0 && queue > q.Fail:
+		q.Status = fmt.Sprintf("failed: accept queue %d, expected at most %d", queue, q.Fail)
+	case q.Warn > 0 && queue > q.Warn:
+		q.Status = fmt.Sprintf("warn: accept queue %d, expected at most %d", queue, q.Warn)
+	}
+	return q
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package status
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// acceptQueues returns accept queue length of listening tcp sockets by port. For the port listening on both
+// ipv4 and ipv6 the longest queue is reported.
+func acceptQueues() (map[int]int, error) {
+	res := map[int]int{}
+	for _, fname := range []string{"net/tcp", "net/tcp6"} {
+		queues, err := parseAcceptQueues(filepath.Join(procRoot, fname))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for port, q := range queues {
+			if curr, ok := res[port]; !ok || q > curr {
+				res[port] = q
+			}
+		}
+	}
+	return res, nil
+}
+
+// parseAcceptQueues parses /proc/net/tcp formatted file and returns accept queue length of listening sockets by port.
+// For listening sockets rx_queue of "tx_queue:rx_queue" field is the number of connections not accepted yet.
+func parseAcceptQueues(fname string) (map[int]int, error) {
+	fh, err := os.Open(fname) //nolint:gosec // procfs file
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", fname, err)
+	}
+	defer fh.Close() // nolint
+
+	res := map[int]int{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] == "sl" || fields[3] != "0A" {
+			continue
+		}
+		port, err := hexPort(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %q: %w", fields[1], scanner.Text(), err)
+		}
+		_, rx, found := strings.Cut(fields[4], ":")
+		if !found {
+			return nil, fmt.Errorf("no rx_queue in %q", scanner.Text())
+		}
+		queue, err := strconv.ParseUint(rx, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rx_queue %s in %q: %w", rx, scanner.Text(), err)
+		}
+		if curr, ok := res[port]; !ok || int(queue) > curr {
+			res[port] = int(queue) // the same port can be listened on a few addresses
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fname, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseAcceptQueues(t *testing.T) {
+	res, err := parseAcceptQueues("testdata/proc_net_tcp_queue.txt")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{8080: 3, 3000: 129, 22: 0}, res, "established connections skipped")
+
+	bad := filepath.Join(t.TempDir(), "tcp")
+	line := "   0: 00000000:1F90 00000000:0000 0A 00000000:ZZ 00:00000000 00000000     0        0 21451 1\n"
+	require.NoError(t, os.WriteFile(bad, []byte(line), 0o600))
+	_, err = parseAcceptQueues(bad)
+	require.Error(t, err)
+
+	_, err = parseAcceptQueues("testdata/no-such-file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_acceptQueues(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "net"), 0o700))
+	data, err := os.ReadFile("testdata/proc_net_tcp_queue.txt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp"), data, 0o600))
+	tcp6 := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n" +
+		"   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000007 " +
+		"00:00000000 00000000     0        0 21460 1 0000000000000000 100 0 0 10 0\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp6"), []byte(tcp6), 0o600))
+
+	origRoot := procRoot
+	procRoot = root
+	defer func() { procRoot = origRoot }()
+
+	res, err := acceptQueues()
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{8080: 7, 3000: 129, 22: 0}, res, "the longest queue of ipv4 and ipv6 reported")
+
+	line := "   0: 00000000:1F90 00000000:0000 0A 00000000:ZZ 00:00000000 00000000     0        0 21451 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp"), []byte(line), 0o600))
+	_, err = acceptQueues()
+	require.Error(t, err)
+
+	info, err := Service{Sockets: []SocketQueue{{Name: "web", Port: 8080, Fail: 100}}}.Get()
+	require.NoError(t, err, "accept queues error doesn't fail the whole status")
+	assert.True(t, strings.HasPrefix(info.Sockets["web"].Status, "failed: can't get accept queues: "), info.Sockets["web"].Status)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package status
+
+import "errors"
+
+// acceptQueues is not supported, accept queues are read from procfs on linux only
+func acceptQueues() (map[int]int, error) {
+	return nil, errors.New("socket queue check is supported on linux only")
+}
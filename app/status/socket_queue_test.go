@@ -0,0 +1,32 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkSocketQueue(t *testing.T) {
+	queues := map[int]int{8080: 3, 3000: 129, 22: 0}
+	tbl := []struct {
+		q      SocketQueue
+		queue  int
+		status string
+	}{
+		{SocketQueue{Name: "ssh", Port: 22}, 0, "ok"},
+		{SocketQueue{Name: "web", Port: 8080, Warn: 10, Fail: 100}, 3, "ok"},
+		{SocketQueue{Name: "web", Port: 8080, Warn: 2, Fail: 100}, 3, "warn: accept queue 3, expected at most 2"},
+		{SocketQueue{Name: "app", Port: 3000, Warn: 10, Fail: 100}, 129, "failed: accept queue 129, expected at most 100"},
+		{SocketQueue{Name: "app", Port: 3000, Fail: 128}, 129, "failed: accept queue 129, expected at most 128"},
+		{SocketQueue{Name: "app", Port: 3000, Warn: 128}, 129, "warn: accept queue 129, expected at most 128"},
+		{SocketQueue{Name: "api", Port: 9000, Warn: 10}, 0, "failed: tcp:9000 not listening"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.status, func(t *testing.T) {
+			res := checkSocketQueue(tt.q, queues)
+			assert.Equal(t, tt.queue, res.Queue)
+			assert.Equal(t, tt.status, res.Status)
+			assert.Equal(t, tt.q.Name, res.Name)
+		})
+	}
+}
@@ -3,9 +3,15 @@ package status
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
@@ -19,8 +25,33 @@ import (
 type Service struct {
 	Volumes     []Volume
 	ExtServices ExtServices
+	HostRoot    string            // optional prefix for volume paths, i.e. /hostroot for host's root mounted into container
+	CoresSample time.Duration     // sampling interval for per-core cpu utilization, disabled if 0, CPU sample used if set
+	CPU         *CPUSampler       // background cpu utilization sampler, disabled if nil
+	Connections []Connection      // ports to count established tcp connections
+	ZFS         bool              // report zfs pools health, requires zpool
+	Labels      map[string]string // static labels attached to the status, i.e. hostname, datacenter and role
+	AllowPorts  []string          // allowlist of listening ports as proto:port or port, check disabled if empty
+	Bindings    []Binding         // ports expected to be held by the processes, linux only
+	Sockets     []SocketQueue     // listening tcp ports to check accept queue length, linux only
+	Mounts      []Mount           // mount points to check expected mount options, linux only
+	Systemd     bool              // report failed systemd units, requires systemctl
+	IgnoreUnits []string          // failed systemd units to ignore, name or glob, i.e. apt-daily*.service
+	UPS         *UPSLimits        // report ups status from apcupsd, requires apcaccess, disabled if nil
+	GPU         *GPULimits        // report gpus health from nvidia-smi, disabled if nil
+	ConfigDrift *ConfigWatch      // report changes of the config file since startup, disabled if nil
+	Kmsg        *KmsgCheck        // report critical kernel log events, linux only, disabled if nil
+	FSErrors    time.Duration     // report ext4 errors, failed if the last error is within this window, linux only, disabled if 0
 }
 
+const (
+	corePeggedPercent = 95              // per-core utilization considered as saturated
+	zfsTimeout        = 5 * time.Second // timeout for zpool commands
+	systemdTimeout    = 5 * time.Second // timeout for systemctl command
+	upsTimeout        = 5 * time.Second // timeout for apcaccess command
+	gpuTimeout        = 5 * time.Second // timeout for nvidia-smi command
+)
+
 // ExtServices declares interface to get status of all external services
 type ExtServices interface {
 	Status() []external.Response
@@ -28,11 +59,13 @@ type ExtServices interface {
 
 // Info contains disk and cpu utilization results
 type Info struct {
+	Labels     map[string]string `json:"labels,omitempty"`
 	HostName   string            `json:"hostname"`
 	Procs      int               `json:"procs"`
 	HostID     string            `json:"host_id"`
 	CPUPercent int               `json:"cpu_percent"`
 	MemPercent int               `json:"mem_percent"`
+	Memory     Memory            `json:"memory"`
 	Uptime     uint64            `json:"uptime"`
 	Volumes    map[string]Volume `json:"volumes,omitempty"`
 	Loads      struct {
@@ -41,13 +74,84 @@ type Info struct {
 		Fifteen float64 `json:"fifteen"`
 	} `json:"load_average"`
 	ExtServices map[string]external.Response `json:"services,omitempty"`
+	CPU         *CPU                         `json:"cpu,omitempty"`
+	CPUCores    *Cores                       `json:"cpu_cores,omitempty"`
+	Connections map[string]Connection        `json:"connections,omitempty"`
+	ZFS         map[string]ZFSPool           `json:"zfs,omitempty"`
+	Listen      *Listen                      `json:"listen,omitempty"`
+	Bindings    map[string]Binding           `json:"bindings,omitempty"`
+	Sockets     map[string]SocketQueue       `json:"sockets,omitempty"`
+	Mounts      map[string]Mount             `json:"mounts,omitempty"`
+	Systemd     *Systemd                     `json:"systemd,omitempty"`
+	UPS         *UPS                         `json:"ups,omitempty"`
+	GPU         *GPUs                        `json:"gpu,omitempty"`
+	ConfigDrift *ConfigDrift                 `json:"config_drift,omitempty"`
+	Kmsg        *Kmsg                        `json:"kmsg,omitempty"`
+	FSErrors    *FSErrors                    `json:"fs_errors,omitempty"`
+	Offline     *Offline                     `json:"offline,omitempty"`
+}
+
+// Offline is reported instead of the failures of remote services if the pre-flight connectivity check failed
+type Offline struct {
+	Preflight string   `json:"preflight"` // pre-flight url, i.e. "ping://10.0.0.1"
+	Reason    string   `json:"reason"`    // summary of the failed pre-flight check
+	Skipped   []string `json:"skipped"`   // remote services skipped as offline
+}
+
+// Memory contains memory and swap usage in bytes
+type Memory struct {
+	Total       uint64 `json:"total"`
+	Used        uint64 `json:"used"`
+	Available   uint64 `json:"available"` // can be allocated without swapping, includes reclaimable cache
+	UsedPercent int    `json:"used_percent"`
+	Swap        *Swap  `json:"swap,omitempty"` // not reported if swap info is unavailable
+}
+
+// Swap contains swap usage in bytes, all zero if swap is not configured
+type Swap struct {
+	Total       uint64 `json:"total"`
+	Used        uint64 `json:"used"`
+	Free        uint64 `json:"free"`
+	UsedPercent int    `json:"used_percent"`
+}
+
+// Cores contains per-core cpu utilization
+type Cores struct {
+	Usage  []int  `json:"usage"`  // percent for each core, ordered by core index
+	Status string `json:"status"` // ok or warn if any core is pegged
+}
+
+// Connection contains input information for a port and the number of established tcp connections with status
+type Connection struct {
+	Name   string `json:"name"`
+	Port   int    `json:"port"`
+	Min    int    `json:"min"`
+	Max    int    `json:"max"` // no upper limit if 0
+	Count  int    `json:"count"`
+	Status string `json:"status"` // ok, failed if below min or warn if above max
 }
 
 // Volume contains input information for a volume and the result for utilization percentage
 type Volume struct {
-	Name         string `json:"name"`
-	Path         string `json:"path"`
-	UsagePercent int    `json:"usage_percent"`
+	Name          string `json:"name"`
+	Path          string `json:"path"`
+	UsagePercent  int    `json:"usage_percent"`
+	InodesTotal   uint64 `json:"inodes_total"` // 0 if the filesystem doesn't report inodes, i.e. btrfs, ntfs
+	InodesUsed    uint64 `json:"inodes_used"`
+	InodesUsedPct int    `json:"inodes_used_percent"`
+	ProbeWrite    bool   `json:"-"`                          // check the volume is writable with a temp file
+	MaxUsedPct    int    `json:"max_used_percent,omitempty"` // failed if usage is above, not checked if 0
+	Writable      *bool  `json:"writable,omitempty"`         // set with ProbeWrite only
+	Status        string `json:"status,omitempty"`           // set with ProbeWrite or MaxUsedPct only, ok or failed with reason
+	Summary       string `json:"summary"`                    // one-line human-readable summary, i.e. "disk / at 82% (ok)"
+}
+
+// volumeStat is space and inodes usage of the volume
+type volumeStat struct {
+	usedPercent       float64
+	inodesTotal       uint64
+	inodesUsed        uint64
+	inodesUsedPercent float64
 }
 
 // Get returns the disk and cpu utilization
@@ -80,18 +184,184 @@ func (s Service) Get() (*Info, error) {
 		MemPercent: int(memp.UsedPercent),
 		Volumes:    map[string]Volume{},
 		Uptime:     hostStat.Uptime,
+		Labels:     s.Labels,
 	}
 	res.Loads.One, res.Loads.Five, res.Loads.Fifteen = loads.Load1, loads.Load5, loads.Load15
 
+	res.Memory = Memory{Total: memp.Total, Used: memp.Used, Available: memp.Available, UsedPercent: int(memp.UsedPercent)}
+	if swap, err := mem.SwapMemory(); err == nil {
+		res.Memory.Swap = &Swap{Total: swap.Total, Used: swap.Used, Free: swap.Free, UsedPercent: int(swap.UsedPercent)}
+	} else {
+		log.Printf("[DEBUG] swap info is unavailable: %v", err)
+	}
+
 	for _, v := range s.Volumes {
-		usage, err := disk.Usage(v.Path)
+		path := v.Path
+		if s.HostRoot != "" {
+			path = filepath.Join(s.HostRoot, v.Path)
+		}
+		usage, err := volumeUsage(path)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get disk usage for %s: %w", v.Path, err)
+			return nil, fmt.Errorf("failed to get disk usage for %s: %w", path, err)
+		}
+		vol := Volume{
+			Name:          v.Name,
+			Path:          v.Path,
+			UsagePercent:  int(usage.usedPercent),
+			InodesTotal:   usage.inodesTotal,
+			InodesUsed:    usage.inodesUsed,
+			InodesUsedPct: int(usage.inodesUsedPercent),
+			MaxUsedPct:    v.MaxUsedPct,
+		}
+		vol.Summary = fmt.Sprintf("disk %s at %d%% (ok)", v.Path, vol.UsagePercent)
+		if v.MaxUsedPct > 0 {
+			vol.Status = "ok"
+			if vol.UsagePercent > v.MaxUsedPct {
+				vol.Status = fmt.Sprintf("failed: usage %d%%, expected at most %d%%", vol.UsagePercent, v.MaxUsedPct)
+				vol.Summary = fmt.Sprintf("disk %s at %d%%, above %d%% (failed)", v.Path, vol.UsagePercent, v.MaxUsedPct)
+			}
+		}
+		if v.ProbeWrite {
+			writable := true
+			if err := probeWrite(path); err != nil {
+				writable, vol.Status = false, "failed: "+err.Error()
+				vol.Summary = fmt.Sprintf("disk %s at %d%%, not writable (failed)", v.Path, vol.UsagePercent)
+			} else if vol.Status == "" {
+				vol.Status = "ok"
+			}
+			vol.Writable = &writable
+		}
+		res.Volumes[v.Name] = vol
+	}
+
+	if s.CPU != nil {
+		res.CPU = s.CPU.Last()
+		if res.CPU != nil && runtime.GOOS != "windows" {
+			res.CPU.LoadAvg = &LoadAvg{One: loads.Load1, Five: loads.Load5, Fifteen: loads.Load15}
+		}
+	}
+
+	if s.CoresSample > 0 {
+		res.CPUCores = &Cores{Usage: []int{}, Status: "ok"}
+		if res.CPU != nil {
+			res.CPUCores.Usage = append(res.CPUCores.Usage, res.CPU.Cores...) // sampled in background, no need to wait
+		} else {
+			usage, err := coresUsage(s.CoresSample)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get per-core cpu usage: %w", err)
+			}
+			for _, u := range usage {
+				res.CPUCores.Usage = append(res.CPUCores.Usage, int(u))
+			}
+		}
+		var pegged []string
+		for i, u := range res.CPUCores.Usage {
+			if u >= corePeggedPercent {
+				pegged = append(pegged, strconv.Itoa(i))
+			}
+		}
+		if len(pegged) > 0 {
+			res.CPUCores.Status = "warn: pegged cores " + strings.Join(pegged, ",")
+		}
+	}
+
+	if len(s.Connections) > 0 {
+		res.Connections = map[string]Connection{}
+		for _, c := range s.Connections {
+			count, err := establishedConns(c.Port)
+			c.Count, c.Status = count, "ok"
+			switch {
+			case err != nil:
+				c.Status = fmt.Sprintf("failed: can't count connections: %v", err)
+			case count < c.Min:
+				c.Status = fmt.Sprintf("failed: %d connections, expected at least %d", count, c.Min)
+			case c.Max > 0 && count > c.Max:
+				c.Status = fmt.Sprintf("warn: %d connections, expected at most %d", count, c.Max)
+			}
+			res.Connections[c.Name] = c
+		}
+	}
+
+	if s.ZFS {
+		res.ZFS = zfsPools(zfsTimeout)
+	}
+
+	if len(s.AllowPorts) > 0 {
+		if ports, err := listeningPorts(); err != nil {
+			res.Listen = &Listen{Ports: []string{}, Unexpected: []ListenPort{},
+				Status: fmt.Sprintf("failed: can't get listening ports: %v", err)}
+		} else {
+			res.Listen = checkListen(ports, s.AllowPorts)
+		}
+	}
+
+	if len(s.Bindings) > 0 {
+		owners, err := portOwners()
+		res.Bindings = map[string]Binding{}
+		for _, b := range s.Bindings {
+			if err != nil {
+				b.Owners, b.Status = []string{}, fmt.Sprintf("failed: can't get listening ports owners: %v", err)
+				res.Bindings[b.Name] = b
+				continue
+			}
+			res.Bindings[b.Name] = checkBinding(b, owners[b.Proto+":"+strconv.Itoa(b.Port)])
+		}
+	}
+
+	if len(s.Sockets) > 0 {
+		queues, err := acceptQueues()
+		res.Sockets = map[string]SocketQueue{}
+		for _, q := range s.Sockets {
+			if err != nil {
+				q.Status = fmt.Sprintf("failed: can't get accept queues: %v", err)
+				res.Sockets[q.Name] = q
+				continue
+			}
+			res.Sockets[q.Name] = checkSocketQueue(q, queues)
+		}
+	}
+
+	if len(s.Mounts) > 0 {
+		mounts, err := mountPoints()
+		res.Mounts = map[string]Mount{}
+		for _, m := range s.Mounts {
+			if err != nil {
+				m.Options, m.Status = []string{}, fmt.Sprintf("failed: can't get mount points: %v", err)
+				res.Mounts[m.Name] = m
+				continue
+			}
+			res.Mounts[m.Name] = checkMount(m, mounts)
+		}
+	}
+
+	if s.Systemd {
+		res.Systemd = systemdFailed(systemdTimeout, s.IgnoreUnits)
+	}
+
+	if s.UPS != nil {
+		res.UPS = upsStatus(upsTimeout, *s.UPS)
+	}
+
+	if s.GPU != nil {
+		res.GPU = gpuStatus(gpuTimeout, *s.GPU)
+	}
+
+	if s.ConfigDrift != nil {
+		res.ConfigDrift = s.ConfigDrift.Check()
+	}
+
+	if s.Kmsg != nil {
+		if res.Kmsg, err = kmsgEvents(*s.Kmsg); err != nil {
+			res.Kmsg = &Kmsg{Window: s.Kmsg.Window.String(), Counts: map[string]int{}, Lines: []string{},
+				Status: fmt.Sprintf("failed: can't read kernel log: %v", err)}
 		}
-		res.Volumes[v.Name] = Volume{
-			Name:         v.Name,
-			Path:         v.Path,
-			UsagePercent: int(usage.UsedPercent),
+	}
+
+	if s.FSErrors > 0 {
+		if devices, err := fsErrorCounts(); err != nil {
+			res.FSErrors = &FSErrors{Devices: map[string]FSDevice{}, Status: fmt.Sprintf("failed: can't read filesystem errors: %v", err)}
+		} else {
+			res.FSErrors = checkFSErrors(devices, s.FSErrors, time.Now())
 		}
 	}
 
@@ -99,9 +369,46 @@ func (s Service) Get() (*Info, error) {
 		res.ExtServices = map[string]external.Response{}
 		for _, v := range s.ExtServices.Status() {
 			res.ExtServices[v.Name] = v
+			if v.Skipped != external.OfflineStatus {
+				continue
+			}
+			if res.Offline == nil {
+				res.Offline = &Offline{Preflight: fmt.Sprintf("%v", v.Body["preflight"]), Reason: fmt.Sprintf("%v", v.Body["preflight_reason"])}
+			}
+			res.Offline.Skipped = append(res.Offline.Skipped, v.Name)
+		}
+		if res.Offline != nil {
+			sort.Strings(res.Offline.Skipped)
 		}
 	}
 
 	log.Printf("[DEBUG] status: %+v", res)
 	return &res, nil
 }
+
+// probeWrite creates, writes and deletes a small temp file in the directory.
+// The file is removed even if write or close failed.
+func probeWrite(dir string) (err error) {
+	fh, err := os.CreateTemp(dir, ".sys-agent-probe-*")
+	if err != nil {
+		return fmt.Errorf("can't create probe file: %w", err)
+	}
+	defer func() {
+		if e := os.Remove(fh.Name()); e != nil && err == nil {
+			err = fmt.Errorf("can't remove probe file: %w", e)
+		}
+	}()
+
+	if _, err = fh.WriteString("sys-agent write probe\n"); err != nil {
+		_ = fh.Close()
+		return fmt.Errorf("can't write probe file: %w", err)
+	}
+	if err = fh.Sync(); err != nil {
+		_ = fh.Close()
+		return fmt.Errorf("can't sync probe file: %w", err)
+	}
+	if err = fh.Close(); err != nil {
+		return fmt.Errorf("can't close probe file: %w", err)
+	}
+	return nil
+}
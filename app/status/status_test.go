@@ -1,7 +1,14 @@
 package status
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -40,6 +47,16 @@ func TestService_Get(t *testing.T) {
 	assert.Equal(t, "root", res.Volumes["root"].Name)
 	assert.Equal(t, "/", res.Volumes["root"].Path)
 	assert.True(t, res.Volumes["root"].UsagePercent > 0)
+
+	assert.True(t, res.Memory.Total > 0)
+	assert.True(t, res.Memory.Used > 0 && res.Memory.Used <= res.Memory.Total, res.Memory.Used)
+	assert.True(t, res.Memory.Available > 0 && res.Memory.Available <= res.Memory.Total, res.Memory.Available)
+	assert.Equal(t, res.MemPercent, res.Memory.UsedPercent)
+	if runtime.GOOS == "linux" {
+		require.NotNil(t, res.Memory.Swap)
+		assert.True(t, res.Memory.Swap.Used <= res.Memory.Swap.Total)
+		assert.True(t, res.Memory.Swap.UsedPercent >= 0 && res.Memory.Swap.UsedPercent <= 100)
+	}
 	assert.True(t, res.MemPercent > 0)
 	assert.True(t, res.Loads.One > 0)
 	assert.True(t, res.Uptime > 0)
@@ -60,9 +77,206 @@ func TestService_GetNoExt(t *testing.T) {
 	assert.Equal(t, "root", res.Volumes["root"].Name)
 	assert.Equal(t, "/", res.Volumes["root"].Path)
 	assert.True(t, res.Volumes["root"].UsagePercent > 0)
+	if vol := res.Volumes["root"]; vol.InodesTotal > 0 { // not reported by some filesystems, i.e. btrfs
+		assert.True(t, vol.InodesUsed > 0 && vol.InodesUsed <= vol.InodesTotal, vol)
+		assert.True(t, vol.InodesUsedPct >= 0 && vol.InodesUsedPct <= 100, vol)
+	}
 	assert.True(t, res.MemPercent > 0)
 	assert.True(t, res.Loads.One > 0)
 	assert.True(t, res.Uptime > 0)
 
 	assert.Equal(t, 0, len(res.ExtServices))
 }
+
+func TestService_GetWithCores(t *testing.T) {
+	{
+		svc := Service{Volumes: []Volume{{Name: "root", Path: "/"}}}
+		res, err := svc.Get()
+		require.NoError(t, err)
+		assert.Nil(t, res.CPUCores, "per-core usage disabled by default")
+	}
+	{
+		svc := Service{Volumes: []Volume{{Name: "root", Path: "/"}}, CoresSample: 50 * time.Millisecond}
+		res, err := svc.Get()
+		require.NoError(t, err)
+		require.NotNil(t, res.CPUCores)
+		assert.True(t, len(res.CPUCores.Usage) > 0)
+		assert.NotEmpty(t, res.CPUCores.Status)
+	}
+}
+
+func TestService_GetWithHostRoot(t *testing.T) {
+	hostRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(hostRoot, "data"), 0o750))
+
+	{ // /data exists only under host root
+		svc := Service{Volumes: []Volume{{Name: "data", Path: "/data"}}, HostRoot: hostRoot}
+		res, err := svc.Get()
+		require.NoError(t, err)
+		assert.Equal(t, 1, len(res.Volumes))
+		assert.Equal(t, "/data", res.Volumes["data"].Path, "reported path is not prefixed")
+		assert.True(t, res.Volumes["data"].UsagePercent > 0)
+	}
+
+	{ // missing path under host root
+		svc := Service{Volumes: []Volume{{Name: "bad", Path: "/bad"}}, HostRoot: hostRoot}
+		_, err := svc.Get()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), filepath.Join(hostRoot, "bad"))
+	}
+}
+
+func TestService_GetWithConnections(t *testing.T) {
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lst.Close()
+	go func() {
+		for {
+			conn, e := lst.Accept()
+			if e != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	conn, err := net.Dial("tcp", lst.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	port := lst.Addr().(*net.TCPAddr).Port
+	time.Sleep(50 * time.Millisecond) // wait for accept
+
+	svc := Service{Connections: []Connection{
+		{Name: "ok", Port: port, Min: 1},
+		{Name: "exploded", Port: port, Min: 1, Max: 1},
+		{Name: "collapsed", Port: port + 1, Min: 1},
+	}}
+	res, err := svc.Get()
+	require.NoError(t, err)
+	t.Logf("%+v", res.Connections)
+	require.Equal(t, 3, len(res.Connections))
+	assert.Equal(t, Connection{Name: "ok", Port: port, Min: 1, Count: 2, Status: "ok"}, res.Connections["ok"],
+		"both sides of local connection counted")
+	assert.Equal(t, "warn: 2 connections, expected at most 1", res.Connections["exploded"].Status)
+	assert.Equal(t, "failed: 0 connections, expected at least 1", res.Connections["collapsed"].Status)
+}
+
+func TestService_GetWithAllowPorts(t *testing.T) {
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lst.Close()
+	port := lst.Addr().(*net.TCPAddr).Port
+
+	svc := Service{AllowPorts: []string{"1"}}
+	res, err := svc.Get()
+	require.NoError(t, err)
+	require.NotNil(t, res.Listen)
+	t.Logf("%+v", res.Listen)
+	assert.Contains(t, res.Listen.Ports, fmt.Sprintf("tcp:%d", port))
+	assert.Contains(t, res.Listen.Status, fmt.Sprintf("tcp:%d", port))
+	assert.True(t, strings.HasPrefix(res.Listen.Status, "failed: unexpected ports"), res.Listen.Status)
+
+	svc = Service{}
+	res, err = svc.Get()
+	require.NoError(t, err)
+	assert.Nil(t, res.Listen, "disabled without allowlist")
+}
+
+func TestService_GetOffline(t *testing.T) {
+	offline := func(name string) external.Response {
+		return external.Response{Name: name, StatusCode: 200, Summary: "skipped, host is offline (ok)",
+			Skipped: external.OfflineStatus, Body: map[string]interface{}{"status": external.OfflineStatus, "preflight": "ping://10.0.0.1",
+				"preflight_reason": "ping 10.0.0.1, 100% loss (failed)"}}
+	}
+	resp := []external.Response{offline("web"), {Name: "backup", StatusCode: 200, Summary: "file exists (ok)"}, offline("billing"),
+		{Name: "remote", StatusCode: 200, Summary: "http 200 (ok)", Body: map[string]interface{}{"status": external.OfflineStatus}}}
+	ex := &ExtServicesMock{StatusFunc: func() []external.Response { return resp }}
+	svc := Service{Volumes: []Volume{{Name: "root", Path: "/"}}, ExtServices: ex}
+
+	res, err := svc.Get()
+	require.NoError(t, err)
+	assert.Equal(t, &Offline{Preflight: "ping://10.0.0.1", Reason: "ping 10.0.0.1, 100% loss (failed)",
+		Skipped: []string{"billing", "web"}}, res.Offline)
+	assert.Equal(t, 4, len(res.ExtServices), "remote body status is not an offline marker")
+	assert.Equal(t, 1, len(res.Volumes), "local checks reported")
+
+	resp = []external.Response{{Name: "web", StatusCode: 200, Summary: "http 200 (ok)"}}
+	res, err = svc.Get()
+	require.NoError(t, err)
+	assert.Nil(t, res.Offline)
+}
+
+func TestService_GetWithProbeWrite(t *testing.T) {
+	dir := t.TempDir()
+	svc := Service{Volumes: []Volume{{Name: "rw", Path: dir, ProbeWrite: true}, {Name: "root", Path: "/"}}}
+	res, err := svc.Get()
+	require.NoError(t, err)
+	require.NotNil(t, res.Volumes["rw"].Writable)
+	assert.True(t, *res.Volumes["rw"].Writable)
+	assert.Equal(t, "ok", res.Volumes["rw"].Status)
+	assert.Equal(t, fmt.Sprintf("disk %s at %d%% (ok)", dir, res.Volumes["rw"].UsagePercent), res.Volumes["rw"].Summary)
+	assert.Nil(t, res.Volumes["root"].Writable, "not probed")
+	assert.Equal(t, "", res.Volumes["root"].Status)
+	assert.Equal(t, fmt.Sprintf("disk / at %d%% (ok)", res.Volumes["root"].UsagePercent), res.Volumes["root"].Summary)
+
+	svc = Service{Volumes: []Volume{{Name: "ro", Path: dir, ProbeWrite: true}}}
+	require.NoError(t, os.Chmod(dir, 0o500))
+	defer os.Chmod(dir, 0o700) //nolint
+	res, err = svc.Get()
+	require.NoError(t, err)
+	if os.Geteuid() != 0 { // root ignores directory permissions
+		assert.Equal(t, fmt.Sprintf("disk %s at %d%%, not writable (failed)", dir, res.Volumes["ro"].UsagePercent),
+			res.Volumes["ro"].Summary)
+	}
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "probe file removed")
+}
+
+func TestService_GetWithMaxUsedPct(t *testing.T) {
+	svc := Service{Volumes: []Volume{{Name: "root", Path: "/", MaxUsedPct: 100}}}
+	res, err := svc.Get()
+	require.NoError(t, err)
+	usage := res.Volumes["root"].UsagePercent
+	assert.Equal(t, "ok", res.Volumes["root"].Status)
+	assert.Equal(t, 100, res.Volumes["root"].MaxUsedPct)
+	assert.Equal(t, fmt.Sprintf("disk / at %d%% (ok)", usage), res.Volumes["root"].Summary)
+
+	if usage < 2 {
+		t.Skip("root volume is empty, can't set the threshold below usage")
+	}
+	svc = Service{Volumes: []Volume{{Name: "root", Path: "/", MaxUsedPct: usage - 1}}}
+	res, err = svc.Get()
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("failed: usage %d%%, expected at most %d%%", usage, usage-1), res.Volumes["root"].Status)
+	assert.Equal(t, fmt.Sprintf("disk / at %d%%, above %d%% (failed)", usage, usage-1), res.Volumes["root"].Summary)
+	assert.Nil(t, res.Volumes["root"].Writable, "not probed")
+}
+
+func Test_probeWrite(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, probeWrite(dir))
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "probe file removed")
+
+	err = probeWrite(filepath.Join(dir, "not-exists"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't create probe file")
+
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	roDir := filepath.Join(dir, "ro")
+	require.NoError(t, os.Mkdir(roDir, 0o500))
+	defer os.Chmod(roDir, 0o700) //nolint
+	err = probeWrite(roDir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+
+	svc := Service{Volumes: []Volume{{Name: "ro", Path: roDir, ProbeWrite: true}}}
+	res, err := svc.Get()
+	require.NoError(t, err)
+	assert.False(t, *res.Volumes["ro"].Writable)
+	assert.Contains(t, res.Volumes["ro"].Status, "failed: can't create probe file")
+}
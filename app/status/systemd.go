@@ -0,0 +1,79 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// systemctlCmd is systemctl binary, can be changed for tests
+var systemctlCmd = "systemctl"
+
+// Systemd contains systemd units in failed state
+type Systemd struct {
+	Failed  []string `json:"failed"`            // names of failed units, excluding ignored
+	Ignored []string `json:"ignored,omitempty"` // names of failed units matching the ignore list
+	Count   int      `json:"count"`             // number of failed units, excluding ignored
+	Status  string   `json:"status"`            // ok or failed with names of failed units
+}
+
+// systemdFailed runs systemctl and returns failed units. Units matching any of ignore patterns (path.Match globs,
+// i.e. "apt-daily*.service") reported as ignored and don't affect the status.
+// Failure to run systemctl reported as failed status, not as error of the whole status.
+func systemdFailed(timeout time.Duration, ignore []string) *Systemd {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, systemctlCmd, "list-units", "--failed", "--all", "--plain", "--no-legend", //nolint:gosec
+		"--no-pager").Output()
+	if err != nil {
+		return &Systemd{Failed: []string{}, Status: fmt.Sprintf("failed: can't run systemctl list-units: %v", err)}
+	}
+	return checkSystemd(parseSystemctlFailed(string(out)), ignore)
+}
+
+// checkSystemd splits failed units to ignored and not ignored, and sets the status
+func checkSystemd(units, ignore []string) *Systemd {
+	res := &Systemd{Failed: []string{}, Status: "ok"}
+	for _, u := range units {
+		if systemdIgnored(u, ignore) {
+			res.Ignored = append(res.Ignored, u)
+			continue
+		}
+		res.Failed = append(res.Failed, u)
+	}
+	sort.Strings(res.Failed)
+	sort.Strings(res.Ignored)
+	res.Count = len(res.Failed)
+	if res.Count > 0 {
+		res.Status = "failed: units " + strings.Join(res.Failed, ", ")
+	}
+	return res
+}
+
+func systemdIgnored(unit string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if ok, err := path.Match(pattern, unit); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSystemctlFailed parses "systemctl list-units --failed --plain --no-legend" output and returns unit names.
+// Lines are "UNIT LOAD ACTIVE SUB DESCRIPTION", some systemd versions prefix failed units with "●" even with --plain.
+func parseSystemctlFailed(out string) []string {
+	res := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "●"))
+		if len(fields) < 4 {
+			continue
+		}
+		res = append(res, fields[0])
+	}
+	return res
+}
@@ -0,0 +1,50 @@
+package status
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_systemdFailed(t *testing.T) {
+	fake, err := filepath.Abs("testdata/systemctl.sh")
+	require.NoError(t, err)
+	orig := systemctlCmd
+	systemctlCmd = fake
+	defer func() { systemctlCmd = orig }()
+
+	{ // several failed units, some ignored
+		res := systemdFailed(time.Second, []string{"apt-daily*.service", "*.mount"})
+		assert.Equal(t, &Systemd{Failed: []string{"backup@db.service", "nginx.service"},
+			Ignored: []string{"apt-daily.service", "run-rpc_pipefs.mount"}, Count: 2,
+			Status: "failed: units backup@db.service, nginx.service"}, res)
+	}
+
+	{ // all failed units ignored
+		res := systemdFailed(time.Second, []string{"*"})
+		assert.Equal(t, 0, res.Count)
+		assert.Equal(t, []string{}, res.Failed)
+		assert.Equal(t, 4, len(res.Ignored))
+		assert.Equal(t, "ok", res.Status)
+	}
+
+	{ // no failed units
+		t.Setenv("SYSTEMCTL_NONE", "1")
+		res := systemdFailed(time.Second, nil)
+		assert.Equal(t, &Systemd{Failed: []string{}, Status: "ok"}, res)
+	}
+
+	systemctlCmd = "testdata/no-such-systemctl"
+	res := systemdFailed(time.Second, nil)
+	assert.Equal(t, []string{}, res.Failed)
+	assert.Contains(t, res.Status, "failed: can't run systemctl list-units:")
+}
+
+func Test_parseSystemctlFailed(t *testing.T) {
+	out := "● nginx.service loaded failed failed nginx\n\nfoo.socket  loaded failed failed Foo Socket\nbad line\n"
+	assert.Equal(t, []string{"nginx.service", "foo.socket"}, parseSystemctlFailed(out))
+	assert.Equal(t, []string{}, parseSystemctlFailed(""))
+}
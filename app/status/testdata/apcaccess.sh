@@ -0,0 +1,5 @@
+#!/usr/bin/env sh
+# fake apcaccess, prints fixture selected by APCACCESS_FIXTURE, online by default
+dir=$(dirname "$0")
+[ "$1" = "status" ] || exit 1
+cat "$dir/apcaccess_${APCACCESS_FIXTURE:-online}.txt"
@@ -0,0 +1,11 @@
+#!/usr/bin/env sh
+# fake nvidia-smi, prints fixture selected by NVIDIA_SMI_FIXTURE, ok by default
+dir=$(dirname "$0")
+case "$1" in --query-gpu=*) ;; *) exit 1 ;; esac
+case "${NVIDIA_SMI_FIXTURE:-ok}" in
+  none) echo "No devices were found"; exit 6 ;;
+  driver) echo "NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver."; exit 9 ;;
+  slow) exec sleep 1 ;;
+  lost) cat "$dir/nvidia_smi_lost.txt"; exit 15 ;;
+esac
+cat "$dir/nvidia_smi_${NVIDIA_SMI_FIXTURE:-ok}.txt"
@@ -0,0 +1,6 @@
+#!/usr/bin/env sh
+# fake systemctl, prints fixture of failed units, or nothing with SYSTEMCTL_NONE set
+dir=$(dirname "$0")
+[ "$1" = "list-units" ] || exit 1
+[ -n "$SYSTEMCTL_NONE" ] && exit 0
+cat "$dir/systemctl_failed.txt"
@@ -0,0 +1,8 @@
+#!/usr/bin/env sh
+# fake zpool, prints fixtures for list and status commands
+dir=$(dirname "$0")
+case "$1" in
+  list) cat "$dir/zpool_list.txt" ;;
+  status) cat "$dir/zpool_status.txt" ;;
+  *) exit 1 ;;
+esac
@@ -0,0 +1,97 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apcaccessCmd is apcaccess binary of apcupsd, can be changed for tests
+var apcaccessCmd = "apcaccess"
+
+// UPSLimits defines thresholds for ups status
+type UPSLimits struct {
+	MinCharge    int           // battery charge percent, failed if below
+	MaxOnBattery time.Duration // time on battery, warn if on battery, failed if longer
+}
+
+// UPS contains ups status reported by apcupsd
+type UPS struct {
+	Name             string  `json:"name"`
+	Model            string  `json:"model"`
+	State            string  `json:"state"` // apcupsd status flags, i.e. ONLINE, ONBATT or ONBATT LOWBATT
+	ChargePercent    int     `json:"charge_percent"`
+	OnBattery        bool    `json:"on_battery"`
+	OnBatterySeconds int     `json:"on_battery_seconds"` // time on battery, 0 if online
+	TimeLeftMinutes  float64 `json:"time_left_minutes"`  // estimated runtime on battery
+	Status           string  `json:"status"`             // ok, warn if on battery, failed on low charge or long on battery
+}
+
+// upsStatus runs apcaccess and returns ups status checked against the limits.
+// Failure to run apcaccess or parse its output reported as failed status, not as error of the whole status.
+func upsStatus(timeout time.Duration, limits UPSLimits) *UPS {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, apcaccessCmd, "status").Output() //nolint:gosec
+	if err != nil {
+		return &UPS{Status: fmt.Sprintf("failed: can't run apcaccess status: %v", err)}
+	}
+	ups, err := parseApcaccess(string(out))
+	if err != nil {
+		return &UPS{Status: fmt.Sprintf("failed: %v", err)}
+	}
+
+	onBattery := time.Duration(ups.OnBatterySeconds) * time.Second
+	ups.Status = "ok"
+	switch {
+	case strings.Contains(ups.State, "COMMLOST"):
+		ups.Status = "failed: communication with ups lost"
+	case strings.Contains(ups.State, "LOWBATT"):
+		ups.Status = fmt.Sprintf("failed: low battery, %d%% charge", ups.ChargePercent)
+	case ups.ChargePercent < limits.MinCharge:
+		ups.Status = fmt.Sprintf("failed: %d%% charge, expected at least %d%%", ups.ChargePercent, limits.MinCharge)
+	case ups.OnBattery && limits.MaxOnBattery > 0 && onBattery > limits.MaxOnBattery:
+		ups.Status = fmt.Sprintf("failed: on battery for %s, expected at most %s", onBattery, limits.MaxOnBattery)
+	case ups.OnBattery:
+		ups.Status = fmt.Sprintf("warn: on battery for %s", onBattery)
+	}
+	return ups
+}
+
+// parseApcaccess parses "apcaccess status" output, "KEY : value" per line with units like "78.0 Percent"
+func parseApcaccess(out string) (*UPS, error) {
+	fields := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	if fields["STATUS"] == "" {
+		return nil, fmt.Errorf("no ups status in apcaccess output")
+	}
+
+	// number returns leading number of the value, i.e. 78.0 for "78.0 Percent"
+	number := func(key string) float64 {
+		v, _ := strconv.ParseFloat(strings.Fields(fields[key] + " 0")[0], 64)
+		return v
+	}
+
+	res := &UPS{
+		Name:            fields["UPSNAME"],
+		Model:           fields["MODEL"],
+		State:           fields["STATUS"],
+		ChargePercent:   int(number("BCHARGE")),
+		OnBattery:       strings.Contains(fields["STATUS"], "ONBATT"),
+		TimeLeftMinutes: number("TIMELEFT"),
+	}
+	if res.OnBattery {
+		res.OnBatterySeconds = int(number("TONBATT"))
+	}
+	return res, nil
+}
@@ -0,0 +1,67 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_upsStatus(t *testing.T) {
+	fake, err := filepath.Abs("testdata/apcaccess.sh")
+	require.NoError(t, err)
+	orig := apcaccessCmd
+	apcaccessCmd = fake
+	defer func() { apcaccessCmd = orig }()
+
+	tbl := []struct {
+		fixture string
+		limits  UPSLimits
+		status  string
+	}{
+		{"online", UPSLimits{MinCharge: 50, MaxOnBattery: 5 * time.Minute}, "ok"},
+		{"onbatt", UPSLimits{MinCharge: 50, MaxOnBattery: 10 * time.Minute}, "warn: on battery for 7m0s"},
+		{"onbatt", UPSLimits{MinCharge: 50}, "warn: on battery for 7m0s"},
+		{"onbatt", UPSLimits{MinCharge: 50, MaxOnBattery: 5 * time.Minute},
+			"failed: on battery for 7m0s, expected at most 5m0s"},
+		{"onbatt", UPSLimits{MinCharge: 80, MaxOnBattery: 10 * time.Minute}, "failed: 78% charge, expected at least 80%"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.status, func(t *testing.T) {
+			t.Setenv("APCACCESS_FIXTURE", tt.fixture)
+			res := upsStatus(time.Second, tt.limits)
+			assert.Equal(t, tt.status, res.Status)
+		})
+	}
+
+	apcaccessCmd = "testdata/no-such-apcaccess"
+	res := upsStatus(time.Second, UPSLimits{})
+	assert.Contains(t, res.Status, "failed: can't run apcaccess status:")
+}
+
+func Test_parseApcaccess(t *testing.T) {
+	online, err := os.ReadFile("testdata/apcaccess_online.txt")
+	require.NoError(t, err)
+	res, err := parseApcaccess(string(online))
+	require.NoError(t, err)
+	assert.Equal(t, &UPS{Name: "ups1", Model: "Back-UPS ES 700G", State: "ONLINE", ChargePercent: 100,
+		TimeLeftMinutes: 42.5}, res)
+
+	onbatt, err := os.ReadFile("testdata/apcaccess_onbatt.txt")
+	require.NoError(t, err)
+	res, err = parseApcaccess(string(onbatt))
+	require.NoError(t, err)
+	assert.Equal(t, &UPS{Name: "ups1", Model: "Back-UPS ES 700G", State: "ONBATT", ChargePercent: 78,
+		OnBattery: true, OnBatterySeconds: 420, TimeLeftMinutes: 31.2}, res)
+
+	res, err = parseApcaccess("STATUS   : ONBATT LOWBATT\nBCHARGE  : 9.0 Percent\nTONBATT  : 1200 Seconds\n")
+	require.NoError(t, err)
+	assert.Equal(t, &UPS{State: "ONBATT LOWBATT", ChargePercent: 9, OnBattery: true, OnBatterySeconds: 1200}, res)
+
+	_, err = parseApcaccess("Error contacting host localhost port 3551: Connection refused\n")
+	require.EqualError(t, err, "no ups status in apcaccess output")
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package status
+
+import (
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// volumeUsage returns used space and inodes of the volume, inodes are from statfs Files and Ffree.
+// Filesystems without fixed inode table, i.e. btrfs, report no inodes.
+func volumeUsage(path string) (volumeStat, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return volumeStat{}, err
+	}
+	return volumeStat{usedPercent: usage.UsedPercent, inodesTotal: usage.InodesTotal, inodesUsed: usage.InodesUsed,
+		inodesUsedPercent: usage.InodesUsedPercent}, nil
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package status
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// volumeUsage returns used space of the volume in percent with GetDiskFreeSpaceEx. The path can be a drive,
+// i.e. C:\, a mounted folder or an UNC share. Used space is calculated from total free bytes, not available
+// to the caller, to match what explorer shows with quotas enabled. NTFS has no inode limit, inodes not reported.
+func volumeUsage(path string) (volumeStat, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return volumeStat{}, err
+	}
+	var freeAvailable, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &freeAvailable, &total, &totalFree); err != nil {
+		return volumeStat{}, err
+	}
+	if total == 0 {
+		return volumeStat{}, nil
+	}
+	return volumeStat{usedPercent: float64(total-totalFree) / float64(total) * 100}, nil
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package status
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_volumeUsage(t *testing.T) {
+	st, err := volumeUsage(os.Getenv("SystemDrive") + `\`)
+	require.NoError(t, err)
+	assert.True(t, st.usedPercent > 0 && st.usedPercent <= 100, st.usedPercent)
+	assert.Equal(t, uint64(0), st.inodesTotal, "no inodes on windows")
+
+	st, err = volumeUsage(t.TempDir())
+	require.NoError(t, err, "folder on the volume")
+	assert.True(t, st.usedPercent > 0 && st.usedPercent <= 100, st.usedPercent)
+
+	_, err = volumeUsage(`Q:\no\such\volume`)
+	require.Error(t, err)
+}
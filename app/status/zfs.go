@@ -0,0 +1,151 @@
+package status
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zpoolCmd is zpool binary, can be changed for tests
+var zpoolCmd = "zpool"
+
+const zfsNoErrors = "No known data errors"
+
+// zfsFailedPool is the name of the entry reporting failure of zpool itself
+const zfsFailedPool = "zpool"
+
+// ZFSPool contains zfs pool health
+type ZFSPool struct {
+	Name         string `json:"name"`
+	State        string `json:"state"`         // ONLINE, DEGRADED, FAULTED, etc.
+	Size         uint64 `json:"size"`          // bytes
+	Allocated    uint64 `json:"allocated"`     // bytes
+	Capacity     int    `json:"capacity"`      // percent
+	Errors       string `json:"errors"`        // data errors reported by zpool status
+	DeviceErrors int    `json:"device_errors"` // sum of read, write and checksum errors of all devices
+	Status       string `json:"status"`        // ok, failed for non-online pool or data errors, warn for device errors
+}
+
+// zfsPools runs zpool list and zpool status and returns health of all pools.
+// Failure to run zpool or parse its output reported as failed status of zfsFailedPool entry,
+// not as error of the whole status.
+func zfsPools(timeout time.Duration) map[string]ZFSPool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	failed := func(msg string, err error) map[string]ZFSPool {
+		return map[string]ZFSPool{zfsFailedPool: {Name: zfsFailedPool, Status: fmt.Sprintf("failed: %s: %v", msg, err)}}
+	}
+
+	listOut, err := exec.CommandContext(ctx, zpoolCmd, "list", "-H", "-p", "-o", "name,health,size,allocated,capacity").Output() //nolint:gosec
+	if err != nil {
+		return failed("can't run zpool list", err)
+	}
+	pools, err := parseZpoolList(string(listOut))
+	if err != nil {
+		return failed("can't parse zpool list", err)
+	}
+
+	statusOut, err := exec.CommandContext(ctx, zpoolCmd, "status").Output() //nolint:gosec
+	if err != nil {
+		return failed("can't run zpool status", err)
+	}
+	for name, st := range parseZpoolStatus(string(statusOut)) {
+		if p, ok := pools[name]; ok {
+			p.Errors, p.DeviceErrors = st.Errors, st.DeviceErrors
+			pools[name] = p
+		}
+	}
+
+	for name, p := range pools {
+		p.Status = "ok"
+		switch {
+		case p.State != "ONLINE":
+			p.Status = "failed: pool is " + p.State
+		case p.Errors != "" && p.Errors != zfsNoErrors:
+			p.Status = "failed: " + p.Errors
+		case p.DeviceErrors > 0:
+			p.Status = fmt.Sprintf("warn: %d device errors", p.DeviceErrors)
+		}
+		pools[name] = p
+	}
+	return pools
+}
+
+// parseZpoolList parses "zpool list -H -p -o name,health,size,allocated,capacity" output.
+// Size, allocated and capacity are "-" for unavailable pools and left as 0.
+func parseZpoolList(out string) (map[string]ZFSPool, error) {
+	res := map[string]ZFSPool{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected zpool list line %q", line)
+		}
+		p := ZFSPool{Name: fields[0], State: fields[1]}
+		p.Size, _ = strconv.ParseUint(fields[2], 10, 64)
+		p.Allocated, _ = strconv.ParseUint(fields[3], 10, 64)
+		p.Capacity, _ = strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+		res[p.Name] = p
+	}
+	return res, nil
+}
+
+// parseZpoolStatus parses "zpool status" output and returns pools with errors line and sum of device errors.
+// Device rows in config section are "NAME STATE READ WRITE CKSUM [message]", counts can be like 1.2K.
+func parseZpoolStatus(out string) map[string]ZFSPool {
+	res := map[string]ZFSPool{}
+	var curr ZFSPool
+	inConfig := false
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "pool:"):
+			if curr.Name != "" {
+				res[curr.Name] = curr
+			}
+			curr, inConfig = ZFSPool{Name: strings.TrimSpace(strings.TrimPrefix(line, "pool:"))}, false
+		case strings.HasPrefix(line, "state:"):
+			curr.State = strings.TrimSpace(strings.TrimPrefix(line, "state:"))
+		case strings.HasPrefix(line, "config:"):
+			inConfig = true
+		case strings.HasPrefix(line, "errors:"):
+			curr.Errors, inConfig = strings.TrimSpace(strings.TrimPrefix(line, "errors:")), false
+		case inConfig:
+			fields := strings.Fields(line)
+			if len(fields) < 5 || fields[0] == "NAME" {
+				continue
+			}
+			for _, f := range fields[2:5] {
+				curr.DeviceErrors += zfsCount(f)
+			}
+		}
+	}
+	if curr.Name != "" {
+		res[curr.Name] = curr
+	}
+	return res
+}
+
+// zfsCount parses error count as reported by zpool status, i.e. 12, 1.2K or 3M
+func zfsCount(s string) int {
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult, s = 1e3, strings.TrimSuffix(s, "K")
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, strings.TrimSuffix(s, "M")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int(v * mult)
+}
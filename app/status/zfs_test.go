@@ -0,0 +1,58 @@
+package status
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_zfsPools(t *testing.T) {
+	fake, err := filepath.Abs("testdata/zpool.sh")
+	require.NoError(t, err)
+	orig := zpoolCmd
+	zpoolCmd = fake
+	defer func() { zpoolCmd = orig }()
+
+	res := zfsPools(time.Second)
+	require.Equal(t, 3, len(res))
+	assert.Equal(t, ZFSPool{Name: "tank", State: "DEGRADED", Size: 3985729650688, Allocated: 2113123123200, Capacity: 53,
+		Errors: "No known data errors", Status: "failed: pool is DEGRADED"}, res["tank"])
+	assert.Equal(t, ZFSPool{Name: "backup", State: "ONLINE", Size: 1992864825344, Allocated: 398572965068, Capacity: 20,
+		Errors: "No known data errors", DeviceErrors: 3, Status: "warn: 3 device errors"}, res["backup"])
+	assert.Equal(t, ZFSPool{Name: "vault", State: "FAULTED", Errors: "2 data errors, use '-v' for a list",
+		DeviceErrors: 1212, Status: "failed: pool is FAULTED"}, res["vault"])
+
+	zpoolCmd = "testdata/no-such-zpool"
+	res = zfsPools(time.Second)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "zpool", res["zpool"].Name)
+	assert.Contains(t, res["zpool"].Status, "failed: can't run zpool list:")
+}
+
+func Test_parseZpoolList(t *testing.T) {
+	res, err := parseZpoolList("tank\tONLINE\t100\t50\t50\n\n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ZFSPool{"tank": {Name: "tank", State: "ONLINE", Size: 100, Allocated: 50, Capacity: 50}}, res)
+
+	_, err = parseZpoolList("tank ONLINE")
+	require.EqualError(t, err, `unexpected zpool list line "tank ONLINE"`)
+}
+
+func Test_parseZpoolStatus(t *testing.T) {
+	out := "  pool: tank\n state: ONLINE\nconfig:\n\n\tNAME STATE READ WRITE CKSUM\n\ttank ONLINE 0 0 0\n" +
+		"\t  sda ONLINE 1 2 3\n\nerrors: 5 data errors, use '-v' for a list\n"
+	res := parseZpoolStatus(out)
+	assert.Equal(t, map[string]ZFSPool{"tank": {Name: "tank", State: "ONLINE", Errors: "5 data errors, use '-v' for a list",
+		DeviceErrors: 6}}, res)
+	assert.Empty(t, parseZpoolStatus("no pools available\n"))
+}
+
+func Test_zfsCount(t *testing.T) {
+	tbl := map[string]int{"0": 0, "12": 12, "1.2K": 1200, "3M": 3000000, "-": 0}
+	for inp, exp := range tbl {
+		assert.Equal(t, exp, zfsCount(inp), inp)
+	}
+}